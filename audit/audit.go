@@ -0,0 +1,187 @@
+// Package audit records who changed the configuration of the server and
+// when: scenarios submitted, live sessions started, retuned and stopped,
+// archives imported. The log is append-only, as for a management-of-change
+// register.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Actions recorded in Entry.Action.
+const (
+	ActionRun           = "run"              // scenario simulated and stored in the history
+	ActionJob           = "job"              // scenario queued as a job
+	ActionLiveStart     = "live.start"       // live session started
+	ActionLiveUpdate    = "live.update"      // parameters of a live session changed
+	ActionLiveApply     = "live.apply"       // tuning suggestion applied to a live session
+	ActionLiveUndo      = "live.undo"        // last parameter change of a live session undone
+	ActionLiveRedo      = "live.redo"        // parameter change of a live session redone
+	ActionLiveSpeed     = "live.speed"       // speed of a live session changed
+	ActionLivePause     = "live.pause"       // live session paused
+	ActionLiveResume    = "live.resume"      // paused live session resumed
+	ActionLiveBreak     = "live.breakpoints" // breakpoints of a live session replaced
+	ActionLiveStop      = "live.stop"        // live session stopped
+	ActionAlarmAck      = "alarm.ack"        // alarm of a live session acknowledged
+	ActionAlarmShelve   = "alarm.shelve"     // alarm of a live session shelved
+	ActionAlarmUnshelve = "alarm.unshelve"   // alarm of a live session unshelved
+	ActionArchiveImport = "archive.import"   // archive imported
+	ActionAnnotate      = "history.annotate" // notes or tags of a stored run edited
+	ActionScheduled     = "schedule.run"     // registered scenario run by the scheduler
+)
+
+// Change is the change of one scenario parameter. Old or New is nil when
+// the parameter is not set, e.g. an output limit being added.
+type Change struct {
+	Param string   `json:"param"`
+	Old   *float64 `json:"old"`
+	New   *float64 `json:"new"`
+}
+
+// Entry is one recorded action.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Action string    `json:"action"`
+	// Target is the run, job or session the action applies to.
+	Target   string               `json:"target,omitempty"`
+	Scenario *simulation.Scenario `json:"scenario,omitempty"`
+	Changes  []Change             `json:"changes,omitempty"`
+	Detail   string               `json:"detail,omitempty"`
+}
+
+// Log keeps the entries in memory and appends each one as a JSON line to
+// its file.
+type Log struct {
+	mu      sync.RWMutex
+	f       *os.File
+	entries []Entry
+}
+
+// Open loads the entries of the log file at path, creating it if needed.
+func Open(path string) (*Log, error) {
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{f: f}
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("journal d'audit corrompu %s ligne %d : %w", path, line, err)
+		}
+		l.entries = append(l.entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Record appends an entry, timestamped now when its time is not set.
+func (l *Log) Record(e Entry) error {
+
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+// Query selects entries; zero fields do not filter.
+type Query struct {
+	User   string
+	Action string
+	Target string
+	Since  time.Time
+	Until  time.Time
+	// Limit keeps the most recent entries only.
+	Limit int
+}
+
+// Find returns the entries matching q, oldest first.
+func (l *Log) Find(q Query) []Entry {
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	out := []Entry{}
+	for _, e := range l.entries {
+		switch {
+		case q.User != "" && e.User != q.User,
+			q.Action != "" && e.Action != q.Action,
+			q.Target != "" && e.Target != q.Target,
+			!q.Since.IsZero() && e.Time.Before(q.Since),
+			!q.Until.IsZero() && !e.Time.Before(q.Until):
+			continue
+		}
+		out = append(out, e)
+	}
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[len(out)-q.Limit:]
+	}
+	return out
+}
+
+// Diff lists the numeric parameters that differ between two scenarios,
+// sorted by name.
+func Diff(old, new simulation.Scenario) []Change {
+
+	properties := simulation.ScenarioSchema().Properties
+	names := make([]string, 0, len(properties))
+	for name, prop := range properties {
+		if prop.Type == "number" || prop.Type == "integer" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	// The JSON forms tell unset optional parameters from zero ones.
+	o, n := numbers(old), numbers(new)
+	var changes []Change
+	for _, name := range names {
+		a, b := o[name], n[name]
+		if (a == nil) != (b == nil) || (a != nil && *a != *b) {
+			changes = append(changes, Change{Param: name, Old: a, New: b})
+		}
+	}
+	return changes
+}
+
+// numbers returns the numeric members of the JSON form of sc.
+func numbers(sc simulation.Scenario) map[string]*float64 {
+	b, _ := json.Marshal(sc)
+	var doc map[string]any
+	json.Unmarshal(b, &doc)
+	out := make(map[string]*float64, len(doc))
+	for name, v := range doc {
+		if x, ok := v.(float64); ok {
+			out[name] = &x
+		}
+	}
+	return out
+}
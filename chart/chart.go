@@ -0,0 +1,275 @@
+// Package chart describes plots independently of the library drawing
+// them, so that the heavy plotting dependencies stay optional. The "svg"
+// backend, written by hand, only needs the standard library; the "gonum"
+// backend of chart/gonumplot registers itself when imported and adds the
+// raster and print formats.
+package chart
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Figure is a plot: labelled axes and the series drawn on them.
+type Figure struct {
+	Title, XLabel, YLabel string
+	Style                 Style
+	// DecimalComma writes the tick labels "0,5" instead of "0.5".
+	DecimalComma bool
+	// Legend lists the labelled series at the top right.
+	Legend bool
+	// XRange and YRange fix the extent of the axes, fitted to the series
+	// when nil.
+	XRange, YRange *Range
+	Series         []Series
+}
+
+// Range is the extent of an axis.
+type Range struct {
+	Min, Max float64
+}
+
+// Style is the look of a figure. Colors are written "#rrggbb" or "#rgb";
+// the font size is in points, the title being drawn 1.2 times larger.
+type Style struct {
+	Background, Foreground string
+	FontSize               float64
+}
+
+// Kinds of series.
+const (
+	KindLine   = "line"
+	KindPoints = "points"
+	// KindBars draws a histogram: X holds the len(Y)+1 edges of the bins.
+	KindBars = "bars"
+	// KindBand shades the region between Y and Upper with Fill, drawn
+	// translucent so that the series below show through.
+	KindBand = "band"
+)
+
+// BandOpacity is the opacity of the fill of bands.
+const BandOpacity = 0.2
+
+// Series is a set of points drawn in one style.
+type Series struct {
+	// Kind is one of the kinds above, KindLine when empty.
+	Kind  string
+	Label string
+	X, Y  []float64
+	// Upper is the upper edge of a band, Y being its lower edge.
+	Upper []float64
+	// Color strokes lines, points and the outline of bars; Fill fills
+	// bars.
+	Color, Fill string
+	// Width and the dash pattern, empty for solid lines, are in points.
+	Width  float64
+	Dashes []float64
+}
+
+// Backend draws figures.
+type Backend interface {
+	// Formats lists the formats Render writes, such as "svg" or "png".
+	Formats() []string
+	// Render draws f to w in the format, on a width × height points page.
+	Render(w io.Writer, f Figure, format string, width, height float64) error
+}
+
+// Rasterizer is implemented by the backends drawing figures as images, as
+// needed by animations.
+type Rasterizer interface {
+	Raster(f Figure, width, height, dpi float64) (image.Image, error)
+}
+
+// ErrUnsupported is returned when no backend can draw a format.
+var ErrUnsupported = errors.New("format de tracé non disponible")
+
+// Preferred is the backend used when none is named and it is registered.
+const Preferred = "gonum"
+
+var backends = struct {
+	sync.RWMutex
+	byName map[string]Backend
+}{byName: map[string]Backend{}}
+
+// Register makes a backend available under name, usually from the init
+// function of the package implementing it.
+func Register(name string, b Backend) {
+	backends.Lock()
+	backends.byName[name] = b
+	backends.Unlock()
+}
+
+// Backends lists the names of the registered backends, sorted.
+func Backends() []string {
+	backends.RLock()
+	defer backends.RUnlock()
+	return slices.Sorted(maps.Keys(backends.byName))
+}
+
+// Formats returns the formats drawn by the backend name, nil when it is
+// not registered.
+func Formats(name string) []string {
+	backends.RLock()
+	defer backends.RUnlock()
+	if b, ok := backends.byName[name]; ok {
+		return b.Formats()
+	}
+	return nil
+}
+
+// Find returns the backend name, or with an empty name the Preferred one,
+// or else any backend drawing the format. The format "gif" requires a
+// Rasterizer, from whose images animations are assembled.
+func Find(name, format string) (Backend, error) {
+	backends.RLock()
+	defer backends.RUnlock()
+
+	supports := func(b Backend) bool {
+		if format == "gif" {
+			_, ok := b.(Rasterizer)
+			return ok
+		}
+		return slices.Contains(b.Formats(), format)
+	}
+	if name != "" {
+		b, ok := backends.byName[name]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("moteur de tracé inconnu %q", name)
+		case !supports(b):
+			return nil, fmt.Errorf("%w : le moteur %q ne produit pas de %s", ErrUnsupported, name, format)
+		}
+		return b, nil
+	}
+	if b, ok := backends.byName[Preferred]; ok && supports(b) {
+		return b, nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(backends.byName)) {
+		if b := backends.byName[name]; supports(b) {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("%w : aucun moteur ne produit de %s", ErrUnsupported, format)
+}
+
+// Render draws f with the backend name, see Find.
+func Render(w io.Writer, f Figure, backend, format string, width, height float64) error {
+	b, err := Find(backend, format)
+	if err != nil {
+		return err
+	}
+	return b.Render(w, f, format, width, height)
+}
+
+// Raster draws f as an image with the backend name, see Find.
+func Raster(f Figure, backend string, width, height, dpi float64) (image.Image, error) {
+	b, err := Find(backend, "gif")
+	if err != nil {
+		return nil, err
+	}
+	return b.(Rasterizer).Raster(f, width, height, dpi)
+}
+
+// ParseColor reads a "#rrggbb" or "#rgb" color.
+func ParseColor(s string) (color.RGBA, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if ok && len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if !ok || len(hex) != 6 || err != nil {
+		return color.RGBA{}, fmt.Errorf("couleur invalide %q, attendu #rrggbb", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// Color returns the color c, black when it is invalid.
+func Color(c string) color.RGBA {
+	rgba, err := ParseColor(c)
+	if err != nil {
+		return color.RGBA{A: 0xff}
+	}
+	return rgba
+}
+
+// Extent returns the ranges of the axes: XRange and YRange when set, the
+// extent of the finite values of the series otherwise, bars including
+// zero and bands both their edges.
+func (f Figure) Extent() (x, y Range) {
+	x = Range{math.Inf(1), math.Inf(-1)}
+	y = x
+	grow := func(r *Range, v float64) {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			r.Min, r.Max = math.Min(r.Min, v), math.Max(r.Max, v)
+		}
+	}
+	for _, s := range f.Series {
+		for _, v := range s.X {
+			grow(&x, v)
+		}
+		for _, v := range s.Y {
+			grow(&y, v)
+		}
+		for _, v := range s.Upper {
+			grow(&y, v)
+		}
+		if s.Kind == KindBars {
+			grow(&y, 0)
+		}
+	}
+	for _, r := range []*Range{&x, &y} {
+		switch {
+		case r.Min > r.Max:
+			*r = Range{0, 1}
+		case r.Min == r.Max:
+			r.Min, r.Max = r.Min-1, r.Max+1
+		}
+	}
+	if f.XRange != nil {
+		x = *f.XRange
+	}
+	if f.YRange != nil {
+		y = *f.YRange
+	}
+	return x, y
+}
+
+// Ticks returns about n round values spanning r, multiples of 1, 2 or 5
+// times a power of ten.
+func Ticks(r Range, n int) []float64 {
+	span := r.Max - r.Min
+	if !(span > 0) || n < 1 {
+		return nil
+	}
+	step := math.Pow(10, math.Floor(math.Log10(span/float64(n))))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if span/(m*step) <= float64(n) {
+			step *= m
+			break
+		}
+	}
+	var ticks []float64
+	for v := math.Ceil(r.Min/step) * step; v <= r.Max+step*1e-9; v += step {
+		// Snapped to the step, -0 written 0.
+		ticks = append(ticks, math.Round(v/step)*step+0)
+	}
+	return ticks
+}
+
+// Number formats a tick label with the decimal separator of the figure.
+func (f Figure) Number(v float64) string {
+	s := strconv.FormatFloat(v, 'g', 6, 64)
+	if f.DecimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
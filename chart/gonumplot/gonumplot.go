@@ -0,0 +1,213 @@
+// Package gonumplot draws chart figures with gonum.org/v1/plot. Importing
+// it registers the "gonum" backend: SVG, PNG, JPEG, TIFF, PDF and EPS, and
+// images for animations.
+package gonumplot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/font"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+func init() {
+	chart.Register("gonum", backend{})
+}
+
+type backend struct{}
+
+func (backend) Formats() []string {
+	return []string{"svg", "png", "jpg", "jpeg", "tif", "tiff", "pdf", "eps"}
+}
+
+func (backend) Render(w io.Writer, f chart.Figure, format string, width, height float64) error {
+	p, err := Plot(f)
+	if err != nil {
+		return err
+	}
+	c, err := p.WriterTo(vg.Points(width), vg.Points(height), format)
+	if err != nil {
+		return err
+	}
+	_, err = c.WriteTo(w)
+	return err
+}
+
+func (backend) Raster(f chart.Figure, width, height, dpi float64) (image.Image, error) {
+	p, err := Plot(f)
+	if err != nil {
+		return nil, err
+	}
+	c := vgimg.NewWith(vgimg.UseWH(vg.Points(width), vg.Points(height)), vgimg.UseDPI(int(dpi)))
+	p.Draw(draw.New(c))
+	return c.Image(), nil
+}
+
+// Check reports a missing font, without which the plots cannot be drawn.
+func Check() error {
+	if !font.DefaultCache.Has(plot.DefaultFont) {
+		return fmt.Errorf("police %s %s introuvable pour les graphes : vérifier la dépendance gonum.org/v1/plot/font/liberation", plot.DefaultFont.Typeface, plot.DefaultFont.Variant)
+	}
+	return nil
+}
+
+// Plot converts a figure into a gonum plot, to draw it on other canvases.
+func Plot(f chart.Figure) (*plot.Plot, error) {
+
+	p := plot.New()
+	p.Title.Text = f.Title
+	p.X.Label.Text = f.XLabel
+	p.Y.Label.Text = f.YLabel
+	p.X.Tick.Marker = ticks(f.DecimalComma)
+	p.Y.Tick.Marker = ticks(f.DecimalComma)
+	p.Legend.Top = true
+	style(p, f.Style)
+	if f.XRange != nil {
+		p.X.Min, p.X.Max = f.XRange.Min, f.XRange.Max
+	}
+	if f.YRange != nil {
+		p.Y.Min, p.Y.Max = f.YRange.Min, f.YRange.Max
+	}
+
+	for _, s := range f.Series {
+		if s.Kind == chart.KindBars {
+			h := &plotter.Histogram{FillColor: chart.Color(s.Fill)}
+			h.LineStyle.Color = chart.Color(s.Color)
+			h.LineStyle.Width = vg.Points(s.Width)
+			for i, y := range s.Y {
+				if i+1 < len(s.X) {
+					h.Bins = append(h.Bins, plotter.HistogramBin{Min: s.X[i], Max: s.X[i+1], Weight: y})
+				}
+			}
+			if len(h.Bins) > 0 {
+				h.Width = h.Bins[0].Max - h.Bins[0].Min
+			}
+			p.Add(h)
+			continue
+		}
+
+		if len(s.X) != len(s.Y) {
+			return nil, fmt.Errorf("Erreur dans le tracé, X et Y ne sont pas de la même taille")
+		}
+		if s.Kind == chart.KindBand {
+			band, err := band(s)
+			if err != nil {
+				return nil, err
+			}
+			p.Add(band)
+			if f.Legend && s.Label != "" {
+				p.Legend.Add(s.Label, band)
+			}
+			continue
+		}
+		points := make(plotter.XYs, len(s.X))
+		for i := range s.X {
+			points[i] = plotter.XY{X: s.X[i], Y: s.Y[i]}
+		}
+		var thumb plot.Thumbnailer
+		if s.Kind == chart.KindPoints {
+			scatter, err := plotter.NewScatter(points)
+			if err != nil {
+				return nil, fmt.Errorf("Erreur dans le tracé : %w", err)
+			}
+			scatter.Color = chart.Color(s.Color)
+			p.Add(scatter)
+			thumb = scatter
+		} else {
+			line, err := plotter.NewLine(points)
+			if err != nil {
+				return nil, fmt.Errorf("Erreur dans le tracé : %w", err)
+			}
+			line.Color = chart.Color(s.Color)
+			line.Width = vg.Points(s.Width)
+			for _, d := range s.Dashes {
+				line.Dashes = append(line.Dashes, vg.Points(d))
+			}
+			p.Add(line)
+			thumb = line
+		}
+		if f.Legend && s.Label != "" {
+			p.Legend.Add(s.Label, thumb)
+		}
+	}
+	return p, nil
+}
+
+// band returns the polygon of a band: along its lower edge, then back
+// along its upper edge, skipping the non-finite points.
+func band(s chart.Series) (*plotter.Polygon, error) {
+
+	if len(s.Upper) != len(s.X) {
+		return nil, fmt.Errorf("Erreur dans le tracé, X et Upper ne sont pas de la même taille")
+	}
+	var points plotter.XYs
+	for i := range s.X {
+		if finite(s.X[i]) && finite(s.Y[i]) {
+			points = append(points, plotter.XY{X: s.X[i], Y: s.Y[i]})
+		}
+	}
+	for i := len(s.X) - 1; i >= 0; i-- {
+		if finite(s.X[i]) && finite(s.Upper[i]) {
+			points = append(points, plotter.XY{X: s.X[i], Y: s.Upper[i]})
+		}
+	}
+	poly, err := plotter.NewPolygon(points)
+	if err != nil {
+		return nil, fmt.Errorf("Erreur dans le tracé : %w", err)
+	}
+	c := chart.Color(s.Fill)
+	poly.Color = color.NRGBA{R: c.R, G: c.G, B: c.B, A: uint8(chart.BandOpacity * 255)}
+	poly.LineStyle.Width = 0
+	return poly, nil
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// style colors the plot, its axes and its legend.
+func style(p *plot.Plot, s chart.Style) {
+
+	fg := chart.Color(s.Foreground)
+	size := vg.Points(s.FontSize)
+
+	p.BackgroundColor = chart.Color(s.Background)
+	p.Title.TextStyle.Color = fg
+	p.Title.TextStyle.Font.Size = size * 1.2
+	for _, a := range []*plot.Axis{&p.X, &p.Y} {
+		a.LineStyle.Color = fg
+		a.Label.TextStyle.Color = fg
+		a.Label.TextStyle.Font.Size = size
+		a.Tick.LineStyle.Color = fg
+		a.Tick.Label.Color = fg
+		a.Tick.Label.Font.Size = size * 5 / 6
+	}
+	p.Legend.TextStyle.Color = fg
+	p.Legend.TextStyle.Font.Size = size
+}
+
+// ticks returns the default ticks of the axes, with decimal commas when
+// comma is set.
+func ticks(comma bool) plot.Ticker {
+	if !comma {
+		return plot.DefaultTicks{}
+	}
+	return plot.TickerFunc(func(min, max float64) []plot.Tick {
+		ticks := plot.DefaultTicks{}.Ticks(min, max)
+		for i := range ticks {
+			ticks[i].Label = strings.Replace(ticks[i].Label, ".", ",", 1)
+		}
+		return ticks
+	})
+}
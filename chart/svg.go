@@ -0,0 +1,185 @@
+package chart
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+func init() {
+	Register("svg", svgBackend{})
+}
+
+// svgBackend writes SVG by hand, with no dependency: the figures keep
+// working in builds leaving gonum/plot out. Text is laid out with
+// approximate glyph widths, the viewer choosing the sans-serif font.
+type svgBackend struct{}
+
+func (svgBackend) Formats() []string { return []string{"svg"} }
+
+// charWidth is the mean advance of a sans-serif glyph, in ems.
+const charWidth = 0.55
+
+func (svgBackend) Render(w io.Writer, f Figure, format string, width, height float64) error {
+
+	if format != "svg" {
+		return fmt.Errorf("%w : le moteur svg ne produit pas de %s", ErrUnsupported, format)
+	}
+	fs := f.Style.FontSize
+	if fs <= 0 {
+		fs = 12
+	}
+	fg, bg := f.Style.Foreground, f.Style.Background
+	if fg == "" {
+		fg = "#000000"
+	}
+	if bg == "" {
+		bg = "#ffffff"
+	}
+
+	xr, yr := f.Extent()
+	xTicks, yTicks := Ticks(xr, 8), Ticks(yr, 6)
+	labelWidth := 0.0
+	for _, v := range yTicks {
+		labelWidth = math.Max(labelWidth, float64(len(f.Number(v)))*charWidth*fs*5/6)
+	}
+
+	// The plotting area, in points from the top left corner.
+	left := fs*1.5 + labelWidth + fs*0.8
+	right := width - fs
+	top := fs * 1.2 * 2
+	bottom := height - fs*3.2
+	if f.Title == "" {
+		top = fs
+	}
+	px := func(x float64) float64 { return left + (x-xr.Min)/(xr.Max-xr.Min)*(right-left) }
+	py := func(y float64) float64 { return bottom - (y-yr.Min)/(yr.Max-yr.Min)*(bottom-top) }
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%gpt" height="%gpt" viewBox="0 0 %g %g" font-family="sans-serif">`+"\n", width, height, width, height)
+	fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", bg)
+	text := func(x, y, size float64, anchor, extra, s string) {
+		fmt.Fprintf(b, `<text x="%.2f" y="%.2f" font-size="%.2f" fill="%s" text-anchor="%s"%s>%s</text>`+"\n", x, y, size, fg, anchor, extra, html.EscapeString(s))
+	}
+
+	if f.Title != "" {
+		text((left+right)/2, fs*1.2*1.3, fs*1.2, "middle", "", f.Title)
+	}
+	text((left+right)/2, height-fs*0.5, fs, "middle", "", f.XLabel)
+	text(fs, (top+bottom)/2, fs, "middle", fmt.Sprintf(` transform="rotate(-90 %.2f %.2f)"`, fs, (top+bottom)/2), f.YLabel)
+
+	fmt.Fprintf(b, `<path d="M%.2f %.2fV%.2fH%.2f" fill="none" stroke="%s"/>`+"\n", left, top, bottom, right, fg)
+	for _, v := range xTicks {
+		x := px(v)
+		fmt.Fprintf(b, `<path d="M%.2f %.2fv%.2f" stroke="%s"/>`+"\n", x, bottom, fs/3, fg)
+		text(x, bottom+fs*1.3, fs*5/6, "middle", "", f.Number(v))
+	}
+	for _, v := range yTicks {
+		y := py(v)
+		fmt.Fprintf(b, `<path d="M%.2f %.2fh%.2f" stroke="%s"/>`+"\n", left, y, -fs/3, fg)
+		text(left-fs/2, y+fs*0.3, fs*5/6, "end", "", f.Number(v))
+	}
+
+	fmt.Fprintf(b, `<clipPath id="area"><rect x="%.2f" y="%.2f" width="%.2f" height="%.2f"/></clipPath>`+"\n", left, top, right-left, bottom-top)
+	fmt.Fprintln(b, `<g clip-path="url(#area)">`)
+	for _, s := range f.Series {
+		stroke := s.Color
+		if stroke == "" {
+			stroke = fg
+		}
+		width := s.Width
+		if width <= 0 {
+			width = 1
+		}
+		switch s.Kind {
+		case KindBars:
+			fill := s.Fill
+			if fill == "" {
+				fill = stroke
+			}
+			for i, y := range s.Y {
+				if i+1 >= len(s.X) {
+					break
+				}
+				x0, x1, y0 := px(s.X[i]), px(s.X[i+1]), py(math.Max(yr.Min, 0))
+				fmt.Fprintf(b, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s" stroke="%s" stroke-width="%g"/>`+"\n", x0, py(y), x1-x0, y0-py(y), fill, stroke, width)
+			}
+		case KindBand:
+			var d strings.Builder
+			n := min(len(s.X), len(s.Y), len(s.Upper))
+			op := "M"
+			for i := range n {
+				if finite(s.X[i]) && finite(s.Y[i]) {
+					fmt.Fprintf(&d, "%s%.2f %.2f", op, px(s.X[i]), py(s.Y[i]))
+					op = "L"
+				}
+			}
+			for i := n - 1; i >= 0; i-- {
+				if finite(s.X[i]) && finite(s.Upper[i]) {
+					fmt.Fprintf(&d, "%s%.2f %.2f", op, px(s.X[i]), py(s.Upper[i]))
+					op = "L"
+				}
+			}
+			if d.Len() > 0 {
+				fmt.Fprintf(b, `<path d="%sZ" fill="%s" fill-opacity="%g" stroke="none"/>`+"\n", d.String(), s.Fill, BandOpacity)
+			}
+		case KindPoints:
+			for i := range min(len(s.X), len(s.Y)) {
+				if finite(s.X[i]) && finite(s.Y[i]) {
+					fmt.Fprintf(b, `<circle cx="%.2f" cy="%.2f" r="%.2f" fill="%s"/>`+"\n", px(s.X[i]), py(s.Y[i]), 2*width, stroke)
+				}
+			}
+		default:
+			var d strings.Builder
+			pen := false
+			for i := range min(len(s.X), len(s.Y)) {
+				if !finite(s.X[i]) || !finite(s.Y[i]) {
+					pen = false
+					continue
+				}
+				op := "L"
+				if !pen {
+					op, pen = "M", true
+				}
+				fmt.Fprintf(&d, "%s%.2f %.2f", op, px(s.X[i]), py(s.Y[i]))
+			}
+			dash := ""
+			if len(s.Dashes) > 0 {
+				parts := make([]string, len(s.Dashes))
+				for i, v := range s.Dashes {
+					parts[i] = fmt.Sprintf("%g", v)
+				}
+				dash = fmt.Sprintf(` stroke-dasharray="%s"`, strings.Join(parts, " "))
+			}
+			fmt.Fprintf(b, `<path d="%s" fill="none" stroke="%s" stroke-width="%g" stroke-linejoin="round"%s/>`+"\n", d.String(), stroke, width, dash)
+		}
+	}
+	fmt.Fprintln(b, `</g>`)
+
+	if f.Legend {
+		y := top + fs
+		for _, s := range f.Series {
+			if s.Label == "" {
+				continue
+			}
+			x := right - fs*2.3
+			text(x, y+fs*0.35, fs, "end", "", s.Label)
+			if s.Kind == KindBand {
+				fmt.Fprintf(b, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s" fill-opacity="%g"/>`+"\n", x+fs*0.3, y-fs*0.35, fs*1.5, fs*0.7, s.Fill, BandOpacity)
+			} else {
+				fmt.Fprintf(b, `<path d="M%.2f %.2fh%.2f" stroke="%s" stroke-width="%g"/>`+"\n", x+fs*0.3, y, fs*1.5, s.Color, math.Max(s.Width, 1))
+			}
+			y += fs * 1.4
+		}
+	}
+
+	fmt.Fprintln(b, `</svg>`)
+	return b.Flush()
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
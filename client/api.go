@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/export"
+	"github.com/Ivan69-tech/PIDControllerResponse/history"
+	"github.com/Ivan69-tech/PIDControllerResponse/jobs"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"github.com/Ivan69-tech/PIDControllerResponse/tuning"
+)
+
+// Result is the answer of Simulate.
+type Result struct {
+	Scenario simulation.Scenario `json:"scenario"`
+	simulation.Result
+}
+
+// Simulate runs a scenario and returns the full response.
+func (c *Client) Simulate(ctx context.Context, sc simulation.Scenario) (*Result, error) {
+	var res Result
+	if err := c.do(ctx, "POST", "/api/v1/simulate", sc, &res, true); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Capabilities lists the options the server supports.
+type Capabilities struct {
+	Controllers   []simulation.Option `json:"controllers"`
+	Plants        []simulation.Option `json:"plants"`
+	Solvers       []simulation.Option `json:"solvers"`
+	TuningRules   []simulation.Option `json:"tuningRules"`
+	ExportFormats []struct {
+		Name        string `json:"name"`
+		MediaType   string `json:"mediaType"`
+		Description string `json:"description"`
+	} `json:"exportFormats"`
+	// ParameterBounds are the slider ranges of the parameters, by plant.
+	ParameterBounds map[string]map[string]simulation.Bounds `json:"parameterBounds"`
+}
+
+// Capabilities returns the options the server supports.
+func (c *Client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	var caps Capabilities
+	if err := c.do(ctx, "GET", "/api/v1/capabilities", nil, &caps, true); err != nil {
+		return nil, err
+	}
+	return &caps, nil
+}
+
+// ParetoRequest is the input of Pareto. Problem.Base is ignored, the
+// scenario is sent separately.
+type ParetoRequest struct {
+	Scenario   simulation.Scenario `json:"scenario"`
+	Objectives []string            `json:"objectives,omitempty"`
+	Samples    int                 `json:"samples,omitempty"`
+	Seed       uint64              `json:"seed,omitempty"`
+	tuning.Problem
+}
+
+// ParetoResult is the answer of Pareto.
+type ParetoResult struct {
+	Objectives []string           `json:"objectives"`
+	Evaluated  int                `json:"evaluated"`
+	Front      []tuning.Candidate `json:"front"`
+}
+
+// Pareto searches the gains trading off the requested objectives. When the
+// constraints cannot be met the error is an *Error with status 422 and the
+// report set.
+func (c *Client) Pareto(ctx context.Context, req ParetoRequest) (*ParetoResult, error) {
+	var res ParetoResult
+	if err := c.do(ctx, "POST", "/api/v1/tuning/pareto", req, &res, true); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GeneticRequest is the input of Genetic. Every option is sent, so start
+// from tuning.DefaultGAOptions() rather than the zero value.
+type GeneticRequest struct {
+	Scenario simulation.Scenario `json:"scenario"`
+	tuning.Problem
+	tuning.GAOptions
+}
+
+// GeneticResult is the answer of Genetic.
+type GeneticResult struct {
+	Best        tuning.Candidate    `json:"best"`
+	Generations []tuning.Generation `json:"generations"`
+}
+
+// Genetic tunes the gains with the genetic algorithm.
+func (c *Client) Genetic(ctx context.Context, req GeneticRequest) (*GeneticResult, error) {
+	var res GeneticResult
+	if err := c.do(ctx, "POST", "/api/v1/tuning/ga", req, &res, true); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ExportPLC translates gains into the parameters of a vendor PID block.
+func (c *Client) ExportPLC(ctx context.Context, format string, P, Ki, Kd, dt float64) (*export.PLCParameters, error) {
+	in := map[string]any{"format": format, "P": P, "Ki": Ki, "Kd": Kd, "dt": dt}
+	var params export.PLCParameters
+	if err := c.do(ctx, "POST", "/api/v1/export/plc", in, &params, true); err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+// JobResult is the result of a finished simulation job.
+type JobResult struct {
+	RunID     string             `json:"runId"`
+	ResultURL string             `json:"resultUrl"`
+	Metrics   simulation.Metrics `json:"metrics"`
+}
+
+// Job is a queued simulation.
+type Job struct {
+	jobs.Job
+	Result *JobResult `json:"result,omitempty"`
+}
+
+// SubmitJob queues a simulation. The webhook may be nil.
+func (c *Client) SubmitJob(ctx context.Context, sc simulation.Scenario, webhook *jobs.Webhook) (*Job, error) {
+	in := map[string]any{"scenario": sc}
+	if webhook != nil {
+		// Webhook.MarshalJSON hides the secret, send it explicitly.
+		in["webhook"] = map[string]string{"url": webhook.URL, "secret": webhook.Secret}
+	}
+	var job Job
+	if err := c.do(ctx, "POST", "/api/v1/jobs", in, &job, false); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Job returns the current state of a job.
+func (c *Client) Job(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, "GET", "/api/v1/jobs/"+url.PathEscape(id), nil, &job, true); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// WaitJob polls a job every interval until it is done or failed.
+func (c *Client) WaitJob(ctx context.Context, id string, interval time.Duration) (*Job, error) {
+	for {
+		job, err := c.Job(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status == jobs.Done || job.Status == jobs.Failed {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RunSummary is a stored run without its series.
+type RunSummary struct {
+	ID       string              `json:"id"`
+	Created  time.Time           `json:"created"`
+	Scenario simulation.Scenario `json:"scenario"`
+}
+
+// History lists the stored runs, newest first.
+func (c *Client) History(ctx context.Context) ([]RunSummary, error) {
+	var list []RunSummary
+	if err := c.do(ctx, "GET", "/api/v1/history", nil, &list, true); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Run returns a stored run with its series.
+func (c *Client) Run(ctx context.Context, id string) (*history.Run, error) {
+	var run history.Run
+	if err := c.do(ctx, "GET", "/api/v1/history/"+url.PathEscape(id), nil, &run, true); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
@@ -0,0 +1,182 @@
+// Package client drives a regulation server over its HTTP API, so that
+// other Go programs can run simulations, tuning searches and jobs with
+// typed values instead of hand-written JSON.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Client calls one regulation server. Its methods are safe for concurrent
+// use.
+type Client struct {
+	// BaseURL is the scheme and host of the server, e.g.
+	// "http://localhost:2222".
+	BaseURL string
+	// HTTPClient sends the requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// Retries is the number of extra attempts made after a network error
+	// or a 429, 502, 503 or 504 answer, with doubling delays starting at
+	// Backoff, or the delay the server asks in Retry-After when longer.
+	Retries int
+	Backoff time.Duration
+}
+
+// New returns a client for the server at baseURL with three retries.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Retries: 3,
+		Backoff: 200 * time.Millisecond,
+	}
+}
+
+// Error is a non-2xx answer of the server.
+type Error struct {
+	StatusCode int
+	// Code is the machine-readable cause, such as "VALIDATION" or
+	// "OVERLOAD"; see GET /api/v1/errors.
+	Code    string
+	Message string
+	// Details lists the invalid fields of a rejected scenario.
+	Details []schema.Error
+	// Report is the infeasibility report of a tuning search, as sent.
+	Report json.RawMessage
+	// RetryAfter is the delay asked by a busy server (429) before trying
+	// again.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("regulation: %d %s", e.StatusCode, e.Message)
+}
+
+// retryable reports whether an answer with this status may succeed later.
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do sends in as JSON (when not nil) and decodes the answer into out (when
+// not nil). Requests are retried only when idempotent is set or when the
+// server answered 429 or 503, which mean the request was not processed.
+func (c *Client) do(ctx context.Context, method, path string, in, out any, idempotent bool) error {
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	delay := c.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := c.send(ctx, method, path, body, out)
+		if err == nil || attempt >= c.Retries {
+			return err
+		}
+		wait := delay
+		var apiErr *Error
+		if errors.As(err, &apiErr) {
+			notProcessed := apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable
+			if !retry || (!idempotent && !notProcessed) {
+				return err
+			}
+			wait = max(wait, apiErr.RetryAfter)
+		} else if !idempotent || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out any) (retry bool, err error) {
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return false, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// NaN and infinities, as in diverged runs, come as strings read back
+	// by numfmt.UnmarshalJSON.
+	req.Header.Set("Accept", "application/json; nonfinite=string")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return retryable(resp.StatusCode), readError(resp)
+	}
+	if out == nil {
+		return false, nil
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+	if err := numfmt.UnmarshalJSON(raw, out); err != nil {
+		return false, fmt.Errorf("regulation: réponse illisible : %w", err)
+	}
+	return false, nil
+}
+
+// readError builds an Error from a problem+json or plain-text error answer.
+func readError(resp *http.Response) error {
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	var doc struct {
+		Code    string          `json:"code"`
+		Detail  string          `json:"detail"`
+		Details []schema.Error  `json:"details"`
+		Report  json.RawMessage `json:"report"`
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") && json.Unmarshal(raw, &doc) == nil {
+		apiErr.Code, apiErr.Message, apiErr.Details, apiErr.Report = doc.Code, doc.Detail, doc.Details, doc.Report
+	} else {
+		apiErr.Message = strings.TrimSpace(string(raw))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"net/http"
+	"strconv"
+)
+
+// accuracyHandler answers the dt-halving study of the posted scenario:
+// ?levels= halvings of its time step (3 by default), the observed
+// convergence order and the time step reaching ?tolerance=, in units of
+// the measurement, 0.1 % of the setpoint by default.
+func accuracyHandler(w http.ResponseWriter, r *http.Request) {
+
+	q := r.URL.Query()
+	levels := 3
+	if s := q.Get("levels"); s != "" {
+		var err error
+		if levels, err = strconv.Atoi(s); err != nil {
+			httpError(w, fmt.Sprintf("Paramètre levels invalide %q", s), http.StatusBadRequest)
+			return
+		}
+	}
+	tolerance := 0.0
+	if s := q.Get("tolerance"); s != "" {
+		var err error
+		if tolerance, err = numfmt.Parse(s); err != nil {
+			httpError(w, fmt.Sprintf("Paramètre tolerance invalide %q", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	// Level i runs 2^i times the iterations of the scenario.
+	release, ok := admission.admit(w, stepCost(sc, 1, 1<<(min(max(levels, 0), 30)+1)-1))
+	if !ok {
+		return
+	}
+	defer release()
+
+	a, err := sc.Accuracy(levels, tolerance)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, a.Rounded())
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// admissionControl sheds simulation work beyond the capacity of the
+// server instead of letting requests pile up. Work is counted in steps:
+// iterations × plants × candidates.
+type admissionControl struct {
+	mu sync.Mutex
+	// capacity is the number of steps allowed in flight at once, and
+	// perRequest the cost above which a request is refused outright; zero
+	// disables the check.
+	capacity   int64
+	perRequest int64
+
+	inFlight int64
+	running  int
+	// rate is a moving average of the steps run per second, for the
+	// Retry-After estimate.
+	rate float64
+}
+
+var admission = &admissionControl{}
+
+// initialRate is the throughput assumed until requests have been measured.
+const initialRate = 1e6
+
+// stepCost returns the cost of simulating sc for each of plants plants and
+// candidates candidate gains.
+func stepCost(sc simulation.Scenario, plants, candidates int) int64 {
+	return int64(math.Max(sc.N, 1)) * int64(max(plants, 1)) * int64(max(candidates, 1))
+}
+
+// shed describes a request refused by admission control.
+type shed struct {
+	// Status is 413 for a request too large to ever run, 429 when the
+	// server is busy; RetryAfter is then set, in seconds. Extra holds the
+	// problem members describing the load.
+	Status     int
+	RetryAfter int
+	Detail     string
+	Extra      map[string]any
+}
+
+// tooLarge checks cost against the per-request limit alone, for work that
+// is queued rather than run at once.
+func (a *admissionControl) tooLarge(cost int64) *shed {
+	if a.perRequest > 0 && cost > a.perRequest {
+		return &shed{
+			Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("Requête trop coûteuse : %d pas de simulation pour %d autorisés, réduire N, le nombre de procédés ou de candidats", cost, a.perRequest),
+			Extra:  map[string]any{"costSteps": cost, "maxRequestSteps": a.perRequest},
+		}
+	}
+	return nil
+}
+
+// reserve reserves cost steps. release must be called once the work is
+// done, unless the request is shed.
+func (a *admissionControl) reserve(cost int64) (release func(), refused *shed) {
+
+	if refused := a.tooLarge(cost); refused != nil {
+		return nil, refused
+	}
+	a.mu.Lock()
+	// A request alone is always admitted, so that one larger than the
+	// capacity can still run on an idle server.
+	if a.capacity > 0 && a.running > 0 && a.inFlight+cost > a.capacity {
+		rate := a.rate
+		if rate == 0 {
+			rate = initialRate
+		}
+		retry := max(1, int(math.Ceil(float64(a.inFlight+cost-a.capacity)/rate)))
+		refused := &shed{Status: http.StatusTooManyRequests, RetryAfter: retry, Detail: "Serveur saturé, réessayer plus tard", Extra: map[string]any{
+			"queueDepth":    a.running,
+			"inFlightSteps": a.inFlight,
+			"capacitySteps": a.capacity,
+			"retryAfter":    retry,
+		}}
+		a.mu.Unlock()
+		return nil, refused
+	}
+	a.inFlight += cost
+	a.running++
+	a.mu.Unlock()
+
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start).Seconds()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.inFlight -= cost
+		a.running--
+		if elapsed > 0 {
+			// Concurrent requests share the CPUs, so the rate measured by
+			// one request underestimates the server's.
+			rate := float64(cost) / elapsed * float64(a.running+1)
+			if a.rate == 0 {
+				a.rate = rate
+			} else {
+				a.rate = 0.8*a.rate + 0.2*rate
+			}
+		}
+	}, nil
+}
+
+// admit is reserve for HTTP handlers: a shed request has been answered
+// and ok is false.
+func (a *admissionControl) admit(w http.ResponseWriter, cost int64) (release func(), ok bool) {
+	release, refused := a.reserve(cost)
+	if refused != nil {
+		refused.write(w)
+		return nil, false
+	}
+	return release, true
+}
+
+func (s *shed) write(w http.ResponseWriter) {
+	if s.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(s.RetryAfter))
+	}
+	code := codeOverload
+	if s.Status == http.StatusRequestEntityTooLarge {
+		code = codeTooLarge
+	}
+	writeProblem(w, s.Status, code, s.Detail, s.Extra)
+}
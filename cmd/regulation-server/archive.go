@@ -0,0 +1,247 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/audit"
+	"github.com/Ivan69-tech/PIDControllerResponse/history"
+	"github.com/Ivan69-tech/PIDControllerResponse/live"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// archiveVersion is the format version written in archive manifests.
+const archiveVersion = 1
+
+// maxArchiveSize bounds the size of an imported archive.
+const maxArchiveSize = 256 << 20
+
+// archiveManifest is manifest.json, the first entry of an archive. The
+// other entries are history/<id>.json, one stored run each,
+// live/<id>.json, the configuration of one live session, and
+// scenarios/<name>.json, one scenario registered by -schedule each.
+// Omitted lists what the archive leaves out, and why.
+type archiveManifest struct {
+	Version   int       `json:"version"`
+	Created   time.Time `json:"created"`
+	Runs      int       `json:"runs"`
+	Live      int       `json:"live"`
+	Scenarios int       `json:"scenarios"`
+	Omitted   []string  `json:"omitted,omitempty"`
+}
+
+// archiveOmitted is the Omitted list of the manifests written.
+var archiveOmitted = []string{
+	"préréglages : intégrés au binaire, identiques sur toute instance de même version",
+}
+
+// liveConfig is the configuration of a live session, as started and in an
+// archive.
+type liveConfig struct {
+	Scenario    simulation.Scenario     `json:"scenario"`
+	Sinks       []live.SinkConfig       `json:"sinks,omitempty"`
+	Alarms      []live.AlarmConfig      `json:"alarms,omitempty"`
+	Breakpoints []live.BreakpointConfig `json:"breakpoints,omitempty"`
+	KPIWindows  []float64               `json:"kpiWindows,omitempty"`
+	// Speed is the factor by which the session runs faster than real
+	// time, 1 when zero.
+	Speed float64 `json:"speed,omitempty"`
+}
+
+// exportArchiveHandler writes the stored runs, the configuration of the
+// running live sessions and the registered scenarios as a zip archive.
+func exportArchiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	list := runs.List()
+	scheduler.Lock()
+	entries := slices.Clone(scheduler.entries)
+	scheduler.Unlock()
+	sessions.Lock()
+	configs := make(map[string]liveConfig, len(sessions.byID))
+	for id, s := range sessions.byID {
+		cfg := sessions.configs[id]
+		cfg.Scenario, _ = s.Snapshot()
+		cfg.Speed = s.Speed()
+		configs[id] = cfg
+	}
+	sessions.Unlock()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name string, v any) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		b, _, err := numfmt.MarshalJSON(v, numfmt.NonFiniteString)
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		json.Indent(&out, b, "", "  ")
+		out.WriteByte('\n')
+		_, err = out.WriteTo(f)
+		return err
+	}
+
+	created := time.Now().UTC()
+	err := add("manifest.json", archiveManifest{
+		Version: archiveVersion, Created: created,
+		Runs: len(list), Live: len(configs), Scenarios: len(entries),
+		Omitted: archiveOmitted,
+	})
+	for _, run := range list {
+		if err == nil {
+			err = add("history/"+run.ID+".json", run)
+		}
+	}
+	ids := make([]string, 0, len(configs))
+	for id := range configs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		if err == nil {
+			err = add("live/"+id+".json", configs[id])
+		}
+	}
+	for _, e := range entries {
+		if err == nil {
+			err = add("scenarios/"+e.Name+".json", e)
+		}
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		httpError(w, "Erreur lors de la création de l'archive", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=regulation-%s.zip", created.Format("20060102-150405")))
+	w.Write(buf.Bytes())
+}
+
+// importArchiveHandler loads an archive written by exportArchiveHandler.
+// Runs whose ID is already stored are skipped. The live sessions are
+// started again unless ?live=false; a session that cannot be started, e.g.
+// because its sink is unreachable from this instance, is reported without
+// failing the import. The registered scenarios are kept for reference
+// only: the schedule of an instance is that of its -schedule file.
+func importArchiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxArchiveSize))
+	if err != nil {
+		httpError(w, "Erreur lors de la lecture de la requête", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		httpError(w, "L'archive n'est pas un fichier zip valide", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	var manifest archiveManifest
+	if err := readArchiveFile(zr, "manifest.json", &manifest); err != nil {
+		httpError(w, "Manifeste de l'archive illisible : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if manifest.Version != archiveVersion {
+		httpError(w, fmt.Sprintf("Version d'archive %d non prise en charge", manifest.Version), http.StatusBadRequest)
+		return
+	}
+
+	report := struct {
+		Runs    int      `json:"runs"`
+		Skipped int      `json:"skipped"`
+		Live    []string `json:"live"`
+		Errors  []string `json:"errors"`
+	}{Live: []string{}, Errors: []string{}}
+	startLive := r.URL.Query().Get("live") != "false"
+
+	for _, f := range zr.File {
+		dir, name := path.Split(f.Name)
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		switch dir {
+		case "history/":
+			var run history.Run
+			if err := readArchiveFile(zr, f.Name, &run); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s : %v", f.Name, err))
+				continue
+			}
+			if _, exists := runs.Get(run.ID); exists {
+				report.Skipped++
+				continue
+			}
+			if err := runs.Add(&run); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s : %v", f.Name, err))
+				continue
+			}
+			report.Runs++
+
+		case "live/":
+			if !startLive {
+				continue
+			}
+			var cfg liveConfig
+			if err := readArchiveFile(zr, f.Name, &cfg); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s : %v", f.Name, err))
+				continue
+			}
+			raw, _ := json.Marshal(cfg.Scenario)
+			sc, errs, err := parseScenario(raw, "")
+			if err == nil && len(errs) > 0 {
+				err = fmt.Errorf("%s %s", errs[0].Path, errs[0].Message)
+			}
+			if errs := cfg.check(); err == nil && len(errs) > 0 {
+				err = fmt.Errorf("%s %s", errs[0].Path, errs[0].Message)
+			}
+			var s *live.Session
+			if err == nil {
+				cfg.Scenario = sc
+				s, _, err = startSession(cfg, time.Now())
+			}
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s : %v", f.Name, err))
+				continue
+			}
+			report.Live = append(report.Live, s.ID)
+		}
+	}
+
+	recordAudit(r, audit.Entry{
+		Action: audit.ActionArchiveImport,
+		Detail: fmt.Sprintf("%d simulation(s) importée(s), %d ignorée(s), %d session(s) relancée(s), %d erreur(s)", report.Runs, report.Skipped, len(report.Live), len(report.Errors)),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, report)
+}
+
+// readArchiveFile decodes the JSON entry name of the archive into v.
+func readArchiveFile(zr *zip.Reader, name string, v any) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	return numfmt.UnmarshalJSON(b, v)
+}
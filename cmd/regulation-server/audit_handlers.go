@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/Ivan69-tech/PIDControllerResponse/audit"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var auditLog *audit.Log
+
+// requestUser names the author of a request: the user authenticated by a
+// reverse proxy in X-Forwarded-User, or else the client address.
+func requestUser(r *http.Request) string {
+	if user := r.Header.Get("X-Forwarded-User"); user != "" {
+		return user
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "anonyme@" + host
+}
+
+// recordAudit appends an entry on behalf of the author of r. A failure is
+// logged but does not fail the request.
+func recordAudit(r *http.Request, e audit.Entry) {
+	if auditLog == nil {
+		return
+	}
+	e.User = requestUser(r)
+	if err := auditLog.Record(e); err != nil {
+		log.Println("Enregistrement dans le journal d'audit impossible :", err)
+	}
+}
+
+// auditHandler lists the audit entries, oldest first, filtered by the
+// optional user, action, target, since, until (RFC 3339) and limit
+// parameters.
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	q := audit.Query{
+		User:   query.Get("user"),
+		Action: query.Get("action"),
+		Target: query.Get("target"),
+	}
+	for name, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if v := query.Get(name); v != "" {
+			var err error
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				httpError(w, "Paramètre "+name+" invalide, date RFC 3339 attendue", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			httpError(w, "Paramètre limit invalide", http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, auditLog.Find(q))
+}
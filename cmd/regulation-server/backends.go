@@ -0,0 +1,11 @@
+//go:build !nogonum
+
+package main
+
+import "github.com/Ivan69-tech/PIDControllerResponse/chart/gonumplot"
+
+// The gonum backend draws PNG plots and animations; builds tagged nogonum
+// leave it out and only serve SVG from the hand-rolled backend.
+func init() {
+	backendChecks = append(backendChecks, gonumplot.Check)
+}
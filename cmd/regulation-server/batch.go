@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+type batchRequest struct {
+	Scenarios []json.RawMessage `json:"scenarios"`
+}
+
+// batchLine is one record of the /api/v1/batch stream: the result of the
+// scenario at Index in the request.
+type batchLine struct {
+	Index int `json:"index"`
+	simulationResult
+}
+
+// batchError is the record of the /api/v1/batch stream standing for the
+// result at Index when it could not be encoded.
+type batchError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// batchHandler runs several scenarios concurrently and streams each result
+// as one NDJSON line as soon as it is computed, so that curves can be drawn
+// while the others run. Lines come in completion order, not request order.
+// Once the client is gone no further scenario is started.
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+	if len(req.Scenarios) == 0 {
+		httpError(w, "Au moins un scénario attendu", http.StatusBadRequest)
+		return
+	}
+
+	scenarios := make([]simulation.Scenario, len(req.Scenarios))
+	var cost int64
+	for i, raw := range req.Scenarios {
+		sc, ok := decodeScenario(w, raw, "/scenarios/"+strconv.Itoa(i))
+		if !ok {
+			return
+		}
+		scenarios[i] = sc
+		cost += stepCost(sc, 1, 1)
+	}
+	release, ok := admission.admit(w, cost)
+	if !ok {
+		return
+	}
+	defer release()
+
+	indexes := make(chan int)
+	done := make(chan batchLine)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(scenarios)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				res := scenarios[i].Run().Rounded()
+				done <- batchLine{Index: i, simulationResult: simulationResult{Scenario: scenarios[i], Result: res}}
+			}
+		}()
+	}
+	go func() {
+	dispatch:
+		for i := range scenarios {
+			select {
+			case indexes <- i:
+			case <-r.Context().Done():
+				break dispatch
+			}
+		}
+		close(indexes)
+		wg.Wait()
+		close(done)
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	rc := http.NewResponseController(w)
+	enc := newJSONEncoder(w, r)
+	for line := range done {
+		// The scenarios running when the client went away still finish;
+		// their lines are simply discarded.
+		err := enc.Encode(line)
+		if err != nil {
+			err = enc.Encode(batchError{Index: line.Index, Error: "Résultat impossible à encoder : " + err.Error()})
+		}
+		if err == nil {
+			rc.Flush()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/export"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// ExportFormat describes an output format offered by the server.
+type ExportFormat struct {
+	Name        string `json:"name"`
+	MediaType   string `json:"mediaType"`
+	Description string `json:"description"`
+}
+
+// exportFormats lists the formats results can be downloaded in.
+var exportFormats = []ExportFormat{
+	{Name: "json", MediaType: "application/json", Description: "Réponse de /sendData (temps X et mesure Y)"},
+	{Name: "plot/svg", MediaType: "image/svg+xml", Description: "Graphe de la consigne et de la mesure, selon la langue et le thème choisis"},
+	{Name: "plot/png", MediaType: "image/png", Description: "Graphe de la consigne et de la mesure en image matricielle"},
+	{Name: "plot/gif", MediaType: "image/gif", Description: "Animation de la réponse : tracé au fil du temps ou balayage d'un paramètre"},
+	{Name: "report/html", MediaType: "text/html", Description: "Rapport comparant des simulations enregistrées : réponses superposées, paramètres et indicateurs"},
+	{Name: "report/pdf", MediaType: "application/pdf", Description: "Rapport de comparaison au format A4 paysage"},
+	{Name: "matlab", MediaType: "text/x-matlab", Description: "Script MATLAB ou Octave : régulateur, modèle du procédé et séries de la simulation, avec les entrées des blocs From Workspace de Simulink"},
+}
+
+func init() {
+	for _, name := range slices.Sorted(maps.Keys(export.PLCFormats)) {
+		exportFormats = append(exportFormats, ExportFormat{
+			Name:        "plc/" + name,
+			MediaType:   "application/json, text/csv",
+			Description: export.PLCFormats[name],
+		})
+	}
+}
+
+// Capabilities is the document served by /api/v1/capabilities.
+type Capabilities struct {
+	Setpoint      *schema.Schema      `json:"setpoint"`
+	Controllers   []simulation.Option `json:"controllers"`
+	Plants        []simulation.Option `json:"plants"`
+	Solvers       []simulation.Option `json:"solvers"`
+	TuningRules   []simulation.Option `json:"tuningRules"`
+	ExportFormats []ExportFormat      `json:"exportFormats"`
+	// PlotThemes are the themes of /api/v1/plot, by name.
+	PlotThemes map[string]simulation.Theme `json:"plotThemes"`
+	// ParameterBounds are the slider ranges of the numeric parameters, by
+	// plant.
+	ParameterBounds map[string]map[string]simulation.Bounds `json:"parameterBounds"`
+	// PlotBackends are the formats drawn by each backend of ?backend=.
+	PlotBackends map[string][]string `json:"plotBackends"`
+	// GridEvents are the predefined grid events of the electrical system.
+	GridEvents []simulation.GridEventPreset `json:"gridEvents"`
+}
+
+func capabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+
+	response := Capabilities{
+		Setpoint:        simulation.Setpoint,
+		Controllers:     simulation.Controllers,
+		Plants:          simulation.Plants,
+		Solvers:         simulation.Solvers,
+		TuningRules:     simulation.TuningRules,
+		ExportFormats:   exportFormats,
+		PlotThemes:      simulation.Themes,
+		ParameterBounds: simulation.ParameterBounds,
+		PlotBackends:    map[string][]string{},
+		GridEvents:      simulation.GridEvents,
+	}
+	for _, name := range chart.Backends() {
+		response.PlotBackends[name] = chart.Formats(name)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, response)
+}
+
+func scenarioSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	writeJSON(w, r, simulation.ScenarioSchema())
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"compress/gzip"
+	"github.com/Ivan69-tech/PIDControllerResponse/ws"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// compressibleTypes are the media types worth compressing: the JSON, CSV
+// and NDJSON series and SVG plots compress several times over. Raster
+// images and archives are already compressed.
+var compressibleTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"application/x-ndjson",
+	"application/javascript",
+	"image/svg+xml",
+	"text/",
+}
+
+var gzipWriters = sync.Pool{New: func() any {
+	gz, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+	return gz
+}}
+
+// compress gzips the responses of compressible types for clients sending
+// Accept-Encoding: gzip. WebSocket upgrades and range requests, whose
+// offsets refer to the uncompressed content, pass through untouched.
+func compress(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ws.IsUpgrade(r) || r.Header.Get("Range") != "" || !acceptsGzip(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.close()
+		h.ServeHTTP(gw, r)
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, q, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.TrimSpace(name) == "gzip" && strings.TrimSpace(q) != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter decides on the first write, from the content type set by the
+// handler, whether to compress.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return
+	}
+	contentType := header.Get("Content-Type")
+	for _, t := range compressibleTypes {
+		if strings.HasPrefix(contentType, t) {
+			header.Set("Content-Encoding", "gzip")
+			header.Del("Content-Length")
+			// The compressed bytes differ from those the strong ETag
+			// stands for.
+			if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+				header.Set("ETag", "W/"+etag)
+			}
+			w.gz = gzipWriters.Get().(*gzip.Writer)
+			w.gz.Reset(w.ResponseWriter)
+			return
+		}
+	}
+}
+
+func (w *gzipWriter) WriteHeader(status int) {
+	// Answers without a body must not announce an encoding.
+	if status != http.StatusNoContent && status != http.StatusNotModified {
+		w.decide()
+	}
+	w.decided = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	w.decide()
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.gz.Write(b)
+}
+
+// Flush sends what has been compressed so far, for streamed answers.
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer.
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(nil)
+	gzipWriters.Put(w.gz)
+	w.gz = nil
+}
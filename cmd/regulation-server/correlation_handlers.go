@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+)
+
+// correlationRequest is either a scenario, whose injected disturbance is
+// the driven load disturbance or else the inflow of the level plant, or
+// uploaded series.
+type correlationRequest struct {
+	Scenario    json.RawMessage `json:"scenario"`
+	Time        []float64       `json:"time"`
+	Disturbance []float64       `json:"disturbance"`
+	PV          []float64       `json:"pv"`
+	OP          []float64       `json:"op"`
+	// MaxLag is in seconds, a quarter of the series when absent.
+	MaxLag *float64 `json:"maxLag"`
+}
+
+// correlationHandler answers the cross-correlation between a disturbance
+// and the PV and OP of a loop, to quantify the potential of a feedforward.
+func correlationHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req correlationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	if req.Scenario != nil {
+		sc, ok := decodeScenario(w, req.Scenario, "/scenario")
+		if !ok {
+			return
+		}
+		driven := sc.Drive != nil && sc.Drive.Disturbance != nil
+		if !driven && sc.Plant != simulation.PlantLevel {
+			httpError(w, "Le scénario n'injecte pas de perturbation : ni drive.disturbance ni procédé level au débit entrant perturbé", http.StatusBadRequest)
+			return
+		}
+		release, ok := admission.admit(w, stepCost(sc, 1, 1))
+		if !ok {
+			return
+		}
+		res := sc.Run()
+		release()
+		req.Time, req.Disturbance, req.PV, req.OP = res.Time, res.Inflow, res.PV, res.U
+		if driven {
+			req.Disturbance = res.Disturbance
+		}
+	}
+
+	maxLag := 0.0
+	if req.MaxLag != nil {
+		maxLag = *req.MaxLag
+	} else if len(req.Time) > 0 {
+		maxLag = (req.Time[len(req.Time)-1] - req.Time[0]) / 4
+	}
+	c, err := simulation.CrossCorrelation(req.Time, req.Disturbance, req.PV, req.OP, maxLag)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, c.Rounded())
+}
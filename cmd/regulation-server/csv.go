@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"net/http"
+)
+
+// csvDialect reads the dialect of a CSV export: ?locale=fr gives that of
+// French spreadsheets, semicolons, decimal commas and dates they read,
+// which ?delimiter=comma|semicolon|tab, ?decimal=point|comma and
+// ?timestamp=rfc3339|datetime|unix override one by one.
+func csvDialect(r *http.Request) (numfmt.CSV, error) {
+
+	q := r.URL.Query()
+	var f numfmt.CSV
+	switch locale := q.Get("locale"); locale {
+	case "", "en":
+	case "fr":
+		f = numfmt.FrenchCSV
+	default:
+		return f, fmt.Errorf("langue inconnue %q, attendu fr ou en", locale)
+	}
+	switch d := q.Get("delimiter"); d {
+	case "":
+	case "comma":
+		f.Comma = ','
+	case "semicolon":
+		f.Comma = ';'
+	case "tab":
+		f.Comma = '\t'
+	default:
+		return f, fmt.Errorf("séparateur de colonnes inconnu %q, attendu comma, semicolon ou tab", d)
+	}
+	switch d := q.Get("decimal"); d {
+	case "":
+	case "point":
+		f.Decimal = '.'
+	case "comma":
+		f.Decimal = ','
+	default:
+		return f, fmt.Errorf("séparateur décimal inconnu %q, attendu point ou comma", d)
+	}
+	if t := q.Get("timestamp"); t != "" {
+		f.Time = t
+	}
+	return f, f.Check()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/datasource"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"mime"
+	"net/http"
+)
+
+// fetchLoopData decodes {"source": {...}} and pulls the loop data. On
+// failure the request has been answered and ok is false.
+func fetchLoopData(w http.ResponseWriter, r *http.Request) (data datasource.LoopData, ok bool) {
+
+	var req struct {
+		Source datasource.Source `json:"source"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return data, false
+	}
+
+	data, err := req.Source.Fetch(r.Context())
+	if err != nil {
+		// Only a remote source is upstream: inline data that does not
+		// parse is an invalid request.
+		status := http.StatusBadRequest
+		switch {
+		case !req.Source.Remote():
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusGatewayTimeout
+		default:
+			status = http.StatusBadGateway
+		}
+		httpError(w, "Import des données impossible : "+err.Error(), status)
+		return data, false
+	}
+	return data, true
+}
+
+// loopDataHandler pulls loop data from a source and returns it normalized.
+func loopDataHandler(w http.ResponseWriter, r *http.Request) {
+
+	data, ok := fetchLoopData(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, data)
+}
+
+// identifyHandler fits a first-order model to the OP → PV data of a source.
+func identifyHandler(w http.ResponseWriter, r *http.Request) {
+
+	data, ok := fetchLoopData(w, r)
+	if !ok {
+		return
+	}
+	if data.OP == nil || data.PV == nil {
+		httpError(w, "Les séries OP et PV sont nécessaires à l'identification", http.StatusBadRequest)
+		return
+	}
+
+	model, err := simulation.IdentifyFirstOrder(data.Time, data.OP, data.PV)
+	if err != nil {
+		writeProblem(w, http.StatusUnprocessableEntity, codeUnsupportedModel, err.Error(), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{
+		"model":   model,
+		"samples": len(data.Time),
+	})
+}
+
+// driveHandler turns a recorded setpoint and disturbance profile into the
+// "drive" member of a scenario. The body is either {"source": {...}} or
+// the CSV itself, sent as text/csv, with the columns time, sp and
+// disturbance. The interpolation and extrapolation parameters choose how
+// the profiles are read between and beyond the samples.
+func driveHandler(w http.ResponseWriter, r *http.Request) {
+
+	var data datasource.LoopData
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		var err error
+		data, err = datasource.ParseCSV(r.Body, datasource.DefaultColumns)
+		if err != nil {
+			httpError(w, "Import des données impossible : "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		var ok bool
+		data, ok = fetchLoopData(w, r)
+		if !ok {
+			return
+		}
+	}
+
+	drive, err := simulation.DriveFromSeries(data.Time, data.SP, data.Disturbance)
+	if err != nil {
+		httpError(w, "Profil inutilisable : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	if err := drive.Shape(q.Get("interpolation"), q.Get("extrapolation")); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, drive)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"io"
+	"net/http"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// usageInterval is the period at which the runtime usage is sampled.
+const usageInterval = time.Minute
+
+// maxUsageSamples is the number of usage samples kept, i.e. two hours.
+const maxUsageSamples = 120
+
+// routeStats accumulates the latency of the requests of one route.
+type routeStats struct {
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sumSeconds"`
+	Max   float64 `json:"maxSeconds"`
+}
+
+var latencies = struct {
+	sync.Mutex
+	byRoute map[string]*routeStats
+}{byRoute: make(map[string]*routeStats)}
+
+// instrument wraps the mux so that the latency of every request is
+// accumulated per route pattern.
+func instrument(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		h.ServeHTTP(w, r)
+		elapsed := time.Since(start).Seconds()
+
+		// The mux sets the pattern on the request it was given.
+		route := r.Pattern
+		if route == "" {
+			route = "inconnue"
+		}
+		latencies.Lock()
+		s, ok := latencies.byRoute[route]
+		if !ok {
+			s = &routeStats{}
+			latencies.byRoute[route] = s
+		}
+		s.Count++
+		s.Sum += elapsed
+		s.Max = max(s.Max, elapsed)
+		latencies.Unlock()
+	})
+}
+
+func routeLatencies() map[string]routeStats {
+	latencies.Lock()
+	defer latencies.Unlock()
+	out := make(map[string]routeStats, len(latencies.byRoute))
+	for route, s := range latencies.byRoute {
+		out[route] = *s
+	}
+	return out
+}
+
+// usageSample is the runtime usage at one time. The heap is measured after
+// a collection so that successive samples can be compared.
+type usageSample struct {
+	At          time.Time `json:"at"`
+	Goroutines  int       `json:"goroutines"`
+	HeapInuse   uint64    `json:"heapInuse"`
+	HeapObjects uint64    `json:"heapObjects"`
+}
+
+var usage = struct {
+	sync.Mutex
+	started time.Time
+	samples []usageSample
+}{started: time.Now()}
+
+func sampleUsage(gc bool) usageSample {
+	if gc {
+		runtime.GC()
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return usageSample{At: time.Now().UTC(), Goroutines: runtime.NumGoroutine(), HeapInuse: ms.HeapInuse, HeapObjects: ms.HeapObjects}
+}
+
+// trackUsage samples the runtime usage every usageInterval, for leak
+// detection.
+func trackUsage() {
+	for range time.Tick(usageInterval) {
+		s := sampleUsage(true)
+		usage.Lock()
+		usage.samples = append(usage.samples, s)
+		if len(usage.samples) > maxUsageSamples {
+			usage.samples = usage.samples[1:]
+		}
+		usage.Unlock()
+	}
+}
+
+// suspectedLeaks compares the usage of the last samples with the first
+// ones, taken after warm-up, and reports the resources that keep growing.
+// It needs half an hour of samples.
+func suspectedLeaks(samples []usageSample) []string {
+
+	const window = 5
+	if len(samples) < 30 {
+		return []string{}
+	}
+	mean := func(s []usageSample, get func(usageSample) float64) float64 {
+		sum := 0.0
+		for _, x := range s {
+			sum += get(x)
+		}
+		return sum / float64(len(s))
+	}
+	first, last := samples[:window], samples[len(samples)-window:]
+
+	var leaks []string
+	check := func(what string, get func(usageSample) float64, slack float64) {
+		before, after := mean(first, get), mean(last, get)
+		// Growth must also be steady: every one of the last samples above
+		// every one of the first.
+		steady := true
+		for _, a := range first {
+			for _, b := range last {
+				steady = steady && get(b) > get(a)
+			}
+		}
+		if after > 1.5*before+slack && steady {
+			leaks = append(leaks, fmt.Sprintf("%s : %.0f → %.0f", what, before, after))
+		}
+	}
+	check("goroutines", func(s usageSample) float64 { return float64(s.Goroutines) }, 10)
+	check("tas (octets)", func(s usageSample) float64 { return float64(s.HeapInuse) }, 4<<20)
+	check("objets du tas", func(s usageSample) float64 { return float64(s.HeapObjects) }, 10000)
+	if leaks == nil {
+		leaks = []string{}
+	}
+	return leaks
+}
+
+// debugHandler reports the runtime usage, the latency of each route, the
+// usage history with the resources suspected to leak, and the soak test
+// progress when one is running.
+func debugHandler(w http.ResponseWriter, r *http.Request) {
+
+	now := sampleUsage(false)
+	usage.Lock()
+	samples := append([]usageSample{}, usage.samples...)
+	started := usage.started
+	usage.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{
+		"uptime":      time.Since(started).Round(time.Second).String(),
+		"goroutines":  now.Goroutines,
+		"heapInuse":   now.HeapInuse,
+		"heapObjects": now.HeapObjects,
+		"routes":      routeLatencies(),
+		"samples":     samples,
+		"leaks":       suspectedLeaks(samples),
+		"soak":        soak.status(),
+	})
+}
+
+// writeRuntimeMetrics writes the runtime usage, route latencies and soak
+// counters for /metrics.
+func writeRuntimeMetrics(w io.Writer) {
+
+	now := sampleUsage(false)
+	writeMetric(w, "regulation_goroutines", "gauge", "Nombre de goroutines.", now.Goroutines)
+	writeMetric(w, "regulation_heap_inuse_bytes", "gauge", "Octets du tas en usage.", now.HeapInuse)
+	writeMetric(w, "regulation_heap_objects", "gauge", "Objets alloués dans le tas.", now.HeapObjects)
+
+	gets, allocs := simulation.BufferStats()
+	writeMetric(w, "regulation_trajectory_buffers_total", "counter", "Tampons de trajectoire pris dans le pool par les balayages et l'optimisation.", gets)
+	writeMetric(w, "regulation_trajectory_buffer_allocations_total", "counter", "Tampons de trajectoire alloués faute d'en trouver un assez grand dans le pool.", allocs)
+
+	routes := routeLatencies()
+	names := make([]string, 0, len(routes))
+	for route := range routes {
+		names = append(names, route)
+	}
+	slices.Sort(names)
+	const name = "regulation_http_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Durée des requêtes par route.\n# TYPE %s summary\n", name, name)
+	for _, route := range names {
+		fmt.Fprintf(w, "%s_sum{route=%q} %v\n%s_count{route=%q} %v\n", name, route, routes[route].Sum, name, route, routes[route].Count)
+	}
+
+	st := soak.status()
+	writeMetric(w, "regulation_soak_simulations_total", "counter", "Simulations lancées par le test d'endurance.", st.Simulations)
+	writeMetric(w, "regulation_soak_live_sessions_total", "counter", "Sessions en direct lancées par le test d'endurance.", st.LiveSessions)
+	writeMetric(w, "regulation_soak_failures_total", "counter", "Requêtes en échec pendant le test d'endurance.", st.Failures)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"io/fs"
+	"net/http"
+)
+
+// defaultScenario is the scenario of the form of index.html as the page
+// loads; both must be changed together.
+const defaultScenario = `{"Sp": 10, "Tau": 1, "K": 1, "P": 5, "Ki": 10, "Kd": 0, "dt": 0.001, "N": 1000}`
+
+// defaultPlaceholder is the element of index.html the default run is
+// inlined into, in place of null.
+const defaultPlaceholder = `<script id="defaultRun" type="application/json">null</script>`
+
+// defaultRun is the default scenario with its result, computed at startup
+// so that the first page load shows a plot without a round trip. ETag is
+// that of the same result from /sendData, for the page to revalidate it.
+type defaultRun struct {
+	Scenario simulation.Scenario `json:"scenario"`
+	Result   simulation.Result   `json:"result"`
+	ETag     string              `json:"etag"`
+}
+
+var (
+	precomputed *defaultRun
+	// indexPage is index.html with the default run inlined, nil until
+	// precomputeDefault ran.
+	indexPage []byte
+)
+
+// precomputeDefault runs the default scenario, puts its result in the
+// cache of /sendData and inlines it into the page.
+func precomputeDefault() error {
+
+	sc, errs, err := parseScenario([]byte(defaultScenario), "")
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("scénario par défaut invalide : %v", errs)
+	}
+	key := cacheKey(sc)
+	res := simulation.Simulation(sc).Rounded()
+	res.Permalink = permalinkPath(sc)
+	results.Put(key, res)
+	run := &defaultRun{Scenario: sc, Result: res, ETag: `"` + resultVersion + "-" + key + `"`}
+
+	page, err := fs.ReadFile(content, "static/html/index.html")
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(page, []byte(defaultPlaceholder)) {
+		return fmt.Errorf("emplacement de la simulation par défaut absent de index.html")
+	}
+	// The encoder escapes <, > and &, so that the JSON cannot close the
+	// script element.
+	b, _, err := numfmt.MarshalJSON(run, numfmt.NonFiniteNull)
+	if err != nil {
+		return err
+	}
+	inlined := bytes.Replace([]byte(defaultPlaceholder), []byte("null"), b, 1)
+	precomputed, indexPage = run, bytes.Replace(page, []byte(defaultPlaceholder), inlined, 1)
+	return nil
+}
+
+// indexHandler serves the home page with the default run inlined.
+func indexHandler(w http.ResponseWriter, r *http.Request) {
+	if indexPage == nil {
+		http.ServeFileFS(w, r, content, "static/html/index.html")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexPage)
+}
+
+// defaultHandler answers the default run computed at startup.
+func defaultHandler(w http.ResponseWriter, r *http.Request) {
+	if precomputed == nil {
+		httpError(w, "Simulation par défaut indisponible", http.StatusServiceUnavailable)
+		return
+	}
+	if notModified(w, r, precomputed.ETag) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, precomputed)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+)
+
+// unknownLayerError reports a preset, run or live session named by the query
+// that does not exist, answered 404 rather than 400.
+type unknownLayerError struct{ error }
+
+// defaultsLayer reads the scenario a partial request body completes: the
+// preset ?preset=, the stored run ?run=, the live session ?live=, or with
+// ?defaults=true the defaults of the scenario schema. It returns nil when
+// none is named, the body then having to be complete.
+func defaultsLayer(r *http.Request) (map[string]any, error) {
+
+	q := r.URL.Query()
+	var base *simulation.Scenario
+	named := 0
+	if name := q.Get("preset"); name != "" {
+		named++
+		p, ok := simulation.FindPreset(name)
+		if !ok {
+			return nil, unknownLayerError{fmt.Errorf("préréglage inconnu %q", name)}
+		}
+		base = &p.Scenario
+	}
+	if id := q.Get("run"); id != "" {
+		named++
+		run, ok := runs.Get(id)
+		if !ok {
+			return nil, unknownLayerError{fmt.Errorf("simulation introuvable %q", id)}
+		}
+		base = &run.Scenario
+	}
+	if id := q.Get("live"); id != "" {
+		named++
+		sessions.Lock()
+		s, ok := sessions.byID[id]
+		sessions.Unlock()
+		if !ok {
+			return nil, unknownLayerError{fmt.Errorf("session introuvable %q", id)}
+		}
+		sc, _ := s.Snapshot()
+		base = &sc
+	}
+	defaults := q.Get("defaults") == "true"
+	if defaults {
+		named++
+	}
+	switch {
+	case named > 1:
+		return nil, errors.New("un seul des paramètres preset, run, live et defaults est accepté")
+	case defaults:
+		return map[string]any{}, nil
+	case base == nil:
+		return nil, nil
+	}
+
+	b, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	return doc, json.Unmarshal(b, &doc)
+}
+
+// withDefaults completes a partial scenario body from the layer named by
+// the query, see defaultsLayer: the body is applied over it as a JSON
+// merge patch (RFC 7396), a null removing a field, then the required fields
+// still missing take the defaults of the schema. Without a layer the body
+// is returned unchanged. An unknown layer is an unknownLayerError.
+func withDefaults(r *http.Request, body []byte) ([]byte, error) {
+
+	base, err := defaultsLayer(r)
+	if err != nil || base == nil {
+		return body, err
+	}
+	var patch any
+	if err := json.Unmarshal(body, &patch); err != nil {
+		// Left for the scenario decoding to report.
+		return body, nil
+	}
+	doc := simulation.ScenarioSchema().Defaults(mergePatch(base, patch))
+	return json.Marshal(doc)
+}
+
+// mergePatch applies an RFC 7396 merge patch to doc.
+func mergePatch(doc, patch any) any {
+
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]any)
+	if !ok {
+		d = map[string]any{}
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(d, name)
+		} else {
+			d[name] = mergePatch(d[name], value)
+		}
+	}
+	return d
+}
+
+// effectiveScenarioHandler answers the scenario the posted body runs once
+// completed, see withDefaults, without simulating it.
+func effectiveScenarioHandler(w http.ResponseWriter, r *http.Request) {
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, sc)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+	"strconv"
+)
+
+// writeDistribution answers the distribution of PV - SP over the samples
+// from ?from= on, defaultFrom when absent, in ?bins= classes: as JSON or,
+// with ?format=svg or png, as a histogram styled by the options of
+// plotHandler.
+func writeDistribution(w http.ResponseWriter, r *http.Request, T, SP, PV []float64, defaultFrom float64) {
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	contentType, ok := plotFormats[format]
+	if format != "" && format != "json" && !ok {
+		httpError(w, fmt.Sprintf("Format inconnu %q, attendu json, svg ou png", format), http.StatusBadRequest)
+		return
+	}
+	opts, err := plotOptions(r)
+	if err == nil && ok {
+		opts, err = plotFormatOptions(r, format)
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from := defaultFrom
+	if s := q.Get("from"); s != "" {
+		if from, err = numfmt.Parse(s); err != nil {
+			httpError(w, fmt.Sprintf("Paramètre from invalide %q", s), http.StatusBadRequest)
+			return
+		}
+	}
+	bins := 30
+	if s := q.Get("bins"); s != "" {
+		if bins, err = strconv.Atoi(s); err != nil {
+			httpError(w, fmt.Sprintf("Paramètre bins invalide %q", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	d, err := simulation.Deviation(T, SP, PV, from, bins)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if contentType == "" {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, r, d.Rounded())
+		return
+	}
+	var buf bytes.Buffer
+	if err := d.WritePlot(&buf, format, opts); err != nil {
+		httpError(w, "Erreur lors du tracé", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	buf.WriteTo(w)
+}
+
+// distributionHandler simulates the posted scenario and answers the
+// distribution of its deviation from the setpoint, over the steady state
+// by default, see Result.SteadyStart.
+func distributionHandler(w http.ResponseWriter, r *http.Request) {
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	release, ok := admission.admit(w, stepCost(sc, 1, 1))
+	if !ok {
+		return
+	}
+	res := sc.Run()
+	release()
+	writeDistribution(w, r, res.Time, res.SP, res.PV, res.SteadyStart())
+}
+
+// distributionLiveHandler answers the distribution of the deviation of a
+// live session over its recent samples.
+func distributionLiveHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	T, SP, PV := s.Recent()
+	from := 0.0
+	if len(T) > 0 {
+		from = T[0]
+	}
+	writeDistribution(w, r, T, SP, PV, from)
+}
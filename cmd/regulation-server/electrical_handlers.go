@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"github.com/Ivan69-tech/PIDControllerResponse/units"
+	"net/http"
+	"strconv"
+)
+
+// flowRequest is the electrical system of a reactive power scenario and
+// the reactive power of its inverters.
+type flowRequest struct {
+	Electrical json.RawMessage `json:"electrical"`
+	Qond       float64         `json:"Qond"`
+}
+
+// decodeElectrical validates raw against the schema of the electrical
+// system and decodes it; on failure the request has been answered and ok
+// is false. With ?lenient=true, strings are read as numbers as in
+// scenarioBody, with their unit when they have one, as "20 kV".
+func decodeElectrical(w http.ResponseWriter, r *http.Request, raw []byte) (e simulation.Electrical, ok bool) {
+
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return e, false
+	}
+	if r.URL.Query().Get("lenient") == "true" {
+		doc = simulation.ElectricalSchema.Coerce(doc, numfmt.Parse)
+		raw, _ = json.Marshal(doc)
+	}
+	errs := simulation.ElectricalSchema.Validate(doc)
+	if len(errs) == 0 {
+		if err := json.Unmarshal(raw, &e); err != nil {
+			httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+			fmt.Println(err)
+			return e, false
+		}
+		errs = e.Check()
+	}
+	if len(errs) > 0 {
+		for i := range errs {
+			errs[i].Path = "/electrical" + errs[i].Path
+		}
+		writeProblem(w, http.StatusBadRequest, codeValidation, "Système électrique invalide", map[string]any{"details": errs})
+		return e, false
+	}
+	return e, true
+}
+
+// readFlowRequest decodes the body of the electrical endpoints; on
+// failure the request has been answered and ok is false.
+func readFlowRequest(w http.ResponseWriter, r *http.Request) (req flowRequest, e simulation.Electrical, ok bool) {
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return req, e, false
+	}
+	if req.Electrical == nil {
+		writeProblem(w, http.StatusBadRequest, codeValidation, "Système électrique invalide", map[string]any{"details": []schema.Error{{Path: "/electrical", Message: "requis"}}})
+		return req, e, false
+	}
+	e, ok = decodeElectrical(w, r, req.Electrical)
+	return req, e, ok
+}
+
+// flowHandler answers the power flow from the inverters to the POC, with
+// the losses of every block, for the posted system and powers.
+func flowHandler(w http.ResponseWriter, r *http.Request) {
+
+	req, e, ok := readFlowRequest(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, e.ComputeFlow(e.Pond, req.Qond).Rounded())
+}
+
+// scanHandler answers the impedance of the posted system seen from the
+// POC at ?points= frequencies from ?from= to ?to=, in Hz or with their
+// unit as "2,5 kHz", by default 500 from the grid frequency to its 50th
+// harmonic: as JSON or, with ?format=svg or png, as a plot of |Z| styled
+// by the options of plotHandler.
+func scanHandler(w http.ResponseWriter, r *http.Request) {
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	contentType, ok := plotFormats[format]
+	if format != "" && format != "json" && !ok {
+		httpError(w, fmt.Sprintf("Format inconnu %q, attendu json, svg ou png", format), http.StatusBadRequest)
+		return
+	}
+	opts, err := plotOptions(r)
+	if err == nil && ok {
+		opts, err = plotFormatOptions(r, format)
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, e, ok := readFlowRequest(w, r)
+	if !ok {
+		return
+	}
+	from, to := e.F, 50*e.F
+	for name, v := range map[string]*float64{"from": &from, "to": &to} {
+		if s := q.Get(name); s != "" {
+			if *v, err = units.Parse(s, units.Hertz); err != nil {
+				httpError(w, fmt.Sprintf("Paramètre %s invalide %q", name, s), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	points := 500
+	if s := q.Get("points"); s != "" {
+		if points, err = strconv.Atoi(s); err != nil {
+			httpError(w, fmt.Sprintf("Paramètre points invalide %q", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	scan, err := e.Scan(from, to, points)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if contentType == "" {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, r, scan.Rounded())
+		return
+	}
+	var buf bytes.Buffer
+	if err := scan.WritePlot(&buf, format, opts); err != nil {
+		httpError(w, "Erreur lors du tracé", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	buf.WriteTo(w)
+}
+
+// decoupledHandler runs the decoupled P/Q control demonstration of the
+// posted parameters, those of simulation.DefaultDecoupled filling the
+// missing ones, so that an empty object runs the standard one.
+func decoupledHandler(w http.ResponseWriter, r *http.Request) {
+
+	var doc any
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+	d := simulation.DefaultDecoupled
+	errs := simulation.DecoupledSchema.Validate(doc)
+	if len(errs) == 0 {
+		raw, _ := json.Marshal(doc)
+		if err := json.Unmarshal(raw, &d); err != nil {
+			httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+			fmt.Println(err)
+			return
+		}
+		errs = d.Check()
+	}
+	if len(errs) > 0 {
+		writeProblem(w, http.StatusBadRequest, codeValidation, "Paramètres invalides", map[string]any{"details": errs})
+		return
+	}
+	release, ok := admission.admit(w, 2*int64(d.N))
+	if !ok {
+		return
+	}
+	res := d.Run()
+	release()
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, res.Rounded())
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resultVersion identifies the simulation code in ETags, so that results
+// cached by clients are revalidated after an upgrade: the VCS revision of
+// the build, or the start time of the process when it is unknown or the
+// tree was modified.
+var resultVersion = func() string {
+	revision, modified := "", false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+	if revision == "" || modified {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return revision[:min(len(revision), 12)]
+}()
+
+// notModified sets the ETag of the JSON answer and reports whether the
+// client already holds it, in which case 304 has been answered. The answer
+// depends on the nonfinite parameter of the Accept header, see nonFinite,
+// so the ETag of its string mode is suffixed and caches vary on Accept.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+
+	if nonFinite(r) == numfmt.NonFiniteString {
+		etag = strings.TrimSuffix(etag, `"`) + `-nonfinite"`
+	}
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("ETag", etag)
+	match := r.Header.Get("If-None-Match")
+	if match == "" {
+		return false
+	}
+	for _, tag := range strings.Split(match, ",") {
+		// If-None-Match uses the weak comparison.
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/export"
+	"net/http"
+)
+
+type plcRequest struct {
+	Format string  `json:"format"`
+	P      float64 `json:"P"`
+	Ki     float64 `json:"Ki"`
+	Kd     float64 `json:"Kd"`
+	Dt     float64 `json:"dt"`
+}
+
+// plcExportHandler translates gains into a vendor block configuration,
+// downloaded as JSON or, with ?type=csv, as CSV in the dialect of
+// csvDialect.
+func plcExportHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req plcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	params, err := export.PLC(req.Format, req.P, req.Ki, req.Kd, req.Dt)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("type") == "csv" {
+		dialect, err := csvDialect(r)
+		if err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "reglage-"+req.Format+".csv"))
+		params.WriteCSV(w, dialect)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "reglage-"+req.Format+".json"))
+	writeJSON(w, r, params)
+}
+
+// matlabExportHandler runs the scenario and downloads it as a MATLAB
+// script: the controller, the plant model and the series of the result,
+// to cross-validate the simulator against a Simulink model.
+func matlabExportHandler(w http.ResponseWriter, r *http.Request) {
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	release, ok := admission.admit(w, stepCost(sc, 1, 1))
+	if !ok {
+		return
+	}
+	defer release()
+
+	res := sc.Run()
+	res.Permalink = permalinkPath(sc)
+	w.Header().Set("Content-Type", "text/x-matlab; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "simulation.m"))
+	export.MATLAB(w, sc, res)
+}
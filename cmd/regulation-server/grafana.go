@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/history"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The /grafana endpoints follow the conventions of the Grafana SimpleJSON
+// datasource (also understood by the Infinity and JSON API plugins): a
+// target is "<run id>:pv" or "<run id>:sp", and the samples of a run are
+// timestamped from the run's creation time.
+
+// grafanaMaxTargets bounds the number of targets proposed by /search.
+const grafanaMaxTargets = 200
+
+func grafanaTestHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func grafanaSearchHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req struct {
+		Target string `json:"target"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	targets := []string{}
+	for _, run := range runs.List() {
+		for _, series := range []string{"pv", "sp"} {
+			target := run.ID + ":" + series
+			if strings.Contains(target, req.Target) && len(targets) < grafanaMaxTargets {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, targets)
+}
+
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+	MaxDataPoints int `json:"maxDataPoints"`
+}
+
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+func grafanaQueryHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req grafanaQuery
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	response := []grafanaSeries{}
+	for _, t := range req.Targets {
+		id, series, _ := strings.Cut(t.Target, ":")
+		run, ok := runs.Get(id)
+		if !ok {
+			continue
+		}
+		response = append(response, grafanaSeries{
+			Target:     t.Target,
+			Datapoints: datapoints(run, series, req.Range.From, req.Range.To, req.MaxDataPoints),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, response)
+}
+
+// datapoints returns [value, epoch ms] pairs of the run series within the
+// time range (unbounded when zero), decimated to at most maxPoints.
+func datapoints(run *history.Run, series string, from, to time.Time, maxPoints int) [][2]float64 {
+
+	var idx []int
+	for i, t := range run.Time {
+		at := run.Created.Add(time.Duration(t * float64(time.Second)))
+		if (!from.IsZero() && at.Before(from)) || (!to.IsZero() && at.After(to)) {
+			continue
+		}
+		idx = append(idx, i)
+	}
+
+	stride := 1
+	if maxPoints > 0 && len(idx) > maxPoints {
+		stride = (len(idx) + maxPoints - 1) / maxPoints
+	}
+
+	points := [][2]float64{}
+	for k := 0; k < len(idx); k += stride {
+		i := idx[k]
+		v := run.PV[i]
+		if series == "sp" {
+			// Runs stored before the setpoint series was kept only have
+			// the scenario setpoint.
+			v = run.Scenario.Sp
+			if i < len(run.SP) {
+				v = run.SP[i]
+			}
+		}
+		ms := float64(run.Created.UnixMilli()) + run.Time[i]*1000
+		points = append(points, [2]float64{v, ms})
+	}
+	return points
+}
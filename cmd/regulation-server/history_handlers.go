@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/audit"
+	"github.com/Ivan69-tech/PIDControllerResponse/history"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runSummary is a run without its series, as listed by /api/v1/history.
+type runSummary struct {
+	ID       string              `json:"id"`
+	Created  time.Time           `json:"created"`
+	Scenario simulation.Scenario `json:"scenario"`
+	Notes    string              `json:"notes,omitempty"`
+	Tags     []string            `json:"tags,omitempty"`
+}
+
+// listHistoryHandler lists the stored runs, restricted to those carrying
+// every ?tag= given and, with ?q=, whose notes contain the text.
+func listHistoryHandler(w http.ResponseWriter, r *http.Request) {
+
+	tags := r.URL.Query()["tag"]
+	text := strings.ToLower(r.URL.Query().Get("q"))
+
+	list := []runSummary{}
+runs:
+	for _, run := range runs.List() {
+		for _, tag := range tags {
+			if !run.HasTag(tag) {
+				continue runs
+			}
+		}
+		if text != "" && !strings.Contains(strings.ToLower(run.Notes), text) {
+			continue
+		}
+		list = append(list, runSummary{ID: run.ID, Created: run.Created, Scenario: run.Scenario, Notes: run.Notes, Tags: run.Tags})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, list)
+}
+
+func getHistoryHandler(w http.ResponseWriter, r *http.Request) {
+
+	run, ok := runs.Get(r.PathValue("id"))
+	if !ok {
+		httpError(w, "Simulation introuvable", http.StatusNotFound)
+		return
+	}
+	// Stored runs never change, only their annotations.
+	if notModified(w, r, runETag(run)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, run)
+}
+
+// stateHistoryHandler answers the internal state of the loop of a stored
+// run as it ended, replaying its scenario: runs are deterministic.
+func stateHistoryHandler(w http.ResponseWriter, r *http.Request) {
+
+	run, ok := runs.Get(r.PathValue("id"))
+	if !ok {
+		httpError(w, "Simulation introuvable", http.StatusNotFound)
+		return
+	}
+	if notModified(w, r, `"`+resultVersion+"-"+run.ID+`-state"`) {
+		return
+	}
+	release, admitted := admission.admit(w, stepCost(run.Scenario, 1, 1))
+	if !admitted {
+		return
+	}
+	state := simulation.FinalState(run.Scenario)
+	release()
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, state)
+}
+
+func runETag(run *history.Run) string {
+	if run.Revision == 0 {
+		return `"` + run.ID + `"`
+	}
+	return `"` + run.ID + "-" + strconv.Itoa(run.Revision) + `"`
+}
+
+// annotateHistoryHandler replaces the notes or the tags of a stored run.
+func annotateHistoryHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req struct {
+		Notes *string  `json:"notes"`
+		Tags  []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+	if req.Notes == nil && req.Tags == nil {
+		httpError(w, "Rien à modifier : notes ou tags attendus", http.StatusBadRequest)
+		return
+	}
+
+	run, err := runs.Annotate(r.PathValue("id"), req.Notes, req.Tags)
+	switch {
+	case errors.Is(err, history.ErrNotFound):
+		httpError(w, "Simulation introuvable", http.StatusNotFound)
+		return
+	case err != nil:
+		httpError(w, "Enregistrement des annotations impossible", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+	recordAudit(r, audit.Entry{Action: audit.ActionAnnotate, Target: run.ID, Detail: strings.Join(run.Tags, ", ")})
+
+	w.Header().Set("ETag", runETag(run))
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, runSummary{ID: run.ID, Created: run.Created, Scenario: run.Scenario, Notes: run.Notes, Tags: run.Tags})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Interactive requests follow a slider being dragged: a few tens per
+// second, of which only the last one matters.
+const (
+	// interactiveSteps bounds the iterations of a run, the time step
+	// growing to keep the simulated duration.
+	interactiveSteps = 20_000
+	// interactivePoints bounds the samples answered.
+	interactivePoints = 500
+	// interactiveDebounce is how long a request waits for a newer one of
+	// the same session before running.
+	interactiveDebounce = 30 * time.Millisecond
+)
+
+// interactiveSlot coalesces the requests of one session: each takes the
+// next generation, and only the latest one runs.
+type interactiveSlot struct {
+	gen     atomic.Uint64
+	run     sync.Mutex
+	pending int
+}
+
+var interactiveSlots = struct {
+	sync.Mutex
+	byID map[string]*interactiveSlot
+}{byID: map[string]*interactiveSlot{}}
+
+// acquireSlot registers a request of the session id and returns its slot
+// and generation; releaseSlot forgets the slot once no request uses it.
+func acquireSlot(id string) (*interactiveSlot, uint64) {
+	interactiveSlots.Lock()
+	defer interactiveSlots.Unlock()
+	slot, ok := interactiveSlots.byID[id]
+	if !ok {
+		slot = &interactiveSlot{}
+		interactiveSlots.byID[id] = slot
+	}
+	slot.pending++
+	return slot, slot.gen.Add(1)
+}
+
+func releaseSlot(id string, slot *interactiveSlot) {
+	interactiveSlots.Lock()
+	defer interactiveSlots.Unlock()
+	if slot.pending--; slot.pending == 0 {
+		delete(interactiveSlots.byID, id)
+	}
+}
+
+// interactiveResult is the response of /api/v1/interactive: the series
+// decimated to interactivePoints samples at most, and the metrics a slider
+// moves.
+type interactiveResult struct {
+	// Scenario is the scenario run, its time step scaled by Coarsening
+	// when N exceeded interactiveSteps.
+	Scenario   simulation.Scenario `json:"scenario"`
+	Coarsening float64             `json:"coarsening"`
+	Time       []float64           `json:"time"`
+	SP         []float64           `json:"sp"`
+	PV         []float64           `json:"pv"`
+	U          []float64           `json:"u"`
+	Metrics    struct {
+		IAE          float64 `json:"iae"`
+		Overshoot    float64 `json:"overshoot"`
+		SettlingTime float64 `json:"settling"`
+		Settled      bool    `json:"settled"`
+		MaxU         float64 `json:"umax"`
+	} `json:"metrics"`
+	Status simulation.Status `json:"status"`
+}
+
+// interactiveHandler simulates the posted scenario quickly enough to follow
+// a slider: N is bounded, the output decimated and the metrics reduced. The
+// requests sharing ?session= are coalesced: one superseded by a newer
+// request of its session, while waiting interactiveDebounce or for the
+// previous run, is answered 204 without running.
+func interactiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	id := r.URL.Query().Get("session")
+	if id == "" {
+		httpError(w, "Paramètre session manquant : identifiant choisi par le client, commun aux requêtes d'un même curseur", http.StatusBadRequest)
+		return
+	}
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+
+	slot, gen := acquireSlot(id)
+	defer releaseSlot(id, slot)
+	superseded := func() bool {
+		if slot.gen.Load() != gen {
+			w.WriteHeader(http.StatusNoContent)
+			return true
+		}
+		return false
+	}
+
+	select {
+	case <-time.After(interactiveDebounce):
+	case <-r.Context().Done():
+		return
+	}
+	if superseded() {
+		return
+	}
+	slot.run.Lock()
+	defer slot.run.Unlock()
+	if superseded() {
+		return
+	}
+
+	res := interactiveResult{Coarsening: 1}
+	if sc.N > interactiveSteps {
+		res.Coarsening = math.Ceil(sc.N / interactiveSteps)
+		sc.Dt *= res.Coarsening
+		sc.N = math.Floor(sc.N / res.Coarsening)
+	}
+	release, ok := admission.admit(w, stepCost(sc, 1, 1))
+	if !ok {
+		return
+	}
+	run := sc.Run()
+	release()
+
+	stride := max(1, int(math.Ceil(float64(len(run.Time))/interactivePoints)))
+	for i := 0; i < len(run.Time); i += stride {
+		if i+stride >= len(run.Time) {
+			// The last sample, where the response has settled or not.
+			i = len(run.Time) - 1
+		}
+		res.Time = append(res.Time, numfmt.Round(run.Time[i]))
+		res.SP = append(res.SP, numfmt.Round(run.SP[i]))
+		res.PV = append(res.PV, numfmt.Round(run.PV[i]))
+		res.U = append(res.U, numfmt.Round(run.U[i]))
+	}
+	m := run.Metrics
+	res.Scenario, res.Status = sc, run.Status
+	res.Metrics.IAE, res.Metrics.Overshoot = numfmt.Round(m.IAE), numfmt.Round(m.Overshoot)
+	res.Metrics.SettlingTime, res.Metrics.Settled = numfmt.Round(m.SettlingTime), m.Settled
+	res.Metrics.MaxU = numfmt.Round(m.MaxU)
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, res)
+}
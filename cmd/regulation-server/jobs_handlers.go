@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/audit"
+	"github.com/Ivan69-tech/PIDControllerResponse/history"
+	"github.com/Ivan69-tech/PIDControllerResponse/jobs"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"math"
+	"net/http"
+	"time"
+)
+
+var pool *jobs.Pool
+
+// jobResult is the result of a simulation job.
+type jobResult struct {
+	RunID     string             `json:"runId"`
+	ResultURL string             `json:"resultUrl"`
+	Metrics   simulation.Metrics `json:"metrics"`
+}
+
+// baseURL returns the scheme and host the client used to reach the server.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// submitJobHandler queues a simulation; the run is stored in the history
+// and the optional webhook is called when it finishes.
+func submitJobHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req struct {
+		Scenario json.RawMessage `json:"scenario"`
+		Webhook  *jobs.Webhook   `json:"webhook"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	sc, ok := decodeScenario(w, req.Scenario, "/scenario")
+	if !ok {
+		return
+	}
+	if req.Webhook != nil {
+		if err := req.Webhook.Validate(); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if refused := admission.tooLarge(stepCost(sc, 1, 1)); refused != nil {
+		refused.write(w)
+		return
+	}
+
+	base := baseURL(r)
+	runID := newID()
+	job, err := pool.Submit(newID(), func() (any, error) {
+		res := sc.Run().Rounded()
+		run := &history.Run{ID: runID, Created: time.Now().UTC(), Scenario: sc, Result: res}
+		if err := runs.Add(run); err != nil {
+			return nil, err
+		}
+		return jobResult{
+			RunID:     runID,
+			ResultURL: base + "/api/v1/history/" + runID,
+			Metrics:   res.Metrics,
+		}, nil
+	}, req.Webhook)
+
+	if errors.Is(err, jobs.ErrQueueFull) {
+		// The queue drains at one job per worker per mean run time.
+		stats := pool.Stats()
+		mean := max(stats.MeanDuration, time.Second)
+		retry := int(math.Ceil((time.Duration(stats.Queued) * mean / time.Duration(max(stats.Workers, 1))).Seconds()))
+		(&shed{Status: http.StatusTooManyRequests, RetryAfter: max(retry, 1), Detail: "File d'attente pleine, réessayer plus tard", Extra: map[string]any{
+			"queueDepth": stats.Queued,
+			"retryAfter": max(retry, 1),
+		}}).write(w)
+		return
+	}
+
+	recordAudit(r, audit.Entry{Action: audit.ActionJob, Target: job.ID, Scenario: &sc, Detail: "simulation " + runID})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, r, job)
+}
+
+func getJobHandler(w http.ResponseWriter, r *http.Request) {
+
+	job, ok := pool.Get(r.PathValue("id"))
+	if !ok {
+		httpError(w, "Job introuvable", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, job)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/live"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// kpiLiveHandler answers the KPIs of a session over each of its windows.
+func kpiLiveHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{"kpi": s.KPIs()})
+}
+
+// kpiHandler answers the KPIs of every running session, by session.
+func kpiHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{"sessions": sessionKPIs()})
+}
+
+// sessionKPIs returns the KPIs of every running session, by session.
+func sessionKPIs() map[string][]live.KPI {
+	sessions.Lock()
+	list := slices.Collect(maps.Values(sessions.byID))
+	sessions.Unlock()
+
+	byID := make(map[string][]live.KPI, len(list))
+	for _, s := range list {
+		byID[s.ID] = s.KPIs()
+	}
+	return byID
+}
+
+// writeKPIMetrics writes the KPIs of the running sessions as gauges
+// labelled by session and window, in seconds, and by band for the time
+// within each band, named after the bands of the scenario.
+func writeKPIMetrics(w io.Writer) {
+
+	byID := sessionKPIs()
+	ids := slices.Sorted(maps.Keys(byID))
+	bands := make(map[string][]string, len(ids))
+	sessions.Lock()
+	for _, id := range ids {
+		if s, ok := sessions.byID[id]; ok {
+			sc, _ := s.Snapshot()
+			for _, b := range sc.Bands {
+				bands[id] = append(bands[id], b.Name)
+			}
+		}
+	}
+	sessions.Unlock()
+
+	gauge := func(name, help string, value func(k live.KPI) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, id := range ids {
+			for _, k := range byID[id] {
+				fmt.Fprintf(w, "%s{session=%q,window=\"%g\"} %v\n", name, id, k.Window, value(k))
+			}
+		}
+	}
+	gauge("regulation_live_kpi_span_seconds", "Durée simulée couverte par la fenêtre des indicateurs d'une session.", func(k live.KPI) float64 { return k.Span })
+	gauge("regulation_live_time_in_alarm_ratio", "Fraction du temps avec une alarme active.", func(k live.KPI) float64 { return k.TimeInAlarm })
+	gauge("regulation_live_error_mean", "Moyenne de l'écart consigne - mesure.", func(k live.KPI) float64 { return k.ErrorMean })
+	gauge("regulation_live_error_variance", "Variance de l'écart consigne - mesure.", func(k live.KPI) float64 { return k.ErrorVariance })
+	gauge("regulation_live_output_travel", "Course de la commande, somme des variations |du| (effort de commande).", func(k live.KPI) float64 { return k.Travel })
+
+	const name = "regulation_live_within_band_ratio"
+	fmt.Fprintf(w, "# HELP %s Fraction du temps avec la mesure dans la bande.\n# TYPE %s gauge\n", name, name)
+	for _, id := range ids {
+		for _, k := range byID[id] {
+			for i, v := range k.WithinBand {
+				if i < len(bands[id]) {
+					fmt.Fprintf(w, "%s{session=%q,window=\"%g\",band=%q} %v\n", name, id, k.Window, bands[id][i], v)
+				}
+			}
+		}
+	}
+}
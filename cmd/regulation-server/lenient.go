@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// scenarioBody returns the scenario document of a request. A form-encoded
+// submission, whose fields are the top-level scenario members, is turned
+// into a JSON object. Its values, and those of a JSON body sent with
+// ?lenient=true, are read leniently: strings where the schema expects
+// numbers are parsed with numfmt.Parse, which accepts "0,5" or "1,5e-3",
+// after the unit of the quantities that have one, as "20 kV".
+func scenarioBody(r *http.Request) ([]byte, error) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// curl -d sends JSON as a form unless told otherwise.
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" && !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		doc := make(map[string]any, len(form))
+		for name, values := range form {
+			doc[name] = values[0]
+		}
+		return json.Marshal(simulation.ScenarioSchema().Coerce(doc, numfmt.Parse))
+	}
+
+	if r.URL.Query().Get("lenient") != "true" {
+		return body, nil
+	}
+	var doc any
+	if json.Unmarshal(body, &doc) != nil {
+		// Left for the scenario decoding to report.
+		return body, nil
+	}
+	return json.Marshal(simulation.ScenarioSchema().Coerce(doc, numfmt.Parse))
+}
@@ -0,0 +1,641 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/audit"
+	"github.com/Ivan69-tech/PIDControllerResponse/live"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"github.com/Ivan69-tech/PIDControllerResponse/ws"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLiveSessions bounds the number of sessions running at once.
+const maxLiveSessions = 16
+
+var sessions = struct {
+	sync.Mutex
+	byID map[string]*live.Session
+	// configs keeps the configuration each session was started with, for
+	// archives.
+	configs map[string]liveConfig
+}{byID: make(map[string]*live.Session), configs: make(map[string]liveConfig)}
+
+// lookupSession returns the session named in the path. On failure the
+// request has been answered and ok is false.
+func lookupSession(w http.ResponseWriter, r *http.Request) (*live.Session, bool) {
+	sessions.Lock()
+	s, ok := sessions.byID[r.PathValue("id")]
+	sessions.Unlock()
+	if !ok {
+		httpError(w, "Session introuvable", http.StatusNotFound)
+	}
+	return s, ok
+}
+
+// writeSession answers the parameters and last sample of a session, with
+// its alarms, its speed and the number of changes that can be undone and
+// redone. The bands to shade are in the scenario, their edges in the
+// sample.
+func writeSession(w http.ResponseWriter, r *http.Request, status int, s *live.Session) {
+	sc, sample := s.Snapshot()
+	undo, redo := s.Edits()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, r, map[string]any{
+		"id":       s.ID,
+		"scenario": sc,
+		"sample":   sample,
+		"alarms":   s.Alarms(),
+		"speed":    s.Speed(),
+		"paused":   s.Paused(),
+		"undo":     len(undo),
+		"redo":     len(redo),
+	})
+}
+
+func startLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req struct {
+		Scenario    json.RawMessage         `json:"scenario"`
+		Sinks       []live.SinkConfig       `json:"sinks"`
+		Alarms      []live.AlarmConfig      `json:"alarms"`
+		Breakpoints []live.BreakpointConfig `json:"breakpoints"`
+		KPIWindows  []float64               `json:"kpiWindows"`
+		Speed       float64                 `json:"speed"`
+		// Start, when set, is the RFC 3339 time of the first sample.
+		Start *time.Time `json:"start"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	sc, ok := decodeScenario(w, req.Scenario, "/scenario")
+	if !ok {
+		return
+	}
+	cfg := liveConfig{Scenario: sc, Sinks: req.Sinks, Alarms: req.Alarms, Breakpoints: req.Breakpoints, KPIWindows: req.KPIWindows, Speed: req.Speed}
+	if errs := cfg.check(); len(errs) > 0 {
+		writeProblem(w, http.StatusBadRequest, codeValidation, "Configuration de session invalide", map[string]any{"details": errs})
+		return
+	}
+
+	origin := time.Now()
+	if req.Start != nil {
+		origin = *req.Start
+	}
+	s, status, err := startSession(cfg, origin)
+	if err != nil {
+		httpError(w, err.Error(), status)
+		return
+	}
+	recordAudit(r, audit.Entry{Action: audit.ActionLiveStart, Target: s.ID, Scenario: &sc})
+	writeSession(w, r, http.StatusCreated, s)
+}
+
+// check reports the invalid alarms, KPI windows and speed of a
+// configuration, the scenario being checked when decoded.
+func (cfg liveConfig) check() []schema.Error {
+	errs := append(live.CheckAlarms(cfg.Alarms), live.CheckBreakpoints(cfg.Breakpoints)...)
+	errs = append(errs, live.CheckKPIWindows(cfg.KPIWindows)...)
+	if cfg.Speed != 0 && !(cfg.Speed >= live.MinSpeed && cfg.Speed <= live.MaxSpeed) {
+		errs = append(errs, schema.Error{Path: "/speed", Message: live.ErrSpeed.Error()})
+	}
+	return errs
+}
+
+// startSession opens the sinks and starts a registered session with its
+// alarms, breakpoints, KPI windows and speed. On failure status is the HTTP status to
+// answer.
+func startSession(cfg liveConfig, origin time.Time) (s *live.Session, status int, err error) {
+
+	id := newID()
+	var sinks []live.Sink
+	for i, c := range cfg.Sinks {
+		sink, err := live.NewSink(c, id, dataDir)
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, http.StatusBadRequest, fmt.Errorf("Puits %d : %v", i, err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	sessions.Lock()
+	defer sessions.Unlock()
+	if len(sessions.byID) >= maxLiveSessions {
+		for _, s := range sinks {
+			s.Close()
+		}
+		return nil, http.StatusServiceUnavailable, fmt.Errorf("Trop de sessions en cours, en arrêter une d'abord")
+	}
+	s = live.StartAt(id, cfg.Scenario, origin, sinks...)
+	s.SetAlarms(cfg.Alarms)
+	s.SetBreakpoints(cfg.Breakpoints)
+	s.SetKPIWindows(cfg.KPIWindows)
+	if cfg.Speed != 0 {
+		s.SetSpeed(cfg.Speed)
+	}
+	sessions.byID[id] = s
+	sessions.configs[id] = cfg
+	return s, http.StatusCreated, nil
+}
+
+func listLiveHandler(w http.ResponseWriter, r *http.Request) {
+	sessions.Lock()
+	ids := make([]string, 0, len(sessions.byID))
+	for id := range sessions.byID {
+		ids = append(ids, id)
+	}
+	sessions.Unlock()
+	slices.Sort(ids)
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{"sessions": ids})
+}
+
+func getLiveHandler(w http.ResponseWriter, r *http.Request) {
+	if s, ok := lookupSession(w, r); ok {
+		writeSession(w, r, http.StatusOK, s)
+	}
+}
+
+// updateLiveHandler applies a partial scenario ({"Sp": 12, "P": 3}) to a
+// running session.
+func updateLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+
+	var changes map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	properties := simulation.ScenarioSchema().Properties
+	var errs []schema.Error
+	for name, value := range changes {
+		prop, known := properties[name]
+		switch {
+		case !known:
+			errs = append(errs, schema.Error{Path: "/" + name, Message: "champ inconnu"})
+		case name == "dt" || name == "N" || (prop.Type != "number" && prop.Type != "integer"):
+			errs = append(errs, schema.Error{Path: "/" + name, Message: "non modifiable pendant une session"})
+		default:
+			for _, e := range prop.Validate(value) {
+				errs = append(errs, schema.Error{Path: "/" + name, Message: e.Message})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	var old simulation.Scenario
+	sc, errs := s.Update(func(sc *simulation.Scenario) {
+		old = *sc
+		for name, value := range changes {
+			p, _ := sc.Param(name)
+			*p = value.(float64)
+		}
+	})
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	recordAudit(r, audit.Entry{Action: audit.ActionLiveUpdate, Target: s.ID, Changes: audit.Diff(old, sc)})
+	writeSession(w, r, http.StatusOK, s)
+}
+
+// speedLiveHandler sets the speed of a session ({"speed": 10}), the factor
+// by which it runs faster than real time, from live.MinSpeed to
+// live.MaxSpeed.
+func speedLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Speed float64 `json:"speed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+	old := s.Speed()
+	if err := s.SetSpeed(req.Speed); err != nil {
+		writeProblem(w, http.StatusBadRequest, codeValidation, "Vitesse invalide", map[string]any{"details": []schema.Error{{Path: "/speed", Message: err.Error()}}})
+		return
+	}
+	recordAudit(r, audit.Entry{Action: audit.ActionLiveSpeed, Target: s.ID, Detail: fmt.Sprintf("vitesse ×%g → ×%g", old, req.Speed)})
+	writeSession(w, r, http.StatusOK, s)
+}
+
+// undoLiveHandler restores the parameters of a session in force before
+// its last change.
+func undoLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	e, err := s.Undo()
+	if err != nil {
+		httpError(w, "Aucune modification à annuler", http.StatusConflict)
+		return
+	}
+	recordAudit(r, audit.Entry{Action: audit.ActionLiveUndo, Target: s.ID, Changes: audit.Diff(e.After, e.Before), Detail: fmt.Sprintf("modification de t = %g s", e.T)})
+	writeSession(w, r, http.StatusOK, s)
+}
+
+// redoLiveHandler applies again the last change of a session undone.
+func redoLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	e, err := s.Redo()
+	if err != nil {
+		httpError(w, "Aucune modification à rétablir", http.StatusConflict)
+		return
+	}
+	recordAudit(r, audit.Entry{Action: audit.ActionLiveRedo, Target: s.ID, Changes: audit.Diff(e.Before, e.After), Detail: fmt.Sprintf("modification de t = %g s", e.T)})
+	writeSession(w, r, http.StatusOK, s)
+}
+
+// pauseLiveHandler pauses a session, for it to be stepped through with
+// stepLiveHandler.
+func pauseLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	if err := s.Pause(); err != nil {
+		httpError(w, "La session est déjà en pause", http.StatusConflict)
+		return
+	}
+	_, sample := s.Snapshot()
+	recordAudit(r, audit.Entry{Action: audit.ActionLivePause, Target: s.ID, Detail: fmt.Sprintf("pause à t = %g s", sample.T)})
+	writeSession(w, r, http.StatusOK, s)
+}
+
+// resumeLiveHandler runs a paused session on in real time.
+func resumeLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	if err := s.Resume(); err != nil {
+		httpError(w, "La session n'est pas en pause", http.StatusConflict)
+		return
+	}
+	_, sample := s.Snapshot()
+	recordAudit(r, audit.Entry{Action: audit.ActionLiveResume, Target: s.ID, Detail: fmt.Sprintf("reprise à t = %g s", sample.T)})
+	writeSession(w, r, http.StatusOK, s)
+}
+
+// stepLiveHandler advances a paused session by exactly one controller
+// cycle and answers the sample, with the error and the P, I and D terms
+// behind its output.
+func stepLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	sample, err := s.Step()
+	if err != nil {
+		httpError(w, "La session doit être en pause pour avancer pas à pas", http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, sample)
+}
+
+// liveEdit is a parameter change of a session as listed by
+// editsLiveHandler.
+type liveEdit struct {
+	T       float64        `json:"t"`
+	Changes []audit.Change `json:"changes"`
+}
+
+// editsLiveHandler lists the parameter changes of a session that can be
+// undone and redone, the next one to undo or redo last.
+func editsLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	list := func(edits []live.Edit) []liveEdit {
+		out := make([]liveEdit, len(edits))
+		for i, e := range edits {
+			out[i] = liveEdit{T: e.T, Changes: audit.Diff(e.Before, e.After)}
+		}
+		return out
+	}
+	undo, redo := s.Edits()
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{"undo": list(undo), "redo": list(redo)})
+}
+
+func stopLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	sessions.Lock()
+	delete(sessions.byID, s.ID)
+	delete(sessions.configs, s.ID)
+	sessions.Unlock()
+
+	s.Stop()
+	recordAudit(r, audit.Entry{Action: audit.ActionLiveStop, Target: s.ID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// alarmsLiveHandler lists the alarms of a session with their state.
+func alarmsLiveHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{"alarms": s.Alarms()})
+}
+
+// stateLiveHandler answers the internal state of the loop of a session:
+// integrator, last error, derivative estimate, filters and plant.
+func stateLiveHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, s.Inspect())
+}
+
+// breakpointsLiveHandler lists the breakpoints of a session with their
+// hits.
+func breakpointsLiveHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{"breakpoints": s.Breakpoints()})
+}
+
+// setBreakpointsHandler replaces the breakpoints of a running session
+// ({"breakpoints": [...]}), to set up a teaching moment without
+// restarting it.
+func setBreakpointsHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Breakpoints []live.BreakpointConfig `json:"breakpoints"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+	if errs := live.CheckBreakpoints(req.Breakpoints); len(errs) > 0 {
+		writeProblem(w, http.StatusBadRequest, codeValidation, "Points d'arrêt invalides", map[string]any{"details": errs})
+		return
+	}
+	s.SetBreakpoints(req.Breakpoints)
+	sessions.Lock()
+	if cfg, ok := sessions.configs[s.ID]; ok {
+		cfg.Breakpoints = req.Breakpoints
+		sessions.configs[s.ID] = cfg
+	}
+	sessions.Unlock()
+	recordAudit(r, audit.Entry{Action: audit.ActionLiveBreak, Target: s.ID, Detail: fmt.Sprintf("%d point(s) d'arrêt", len(req.Breakpoints))})
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{"breakpoints": s.Breakpoints()})
+}
+
+// ackAlarmHandler acknowledges an alarm of a session.
+func ackAlarmHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	a, err := s.Acknowledge(r.PathValue("name"))
+	writeAlarm(w, r, s, a, err, audit.ActionAlarmAck, "")
+}
+
+// shelveAlarmHandler shelves an alarm of a session for a duration in
+// seconds, at most live.MaxShelve, with the reason the operator gives.
+func shelveAlarmHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Duration float64 `json:"duration"`
+		Reason   string  `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+	if req.Duration <= 0 || req.Duration > live.MaxShelve.Seconds() {
+		writeProblem(w, http.StatusBadRequest, codeValidation, "Mise en veille invalide", map[string]any{"details": []schema.Error{{
+			Path: "/duration", Message: fmt.Sprintf("la durée de mise en veille doit être comprise entre 0 exclu et %g s", live.MaxShelve.Seconds()),
+		}}})
+		return
+	}
+	detail := fmt.Sprintf("pour %g s", req.Duration)
+	if req.Reason != "" {
+		detail += " : " + req.Reason
+	}
+	a, err := s.Shelve(r.PathValue("name"), time.Duration(req.Duration*float64(time.Second)))
+	writeAlarm(w, r, s, a, err, audit.ActionAlarmShelve, detail)
+}
+
+// unshelveAlarmHandler puts a shelved alarm of a session back in service.
+func unshelveAlarmHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	a, err := s.Unshelve(r.PathValue("name"))
+	writeAlarm(w, r, s, a, err, audit.ActionAlarmUnshelve, "")
+}
+
+// writeAlarm answers the alarm an operator action applied to and records
+// the action, or answers its error: 404 for an unknown alarm, 409 for an
+// action the state of the alarm does not allow.
+func writeAlarm(w http.ResponseWriter, r *http.Request, s *live.Session, a live.Alarm, err error, action, detail string) {
+	switch {
+	case errors.Is(err, live.ErrUnknownAlarm):
+		httpError(w, "Alarme introuvable", http.StatusNotFound)
+		return
+	case err != nil:
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	recordAudit(r, audit.Entry{Action: action, Target: s.ID, Detail: strings.TrimSpace("alarme " + a.Name + " " + detail)})
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, a)
+}
+
+// plotLiveHandler answers the trend plot of a session, its recent samples
+// over its bands with the limits of its alarms, as SVG or, with
+// ?format=png, PNG, styled by the options of plotHandler.
+func plotLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "svg"
+	}
+	contentType, ok := plotFormats[format]
+	if !ok {
+		httpError(w, fmt.Sprintf("Format d'image inconnu %q, attendu svg ou png", format), http.StatusBadRequest)
+		return
+	}
+	opts, err := plotFormatOptions(r, format)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := s.WritePlot(&buf, format, opts); err != nil {
+		httpError(w, "Erreur lors du tracé", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	buf.WriteTo(w)
+}
+
+// suggestionsLiveHandler lists the pending tuning suggestions of a session.
+func suggestionsLiveHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{"suggestions": s.Suggestions()})
+}
+
+// applySuggestionHandler applies a pending suggestion to its session, as a
+// PATCH with the suggested values would.
+func applySuggestionHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	old, _ := s.Snapshot()
+	sc, err := s.Apply(r.PathValue("sid"))
+	if errors.Is(err, live.ErrUnknownSuggestion) {
+		httpError(w, "Suggestion introuvable ou périmée", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	recordAudit(r, audit.Entry{Action: audit.ActionLiveApply, Target: s.ID, Changes: audit.Diff(old, sc), Detail: "suggestion " + r.PathValue("sid")})
+	writeSession(w, r, http.StatusOK, s)
+}
+
+// writeBreaks sends a "breakpoint" message for every pause of the batch
+// on a breakpoint, with the sample it paused at.
+func writeBreaks(conn *ws.Conn, batch []live.Sample) error {
+	for _, smp := range batch {
+		for _, e := range smp.Events {
+			if e.Type != simulation.EventBreakpoint {
+				continue
+			}
+			if err := conn.WriteJSON(map[string]any{"type": "breakpoint", "data": map[string]any{"event": e, "sample": smp}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// streamLiveHandler pushes the samples of a session over a WebSocket, one
+// message per batch, and the tuning suggestions as they are made, until
+// the session stops or the client leaves.
+func streamLiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	s, ok := lookupSession(w, r)
+	if !ok {
+		return
+	}
+	conn, err := ws.Upgrade(w, r)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer conn.Close()
+
+	samples, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+	suggestions, unsubscribeSuggestions := s.SubscribeSuggestions()
+	defer unsubscribeSuggestions()
+
+	gone := make(chan struct{})
+	go func() {
+		conn.ReadMessage()
+		close(gone)
+	}()
+
+	for {
+		select {
+		case batch := <-samples:
+			if err := conn.WriteJSON(map[string]any{"type": "samples", "data": batch}); err != nil {
+				return
+			}
+			if err := writeBreaks(conn, batch); err != nil {
+				return
+			}
+		case sug := <-suggestions:
+			if err := conn.WriteJSON(map[string]any{"type": "suggestion", "data": sug}); err != nil {
+				return
+			}
+		case <-s.Done():
+			conn.WriteJSON(map[string]any{"type": "stopped"})
+			return
+		case <-gone:
+			return
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/audit"
+	"github.com/Ivan69-tech/PIDControllerResponse/history"
+	"github.com/Ivan69-tech/PIDControllerResponse/jobs"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"github.com/Ivan69-tech/PIDControllerResponse/tuning"
+	"github.com/Ivan69-tech/PIDControllerResponse/ws"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+func getDataHandler(w http.ResponseWriter, r *http.Request) {
+
+	data, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+
+	fmt.Println("Donnée reçue:", data)
+	runScenario(w, r, data)
+}
+
+// runScenario answers the result of the scenario, from the cache when the
+// same scenario already ran, and stores new runs in the history.
+func runScenario(w http.ResponseWriter, r *http.Request, data simulation.Scenario) {
+
+	// The result only depends on the scenario and the simulation code.
+	key := cacheKey(data)
+	if notModified(w, r, `"`+resultVersion+"-"+key+`"`) {
+		return
+	}
+	response, ok := results.Get(key)
+	if !ok {
+		release, admitted := admission.admit(w, stepCost(data, 1, 1))
+		if !admitted {
+			return
+		}
+		response = simulation.Simulation(data).Rounded()
+		response.Permalink = permalinkPath(data)
+		release()
+		results.Put(key, response)
+
+		run := &history.Run{ID: newID(), Created: time.Now().UTC(), Scenario: data, Result: response}
+		if err := runs.Add(run); err != nil {
+			log.Println("Enregistrement dans l'historique impossible :", err)
+		}
+		recordAudit(r, audit.Entry{Action: audit.ActionRun, Target: run.ID, Scenario: &data})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, response)
+}
+
+// parseScenario validates raw against the scenario schema and decodes it.
+// Schema violations are returned with their paths below prefix; err is set
+// when raw is not JSON at all.
+func parseScenario(raw []byte, prefix string) (data simulation.Scenario, errs []schema.Error, err error) {
+
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return data, nil, err
+	}
+
+	if errs := simulation.ScenarioSchema().Validate(doc); len(errs) > 0 {
+		for i := range errs {
+			if prefix != "" && errs[i].Path == "/" {
+				errs[i].Path = prefix
+			} else {
+				errs[i].Path = prefix + errs[i].Path
+			}
+		}
+		return data, errs, nil
+	}
+
+	if err = json.Unmarshal(raw, &data); err != nil {
+		return data, nil, err
+	}
+	errs = data.Check()
+	for i := range errs {
+		errs[i].Path = prefix + errs[i].Path
+	}
+	return data, errs, nil
+}
+
+// decodeScenario is parseScenario for HTTP handlers: on failure the request
+// has been answered and ok is false.
+func decodeScenario(w http.ResponseWriter, raw []byte, prefix string) (data simulation.Scenario, ok bool) {
+
+	data, errs, err := parseScenario(raw, prefix)
+	if err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return data, false
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return data, false
+	}
+	return data, true
+}
+
+// writeValidationErrors answers 400 with every schema violation so that
+// clients can point at the offending fields. Violations all about the
+// plant model are reported as UNSUPPORTED_MODEL, with 422.
+func writeValidationErrors(w http.ResponseWriter, errs []schema.Error) {
+	status, code := http.StatusUnprocessableEntity, codeUnsupportedModel
+	for _, e := range errs {
+		if !strings.HasSuffix(e.Path, "/plant") && !strings.Contains(e.Path, "/stateSpace") {
+			status, code = http.StatusBadRequest, codeValidation
+		}
+	}
+	writeProblem(w, status, code, "Scénario invalide", map[string]any{"details": errs})
+}
+
+//go:embed static/html/*.html
+//go:embed static/js/*.js
+
+var content embed.FS
+
+//go:embed api/openapi.json
+var openAPISpec []byte
+
+var results *resultCache
+
+// evaluations caches the metrics of the scenarios evaluated by the tuning
+// engines, apart from results so that a sweep does not evict the full
+// results of the other endpoints.
+var evaluations *resultCache
+
+// dataDir is the storage directory given by the -data option.
+var dataDir string
+
+var runs *history.Store
+
+func main() {
+
+	addr := flag.String("addr", ":2222", "adresse d'écoute du serveur HTTP")
+	flag.StringVar(&dataDir, "data", "data", "répertoire de stockage des résultats")
+	cacheSize := flag.Int("cache", 256, "nombre de résultats gardés en cache (0 pour désactiver)")
+	workers := flag.Int("workers", runtime.NumCPU(), "nombre de simulations exécutées en parallèle par la file de jobs")
+	queueSize := flag.Int("queue", 64, "taille de la file d'attente des jobs")
+	recordDir := flag.String("record", "", "répertoire où enregistrer chaque requête et sa réponse (fixtures de test)")
+	flag.BoolVar(&admin, "admin", false, "exposer les profils pprof et la capture de trace d'exécution sous /debug/pprof/")
+	flag.Int64Var(&admission.capacity, "max-steps", int64(runtime.NumCPU())*20_000_000, "pas de simulation admis en parallèle (itérations × procédés × candidats) avant de répondre 429, 0 pour ne pas limiter")
+	flag.Int64Var(&admission.perRequest, "max-request-steps", 2_000_000_000, "pas de simulation au-delà desquels une requête est refusée (413), 0 pour ne pas limiter")
+	flag.IntVar(&retention.MaxRuns, "retention-runs", 10_000, "nombre maximal de simulations gardées dans l'historique, les plus anciennes supprimées d'abord (0 pour ne pas limiter)")
+	flag.Int64Var(&retention.MaxBytes, "retention-bytes", 0, "taille maximale de l'historique en octets (0 pour ne pas limiter)")
+	flag.DurationVar(&retention.TTL, "retention-ttl", 0, "durée de conservation des simulations, 720h pour 30 jours (0 pour ne pas limiter) ; l'étiquette keep les exempte")
+	schedulePath := flag.String("schedule", "", "fichier JSON des scénarios à exécuter périodiquement, selon une planification de type cron")
+	selftest := flag.Bool("selftest", false, "comparer le simulateur aux solutions analytiques en boucle fermée puis quitter, avec le code 1 en cas d'écart (intégration continue)")
+	debugElectrical := flag.Bool("debug-electrical", false, "journaliser les valeurs intermédiaires de chaque écoulement de puissance (S, I, Q_L, Q_C, Qsys, QPoc)")
+	soakFor := flag.Duration("soak", 0, "durée d'un test d'endurance lançant des simulations aléatoires en continu (0 pour aucun), suivi sur /debug")
+	wsOrigins := flag.String("ws-origins", "", "origines, séparées par des virgules, des pages web autorisées à ouvrir les WebSockets en plus du serveur lui-même (* pour toutes)")
+	flag.Parse()
+
+	if *wsOrigins != "" {
+		ws.AllowedOrigins = strings.Split(*wsOrigins, ",")
+	}
+
+	if *selftest {
+		os.Exit(runSelftest(os.Stdout))
+	}
+
+	if *debugElectrical {
+		simulation.DebugLog = log.New(os.Stderr, "électrique : ", log.LstdFlags|log.Lmicroseconds)
+	}
+
+	results = newResultCache(*cacheSize)
+	evaluations = newResultCache(16 * *cacheSize)
+	tuning.Evaluate = cachedMetrics
+	pool = jobs.NewPool(*workers, *queueSize)
+
+	if err := selfCheck(dataDir); err != nil {
+		log.Fatal(err)
+	}
+
+	var err error
+	runs, err = history.Open(filepath.Join(dataDir, "history"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	startPruning()
+
+	auditLog, err = audit.Open(filepath.Join(dataDir, "audit.ndjson"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := precomputeDefault(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *schedulePath != "" {
+		entries, err := loadSchedule(*schedulePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		startScheduler(entries)
+	}
+
+	log.Println("Serveur démarré sur http://localhost" + *addr)
+	go trackUsage()
+	var handler http.Handler = instrument(routes())
+	if *soakFor > 0 {
+		go soak.run(handler, *soakFor)
+	}
+	if *recordDir != "" {
+		handler = record(*recordDir, handler)
+	}
+	handler = compress(handler)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
+
+// routes returns the handler of every endpoint, so that the server can be
+// driven by net/http/httptest as well as by ListenAndServe.
+func routes() *http.ServeMux {
+
+	mux := http.NewServeMux()
+
+	staticFS, _ := fs.Sub(content, "static")
+	htmlFS, _ := fs.Sub(content, "static/html")
+
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(staticFS))))
+	mux.HandleFunc("GET /{$}", indexHandler)
+	mux.HandleFunc("GET /default", defaultHandler)
+	mux.HandleFunc("/sendData", getDataHandler)
+	mux.HandleFunc("GET /api/v1/permalinks/{code}", permalinkHandler)
+	mux.HandleFunc("GET /api/v1/permalinks/{code}/scenario", permalinkScenarioHandler)
+	mux.HandleFunc("POST /api/v1/simulate", simulateHandler)
+	mux.HandleFunc("POST /api/v1/simulate/ndjson", simulateNDJSONHandler)
+	mux.HandleFunc("POST /api/v1/simulate/dual", dualHandler)
+	mux.HandleFunc("POST /api/v1/simulate/factorial", factorialHandler)
+	mux.HandleFunc("POST /api/v1/scenario/effective", effectiveScenarioHandler)
+	mux.HandleFunc("POST /api/v1/interactive", interactiveHandler)
+	mux.HandleFunc("POST /api/v1/batch", batchHandler)
+	mux.HandleFunc("POST /api/v1/plot", plotHandler)
+	mux.HandleFunc("POST /api/v1/plot/animation", animationHandler)
+	mux.HandleFunc("POST /api/v1/phase-plane", phasePlaneHandler)
+	mux.HandleFunc("POST /api/v1/distribution", distributionHandler)
+	mux.HandleFunc("POST /api/v1/correlation", correlationHandler)
+	mux.HandleFunc("POST /api/v1/sensitivity", sensitivityHandler)
+	mux.HandleFunc("POST /api/v1/accuracy", accuracyHandler)
+	mux.HandleFunc("POST /api/v1/electrical/flow", flowHandler)
+	mux.HandleFunc("POST /api/v1/electrical/scan", scanHandler)
+	mux.HandleFunc("POST /api/v1/electrical/decoupled", decoupledHandler)
+	mux.HandleFunc("GET /api/v1/openapi.json", openAPIHandler)
+	mux.HandleFunc("GET /api/v1/capabilities", capabilitiesHandler)
+	mux.HandleFunc("GET /api/v1/errors", errorsHandler)
+	mux.HandleFunc("GET /api/v1/presets", listPresetsHandler)
+	mux.HandleFunc("GET /api/v1/presets/{name}", getPresetHandler)
+	mux.HandleFunc("GET /api/v1/schemas/scenario.json", scenarioSchemaHandler)
+	mux.HandleFunc("GET /metrics", metricsHandler)
+	mux.HandleFunc("GET /debug", debugHandler)
+	mux.HandleFunc("GET /selftest", selftestHandler)
+	mux.HandleFunc("GET /debug/storage", storageHandler)
+	if admin {
+		registerProfiling(mux)
+	}
+	mux.HandleFunc("POST /api/v1/sweeps", createSweepHandler)
+	mux.HandleFunc("GET /api/v1/sweeps/{id}", getSweepHandler)
+	mux.HandleFunc("POST /api/v1/sweeps/{id}/refine", refineSweepHandler)
+	mux.HandleFunc("GET /api/v1/sweeps/{id}/surface", sweepSurfaceHandler)
+	mux.HandleFunc("POST /api/v1/tuning/pareto", paretoHandler)
+	mux.HandleFunc("POST /api/v1/tuning/ga", geneticHandler)
+	mux.HandleFunc("GET /api/v1/tuning/ga/stream", geneticStreamHandler)
+	mux.HandleFunc("POST /api/v1/tuning/rules/{rule}", tuningRuleHandler)
+	mux.HandleFunc("POST /api/v1/export/plc", plcExportHandler)
+	mux.HandleFunc("POST /api/v1/export/matlab", matlabExportHandler)
+	mux.HandleFunc("POST /api/v1/loopdata", loopDataHandler)
+	mux.HandleFunc("POST /api/v1/identify", identifyHandler)
+	mux.HandleFunc("POST /api/v1/drive", driveHandler)
+	mux.HandleFunc("POST /api/v1/live", startLiveHandler)
+	mux.HandleFunc("GET /api/v1/live", listLiveHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}", getLiveHandler)
+	mux.HandleFunc("PATCH /api/v1/live/{id}", updateLiveHandler)
+	mux.HandleFunc("DELETE /api/v1/live/{id}", stopLiveHandler)
+	mux.HandleFunc("PUT /api/v1/live/{id}/speed", speedLiveHandler)
+	mux.HandleFunc("POST /api/v1/live/{id}/undo", undoLiveHandler)
+	mux.HandleFunc("POST /api/v1/live/{id}/redo", redoLiveHandler)
+	mux.HandleFunc("POST /api/v1/live/{id}/pause", pauseLiveHandler)
+	mux.HandleFunc("POST /api/v1/live/{id}/resume", resumeLiveHandler)
+	mux.HandleFunc("POST /api/v1/live/{id}/step", stepLiveHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}/edits", editsLiveHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}/state", stateLiveHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}/alarms", alarmsLiveHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}/breakpoints", breakpointsLiveHandler)
+	mux.HandleFunc("PUT /api/v1/live/{id}/breakpoints", setBreakpointsHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}/plot", plotLiveHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}/kpi", kpiLiveHandler)
+	mux.HandleFunc("GET /api/v1/kpi", kpiHandler)
+	mux.HandleFunc("POST /api/v1/live/{id}/alarms/{name}/ack", ackAlarmHandler)
+	mux.HandleFunc("POST /api/v1/live/{id}/alarms/{name}/shelve", shelveAlarmHandler)
+	mux.HandleFunc("POST /api/v1/live/{id}/alarms/{name}/unshelve", unshelveAlarmHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}/stream", streamLiveHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}/distribution", distributionLiveHandler)
+	mux.HandleFunc("GET /api/v1/live/{id}/suggestions", suggestionsLiveHandler)
+	mux.HandleFunc("POST /api/v1/live/{id}/suggestions/{sid}/apply", applySuggestionHandler)
+	mux.HandleFunc("POST /api/v1/jobs", submitJobHandler)
+	mux.HandleFunc("GET /api/v1/jobs/{id}", getJobHandler)
+	mux.HandleFunc("GET /api/v1/history", listHistoryHandler)
+	mux.HandleFunc("GET /api/v1/history/{id}", getHistoryHandler)
+	mux.HandleFunc("GET /api/v1/history/{id}/state", stateHistoryHandler)
+	mux.HandleFunc("PATCH /api/v1/history/{id}", annotateHistoryHandler)
+	mux.HandleFunc("GET /api/v1/report", reportHandler)
+	mux.HandleFunc("GET /api/v1/schedule", listScheduleHandler)
+	mux.HandleFunc("POST /api/v1/schedule/{name}/run", runScheduleHandler)
+	mux.HandleFunc("GET /api/v1/archive", exportArchiveHandler)
+	mux.HandleFunc("POST /api/v1/archive", importArchiveHandler)
+	mux.HandleFunc("GET /api/v1/audit", auditHandler)
+	// The audit log is asked for at /audit, next to /metrics and /debug,
+	// by operators who do not use the API.
+	mux.HandleFunc("GET /audit", auditHandler)
+	mux.HandleFunc("GET /grafana/{$}", grafanaTestHandler)
+	mux.HandleFunc("POST /grafana/search", grafanaSearchHandler)
+	mux.HandleFunc("POST /grafana/query", grafanaQueryHandler)
+	mux.Handle("/", http.StripPrefix("/", http.FileServer(http.FS(htmlFS))))
+
+	return mux
+}
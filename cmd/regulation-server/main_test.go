@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// FuzzParseScenario feeds arbitrary bodies to the scenario decoding, which
+// must neither panic nor accept a scenario its own output does not parse
+// back to, and must answer 400 or 422 whenever it refuses one.
+func FuzzParseScenario(f *testing.F) {
+
+	for _, seed := range []string{
+		defaultScenario,
+		`{"Sp": 1, "Tau": 1, "K": 1, "P": 2, "Ki": 1, "Kd": 0, "dt": 0.05, "N": 100, "theta": 0.2, "uMax": 3, "backCalculation": 1}`,
+		`{"Sp": 1, "Tau": 1, "K": 1, "P": 2, "Ki": 1, "Kd": 0, "dt": 0, "N": -1}`,
+		`{"Sp": 1, "Tau": 1, "K": 1, "P": 2, "Ki": 1, "Kd": 0, "dt": 1, "N": 1000000000, "theta": 1e8}`,
+		`{"plant": "stateSpace", "stateSpace": {"states": 2, "inputs": 1, "outputs": 1, "A": [0]}}`,
+		`{"drive": {"sp": {"points": []}}}`,
+		`[]`,
+		`{`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+
+		sc, errs, err := parseScenario(raw, "/scenario")
+		if err == nil && len(errs) == 0 {
+			b, err := json.Marshal(sc)
+			if err != nil {
+				t.Fatalf("accepted scenario does not marshal: %v", err)
+			}
+			if _, errs, err := parseScenario(b, ""); err != nil || len(errs) > 0 {
+				t.Fatalf("accepted scenario %s does not parse back: %v %v", b, err, errs)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		_, ok := decodeScenario(w, raw, "")
+		switch {
+		case ok != (err == nil && len(errs) == 0):
+			t.Fatalf("decodeScenario ok = %t, parseScenario errs %v, err %v", ok, errs, err)
+		case !ok && w.Code != http.StatusBadRequest && w.Code != http.StatusUnprocessableEntity:
+			t.Fatalf("refused scenario answered %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// writeMetric writes one sample in the Prometheus text exposition format.
+func writeMetric(w io.Writer, name, kind, help string, value any) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	stats := results.Stats()
+	writeMetric(w, "regulation_cache_entries", "gauge", "Nombre de résultats en cache.", stats.Entries)
+	writeMetric(w, "regulation_cache_capacity", "gauge", "Nombre maximal de résultats en cache.", stats.Capacity)
+	writeMetric(w, "regulation_cache_hits_total", "counter", "Simulations servies depuis le cache.", stats.Hits)
+	writeMetric(w, "regulation_cache_misses_total", "counter", "Simulations absentes du cache.", stats.Misses)
+	writeMetric(w, "regulation_cache_evictions_total", "counter", "Résultats retirés du cache faute de place.", stats.Evictions)
+	stats = evaluations.Stats()
+	writeMetric(w, "regulation_evaluation_cache_entries", "gauge", "Nombre d'évaluations des moteurs de réglage en cache.", stats.Entries)
+	writeMetric(w, "regulation_evaluation_cache_hits_total", "counter", "Évaluations des moteurs de réglage servies depuis le cache.", stats.Hits)
+	writeMetric(w, "regulation_evaluation_cache_misses_total", "counter", "Évaluations des moteurs de réglage absentes du cache.", stats.Misses)
+	writeRuntimeMetrics(w)
+	writeKPIMetrics(w)
+}
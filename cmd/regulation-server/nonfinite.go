@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// nonFinite reads how the client wants NaN and infinities written in JSON
+// answers, from the nonfinite parameter of its Accept header:
+// "application/json; nonfinite=string" writes "NaN", "Infinity" and
+// "-Infinity", anything else null.
+func nonFinite(r *http.Request) numfmt.NonFinite {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if _, params, err := mime.ParseMediaType(accept); err == nil && params["nonfinite"] == string(numfmt.NonFiniteString) {
+			return numfmt.NonFiniteString
+		}
+	}
+	return numfmt.NonFiniteNull
+}
+
+// writeJSON writes v as JSON with its non-finite numbers written as the
+// client asked, see nonFinite. When some were written null, the header
+// X-Non-Finite counts them, telling them apart from absent values; it
+// must then be called before the status is written.
+func writeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	mode := nonFinite(r)
+	b, n, err := numfmt.MarshalJSON(v, mode)
+	if err != nil {
+		return err
+	}
+	if n > 0 && mode == numfmt.NonFiniteNull {
+		w.Header().Set("X-Non-Finite", strconv.Itoa(n))
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// jsonEncoder writes a stream of JSON values, one per line, with the
+// non-finite numbers written as the client asked.
+type jsonEncoder struct {
+	w    io.Writer
+	mode numfmt.NonFinite
+}
+
+func newJSONEncoder(w io.Writer, r *http.Request) *jsonEncoder {
+	return &jsonEncoder{w: w, mode: nonFinite(r)}
+}
+
+func (e *jsonEncoder) Encode(v any) error {
+	b, _, err := numfmt.MarshalJSON(v, e.mode)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+)
+
+// permalinkPath returns the path of the permalink of the scenario.
+func permalinkPath(sc simulation.Scenario) string {
+	return "/api/v1/permalinks/" + sc.Permalink()
+}
+
+// readPermalink decodes and validates the scenario of the {code} of the
+// path. On failure the request has been answered and ok is false.
+func readPermalink(w http.ResponseWriter, r *http.Request) (simulation.Scenario, bool) {
+	raw, err := simulation.ParsePermalink(r.PathValue("code"))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return simulation.Scenario{}, false
+	}
+	return decodeScenario(w, raw, "")
+}
+
+// permalinkHandler reruns the scenario of a permalink, answering as
+// /sendData.
+func permalinkHandler(w http.ResponseWriter, r *http.Request) {
+	data, ok := readPermalink(w, r)
+	if !ok {
+		return
+	}
+	runScenario(w, r, data)
+}
+
+// permalinkScenarioHandler returns the scenario of a permalink, to edit it
+// before running it again.
+func permalinkScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	data, ok := readPermalink(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, data)
+}
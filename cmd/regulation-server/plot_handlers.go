@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// plotFormats are the image formats of /api/v1/plot, by their ?format=.
+var plotFormats = map[string]string{
+	"svg": "image/svg+xml",
+	"png": "image/png",
+}
+
+// plotHandler simulates the posted scenario and answers the plot of its
+// response, as SVG or, with ?format=png, PNG. ?locale=en labels it in
+// English with decimal points, for reports; ?theme= and the custom colors
+// and sizes style it, see plotOptions.
+func plotHandler(w http.ResponseWriter, r *http.Request) {
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "svg"
+	}
+	contentType, ok := plotFormats[format]
+	if !ok {
+		httpError(w, fmt.Sprintf("Format d'image inconnu %q, attendu svg ou png", format), http.StatusBadRequest)
+		return
+	}
+	opts, err := plotFormatOptions(r, format)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	release, ok := admission.admit(w, stepCost(sc, 1, 1))
+	if !ok {
+		return
+	}
+	defer release()
+
+	var buf bytes.Buffer
+	if err := sc.Run().WritePlot(&buf, format, opts); err != nil {
+		httpError(w, "Erreur lors du tracé", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	buf.WriteTo(w)
+}
+
+// plotOptions reads the plot options from the query parameters named after
+// their JSON members: ?locale=, ?theme=light|dark|projector, the colors
+// ?background=, ?foreground=, ?setpoint= and ?measurement= as "#rrggbb",
+// the sizes in points ?fontSize= and ?lineWidth=, and ?backend=, the plot
+// backend among those of /api/v1/capabilities.
+func plotOptions(r *http.Request) (simulation.PlotOptions, error) {
+
+	q := r.URL.Query()
+	opts := simulation.PlotOptions{
+		Locale:      q.Get("locale"),
+		Theme:       q.Get("theme"),
+		Background:  q.Get("background"),
+		Foreground:  q.Get("foreground"),
+		Setpoint:    q.Get("setpoint"),
+		Measurement: q.Get("measurement"),
+		Backend:     q.Get("backend"),
+	}
+	for name, size := range map[string]*float64{"fontSize": &opts.FontSize, "lineWidth": &opts.LineWidth} {
+		if s := q.Get(name); s != "" {
+			v, err := numfmt.Parse(s)
+			if err != nil {
+				return opts, fmt.Errorf("paramètre %s invalide %q", name, s)
+			}
+			*size = v
+		}
+	}
+	return opts, opts.Check()
+}
+
+// plotFormatOptions reads the plot options and checks that their backend
+// draws the format, answering which backends do otherwise.
+func plotFormatOptions(r *http.Request, format string) (simulation.PlotOptions, error) {
+
+	opts, err := plotOptions(r)
+	if err != nil {
+		return opts, err
+	}
+	if err := opts.CheckFormat(format); err != nil {
+		return opts, fmt.Errorf("%v (moteurs disponibles : %s)", err, strings.Join(chart.Backends(), ", "))
+	}
+	return opts, nil
+}
+
+// animationHandler simulates the posted scenario and answers an animated
+// GIF of its response: by default the trace drawn over time, or with
+// ?mode=sweep the response morphing as the parameter ?param= goes from
+// ?from= to ?to=. ?frames= sets the number of frames; the plot options are
+// those of plotHandler.
+func animationHandler(w http.ResponseWriter, r *http.Request) {
+
+	q := r.URL.Query()
+	opts, err := plotFormatOptions(r, "gif")
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	frames := 40
+	if s := q.Get("frames"); s != "" {
+		frames, err = strconv.Atoi(s)
+		if err != nil || frames < 1 || frames > simulation.MaxFrames {
+			httpError(w, fmt.Sprintf("Paramètre frames invalide %q : entier de 1 à %d attendu", s, simulation.MaxFrames), http.StatusBadRequest)
+			return
+		}
+	}
+	mode := q.Get("mode")
+	if mode != "" && mode != "trace" && mode != "sweep" {
+		httpError(w, fmt.Sprintf("Mode d'animation inconnu %q, attendu trace ou sweep", mode), http.StatusBadRequest)
+		return
+	}
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if mode == "sweep" {
+		param := q.Get("param")
+		if _, err := sc.Param(param); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var bounds [2]float64
+		for i, name := range []string{"from", "to"} {
+			if bounds[i], err = numfmt.Parse(q.Get(name)); err != nil {
+				httpError(w, fmt.Sprintf("Paramètre %s invalide %q", name, q.Get(name)), http.StatusBadRequest)
+				return
+			}
+		}
+
+		release, ok := admission.admit(w, stepCost(sc, 1, frames))
+		if !ok {
+			return
+		}
+		defer release()
+		values := make([]float64, frames)
+		runs := make([]simulation.Result, frames)
+		for i := range values {
+			values[i] = bounds[0]
+			if frames > 1 {
+				values[i] += (bounds[1] - bounds[0]) * float64(i) / float64(frames-1)
+			}
+			run := sc
+			p, _ := run.Param(param)
+			*p = values[i]
+			runs[i] = run.Run()
+		}
+		err = simulation.AnimateSweep(&buf, runs, param, values, opts)
+	} else {
+		release, ok := admission.admit(w, stepCost(sc, 1, 1))
+		if !ok {
+			return
+		}
+		defer release()
+		err = sc.Run().AnimateTrace(&buf, frames, opts)
+	}
+	if err != nil {
+		httpError(w, "Erreur lors du tracé", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/gif")
+	buf.WriteTo(w)
+}
+
+// phasePlaneHandler simulates the posted scenario and answers its error
+// phase-plane trajectory, as JSON or, with ?format=svg or png, as a plot
+// styled by the options of plotHandler.
+func phasePlaneHandler(w http.ResponseWriter, r *http.Request) {
+
+	format := r.URL.Query().Get("format")
+	contentType, ok := plotFormats[format]
+	if format != "" && format != "json" && !ok {
+		httpError(w, fmt.Sprintf("Format inconnu %q, attendu json, svg ou png", format), http.StatusBadRequest)
+		return
+	}
+	opts, err := plotOptions(r)
+	if err == nil && ok {
+		opts, err = plotFormatOptions(r, format)
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	release, ok := admission.admit(w, stepCost(sc, 1, 1))
+	if !ok {
+		return
+	}
+	defer release()
+	pp := sc.Run().PhasePlane()
+
+	if contentType == "" {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, r, pp.Rounded())
+		return
+	}
+	var buf bytes.Buffer
+	if err := pp.WritePlot(&buf, format, opts); err != nil {
+		httpError(w, "Erreur lors du tracé", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+	"strconv"
+)
+
+// listPresetsHandler lists the presets, only the examples of one difficulty
+// tier with ?tier=.
+func listPresetsHandler(w http.ResponseWriter, r *http.Request) {
+
+	presets := simulation.Presets
+	if s := r.URL.Query().Get("tier"); s != "" {
+		tier, err := strconv.Atoi(s)
+		if err != nil || tier < simulation.TierLagDominant || tier > simulation.TierIntegrating {
+			httpError(w, "Niveau de difficulté invalide", http.StatusBadRequest)
+			return
+		}
+		presets = nil
+		for _, p := range simulation.Presets {
+			if p.Tier == tier {
+				presets = append(presets, p)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, presets)
+}
+
+func getPresetHandler(w http.ResponseWriter, r *http.Request) {
+	preset, ok := simulation.FindPreset(r.PathValue("name"))
+	if !ok {
+		httpError(w, "Préréglage introuvable", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, preset)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes of the problem+json answers. They are stable: clients branch
+// on them rather than on the French messages.
+const (
+	// codeValidation: the request is malformed or a value is out of range.
+	codeValidation = "VALIDATION"
+	// codeUnsupportedModel: the plant is not one the simulator handles, or
+	// the data cannot be fitted by one.
+	codeUnsupportedModel = "UNSUPPORTED_MODEL"
+	// codeDiverged: every simulated response diverged.
+	codeDiverged = "DIVERGED"
+	// codeInfeasible: no tuning satisfies the constraints.
+	codeInfeasible = "INFEASIBLE"
+	// codeTimeout: a remote source did not answer in time.
+	codeTimeout = "TIMEOUT"
+	// codeOverload: the server is busy, retry after Retry-After.
+	codeOverload = "OVERLOAD"
+	// codeTooLarge: the request costs more than a request may.
+	codeTooLarge = "TOO_LARGE"
+	codeNotFound = "NOT_FOUND"
+	// codeConflict: the resource is not in a state the request applies to,
+	// such as undoing with no change left to undo.
+	codeConflict = "CONFLICT"
+	// codeUpstream: a remote source answered with an error.
+	codeUpstream = "UPSTREAM"
+	codeInternal = "INTERNAL"
+)
+
+// errorCode describes a code for the /api/v1/errors catalog.
+type errorCode struct {
+	Code   string `json:"code"`
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+}
+
+var errorCodes = []errorCode{
+	{codeValidation, http.StatusBadRequest, "Requête invalide"},
+	{codeUnsupportedModel, http.StatusUnprocessableEntity, "Modèle de procédé non pris en charge"},
+	{codeDiverged, http.StatusUnprocessableEntity, "Réponse divergente"},
+	{codeInfeasible, http.StatusUnprocessableEntity, "Contraintes irréalisables"},
+	{codeTimeout, http.StatusGatewayTimeout, "Délai dépassé"},
+	{codeOverload, http.StatusTooManyRequests, "Serveur saturé"},
+	{codeTooLarge, http.StatusRequestEntityTooLarge, "Requête trop coûteuse"},
+	{codeNotFound, http.StatusNotFound, "Ressource introuvable"},
+	{codeConflict, http.StatusConflict, "Conflit avec l'état de la ressource"},
+	{codeUpstream, http.StatusBadGateway, "Source distante en erreur"},
+	{codeInternal, http.StatusInternalServerError, "Erreur interne"},
+}
+
+// writeProblem answers an RFC 7807 problem details document. The members
+// of extra, such as the invalid fields of a scenario, are added to it.
+func writeProblem(w http.ResponseWriter, status int, code, detail string, extra map[string]any) {
+
+	title := http.StatusText(status)
+	for _, c := range errorCodes {
+		if c.Code == code {
+			title = c.Title
+		}
+	}
+	doc := map[string]any{
+		"type":   "/api/v1/errors#" + code,
+		"title":  title,
+		"status": status,
+		"code":   code,
+	}
+	if detail != "" {
+		doc["detail"] = detail
+	}
+	for k, v := range extra {
+		doc[k] = v
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(doc)
+}
+
+// httpError is http.Error answering problem+json, with the code implied by
+// the status.
+func httpError(w http.ResponseWriter, detail string, status int) {
+	code := codeInternal
+	switch status {
+	case http.StatusBadRequest:
+		code = codeValidation
+	case http.StatusNotFound:
+		code = codeNotFound
+	case http.StatusConflict:
+		code = codeConflict
+	case http.StatusBadGateway:
+		code = codeUpstream
+	case http.StatusGatewayTimeout:
+		code = codeTimeout
+	case http.StatusRequestEntityTooLarge:
+		code = codeTooLarge
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		code = codeOverload
+	}
+	writeProblem(w, status, code, detail, nil)
+}
+
+// errorsHandler lists the error codes, the targets of the problem types.
+func errorsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(errorCodes)
+}
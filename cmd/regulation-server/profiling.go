@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// admin enables the profiling endpoints, see the -admin option. They expose
+// the internals of the process and must not be reachable from untrusted
+// networks.
+var admin bool
+
+// registerProfiling mounts net/http/pprof under /debug/pprof/: the index
+// and named profiles (heap, goroutine, allocs, block, mutex), CPU profiles
+// with /debug/pprof/profile?seconds=N and runtime/trace captures with
+// /debug/pprof/trace?seconds=N, to be read with go tool pprof and go tool
+// trace.
+func registerProfiling(mux *http.ServeMux) {
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/ws"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// exchange is one recorded request and the response the server gave, as
+// written by the -record option. A set of exchanges is a fixture that can
+// be replayed against routes() with net/http/httptest to detect changes of
+// the API behavior.
+type exchange struct {
+	Request  recordedMessage `json:"request"`
+	Response recordedMessage `json:"response"`
+}
+
+// recordedMessage is one side of an exchange. Bodies that are valid JSON are
+// kept as is, other text as a string and binary bodies, such as PNG plots or
+// archives, in base64 as BodyBase64.
+type recordedMessage struct {
+	Method      string `json:"method,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Query       string `json:"query,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        any    `json:"body,omitempty"`
+	BodyBase64  []byte `json:"bodyBase64,omitempty"`
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// record wraps h so that every exchange is written to dir as
+// <sequence>-<method>-<path>.json. WebSocket upgrades are not recorded.
+func record(dir string, h http.Handler) http.Handler {
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Println(err)
+	}
+	var seq atomic.Int64
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if ws.IsUpgrade(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		reqBody, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+		rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+
+		ex := exchange{
+			Request: recordedMessage{
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				Query:       r.URL.RawQuery,
+				ContentType: r.Header.Get("Content-Type"),
+			},
+			Response: recordedMessage{
+				Status:      rec.status,
+				ContentType: w.Header().Get("Content-Type"),
+			},
+		}
+		ex.Request.setBody(reqBody)
+		ex.Response.setBody(rec.body.Bytes())
+
+		slug := strings.Trim(unsafeName.ReplaceAllString(r.URL.Path, "-"), "-")
+		if slug == "" {
+			slug = "index"
+		}
+		name := fmt.Sprintf("%05d-%s-%s.json", seq.Add(1), strings.ToLower(r.Method), slug)
+		data, err := json.MarshalIndent(ex, "", "  ")
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, name), data, 0o644)
+		}
+		if err != nil {
+			fmt.Println(err)
+		}
+	})
+}
+
+func (m *recordedMessage) setBody(b []byte) {
+	switch {
+	case len(b) == 0:
+	case json.Valid(b):
+		m.Body = json.RawMessage(b)
+	case utf8.Valid(b):
+		m.Body = string(b)
+	default:
+		m.BodyBase64 = b
+	}
+}
+
+// recordingWriter keeps a copy of the status and body sent to the client.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer,
+// so that the streamed endpoints can flush while recorded.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/audit"
+	"github.com/Ivan69-tech/PIDControllerResponse/history"
+	"github.com/Ivan69-tech/PIDControllerResponse/jobs"
+	"github.com/Ivan69-tech/PIDControllerResponse/tuning"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateFixtures = flag.Bool("update", false, "rewrite the responses of testdata/fixtures with the replayed ones")
+
+// volatilePaths are the endpoints whose answers depend on the wall clock or
+// on the load of the machine, such as the state of a running live session
+// or the metrics of the process. Only their status and content type are
+// compared.
+var volatilePaths = []*regexp.Regexp{
+	regexp.MustCompile(`^/api/v1/live(/|$)`),
+	regexp.MustCompile(`^/api/v1/kpi$`),
+	regexp.MustCompile(`^/api/v1/jobs/`),
+	regexp.MustCompile(`^/api/v1/archive$`),
+	regexp.MustCompile(`^/api/v1/report$`),
+	regexp.MustCompile(`^/metrics$`),
+	regexp.MustCompile(`^/debug(/|$)`),
+	regexp.MustCompile(`^/grafana/query$`),
+}
+
+var (
+	// recordedID matches the identifiers of newID.
+	recordedID = regexp.MustCompile(`^[0-9a-f]{16}$`)
+	timestamp  = regexp.MustCompile(`\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?(Z|[+-]\d\d:\d\d)`)
+	// resultETag matches the ETags of results, whose version changes with
+	// the build.
+	resultETag = regexp.MustCompile(`[0-9a-z]{1,13}-([0-9a-f]{64})`)
+	// resultCode matches the version in the notes of scheduled runs.
+	resultCode = regexp.MustCompile(`code [0-9a-z]{1,13}\b`)
+	jobPath    = regexp.MustCompile(`^/api/v1/jobs/`)
+)
+
+// startTestServer sets up the state main sets up before serving, on a
+// fresh data directory.
+func startTestServer(t *testing.T) {
+	t.Helper()
+
+	dataDir = t.TempDir()
+	results = newResultCache(256)
+	evaluations = newResultCache(16 * 256)
+	tuning.Evaluate = cachedMetrics
+	pool = jobs.NewPool(2, 16)
+	var err error
+	if runs, err = history.Open(filepath.Join(dataDir, "history")); err != nil {
+		t.Fatal(err)
+	}
+	if auditLog, err = audit.Open(filepath.Join(dataDir, "audit.ndjson")); err != nil {
+		t.Fatal(err)
+	}
+	if err := precomputeDefault(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := loadSchedule("testdata/schedule.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(entries)
+}
+
+// TestReplayFixtures replays the exchanges of testdata/fixtures, recorded
+// with -record against a fresh data directory and the schedule of
+// testdata/schedule.json, in order against routes(), and compares the
+// answers with the recorded ones. The identifiers the server draws at
+// random are mapped from the recorded answers to the replayed ones.
+// WebSocket streams are not recorded, hence not replayed.
+//
+// After a deliberate change of the answers, go test -run TestReplayFixtures
+// -update rewrites the recorded responses that differ; new endpoints are
+// recorded with
+//
+//	regulation-server -data $(mktemp -d) -record testdata/fixtures -schedule testdata/schedule.json
+func TestReplayFixtures(t *testing.T) {
+
+	startTestServer(t)
+	h := routes()
+
+	names, err := filepath.Glob("testdata/fixtures/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatal("no fixture in testdata/fixtures")
+	}
+
+	ids := map[string]string{}
+	substitute := func(s string) string {
+		for recorded, replayed := range ids {
+			s = strings.ReplaceAll(s, recorded, replayed)
+		}
+		return s
+	}
+
+	for _, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ex exchange
+		if err := json.Unmarshal(b, &ex); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		path := substitute(ex.Request.Path)
+		target := path
+		if ex.Request.Query != "" {
+			target += "?" + substitute(ex.Request.Query)
+		}
+		body := substitute(string(ex.Request.body()))
+		var w *httptest.ResponseRecorder
+		// A job is polled until it reaches the state it was recorded in.
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			r := httptest.NewRequest(ex.Request.Method, target, strings.NewReader(body))
+			r.RemoteAddr = "127.0.0.1:40000"
+			if ex.Request.ContentType != "" {
+				r.Header.Set("Content-Type", ex.Request.ContentType)
+			}
+			w = httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if !jobPath.MatchString(path) || jobStatus(w.Body.Bytes()) == jobStatus(ex.Response.body()) || time.Now().After(deadline) {
+				break
+			}
+		}
+
+		var wantDoc, gotDoc any
+		if json.Unmarshal(ex.Response.body(), &wantDoc) == nil && json.Unmarshal(w.Body.Bytes(), &gotDoc) == nil {
+			learnIDs(wantDoc, gotDoc, ids)
+		}
+
+		if msg := compare(ex, w, substitute); msg != "" {
+			if *updateFixtures {
+				rewriteFixture(t, name, ex, w, ids)
+				continue
+			}
+			t.Errorf("%s: %s", filepath.Base(name), msg)
+		}
+	}
+}
+
+// compare returns how the replayed answer differs from the recorded one,
+// or "" when it does not.
+func compare(ex exchange, w *httptest.ResponseRecorder, substitute func(string) string) string {
+
+	if w.Code != ex.Response.Status {
+		return fmt.Sprintf("status %d, recorded %d\n%s", w.Code, ex.Response.Status, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ex.Response.ContentType {
+		return fmt.Sprintf("content type %q, recorded %q", ct, ex.Response.ContentType)
+	}
+	if volatile(substitute(ex.Request.Path)) {
+		return ""
+	}
+
+	want, got := ex.Response.body(), w.Body.Bytes()
+	if json.Valid(want) && json.Valid(got) {
+		if !reflect.DeepEqual(normalize(json.RawMessage(substitute(string(want)))), normalize(got)) {
+			return fmt.Sprintf("body differs from the recorded one\n got: %.2000s\nwant: %.2000s", got, want)
+		}
+		return ""
+	}
+	if ex.Response.BodyBase64 != nil {
+		if !bytes.Equal(got, want) {
+			return fmt.Sprintf("binary body of %d bytes differs from the recorded one of %d bytes", len(got), len(want))
+		}
+		return ""
+	}
+	if g, w := normalizeText(string(got)), normalizeText(substitute(string(want))); g != w {
+		return fmt.Sprintf("body differs from the recorded one\n got: %.2000s\nwant: %.2000s", g, w)
+	}
+	return ""
+}
+
+// rewriteFixture writes the replayed response in place of the recorded one,
+// with the replayed identifiers mapped back to the recorded ones so that the
+// requests of the fixtures still refer to them.
+func rewriteFixture(t *testing.T, name string, ex exchange, w *httptest.ResponseRecorder, ids map[string]string) {
+	t.Helper()
+
+	body := w.Body.String()
+	for recorded, replayed := range ids {
+		body = strings.ReplaceAll(body, replayed, recorded)
+	}
+	ex.Response = recordedMessage{Status: w.Code, ContentType: w.Header().Get("Content-Type")}
+	ex.Response.setBody([]byte(body))
+	data, err := json.MarshalIndent(ex, "", "  ")
+	if err == nil {
+		err = os.WriteFile(name, data, 0o644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// body returns the recorded body as it was sent.
+func (m recordedMessage) body() []byte {
+	switch b := m.Body.(type) {
+	case nil:
+		return m.BodyBase64
+	case string:
+		return []byte(b)
+	default:
+		raw, _ := json.Marshal(b)
+		return raw
+	}
+}
+
+func jobStatus(b []byte) string {
+	var job struct {
+		Status string `json:"status"`
+	}
+	json.Unmarshal(b, &job)
+	return job.Status
+}
+
+func volatile(path string) bool {
+	for _, re := range volatilePaths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// learnIDs maps the identifiers of the recorded answer to those at the same
+// place in the replayed one.
+func learnIDs(want, got any, ids map[string]string) {
+	switch w := want.(type) {
+	case string:
+		if g, ok := got.(string); ok && w != g && recordedID.MatchString(w) && recordedID.MatchString(g) {
+			ids[w] = g
+		}
+	case map[string]any:
+		if g, ok := got.(map[string]any); ok {
+			for k, v := range w {
+				learnIDs(v, g[k], ids)
+			}
+		}
+	case []any:
+		if g, ok := got.([]any); ok {
+			for i := range min(len(w), len(g)) {
+				learnIDs(w[i], g[i], ids)
+			}
+		}
+	}
+}
+
+// normalize decodes a JSON answer with its timestamps and result versions
+// masked.
+func normalize(b []byte) any {
+	var doc any
+	json.Unmarshal([]byte(normalizeText(string(b))), &doc)
+	return doc
+}
+
+func normalizeText(s string) string {
+	s = timestamp.ReplaceAllString(s, "<time>")
+	s = resultCode.ReplaceAllString(s, "code <version>")
+	return resultETag.ReplaceAllString(s, "<version>-$1")
+}
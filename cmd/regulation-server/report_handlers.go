@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/report"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+	"strings"
+)
+
+// reportFormats are the media types of the comparison report by format.
+var reportFormats = map[string]string{
+	"html": "text/html; charset=utf-8",
+	"pdf":  "application/pdf",
+}
+
+// reportHandler answers a comparison report of the stored runs given by
+// ?id=, repeated: their responses overlaid and a table of their parameters
+// and metrics with the best values highlighted, as HTML or, with
+// ?format=pdf, as PDF. The plot options are those of plotHandler.
+func reportHandler(w http.ResponseWriter, r *http.Request) {
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "html"
+	}
+	mediaType, ok := reportFormats[format]
+	if !ok {
+		httpError(w, fmt.Sprintf("Format de rapport inconnu %q, attendu html ou pdf", format), http.StatusBadRequest)
+		return
+	}
+	opts, err := plotFormatOptions(r, "svg")
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ids := q["id"]
+	switch {
+	case len(ids) == 0:
+		httpError(w, "Au moins une simulation (?id=) est attendue", http.StatusBadRequest)
+		return
+	case len(ids) > len(simulation.ComparisonPalette):
+		httpError(w, fmt.Sprintf("Au plus %d simulations peuvent être comparées", len(simulation.ComparisonPalette)), http.StatusBadRequest)
+		return
+	}
+	c := report.Comparison{Options: opts}
+	for _, id := range ids {
+		run, ok := runs.Get(id)
+		if !ok {
+			httpError(w, "Simulation introuvable : "+id, http.StatusNotFound)
+			return
+		}
+		label := run.ID
+		if len(run.Tags) > 0 {
+			label += " (" + strings.Join(run.Tags, ", ") + ")"
+		}
+		c.Runs = append(c.Runs, report.Run{Label: label, Scenario: run.Scenario, Result: run.Result})
+	}
+
+	var buf bytes.Buffer
+	if format == "pdf" {
+		err = c.WritePDF(&buf)
+	} else {
+		err = c.WriteHTML(&buf)
+	}
+	if errors.Is(err, chart.ErrUnsupported) {
+		httpError(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		httpError(w, "Erreur lors de la génération du rapport", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/Ivan69-tech/PIDControllerResponse/history"
+	"io/fs"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// pruneInterval is the period at which the history is pruned.
+const pruneInterval = time.Minute
+
+// retention bounds the history, see the -retention-* options.
+var retention history.Retention
+
+var lastPrune = struct {
+	sync.Mutex
+	time    time.Time
+	removed int
+	err     string
+}{}
+
+// prune applies the retention to the history once.
+func prune() {
+
+	removed, err := runs.Prune(retention, time.Now())
+	lastPrune.Lock()
+	lastPrune.time, lastPrune.removed, lastPrune.err = time.Now().UTC(), len(removed), ""
+	if err != nil {
+		lastPrune.err = err.Error()
+	}
+	lastPrune.Unlock()
+
+	if err != nil {
+		log.Println("Nettoyage de l'historique interrompu :", err)
+	} else if len(removed) > 0 {
+		log.Printf("Nettoyage de l'historique : %d simulation(s) supprimée(s)", len(removed))
+	}
+}
+
+// startPruning prunes the history now and every pruneInterval, when a
+// retention is set.
+func startPruning() {
+	if retention == (history.Retention{}) {
+		return
+	}
+	prune()
+	go func() {
+		for range time.Tick(pruneInterval) {
+			prune()
+		}
+	}()
+}
+
+// storageReport is the response of /debug/storage.
+type storageReport struct {
+	History   history.Usage     `json:"history"`
+	Retention history.Retention `json:"retention"`
+	// TTL is Retention.TTL as a Go duration, such as "720h0m0s".
+	TTL string `json:"ttl,omitempty"`
+	// DataBytes is the size of every file of the storage directory,
+	// including the audit log.
+	DataBytes int64 `json:"dataBytes"`
+	LastPrune *struct {
+		Time    time.Time `json:"time"`
+		Removed int       `json:"removed"`
+		Error   string    `json:"error,omitempty"`
+	} `json:"lastPrune,omitempty"`
+}
+
+// storageHandler reports the storage used by the history and the data
+// directory, the retention and the outcome of the last pruning.
+func storageHandler(w http.ResponseWriter, r *http.Request) {
+
+	report := storageReport{History: runs.Usage(), Retention: retention}
+	if retention.TTL > 0 {
+		report.TTL = retention.TTL.String()
+	}
+	filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				report.DataBytes += info.Size()
+			}
+		}
+		return nil
+	})
+	lastPrune.Lock()
+	if !lastPrune.time.IsZero() {
+		report.LastPrune = &struct {
+			Time    time.Time `json:"time"`
+			Removed int       `json:"removed"`
+			Error   string    `json:"error,omitempty"`
+		}{lastPrune.time, lastPrune.removed, lastPrune.err}
+	}
+	lastPrune.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, report)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/audit"
+	"github.com/Ivan69-tech/PIDControllerResponse/cron"
+	"github.com/Ivan69-tech/PIDControllerResponse/history"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scheduledTag marks the runs stored by the scheduler, which are also
+// tagged with the name of their entry: GET /api/v1/history?tag=<name>
+// then follows the performance of a tuning from night to night, across
+// code and model changes.
+const scheduledTag = "scheduled"
+
+// scheduleEntry is a scenario the scheduler runs periodically, as read
+// from the file of the -schedule option:
+//
+//	{"runs": [{"name": "four", "cron": "0 2 * * *", "preset": "..."},
+//	          {"name": "bac", "cron": "@nightly", "scenario": {...}}]}
+type scheduleEntry struct {
+	Name     string          `json:"name"`
+	Cron     string          `json:"cron"`
+	Preset   string          `json:"preset,omitempty"`
+	Scenario json.RawMessage `json:"scenario,omitempty"`
+
+	schedule cron.Schedule
+	scenario simulation.Scenario
+}
+
+// scheduleStatus is an entry as listed by /api/v1/schedule.
+type scheduleStatus struct {
+	Name string    `json:"name"`
+	Cron string    `json:"cron"`
+	Next time.Time `json:"next"`
+	// Last is the ID of the last run stored, LastStatus its outcome.
+	Last       string            `json:"last,omitempty"`
+	LastTime   *time.Time        `json:"lastTime,omitempty"`
+	LastStatus simulation.Status `json:"lastStatus,omitempty"`
+}
+
+var scheduler = struct {
+	sync.Mutex
+	entries []*scheduleEntry
+	status  map[string]*scheduleStatus
+}{status: map[string]*scheduleStatus{}}
+
+// loadSchedule reads and checks the schedule file at path.
+func loadSchedule(path string) ([]*scheduleEntry, error) {
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Runs []*scheduleEntry `json:"runs"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("planification %s illisible : %w", path, err)
+	}
+
+	names := map[string]bool{}
+	for i, e := range doc.Runs {
+		where := fmt.Sprintf("planification %s, /runs/%d", path, i)
+		switch {
+		case e.Name == "" || strings.ContainsAny(e.Name, "/ "):
+			return nil, fmt.Errorf("%s : nom invalide %q", where, e.Name)
+		case names[e.Name]:
+			return nil, fmt.Errorf("%s : nom %q en double", where, e.Name)
+		case (e.Preset == "") == (e.Scenario == nil):
+			return nil, fmt.Errorf("%s : preset ou scenario attendu, pas les deux", where)
+		}
+		names[e.Name] = true
+		if e.schedule, err = cron.Parse(e.Cron); err != nil {
+			return nil, fmt.Errorf("%s : %w", where, err)
+		}
+		if e.Preset != "" {
+			p, ok := simulation.FindPreset(e.Preset)
+			if !ok {
+				return nil, fmt.Errorf("%s : préréglage inconnu %q", where, e.Preset)
+			}
+			e.scenario = p.Scenario
+			continue
+		}
+		sc, errs, err := parseScenario(e.Scenario, "/scenario")
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", where, err)
+		}
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("%s : scénario invalide, %s %s", where, errs[0].Path, errs[0].Message)
+		}
+		e.scenario = sc
+	}
+	return doc.Runs, nil
+}
+
+// startScheduler runs every entry at the times of its schedule, in the
+// local time of the server, until the process exits.
+func startScheduler(entries []*scheduleEntry) {
+
+	scheduler.Lock()
+	scheduler.entries = entries
+	for _, e := range entries {
+		scheduler.status[e.Name] = &scheduleStatus{Name: e.Name, Cron: e.Cron, Next: e.schedule.Next(time.Now())}
+	}
+	scheduler.Unlock()
+
+	for _, e := range entries {
+		go func() {
+			for {
+				scheduler.Lock()
+				next := scheduler.status[e.Name].Next
+				scheduler.Unlock()
+				if next.IsZero() {
+					return
+				}
+				time.Sleep(time.Until(next))
+				runScheduled(e)
+			}
+		}()
+	}
+}
+
+// runScheduled runs an entry and stores the run in the history.
+func runScheduled(e *scheduleEntry) (*history.Run, error) {
+
+	sc := e.scenario
+	res := sc.Run().Rounded()
+	run := &history.Run{
+		ID:       newID(),
+		Created:  time.Now().UTC(),
+		Scenario: sc,
+		Result:   res,
+		Notes:    fmt.Sprintf("Exécution planifiée %q (%s), code %s", e.Name, e.Cron, resultVersion),
+		Tags:     []string{scheduledTag, e.Name},
+	}
+	err := runs.Add(run)
+
+	scheduler.Lock()
+	st := scheduler.status[e.Name]
+	st.Next = e.schedule.Next(time.Now())
+	if err == nil {
+		st.Last, st.LastTime, st.LastStatus = run.ID, &run.Created, res.Status
+	}
+	scheduler.Unlock()
+
+	if err != nil {
+		log.Printf("Exécution planifiée %q non enregistrée : %v", e.Name, err)
+		return nil, err
+	}
+	if auditLog != nil {
+		if err := auditLog.Record(audit.Entry{Action: audit.ActionScheduled, Target: run.ID, Scenario: &sc, Detail: e.Name}); err != nil {
+			log.Println("Enregistrement dans le journal d'audit impossible :", err)
+		}
+	}
+	return run, nil
+}
+
+// listScheduleHandler lists the scheduled entries, their next run and the
+// outcome of their last one.
+func listScheduleHandler(w http.ResponseWriter, r *http.Request) {
+
+	scheduler.Lock()
+	list := make([]scheduleStatus, 0, len(scheduler.entries))
+	for _, e := range scheduler.entries {
+		list = append(list, *scheduler.status[e.Name])
+	}
+	scheduler.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, list)
+}
+
+// runScheduleHandler runs a scheduled entry at once, as when checking a
+// change without waiting for the night, and answers the stored run.
+func runScheduleHandler(w http.ResponseWriter, r *http.Request) {
+
+	var entry *scheduleEntry
+	scheduler.Lock()
+	for _, e := range scheduler.entries {
+		if e.Name == r.PathValue("name") {
+			entry = e
+		}
+	}
+	scheduler.Unlock()
+	if entry == nil {
+		httpError(w, "Planification introuvable", http.StatusNotFound)
+		return
+	}
+	release, ok := admission.admit(w, stepCost(entry.scenario, 1, 1))
+	if !ok {
+		return
+	}
+	run, err := runScheduled(entry)
+	release()
+	if err != nil {
+		httpError(w, "Erreur lors de l'enregistrement de la simulation", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/history/"+run.ID)
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, r, runSummary{ID: run.ID, Created: run.Created, Scenario: run.Scenario, Notes: run.Notes, Tags: run.Tags})
+}
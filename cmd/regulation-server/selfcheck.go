@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// requiredAssets lists the embedded files the web UI cannot work without.
+var requiredAssets = []string{
+	"static/html/index.html",
+	"static/html/nav.html",
+	"static/js/chart.js",
+	"static/js/jquery.js",
+	"static/js/chartjs-adapter-date-fns.js",
+	"static/js/marked.min.js",
+}
+
+// backendChecks verify the plot backends compiled in, such as their fonts.
+var backendChecks []func() error
+
+// selfCheck verifies that the embedded assets, the fonts used by the SVG
+// plots and the storage directory are usable, so that a broken build or
+// deployment fails at startup instead of with 404s or panics on the first
+// request. Every problem found is reported, not only the first one.
+func selfCheck(dataDir string) error {
+
+	var problems []string
+
+	for _, name := range requiredAssets {
+		info, err := fs.Stat(content, name)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("fichier embarqué manquant %q : vérifier qu'il existe puis recompiler le binaire", name))
+			continue
+		}
+		if info.Size() == 0 {
+			problems = append(problems, fmt.Sprintf("fichier embarqué vide %q : le restaurer depuis le dépôt puis recompiler le binaire", name))
+		}
+	}
+
+	for _, check := range backendChecks {
+		if err := check(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if err := checkWritable(dataDir); err != nil {
+		problems = append(problems, fmt.Sprintf("répertoire de stockage %q inutilisable (%v) : le créer avec les droits d'écriture ou changer l'option -data", dataDir, err))
+	}
+
+	if len(problems) > 0 {
+		return errors.New("auto-vérification au démarrage échouée :\n - " + strings.Join(problems, "\n - "))
+	}
+	return nil
+}
+
+// checkWritable creates dir if needed and makes sure a file can be written
+// and removed in it.
+func checkWritable(dir string) error {
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, ".selfcheck-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+
+	return os.Remove(filepath.Clean(name))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/validation"
+	"io"
+	"net/http"
+)
+
+// selftestHandler runs the accuracy suite of package validation and
+// answers its report, with 500 when a case fails, so that a monitoring
+// probe or a deployment check sees a regression of the simulator.
+func selftestHandler(w http.ResponseWriter, r *http.Request) {
+
+	rep := validation.Suite()
+	w.Header().Set("Content-Type", "application/json")
+	if !rep.Passed {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	writeJSON(w, r, rep.Rounded())
+}
+
+// runSelftest runs the accuracy suite for -selftest, writing one line per
+// case to w, and returns the exit status: 1 when a case fails.
+func runSelftest(w io.Writer) int {
+
+	rep := validation.Suite()
+	for _, c := range rep.Cases {
+		status := "ok"
+		if !c.Passed {
+			status = "ÉCHEC"
+		}
+		fmt.Fprintf(w, "%-6s %-16s", status, c.Name)
+		for _, run := range c.Runs {
+			fmt.Fprintf(w, "  dt = %-8.3g erreur max = %-9.3g", run.Dt, run.MaxError)
+		}
+		fmt.Fprintln(w)
+		if c.Reason != "" {
+			fmt.Fprintln(w, "       "+c.Reason)
+		}
+	}
+	i := rep.Integral
+	status := "ok"
+	if !i.Passed {
+		status = "ÉCHEC"
+	}
+	fmt.Fprintf(w, "%-6s %-16s  %d pas de %g s, erreur relative %.3g (somme naïve : %.3g)\n", status, "intégrale", i.Steps, i.Dt, i.Error, i.NaiveError)
+	if !rep.Passed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+	"slices"
+)
+
+// sensitivityHandler answers the local sensitivity of the metrics ?metric=,
+// repeated, to each gain of the posted scenario, nudged by ?step= of its
+// value (1 % by default): which knob to turn next, and which way.
+func sensitivityHandler(w http.ResponseWriter, r *http.Request) {
+
+	q := r.URL.Query()
+	metrics := q["metric"]
+	for _, name := range metrics {
+		if !slices.Contains(simulation.MetricNames, name) {
+			httpError(w, fmt.Sprintf("Indicateur inconnu %q", name), http.StatusBadRequest)
+			return
+		}
+	}
+	step := 0.01
+	if s := q.Get("step"); s != "" {
+		v, err := numfmt.Parse(s)
+		if err != nil {
+			httpError(w, fmt.Sprintf("Paramètre step invalide %q", s), http.StatusBadRequest)
+			return
+		}
+		step = v
+	}
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	release, ok := admission.admit(w, stepCost(sc, 1, 1+2*len(simulation.SensitivityGains)))
+	if !ok {
+		return
+	}
+	defer release()
+
+	s, err := sc.Sensitivity(metrics, step)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, s.Rounded())
+}
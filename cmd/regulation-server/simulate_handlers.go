@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// simulationResult is the response of /api/v1/simulate.
+type simulationResult struct {
+	Scenario simulation.Scenario `json:"scenario"`
+	simulation.Result
+}
+
+// startTime reads the optional ?start= parameter anchoring a result at an
+// absolute time: an RFC 3339 timestamp or "now". On failure the request has
+// been answered and ok is false.
+func startTime(w http.ResponseWriter, r *http.Request) (start *time.Time, ok bool) {
+
+	value := r.URL.Query().Get("start")
+	switch value {
+	case "":
+		return nil, true
+	case "now":
+		now := time.Now().UTC()
+		return &now, true
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Paramètre start invalide %q : date RFC 3339 ou \"now\" attendue", value), http.StatusBadRequest)
+		return nil, false
+	}
+	return &t, true
+}
+
+// readScenario reads and validates the scenario posted as the request body,
+// JSON or form-encoded, completed by the layer the query names, see
+// withDefaults. On failure the request has been answered and ok is false.
+func readScenario(w http.ResponseWriter, r *http.Request) (simulation.Scenario, bool) {
+	body, err := scenarioBody(r)
+	if err != nil {
+		httpError(w, "Erreur lors de la lecture de la requête", http.StatusBadRequest)
+		fmt.Println(err)
+		return simulation.Scenario{}, false
+	}
+	if body, err = withDefaults(r, body); err != nil {
+		status := http.StatusBadRequest
+		if errors.As(err, new(unknownLayerError)) {
+			status = http.StatusNotFound
+		}
+		httpError(w, err.Error(), status)
+		return simulation.Scenario{}, false
+	}
+	return decodeScenario(w, body, "")
+}
+
+func simulateHandler(w http.ResponseWriter, r *http.Request) {
+
+	start, ok := startTime(w, r)
+	if !ok {
+		return
+	}
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	res, ok := cachedResult(w, sc)
+	if !ok {
+		return
+	}
+	if start != nil {
+		res = res.At(*start)
+	}
+
+	if r.URL.Query().Get("type") == "csv" {
+		dialect, err := csvDialect(r)
+		if err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "simulation.csv"))
+		res.WriteCSV(w, dialect)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, simulationResult{Scenario: sc, Result: res})
+}
+
+// cachedResult returns the rounded result of the scenario with its
+// permalink, from results when the same scenario ran before. Otherwise the
+// scenario is simulated once admitted; if it is not, the request has been
+// answered and ok is false.
+func cachedResult(w http.ResponseWriter, sc simulation.Scenario) (simulation.Result, bool) {
+
+	key := cacheKey(sc)
+	if res, ok := results.Get(key); ok {
+		return res, true
+	}
+	release, ok := admission.admit(w, stepCost(sc, 1, 1))
+	if !ok {
+		return simulation.Result{}, false
+	}
+	res := simulation.Simulation(sc).Rounded()
+	res.Permalink = permalinkPath(sc)
+	release()
+	results.Put(key, res)
+	return res, true
+}
+
+// cachedMetrics is tuning.Evaluate through evaluations, so that the
+// optimizers and sweeps of different requests simulate a scenario once.
+func cachedMetrics(sc simulation.Scenario) simulation.Metrics {
+
+	key := cacheKey(sc)
+	if res, ok := evaluations.Get(key); ok {
+		return res.Metrics
+	}
+	m := sc.Evaluate()
+	evaluations.Put(key, simulation.Result{Metrics: m})
+	return m
+}
+
+// simulateNDJSONHandler streams one JSON record per sample, readable with
+// pandas.read_json(..., lines=True). With ?start= every record also has
+// its RFC 3339 timestamp.
+func simulateNDJSONHandler(w http.ResponseWriter, r *http.Request) {
+
+	start, ok := startTime(w, r)
+	if !ok {
+		return
+	}
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	res, ok := cachedResult(w, sc)
+	if !ok {
+		return
+	}
+	if start != nil {
+		res = res.At(*start)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := newJSONEncoder(w, r)
+	for i := range res.Time {
+		var ts *time.Time
+		if res.Start != nil {
+			ts = &res.Timestamps[i]
+		}
+		enc.Encode(struct {
+			TS *time.Time `json:"ts,omitempty"`
+			T  float64    `json:"t"`
+			SP float64    `json:"sp"`
+			PV float64    `json:"pv"`
+			U  float64    `json:"u"`
+		}{ts, res.Time[i], res.SP[i], res.PV[i], res.U[i]})
+	}
+}
+
+// dualHandler runs the posted scenario on its ideal plant and with its
+// non-idealities, see simulation.Dual, and answers both runs or, with
+// ?format=svg or png, their overlay styled as by plotHandler.
+func dualHandler(w http.ResponseWriter, r *http.Request) {
+
+	format := r.URL.Query().Get("format")
+	contentType, ok := plotFormats[format]
+	if format != "" && !ok {
+		httpError(w, fmt.Sprintf("Format d'image inconnu %q, attendu svg ou png", format), http.StatusBadRequest)
+		return
+	}
+	var opts simulation.PlotOptions
+	if format != "" {
+		var err error
+		if opts, err = plotFormatOptions(r, format); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	release, ok := admission.admit(w, stepCost(sc, 1, sc.DualRuns()))
+	if !ok {
+		return
+	}
+	defer release()
+
+	d := sc.Dual()
+	if format == "" {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, r, d.Rounded())
+		return
+	}
+	f, err := d.Figure(opts)
+	if err != nil {
+		httpError(w, "Erreur lors du tracé", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := opts.Render(&buf, f, format); err != nil {
+		httpError(w, "Erreur lors du tracé", http.StatusInternalServerError)
+		fmt.Println(err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	buf.WriteTo(w)
+}
+
+// factorialHandler runs every combination of the non-idealities of the
+// posted scenario and answers which one degrades which of the metrics
+// ?metric=, repeated, most, see simulation.Factorial.
+func factorialHandler(w http.ResponseWriter, r *http.Request) {
+
+	metrics := r.URL.Query()["metric"]
+	for _, name := range metrics {
+		if !slices.Contains(simulation.MetricNames, name) {
+			httpError(w, fmt.Sprintf("Indicateur inconnu %q", name), http.StatusBadRequest)
+			return
+		}
+	}
+
+	sc, ok := readScenario(w, r)
+	if !ok {
+		return
+	}
+	release, ok := admission.admit(w, stepCost(sc, 1, sc.FactorialRuns()))
+	if !ok {
+		return
+	}
+	defer release()
+
+	f, err := sc.Factorial(metrics)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, f.Rounded())
+}
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
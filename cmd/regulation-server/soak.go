@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// soakLiveEvery is the number of simulations between two live sessions
+// started by the soak test.
+const soakLiveEvery = 50
+
+// soakLiveDuration is how long each live session of the soak test runs.
+const soakLiveDuration = 3 * time.Second
+
+// soakStatus is the progress of the soak test, reported on /debug.
+type soakStatus struct {
+	Running      bool       `json:"running"`
+	Started      *time.Time `json:"started,omitempty"`
+	Until        *time.Time `json:"until,omitempty"`
+	Simulations  int64      `json:"simulations"`
+	LiveSessions int64      `json:"liveSessions"`
+	Failures     int64      `json:"failures"`
+	LastFailure  string     `json:"lastFailure,omitempty"`
+}
+
+// soakTest runs random scenarios through the HTTP handlers for a given
+// time, started by the -soak option, so that leaks in the handlers, the
+// streaming and the live sessions show up in the usage tracked by /debug.
+type soakTest struct {
+	mu sync.Mutex
+	st soakStatus
+}
+
+var soak soakTest
+
+func (s *soakTest) status() soakStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.st
+}
+
+// run drives h until d has elapsed, then logs a summary.
+func (s *soakTest) run(h http.Handler, d time.Duration) {
+
+	start := time.Now()
+	started, until := start.UTC(), start.Add(d).UTC()
+	s.mu.Lock()
+	s.st = soakStatus{Running: true, Started: &started, Until: &until}
+	s.mu.Unlock()
+	log.Println("Test d'endurance démarré pour", d)
+
+	rng := rand.New(rand.NewPCG(uint64(start.UnixNano()), 0))
+	for i := 1; time.Since(start) < d; i++ {
+		s.request(h, "POST", "/api/v1/simulate", randomScenario(rng), http.StatusOK, &s.st.Simulations)
+		if i%soakLiveEvery == 0 {
+			s.live(h, randomScenario(rng))
+		}
+	}
+
+	st := s.status()
+	s.mu.Lock()
+	s.st.Running = false
+	s.mu.Unlock()
+	log.Printf("Test d'endurance terminé : %d simulations, %d sessions en direct, %d échecs", st.Simulations, st.LiveSessions, st.Failures)
+	usage.Lock()
+	leaks := suspectedLeaks(usage.samples)
+	usage.Unlock()
+	if len(leaks) > 0 {
+		log.Println("Fuites suspectées :", leaks)
+	}
+}
+
+// live starts a session, lets it run and stops it.
+func (s *soakTest) live(h http.Handler, sc simulation.Scenario) {
+
+	rec := s.request(h, "POST", "/api/v1/live", map[string]any{"scenario": sc}, http.StatusCreated, &s.st.LiveSessions)
+	if rec == nil {
+		return
+	}
+	var session struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &session)
+	time.Sleep(soakLiveDuration)
+	s.request(h, "PATCH", "/api/v1/live/"+session.ID, map[string]any{"Sp": sc.Sp * 1.1}, http.StatusOK, nil)
+	s.request(h, "DELETE", "/api/v1/live/"+session.ID, nil, http.StatusNoContent, nil)
+}
+
+// request serves one request through h and counts it. The recorder is
+// returned when the answer has the expected status.
+func (s *soakTest) request(h http.Handler, method, path string, body any, want int, counter *int64) *httptest.ResponseRecorder {
+
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("X-Forwarded-User", "soak")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if counter != nil {
+		*counter++
+	}
+	if rec.Code != want {
+		s.st.Failures++
+		s.st.LastFailure = method + " " + path + " : " + rec.Body.String()
+		return nil
+	}
+	return rec
+}
+
+// randomScenario draws a stable-ish loop with a few thousand steps.
+func randomScenario(rng *rand.Rand) simulation.Scenario {
+	between := func(lo, hi float64) float64 { return lo + (hi-lo)*rng.Float64() }
+	controllers := []string{simulation.ControllerPID, simulation.ControllerExternalReset, simulation.ControllerAdaptive}
+	tau := between(0.1, 10)
+	sc := simulation.Scenario{
+		Controller: controllers[rng.IntN(len(controllers))],
+		Sp:         between(1, 100),
+		Tau:        tau,
+		K:          between(0.2, 5),
+		P:          between(0.1, 10),
+		Ki:         between(0.01, 5),
+		Kd:         between(0, 0.5),
+		Dt:         tau / 100,
+		N:          float64(1000 + rng.IntN(4000)),
+	}
+	if rng.IntN(4) == 0 {
+		uMax := between(10, 200)
+		sc.UMax = &uMax
+	}
+	return sc
+}
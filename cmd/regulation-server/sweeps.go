@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"github.com/Ivan69-tech/PIDControllerResponse/tuning"
+	"net/http"
+	"sync"
+)
+
+// maxSweeps bounds the number of sweeps kept in memory; the oldest one is
+// dropped when a new sweep is created beyond it.
+const maxSweeps = 64
+
+// sweeps holds the sweeps by id. Its lock only guards the map: each sweep
+// has its own, held while it is refined, so that refining one sweep does
+// not block the requests on the others.
+var sweeps = struct {
+	sync.Mutex
+	byID  map[string]*sweepEntry
+	order []string
+}{byID: make(map[string]*sweepEntry)}
+
+type sweepEntry struct {
+	sync.Mutex
+	sweep *tuning.Sweep
+}
+
+// lookupSweep returns the sweep of the {id} of the path, locked. On
+// failure the request has been answered and ok is false.
+func lookupSweep(w http.ResponseWriter, r *http.Request) (e *sweepEntry, ok bool) {
+	sweeps.Lock()
+	e, ok = sweeps.byID[r.PathValue("id")]
+	sweeps.Unlock()
+	if !ok {
+		httpError(w, "Balayage introuvable", http.StatusNotFound)
+		return nil, false
+	}
+	e.Lock()
+	return e, true
+}
+
+// sweepCost returns the cost of n cells of a sweep, each simulated with
+// the largest N, and smallest dt, its ranges reach.
+func sweepCost(base simulation.Scenario, x, y tuning.Axis, n int) int64 {
+	worst := base
+	for _, sc := range tuning.Corners(base, x, y) {
+		worst.N, worst.Dt = max(worst.N, sc.N), min(worst.Dt, sc.Dt)
+	}
+	return stepCost(worst, 1, n)
+}
+
+type sweepRequest struct {
+	Scenario json.RawMessage `json:"scenario"`
+	X        tuning.Axis     `json:"x"`
+	Y        tuning.Axis     `json:"y"`
+	Metric   string          `json:"metric"`
+	Grid     int             `json:"grid"`
+}
+
+type sweepResponse struct {
+	ID string `json:"id"`
+	*tuning.Sweep
+}
+
+// newID returns a random identifier for server-side state.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func createSweepHandler(w http.ResponseWriter, r *http.Request) {
+
+	req := sweepRequest{Metric: "itae", Grid: 5}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	base, ok := decodeScenario(w, req.Scenario, "/scenario")
+	if !ok {
+		return
+	}
+
+	// The grid is checked before its size is taken as the cost.
+	if err := tuning.ValidateSweep(base, req.X, req.Y, req.Metric, req.Grid); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	release, ok := admission.admit(w, sweepCost(base, req.X, req.Y, req.Grid*req.Grid))
+	if !ok {
+		return
+	}
+	sweep, err := tuning.NewSweep(base, req.X, req.Y, req.Metric, req.Grid)
+	release()
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := newID()
+	sweeps.Lock()
+	sweeps.byID[id] = &sweepEntry{sweep: sweep}
+	sweeps.order = append(sweeps.order, id)
+	if len(sweeps.order) > maxSweeps {
+		delete(sweeps.byID, sweeps.order[0])
+		sweeps.order = sweeps.order[1:]
+	}
+	sweeps.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, r, sweepResponse{ID: id, Sweep: sweep})
+}
+
+func getSweepHandler(w http.ResponseWriter, r *http.Request) {
+
+	e, ok := lookupSweep(w, r)
+	if !ok {
+		return
+	}
+	defer e.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, sweepResponse{ID: r.PathValue("id"), Sweep: e.sweep})
+}
+
+// refineSweepHandler refines the sweep around its best cells and returns
+// only the points computed by this call.
+func refineSweepHandler(w http.ResponseWriter, r *http.Request) {
+
+	req := struct {
+		Best int `json:"best"`
+	}{Best: 3}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+			fmt.Println(err)
+			return
+		}
+	}
+	if req.Best < 1 {
+		httpError(w, "best doit être au moins 1", http.StatusBadRequest)
+		return
+	}
+
+	e, ok := lookupSweep(w, r)
+	if !ok {
+		return
+	}
+	defer e.Unlock()
+	id, sweep := r.PathValue("id"), e.sweep
+
+	// Each refined cell adds at most the 8 points around it.
+	release, ok := admission.admit(w, sweepCost(sweep.Base, sweep.X, sweep.Y, 8*req.Best))
+	if !ok {
+		return
+	}
+	points := sweep.Refine(req.Best)
+	release()
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{
+		"id":     id,
+		"level":  sweep.Level,
+		"points": points,
+	})
+}
+
+// sweepSurfaceHandler exports the metric landscape of a sweep for 3D
+// viewers: a plotly.js figure by default, or with ?format=csv the grid, in
+// the dialect of csvDialect, and with ?format=obj a Wavefront mesh.
+func sweepSurfaceHandler(w http.ResponseWriter, r *http.Request) {
+
+	id := r.PathValue("id")
+	e, ok := lookupSweep(w, r)
+	if !ok {
+		return
+	}
+	surface := e.sweep.Surface()
+	e.Unlock()
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "plotly":
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, r, surface.Plotly())
+	case "csv":
+		dialect, err := csvDialect(r)
+		if err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "balayage-"+id+".csv"))
+		surface.WriteCSV(w, dialect)
+	case "obj":
+		w.Header().Set("Content-Type", "model/obj")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "balayage-"+id+".obj"))
+		surface.WriteOBJ(w)
+	default:
+		httpError(w, fmt.Sprintf("Format inconnu %q, attendu plotly, csv ou obj", format), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+	"github.com/Ivan69-tech/PIDControllerResponse/tuning"
+	"github.com/Ivan69-tech/PIDControllerResponse/ws"
+	"net/http"
+)
+
+type paretoRequest struct {
+	Scenario   json.RawMessage `json:"scenario"`
+	Objectives []string        `json:"objectives"`
+	Samples    int             `json:"samples"`
+	Seed       uint64          `json:"seed"`
+	tuning.Problem
+}
+
+// plants returns the number of plants each candidate is simulated on.
+func plants(p tuning.Problem) int {
+	if p.Robust == nil {
+		return 1
+	}
+	return p.Robust.Plants + 1
+}
+
+// writeTuningError answers an optimizer failure: 422 with the report when
+// the constraints cannot be met or when every candidate diverged, 400
+// otherwise.
+func writeTuningError(w http.ResponseWriter, err error) {
+
+	var infeasible *tuning.InfeasibleError
+	switch {
+	case errors.As(err, &infeasible):
+		writeProblem(w, http.StatusUnprocessableEntity, codeInfeasible, err.Error(), map[string]any{"report": infeasible.Report})
+	case errors.Is(err, tuning.ErrUnstable):
+		writeProblem(w, http.StatusUnprocessableEntity, codeDiverged, err.Error(), nil)
+	default:
+		httpError(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func paretoHandler(w http.ResponseWriter, r *http.Request) {
+
+	req := paretoRequest{Objectives: []string{"itae", "effort"}, Samples: 200, Seed: 1}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	base, ok := decodeScenario(w, req.Scenario, "/scenario")
+	if !ok {
+		return
+	}
+	req.Base = base
+
+	release, ok := admission.admit(w, stepCost(base, plants(req.Problem), req.Samples))
+	if !ok {
+		return
+	}
+	defer release()
+
+	front, evaluated, err := tuning.Pareto(req.Problem, req.Objectives, req.Samples, req.Seed)
+	if err != nil {
+		writeTuningError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{
+		"objectives": req.Objectives,
+		"evaluated":  evaluated,
+		"front":      front,
+	})
+}
+
+type geneticRequest struct {
+	Scenario json.RawMessage `json:"scenario"`
+	tuning.Problem
+	tuning.GAOptions
+}
+
+func newGeneticRequest() geneticRequest {
+	return geneticRequest{GAOptions: tuning.DefaultGAOptions()}
+}
+
+func geneticHandler(w http.ResponseWriter, r *http.Request) {
+
+	req := newGeneticRequest()
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+
+	base, ok := decodeScenario(w, req.Scenario, "/scenario")
+	if !ok {
+		return
+	}
+	req.Base = base
+	// The options are checked before their product is taken as the cost.
+	if err := req.GAOptions.Validate(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	release, ok := admission.admit(w, stepCost(base, plants(req.Problem), req.Population*req.Generations))
+	if !ok {
+		return
+	}
+	defer release()
+
+	var history []tuning.Generation
+	best, err := tuning.Genetic(req.Problem, req.GAOptions, func(g tuning.Generation) bool {
+		history = append(history, g)
+		return r.Context().Err() == nil
+	})
+	if err != nil {
+		writeTuningError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, map[string]any{
+		"best":        best,
+		"generations": history,
+	})
+}
+
+// geneticStreamHandler runs the genetic tuner over a WebSocket: the client
+// sends the same document as for POST /api/v1/tuning/ga, then receives one
+// "generation" message per generation and a final "result" or "error"
+// message. Closing the socket stops the search.
+func geneticStreamHandler(w http.ResponseWriter, r *http.Request) {
+
+	conn, err := ws.Upgrade(w, r)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer conn.Close()
+
+	fail := func(message string, details any) {
+		conn.WriteJSON(map[string]any{"type": "error", "message": message, "details": details})
+	}
+
+	msg, err := conn.ReadMessage()
+	if err != nil {
+		return
+	}
+
+	req := newGeneticRequest()
+	if err := json.Unmarshal(msg, &req); err != nil {
+		fail("Erreur lors du décodage de la donnée", nil)
+		return
+	}
+	base, errs, err := parseScenario(req.Scenario, "/scenario")
+	if err != nil {
+		fail("Erreur lors du décodage de la donnée", nil)
+		return
+	}
+	if len(errs) > 0 {
+		fail("Scénario invalide", errs)
+		return
+	}
+	req.Base = base
+	if err := req.GAOptions.Validate(); err != nil {
+		fail(err.Error(), nil)
+		return
+	}
+
+	release, refused := admission.reserve(stepCost(base, plants(req.Problem), req.Population*req.Generations))
+	if refused != nil {
+		fail(refused.Detail, refused.Extra)
+		return
+	}
+	defer release()
+
+	// Any message or error on the read side means the client went away.
+	gone := make(chan struct{})
+	go func() {
+		conn.ReadMessage()
+		close(gone)
+	}()
+
+	best, err := tuning.Genetic(req.Problem, req.GAOptions, func(g tuning.Generation) bool {
+		select {
+		case <-gone:
+			return false
+		default:
+		}
+		return conn.WriteJSON(map[string]any{"type": "generation", "data": g}) == nil
+	})
+	var infeasible *tuning.InfeasibleError
+	if errors.As(err, &infeasible) {
+		fail(err.Error(), infeasible.Report)
+		return
+	}
+	if err != nil {
+		fail(err.Error(), nil)
+		return
+	}
+	conn.WriteJSON(map[string]any{"type": "result", "data": best})
+}
+
+// tuningRuleHandler applies a tuning rule of simulation.TuningRules to the
+// model posted and returns the gains with their explanation, to be kept in
+// the "tuning" member of the scenario run with them.
+func tuningRuleHandler(w http.ResponseWriter, r *http.Request) {
+
+	var rule *simulation.Option
+	for i, o := range simulation.TuningRules {
+		if o.Name == r.PathValue("rule") {
+			rule = &simulation.TuningRules[i]
+		}
+	}
+	if rule == nil {
+		httpError(w, "Règle de réglage introuvable", http.StatusNotFound)
+		return
+	}
+
+	var doc any
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+		fmt.Println(err)
+		return
+	}
+	var model simulation.DelayedModel
+	errs := rule.Parameters.Validate(doc)
+	if len(errs) == 0 {
+		raw, _ := json.Marshal(doc)
+		if err := json.Unmarshal(raw, &model); err != nil {
+			httpError(w, "Erreur lors du décodage de la donnée", http.StatusBadRequest)
+			fmt.Println(err)
+			return
+		}
+		errs = model.Check()
+	}
+	if len(errs) > 0 {
+		writeProblem(w, http.StatusBadRequest, codeValidation, "Modèle invalide", map[string]any{"details": errs})
+		return
+	}
+	t, err := simulation.ApplyRule(rule.Name, model)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, r, t)
+}
@@ -0,0 +1,154 @@
+// Package cron reads the five-field schedules of crontab(5), such as
+// "0 2 * * *" for every night at 2:00, and computes their next times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// macros are the shorthands accepted in place of the five fields.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@nightly":  "0 2 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is a parsed schedule: the sets of minutes, hours, days of the
+// month, months and days of the week it fires at.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record a "*" day field: when both day fields are
+	// restricted, either matching is enough, as in crontab(5).
+	domAny, dowAny bool
+}
+
+// fields are the ranges of the five fields; Sunday is 0 or 7.
+var fields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"heure", 0, 23},
+	{"jour du mois", 1, 31},
+	{"mois", 1, 12},
+	{"jour de la semaine", 0, 7},
+}
+
+// Parse reads a schedule: five fields separated by spaces, each "*", a
+// number, a range "a-b", a step "*/n" or "a-b/n", or a comma-separated
+// list of them; or one of @hourly, @daily, @nightly (2:00), @weekly,
+// @monthly and @yearly.
+func Parse(spec string) (Schedule, error) {
+
+	if m, ok := macros[strings.TrimSpace(spec)]; ok {
+		spec = m
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return Schedule{}, fmt.Errorf("planification invalide %q : 5 champs attendus (minute heure jour mois jour-de-semaine)", spec)
+	}
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i].min, fields[i].max)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("planification invalide %q, %s : %w", spec, fields[i].name, err)
+		}
+		sets[i] = set
+	}
+	// Sunday is both 0 and 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return Schedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: parts[2] == "*", dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseField(field string, lo, hi int) (uint64, error) {
+
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		span, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("pas invalide %q", stepText)
+			}
+		}
+		from, to := lo, hi
+		if span != "*" {
+			a, b, isRange := strings.Cut(span, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("valeur invalide %q", a)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("valeur invalide %q", b)
+				}
+			} else if hasStep {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return 0, fmt.Errorf("%q hors de %d-%d", item, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<v) != 0
+}
+
+// day reports whether the schedule fires on the day of t.
+func (s Schedule) day(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t the schedule fires at, in the
+// location of t, or the zero time when it never does, as for February 30.
+func (s Schedule) Next(t time.Time) time.Time {
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Five years cover every combination of a leap day and a weekday.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.day(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,302 @@
+package datasource
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxBytes bounds the size of the data pulled from a source.
+const MaxBytes = 32 << 20
+
+// Timeout bounds the duration of a remote pull.
+const Timeout = 30 * time.Second
+
+// LoopData is the history of a control loop, with times in seconds from
+// the first sample. Series absent from the source are nil.
+type LoopData struct {
+	Start time.Time `json:"start,omitempty"`
+	Time  []float64 `json:"time"`
+	SP    []float64 `json:"sp"`
+	PV    []float64 `json:"pv"`
+	OP    []float64 `json:"op"`
+	// Disturbance is an external load, such as an ambient temperature.
+	Disturbance []float64 `json:"disturbance,omitempty"`
+}
+
+// Columns maps the loop series to the names of the source columns.
+type Columns struct {
+	Time string `json:"time"`
+	SP   string `json:"sp"`
+	PV   string `json:"pv"`
+	OP   string `json:"op"`
+
+	Disturbance string `json:"disturbance,omitempty"`
+}
+
+// DefaultColumns are the columns read when a source does not name them.
+var DefaultColumns = Columns{Time: "time", SP: "sp", PV: "pv", OP: "op", Disturbance: "disturbance"}
+
+// Source describes where loop data is pulled from.
+//
+//   - "csv": Data holds the CSV text inline;
+//   - "http": URL points to a CSV document;
+//   - "influxdb": URL is the InfluxDB server, Query an InfluxQL query run on
+//     Database through the /query API (InfluxDB 1.x, or 2.x with a DBRP
+//     mapping).
+type Source struct {
+	Type     string  `json:"type"`
+	Data     string  `json:"data,omitempty"`
+	URL      string  `json:"url,omitempty"`
+	Database string  `json:"database,omitempty"`
+	Query    string  `json:"query,omitempty"`
+	Username string  `json:"username,omitempty"`
+	Password string  `json:"password,omitempty"`
+	Token    string  `json:"token,omitempty"`
+	Columns  Columns `json:"columns"`
+}
+
+// Fetch pulls the loop data from the source.
+func (s Source) Fetch(ctx context.Context) (LoopData, error) {
+
+	cols := s.Columns
+	if cols == (Columns{}) {
+		cols = DefaultColumns
+	}
+
+	switch s.Type {
+	case "csv":
+		return ParseCSV(strings.NewReader(s.Data), cols)
+
+	case "http":
+		body, err := get(ctx, s.URL, nil)
+		if err != nil {
+			return LoopData{}, err
+		}
+		defer body.Close()
+		return ParseCSV(body, cols)
+
+	case "influxdb":
+		return s.fetchInflux(ctx, cols)
+	}
+	return LoopData{}, fmt.Errorf("type de source inconnu %q", s.Type)
+}
+
+// Remote reports whether the data is pulled from a server, rather than
+// given inline or by a source of unknown type.
+func (s Source) Remote() bool {
+	return s.Type == "http" || s.Type == "influxdb"
+}
+
+// get performs a bounded GET request.
+func get(ctx context.Context, rawURL string, header http.Header) (io.ReadCloser, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("URL invalide %q", rawURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("la source a répondu %s", resp.Status)
+	}
+	return readCloser{io.LimitReader(resp.Body, MaxBytes), func() error { cancel(); return resp.Body.Close() }}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error { return r.close() }
+
+// ParseCSV reads loop data from CSV with a header row. The time column
+// holds either seconds or RFC 3339 timestamps.
+func ParseCSV(r io.Reader, cols Columns) (LoopData, error) {
+
+	cr := csv.NewReader(io.LimitReader(r, MaxBytes))
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return LoopData{}, fmt.Errorf("en-tête CSV illisible : %w", err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+
+	var rows [][]any
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return LoopData{}, err
+		}
+		row := make([]any, len(rec))
+		for i, field := range rec {
+			row[i] = field
+		}
+		rows = append(rows, row)
+	}
+	return build(index, rows, cols)
+}
+
+func (s Source) fetchInflux(ctx context.Context, cols Columns) (LoopData, error) {
+
+	q := url.Values{"db": {s.Database}, "q": {s.Query}, "epoch": {"ms"}}
+	header := http.Header{}
+	switch {
+	case s.Token != "":
+		header.Set("Authorization", "Token "+s.Token)
+	case s.Username != "":
+		// Basic authentication keeps the password out of URLs and errors.
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(s.Username+":"+s.Password)))
+	}
+
+	body, err := get(ctx, strings.TrimRight(s.URL, "/")+"/query?"+q.Encode(), header)
+	if err != nil {
+		return LoopData{}, err
+	}
+	defer body.Close()
+
+	var resp struct {
+		Results []struct {
+			Error  string `json:"error"`
+			Series []struct {
+				Columns []string `json:"columns"`
+				Values  [][]any  `json:"values"`
+			} `json:"series"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return LoopData{}, fmt.Errorf("réponse InfluxDB illisible : %w", err)
+	}
+	if len(resp.Results) == 0 || len(resp.Results[0].Series) == 0 {
+		if len(resp.Results) > 0 && resp.Results[0].Error != "" {
+			return LoopData{}, fmt.Errorf("InfluxDB : %s", resp.Results[0].Error)
+		}
+		return LoopData{}, fmt.Errorf("la requête InfluxDB ne renvoie aucune série")
+	}
+
+	series := resp.Results[0].Series[0]
+	index := map[string]int{}
+	for i, name := range series.Columns {
+		index[name] = i
+	}
+	return build(index, series.Values, cols)
+}
+
+// build extracts the mapped columns from rows of strings or numbers.
+func build(index map[string]int, rows [][]any, cols Columns) (LoopData, error) {
+
+	var data LoopData
+	ti, ok := index[cols.Time]
+	if !ok {
+		return data, fmt.Errorf("colonne de temps %q absente", cols.Time)
+	}
+
+	type target struct {
+		name string
+		dst  *[]float64
+	}
+	var targets []target
+	for _, t := range []target{{cols.SP, &data.SP}, {cols.PV, &data.PV}, {cols.OP, &data.OP}, {cols.Disturbance, &data.Disturbance}} {
+		if _, ok := index[t.name]; ok && t.name != "" {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		return data, fmt.Errorf("aucune des colonnes %q, %q, %q, %q n'est présente", cols.SP, cols.PV, cols.OP, cols.Disturbance)
+	}
+
+	var t0 float64
+	for n, row := range rows {
+		t, abs, err := parseTime(cell(row, ti))
+		if err != nil {
+			return data, fmt.Errorf("ligne %d : %w", n+1, err)
+		}
+		if n == 0 {
+			t0 = t
+			if abs {
+				data.Start = time.UnixMilli(int64(t * 1000)).UTC()
+			}
+		}
+		data.Time = append(data.Time, t-t0)
+
+		for _, tg := range targets {
+			v, err := parseNumber(cell(row, index[tg.name]))
+			if err != nil {
+				return data, fmt.Errorf("ligne %d, colonne %s : %w", n+1, tg.name, err)
+			}
+			*tg.dst = append(*tg.dst, v)
+		}
+	}
+	if len(data.Time) < 2 {
+		return data, fmt.Errorf("au moins deux échantillons sont nécessaires")
+	}
+	return data, nil
+}
+
+func cell(row []any, i int) any {
+	if i < len(row) {
+		return row[i]
+	}
+	return nil
+}
+
+func parseNumber(v any) (float64, error) {
+	switch x := v.(type) {
+	case float64:
+		return x, nil
+	case json.Number:
+		return x.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(x), 64)
+	}
+	return 0, fmt.Errorf("valeur numérique attendue, reçu %v", v)
+}
+
+// parseTime returns the time in seconds and whether it is absolute. Numbers
+// above 1e11 are taken as Unix epoch milliseconds.
+func parseTime(v any) (float64, bool, error) {
+	if s, ok := v.(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(s)); err == nil {
+			return float64(t.UnixMilli()) / 1000, true, nil
+		}
+	}
+	x, err := parseNumber(v)
+	if err != nil {
+		return 0, false, err
+	}
+	if x > 1e11 {
+		return x / 1000, true, nil
+	}
+	return x, false, nil
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// MATLAB writes a run as a MATLAB script, also read by GNU Octave with the
+// control package, to cross-validate the simulator against Simulink: the
+// controller as a discrete pid object computed as the simulator does, the
+// plant model as a tf or ss object with its dead time, and the series of
+// the result as column vectors, with the [t, value] matrices read by the
+// From Workspace blocks.
+func MATLAB(w io.Writer, sc simulation.Scenario, res simulation.Result) error {
+
+	b := bufio.NewWriter(w)
+	num := numfmt.String
+	line := func(format string, args ...any) {
+		fmt.Fprintf(b, format+"\n", args...)
+	}
+
+	line("%% Simulation d'une boucle PID exportée par regulation-server.")
+	if res.Permalink != "" {
+		line("%% Scénario : %s", res.Permalink)
+	}
+	line("%% Le régulateur et le procédé utilisent pid, tf et ss de la Control")
+	line("%% System Toolbox (Octave : pkg load control).")
+	line("")
+	line("dt = %s;", num(sc.Dt))
+	line("N = %s;", num(sc.N))
+	line("Sp = %s;", num(sc.Sp))
+	line("")
+
+	line("%% Régulateur parallèle u = P·e + Ki·∫e dt + Kd·de/dt, l'intégrale et la")
+	line("%% dérivée de l'erreur calculées par différences arrière comme le simulateur.")
+	if sc.Controller != "" && sc.Controller != simulation.ControllerPID {
+		line("%% Régulateur %s : seuls les gains nominaux sont exportés.", sc.Controller)
+	}
+	line("P = %s;", num(sc.P))
+	line("Ki = %s;", num(sc.Ki))
+	line("Kd = %s;", num(sc.Kd))
+	line("C = pid(P, Ki, Kd, 0, dt, 'IFormula', 'BackwardEuler', 'DFormula', 'BackwardEuler');")
+	if sc.UMin != nil {
+		line("uMin = %s;", num(*sc.UMin))
+	}
+	if sc.UMax != nil {
+		line("uMax = %s;", num(*sc.UMax))
+	}
+	line("")
+
+	plant := sc.Plant
+	if plant == "" {
+		plant = simulation.PlantFirstOrder
+	}
+	line("%% Procédé %s, intégré par le simulateur selon Euler explicite.", plant)
+	if sc.KDrift != 0 || sc.Variation != nil {
+		line("%% Ses paramètres varient pendant la simulation : le modèle est le nominal.")
+	}
+	switch plant {
+	case simulation.PlantStateSpace:
+		ss := sc.StateSpace
+		line("A = %s;", matrix(ss.A, ss.States, ss.States))
+		line("B = %s;", matrix(ss.B, ss.States, ss.Inputs))
+		line("C_ss = %s;", matrix(ss.C, ss.Outputs, ss.States))
+		if len(ss.D) > 0 {
+			line("D = %s;", matrix(ss.D, ss.Outputs, ss.Inputs))
+		} else {
+			line("D = zeros(%d, %d);", ss.Outputs, ss.Inputs)
+		}
+		line("%% La commande est la première entrée et la mesure la première sortie.")
+		line("G = ss(A, B(:, 1), C_ss(1, :), D(1, 1));")
+	case simulation.PlantLevel:
+		line("K = %s;", num(sc.K))
+		line("Tau = %s;", num(sc.Tau))
+		line("%% Niveau intégrateur : la commande ouvre la vanne de sortie, le débit")
+		line("%% entrant est une perturbation.")
+		line("G = tf(-K, [Tau 0]);")
+	case simulation.PlantPH, simulation.PlantReactive:
+		line("K = %s;", num(sc.K))
+		line("Tau = %s;", num(sc.Tau))
+		line("%% Procédé non linéaire : G est seulement sa réponse du premier ordre.")
+		line("G = tf(K, [Tau 1]);")
+	default:
+		line("K = %s;", num(sc.K))
+		line("Tau = %s;", num(sc.Tau))
+		line("G = tf(K, [Tau 1]);")
+		if plant == simulation.PlantHeatCool {
+			line("%% Refroidissement, pour une commande négative.")
+			line("Kcool = %s;", num(sc.Cooling.K))
+			line("TauCool = %s;", num(sc.Cooling.Tau))
+			line("Gcool = tf(Kcool, [TauCool 1]);")
+		}
+	}
+	if sc.Theta != 0 {
+		line("theta = %s;", num(sc.Theta))
+		line("G.InputDelay = theta;")
+	}
+	line("")
+
+	line("%% Résultat de la simulation.")
+	series := []struct {
+		name string
+		xs   []float64
+	}{
+		{"t", res.Time},
+		{"sp", res.SP},
+		{"pv", res.PV},
+		{"u", res.U},
+		{"d", res.Disturbance},
+	}
+	for _, s := range series {
+		if s.xs == nil {
+			continue
+		}
+		column(b, s.name, s.xs)
+	}
+	line("")
+	line("%% Entrées des blocs From Workspace de Simulink.")
+	line("sp_ws = [t sp];")
+	line("u_ws = [t u];")
+	if res.Disturbance != nil {
+		line("d_ws = [t d];")
+	}
+	return b.Flush()
+}
+
+// matrix writes the rows×cols coefficients, row by row, as a MATLAB matrix.
+func matrix(data []float64, rows, cols int) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := range rows {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		for j := range cols {
+			if j > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(numfmt.String(data[i*cols+j]))
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// column writes the series as a column vector, one value per line.
+func column(w io.Writer, name string, xs []float64) {
+	fmt.Fprintf(w, "%s = [\n", name)
+	for _, x := range xs {
+		fmt.Fprintln(w, numfmt.String(x))
+	}
+	fmt.Fprintln(w, "];")
+}
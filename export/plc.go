@@ -0,0 +1,129 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// Parameter is one value to enter in a controller block.
+type Parameter struct {
+	Name        string  `json:"name"`
+	Value       float64 `json:"value"`
+	Unit        string  `json:"unit"`
+	Description string  `json:"description"`
+}
+
+// PLCParameters is a tuning translated into a vendor's conventions.
+type PLCParameters struct {
+	Format     string      `json:"format"`
+	Block      string      `json:"block"`
+	Parameters []Parameter `json:"parameters"`
+	Notes      []string    `json:"notes,omitempty"`
+}
+
+// PLCFormats lists the formats accepted by PLC, with a description.
+var PLCFormats = map[string]string{
+	"isa":                 "Forme standard ISA : Kc, Ti et Td en secondes",
+	"siemens-pid-compact": "Siemens PID_Compact (Retain.CtrlParams)",
+	"ab-pide-independent": "Allen-Bradley PIDE, gains indépendants (minutes)",
+	"ab-pide-dependent":   "Allen-Bradley PIDE, gains dépendants (minutes)",
+}
+
+// PLC translates the parallel gains used by the simulator
+// (u = P·e + Ki·∫e dt + Kd·de/dt, time in seconds) sampled every dt seconds
+// into the parameters of the requested controller block.
+func PLC(format string, P, Ki, Kd, dt float64) (PLCParameters, error) {
+
+	out := PLCParameters{Format: format}
+
+	// Standard (dependent) form u = Kc·(e + 1/Ti·∫e dt + Td·de/dt).
+	standard := func() (Ti, Td float64, err error) {
+		if P == 0 {
+			return 0, 0, fmt.Errorf("le format %s nécessite un coefficient proportionnel non nul", format)
+		}
+		if Ki != 0 {
+			Ti = P / Ki
+		} else {
+			out.Notes = append(out.Notes, "Action intégrale désactivée (Ki = 0) : Ti est laissé à 0.")
+		}
+		return Ti, Kd / P, nil
+	}
+
+	switch format {
+	case "isa":
+		Ti, Td, err := standard()
+		if err != nil {
+			return out, err
+		}
+		out.Block = "PID ISA"
+		out.Parameters = []Parameter{
+			{"Kc", P, "", "Gain du régulateur"},
+			{"Ti", Ti, "s", "Temps d'intégrale"},
+			{"Td", Td, "s", "Temps de dérivée"},
+		}
+
+	case "siemens-pid-compact":
+		Ti, Td, err := standard()
+		if err != nil {
+			return out, err
+		}
+		out.Block = "PID_Compact"
+		out.Parameters = []Parameter{
+			{"Retain.CtrlParams.Gain", P, "", "Gain proportionnel"},
+			{"Retain.CtrlParams.Ti", Ti, "s", "Temps d'intégration (0 désactive l'intégrale)"},
+			{"Retain.CtrlParams.Td", Td, "s", "Temps de dérivation"},
+			{"Retain.CtrlParams.TdFiltRatio", 0, "", "Coefficient du filtre de dérivée"},
+			{"Retain.CtrlParams.PWeighting", 1, "", "Pondération de l'action P"},
+			{"Retain.CtrlParams.DWeighting", 1, "", "Pondération de l'action D"},
+			{"Retain.CtrlParams.Cycle", dt, "s", "Période d'échantillonnage"},
+		}
+		out.Notes = append(out.Notes, "Le simulateur n'a pas de filtre de dérivée : TdFiltRatio = 0 reproduit la simulation mais amplifie le bruit réel.")
+
+	case "ab-pide-independent":
+		out.Block = "PIDE (PVEUMax/CVEUMax en unités physiques, DependIndepend = 0)"
+		out.Parameters = []Parameter{
+			{"PGain", P, "", "Gain proportionnel"},
+			{"IGain", Ki * 60, "1/min", "Gain intégral"},
+			{"DGain", Kd / 60, "min", "Gain dérivé"},
+		}
+
+	case "ab-pide-dependent":
+		Ti, Td, err := standard()
+		if err != nil {
+			return out, err
+		}
+		igain := 0.0
+		if Ti != 0 {
+			igain = 60 / Ti
+		}
+		out.Block = "PIDE (DependIndepend = 1)"
+		out.Parameters = []Parameter{
+			{"PGain", P, "", "Gain du régulateur"},
+			{"IGain", igain, "répétitions/min", "Gain intégral"},
+			{"DGain", Td / 60, "min", "Temps de dérivée"},
+		}
+
+	default:
+		return out, fmt.Errorf("format d'export inconnu %q", format)
+	}
+
+	for i := range out.Parameters {
+		out.Parameters[i].Value = numfmt.Round(out.Parameters[i].Value)
+	}
+	return out, nil
+}
+
+// WriteCSV writes the parameters as name,value,unit,description rows in
+// the dialect f.
+func (p PLCParameters) WriteCSV(w io.Writer, f numfmt.CSV) error {
+
+	cw := f.Writer(w)
+	cw.Write([]string{"name", "value", "unit", "description"})
+	for _, param := range p.Parameters {
+		cw.Write([]string{param.Name, f.Number(param.Value), param.Unit, param.Description})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -1,8 +1,11 @@
-module regulation
+module github.com/Ivan69-tech/PIDControllerResponse
 
 go 1.23.4
 
-require gonum.org/v1/plot v0.15.0
+require (
+	golang.org/x/image v0.21.0
+	gonum.org/v1/plot v0.15.0
+)
 
 require (
 	git.sr.ht/~sbinet/gg v0.6.0 // indirect
@@ -14,6 +17,5 @@ require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	go.einride.tech/pid v0.1.3 // indirect
-	golang.org/x/image v0.21.0 // indirect
 	golang.org/x/text v0.19.0 // indirect
 )
@@ -0,0 +1,167 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Run is a stored simulation.
+type Run struct {
+	ID       string              `json:"id"`
+	Created  time.Time           `json:"created"`
+	Scenario simulation.Scenario `json:"scenario"`
+	simulation.Result
+
+	// Notes and Tags annotate the run; unlike the run itself they can be
+	// edited, each edit incrementing Revision.
+	Notes    string   `json:"notes,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Revision int      `json:"revision,omitempty"`
+}
+
+// HasTag reports whether the run is tagged with tag, ignoring case.
+func (r *Run) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNotFound is returned for an unknown run.
+var ErrNotFound = errors.New("simulation introuvable")
+
+// Store keeps runs in memory and persists each one as a JSON file in its
+// directory.
+type Store struct {
+	dir string
+
+	mu   sync.RWMutex
+	runs map[string]*Run
+	// sizes are the sizes of the files of the runs, in bytes.
+	sizes map[string]int64
+}
+
+// Open loads the runs stored in dir, creating it if needed.
+func Open(dir string) (*Store, error) {
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, runs: make(map[string]*Run), sizes: make(map[string]int64)}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var run Run
+		if err := numfmt.UnmarshalJSON(b, &run); err != nil {
+			return nil, fmt.Errorf("historique corrompu %s : %w", name, err)
+		}
+		s.runs[run.ID] = &run
+		s.sizes[run.ID] = int64(len(b))
+	}
+	return s, nil
+}
+
+// Add stores a run; its ID must be set and unique.
+func (s *Store) Add(run *Run) error {
+
+	if run.ID == "" || strings.ContainsAny(run.ID, `/\.`) {
+		return fmt.Errorf("identifiant de simulation invalide %q", run.ID)
+	}
+	b, err := marshal(run)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, run.ID+".json"), b, 0o644); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.runs[run.ID] = run
+	s.sizes[run.ID] = int64(len(b))
+	s.mu.Unlock()
+	return nil
+}
+
+// Get returns the run with the given ID.
+func (s *Store) Get(id string) (*Run, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	run, ok := s.runs[id]
+	return run, ok
+}
+
+// List returns the stored runs, newest first.
+func (s *Store) List() []*Run {
+	s.mu.RLock()
+	runs := make([]*Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, run)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Created.After(runs[j].Created) })
+	return runs
+}
+
+// Annotate replaces the notes and tags of a run, those given as nil being
+// kept, and returns the updated run. Tags are trimmed and deduplicated,
+// ignoring case.
+func (s *Store) Annotate(id string, notes *string, tags []string) (*Run, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.runs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	run := *old
+	if notes != nil {
+		run.Notes = strings.TrimSpace(*notes)
+	}
+	if tags != nil {
+		run.Tags = nil
+		for _, t := range tags {
+			if t = strings.TrimSpace(t); t != "" && !run.HasTag(t) {
+				run.Tags = append(run.Tags, t)
+			}
+		}
+	}
+	run.Revision++
+
+	b, err := marshal(&run)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, run.ID+".json"), b, 0o644); err != nil {
+		return nil, err
+	}
+	s.runs[id] = &run
+	s.sizes[id] = int64(len(b))
+	return &run, nil
+}
+
+// marshal encodes a run for its file, diverged runs keeping their NaN and
+// infinite samples.
+func marshal(run *Run) ([]byte, error) {
+	b, _, err := numfmt.MarshalJSON(run, numfmt.NonFiniteString)
+	return b, err
+}
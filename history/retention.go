@@ -0,0 +1,93 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// KeepTag exempts the runs tagged with it from retention.
+const KeepTag = "keep"
+
+// Retention bounds the history; zero values do not limit.
+type Retention struct {
+	// MaxRuns and MaxBytes bound the number of runs and the size of their
+	// files, the oldest runs being removed first.
+	MaxRuns  int   `json:"maxRuns,omitempty"`
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+	// TTL is the age beyond which runs are removed, left out of JSON
+	// where a duration would be written in nanoseconds.
+	TTL time.Duration `json:"-"`
+}
+
+// Usage is the storage used by the history.
+type Usage struct {
+	Runs  int   `json:"runs"`
+	Bytes int64 `json:"bytes"`
+	// Kept counts the runs exempted from retention by KeepTag.
+	Kept   int        `json:"kept"`
+	Oldest *time.Time `json:"oldest,omitempty"`
+	Newest *time.Time `json:"newest,omitempty"`
+}
+
+// Usage returns the storage used by the history.
+func (s *Store) Usage() Usage {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u := Usage{Runs: len(s.runs)}
+	for id, run := range s.runs {
+		u.Bytes += s.sizes[id]
+		if run.HasTag(KeepTag) {
+			u.Kept++
+		}
+		if u.Oldest == nil || run.Created.Before(*u.Oldest) {
+			u.Oldest = &run.Created
+		}
+		if u.Newest == nil || run.Created.After(*u.Newest) {
+			u.Newest = &run.Created
+		}
+	}
+	return u
+}
+
+// Prune removes the runs beyond the retention at time now: those older
+// than the TTL, then the oldest ones until the history fits MaxRuns and
+// MaxBytes. Runs tagged with KeepTag are never removed, though they count
+// against the limits. It returns the IDs of the removed runs.
+func (s *Store) Prune(p Retention, now time.Time) ([]string, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var candidates []*Run
+	count, bytes := len(s.runs), int64(0)
+	for id, run := range s.runs {
+		bytes += s.sizes[id]
+		if !run.HasTag(KeepTag) {
+			candidates = append(candidates, run)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Created.Before(candidates[j].Created) })
+
+	var removed []string
+	for _, run := range candidates {
+		expired := p.TTL > 0 && now.Sub(run.Created) > p.TTL
+		tooMany := p.MaxRuns > 0 && count > p.MaxRuns
+		tooLarge := p.MaxBytes > 0 && bytes > p.MaxBytes
+		if !expired && !tooMany && !tooLarge {
+			break
+		}
+		err := os.Remove(filepath.Join(s.dir, run.ID+".json"))
+		if err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		count, bytes = count-1, bytes-s.sizes[run.ID]
+		delete(s.runs, run.ID)
+		delete(s.sizes, run.ID)
+		removed = append(removed, run.ID)
+	}
+	return removed, nil
+}
@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	Queued  Status = "queued"
+	Running Status = "running"
+	Done    Status = "done"
+	Failed  Status = "failed"
+)
+
+// ErrQueueFull is returned by Submit when the queue cannot take more jobs.
+var ErrQueueFull = errors.New("file d'attente pleine")
+
+// maxKept bounds the number of finished jobs remembered by a pool.
+const maxKept = 1000
+
+// Job is a unit of work run by a pool.
+type Job struct {
+	ID       string     `json:"id"`
+	Status   Status     `json:"status"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Result   any        `json:"result,omitempty"`
+	Webhook  *Webhook   `json:"webhook,omitempty"`
+
+	run func() (any, error)
+}
+
+// Pool runs submitted jobs on a fixed number of workers.
+type Pool struct {
+	queue   chan *Job
+	workers int
+
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	finished []string
+	// mean is a moving average of the run time of the jobs.
+	mean time.Duration
+}
+
+// NewPool starts workers goroutines consuming a queue of queueSize jobs.
+func NewPool(workers, queueSize int) *Pool {
+
+	p := &Pool{
+		queue:   make(chan *Job, queueSize),
+		workers: workers,
+		jobs:    make(map[string]*Job),
+	}
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues run under the given ID. The webhook, if any, is notified
+// when the job finishes.
+func (p *Pool) Submit(id string, run func() (any, error), hook *Webhook) (Job, error) {
+
+	job := &Job{ID: id, Status: Queued, Created: time.Now().UTC(), Webhook: hook, run: run}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case p.queue <- job:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	p.jobs[id] = job
+	return *job, nil
+}
+
+// Stats is the load of a pool.
+type Stats struct {
+	Queued  int
+	Workers int
+	// MeanDuration is a moving average of the run time of the jobs, zero
+	// before the first one finishes.
+	MeanDuration time.Duration
+}
+
+// Stats returns the current load of the pool.
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Stats{Queued: len(p.queue), Workers: p.workers, MeanDuration: p.mean}
+}
+
+// Get returns a snapshot of the job.
+func (p *Pool) Get(id string) (Job, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	job, ok := p.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func (p *Pool) work() {
+	for job := range p.queue {
+
+		p.mu.Lock()
+		started := time.Now().UTC()
+		job.Status = Running
+		job.Started = &started
+		p.mu.Unlock()
+
+		result, err := job.run()
+
+		finished := time.Now().UTC()
+		p.mu.Lock()
+		job.Finished = &finished
+		if took := finished.Sub(started); p.mean == 0 {
+			p.mean = took
+		} else {
+			p.mean = (4*p.mean + took) / 5
+		}
+		if err != nil {
+			job.Status = Failed
+			job.Error = err.Error()
+		} else {
+			job.Status = Done
+			job.Result = result
+		}
+		snapshot := *job
+		p.finished = append(p.finished, job.ID)
+		if len(p.finished) > maxKept {
+			delete(p.jobs, p.finished[0])
+			p.finished = p.finished[1:]
+		}
+		p.mu.Unlock()
+
+		if snapshot.Webhook != nil {
+			go func() {
+				if err := snapshot.Webhook.Notify(snapshot); err != nil {
+					log.Println("webhook du job", snapshot.ID, ":", err)
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// Webhook is called with a POST of the finished job.
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret, when set, signs the body: the X-Regulation-Signature header
+	// holds "sha256=" followed by the hex HMAC-SHA256 of the body.
+	Secret string `json:"secret,omitempty"`
+}
+
+// webhookAttempts is the number of deliveries tried, with doubling delays
+// starting at webhookBackoff.
+const (
+	webhookAttempts = 4
+	webhookBackoff  = time.Second
+)
+
+// Validate checks the webhook URL.
+func (h Webhook) Validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL de webhook invalide %q", h.URL)
+	}
+	return nil
+}
+
+// MarshalJSON hides the secret when jobs are listed.
+func (h Webhook) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		URL string `json:"url"`
+	}{h.URL})
+}
+
+// Notify delivers the job, retrying on network errors and 5xx answers.
+func (h Webhook) Notify(job Job) error {
+
+	body, _, err := numfmt.MarshalJSON(map[string]any{
+		"jobId":    job.ID,
+		"status":   job.Status,
+		"error":    job.Error,
+		"result":   job.Result,
+		"finished": job.Finished,
+	}, numfmt.NonFiniteNull)
+	if err != nil {
+		return err
+	}
+
+	delay := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := h.post(body)
+		if err == nil || !retry || attempt == webhookAttempts {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (h Webhook) post(body []byte) (retry bool, err error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Regulation-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("le webhook a répondu %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("le webhook a répondu %s", resp.Status)
+	}
+	return false, nil
+}
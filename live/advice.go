@@ -0,0 +1,174 @@
+package live
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// AdviceInterval is the wall-clock period at which a session diagnoses its
+// recent samples for tuning suggestions.
+const AdviceInterval = 5 * time.Second
+
+// adviceWindow is the number of recent samples diagnosed.
+const adviceWindow = 4000
+
+// ErrUnknownSuggestion is returned by Apply for a suggestion that does not
+// exist or is no longer pending.
+var ErrUnknownSuggestion = errors.New("suggestion introuvable ou périmée")
+
+// advisor keeps the recent samples of a session and the suggestions made
+// for its current parameters. It is guarded by the session mutex.
+type advisor struct {
+	window  []Sample
+	pending []simulation.Suggestion
+	// made holds the kinds already suggested since the last parameter
+	// change, so that a suggestion is not repeated.
+	made map[string]bool
+	seq  int
+	// generation counts the resets, to discard a diagnosis made before
+	// a parameter change.
+	generation int
+	subs       map[chan simulation.Suggestion]struct{}
+}
+
+func (a *advisor) record(batch []Sample) {
+	a.window = append(a.window, batch...)
+	if len(a.window) > 2*adviceWindow {
+		a.window = append(a.window[:0], a.window[len(a.window)-adviceWindow:]...)
+	}
+}
+
+// reset drops the samples and suggestions, which no longer describe the
+// loop once its parameters have changed.
+func (a *advisor) reset() {
+	a.window = a.window[:0]
+	a.pending = nil
+	a.made = nil
+	a.generation++
+}
+
+// advise diagnoses the session every AdviceInterval until it stops, and
+// pushes new suggestions to the subscribers.
+func (s *Session) advise() {
+
+	ticker := time.NewTicker(AdviceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		sc := s.loop.Scenario
+		generation := s.advisor.generation
+		window := s.advisor.window[max(len(s.advisor.window)-adviceWindow, 0):]
+		T := make([]float64, len(window))
+		SP := make([]float64, len(window))
+		PV := make([]float64, len(window))
+		U := make([]float64, len(window))
+		for i, smp := range window {
+			T[i], SP[i], PV[i], U[i] = smp.T, smp.SP, smp.PV, smp.U
+		}
+		s.mu.Unlock()
+
+		// The diagnosis runs without the lock so that the loop keeps pace.
+		found := simulation.Advise(sc, T, SP, PV, U)
+
+		s.mu.Lock()
+		if s.advisor.generation != generation {
+			// The parameters changed meanwhile, the diagnosis is stale.
+			s.mu.Unlock()
+			continue
+		}
+		var fresh []simulation.Suggestion
+		for _, sug := range found {
+			if s.advisor.made[sug.Kind] {
+				continue
+			}
+			if s.advisor.made == nil {
+				s.advisor.made = make(map[string]bool)
+			}
+			s.advisor.made[sug.Kind] = true
+			s.advisor.seq++
+			sug.ID = strconv.Itoa(s.advisor.seq)
+			s.advisor.pending = append(s.advisor.pending, sug)
+			fresh = append(fresh, sug)
+		}
+		subs := make([]chan simulation.Suggestion, 0, len(s.advisor.subs))
+		for ch := range s.advisor.subs {
+			subs = append(subs, ch)
+		}
+		s.mu.Unlock()
+
+		for _, sug := range fresh {
+			for _, ch := range subs {
+				select {
+				case ch <- sug:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// Suggestions returns the pending tuning suggestions.
+func (s *Session) Suggestions() []simulation.Suggestion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]simulation.Suggestion{}, s.advisor.pending...)
+}
+
+// SubscribeSuggestions returns a channel receiving each new suggestion,
+// and a function to unsubscribe.
+func (s *Session) SubscribeSuggestions() (<-chan simulation.Suggestion, func()) {
+
+	ch := make(chan simulation.Suggestion, 4)
+	s.mu.Lock()
+	if s.advisor.subs == nil {
+		s.advisor.subs = make(map[chan simulation.Suggestion]struct{})
+	}
+	s.advisor.subs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.advisor.subs, ch)
+		s.mu.Unlock()
+	}
+}
+
+// Apply applies the changes of a pending suggestion, as Update would.
+func (s *Session) Apply(id string) (simulation.Scenario, error) {
+
+	s.mu.Lock()
+	var sug *simulation.Suggestion
+	for i := range s.advisor.pending {
+		if s.advisor.pending[i].ID == id {
+			sug = &s.advisor.pending[i]
+		}
+	}
+	s.mu.Unlock()
+	if sug == nil {
+		return simulation.Scenario{}, ErrUnknownSuggestion
+	}
+
+	changes := sug.Changes
+	sc, errs := s.Update(func(sc *simulation.Scenario) {
+		for name, value := range changes {
+			if p, err := sc.Param(name); err == nil {
+				*p = value
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return sc, fmt.Errorf("suggestion %s inapplicable : %s %s", id, errs[0].Path, errs[0].Message)
+	}
+	return sc, nil
+}
@@ -0,0 +1,246 @@
+package live
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Alarm kinds, the condition that makes an alarm active.
+const (
+	AlarmHigh      = "high"      // measurement at or above Limit
+	AlarmLow       = "low"       // measurement at or below Limit
+	AlarmDeviation = "deviation" // |measurement − setpoint| at or above Limit
+)
+
+// AlarmState is the state of an alarm, after the ISA-18.2 state model.
+type AlarmState string
+
+const (
+	AlarmNormal         AlarmState = "normal"         // inactive, nothing to acknowledge
+	AlarmUnacknowledged AlarmState = "unacknowledged" // active, awaiting acknowledgment
+	AlarmAcknowledged   AlarmState = "acknowledged"   // active, acknowledged
+	AlarmReturned       AlarmState = "returned"       // back to normal before being acknowledged
+	AlarmShelved        AlarmState = "shelved"        // suppressed by the operator for a while
+)
+
+// MaxShelve bounds the time an alarm stays shelved: shelving is temporary,
+// an alarm cannot be forgotten on the shelf.
+const MaxShelve = 8 * time.Hour
+
+// Errors returned by the alarm operations.
+var (
+	ErrUnknownAlarm       = errors.New("alarme inconnue")
+	ErrNotAcknowledgeable = errors.New("l'alarme n'attend pas d'acquittement")
+	ErrNotShelved         = errors.New("l'alarme n'est pas mise en veille")
+)
+
+// AlarmConfig configures an alarm of a session.
+type AlarmConfig struct {
+	Name  string  `json:"name"`
+	Kind  string  `json:"kind"`
+	Limit float64 `json:"limit"`
+	// Deadband is how far back past Limit the measurement must go for an
+	// active alarm to clear, so that it does not chatter around the limit.
+	Deadband float64 `json:"deadband,omitempty"`
+}
+
+// CheckAlarms reports the invalid members of the alarm configurations,
+// with paths below /alarms.
+func CheckAlarms(configs []AlarmConfig) []schema.Error {
+	var errs []schema.Error
+	for i, cfg := range configs {
+		path := fmt.Sprintf("/alarms/%d", i)
+		switch {
+		case cfg.Name == "":
+			errs = append(errs, schema.Error{Path: path + "/name", Message: "nom requis"})
+		case slices.ContainsFunc(configs[:i], func(c AlarmConfig) bool { return c.Name == cfg.Name }):
+			errs = append(errs, schema.Error{Path: path + "/name", Message: fmt.Sprintf("alarme %q déjà définie", cfg.Name)})
+		}
+		if !slices.Contains([]string{AlarmHigh, AlarmLow, AlarmDeviation}, cfg.Kind) {
+			errs = append(errs, schema.Error{Path: path + "/kind", Message: fmt.Sprintf("type d'alarme inconnu %q, attendu high, low ou deviation", cfg.Kind)})
+		}
+		if cfg.Kind == AlarmDeviation && cfg.Limit <= 0 {
+			errs = append(errs, schema.Error{Path: path + "/limit", Message: "l'écart d'alarme doit être strictement positif"})
+		}
+		if cfg.Deadband < 0 {
+			errs = append(errs, schema.Error{Path: path + "/deadband", Message: "la bande morte doit être positive ou nulle"})
+		}
+	}
+	return errs
+}
+
+// Alarm is an alarm of a session and its state. Since is the simulated
+// time of its last change of state; ShelvedUntil, set while it is shelved,
+// the time it comes back by itself.
+type Alarm struct {
+	AlarmConfig
+	State        AlarmState `json:"state"`
+	Active       bool       `json:"active"`
+	Since        float64    `json:"since"`
+	ShelvedUntil *time.Time `json:"shelvedUntil,omitempty"`
+}
+
+// condition tells whether the alarm is active for the sample, the limit
+// moved back by the deadband while it already is.
+func (a *Alarm) condition(sp, pv float64) bool {
+	margin := 0.0
+	if a.Active {
+		margin = a.Deadband
+	}
+	switch a.Kind {
+	case AlarmHigh:
+		return pv >= a.Limit-margin
+	case AlarmLow:
+		return pv <= a.Limit+margin
+	}
+	return math.Abs(pv-sp) >= a.Limit-margin
+}
+
+// update follows the condition at time t and returns the event of the
+// change of state, if any. A shelved alarm tracks its condition silently.
+func (a *Alarm) update(t, sp, pv float64) (simulation.Event, bool) {
+
+	active := a.condition(sp, pv)
+	if active == a.Active {
+		return simulation.Event{}, false
+	}
+	a.Active = active
+	if a.State == AlarmShelved {
+		return simulation.Event{}, false
+	}
+	switch {
+	case active:
+		a.State = AlarmUnacknowledged
+	case a.State == AlarmUnacknowledged:
+		a.State = AlarmReturned
+	default:
+		a.State = AlarmNormal
+	}
+	a.Since = t
+	if active {
+		return a.event(t, fmt.Sprintf("Alarme %s active (mesure %g)", a.Name, pv)), true
+	}
+	return a.event(t, fmt.Sprintf("Alarme %s revenue à la normale (mesure %g)", a.Name, pv)), true
+}
+
+func (a *Alarm) event(t float64, message string) simulation.Event {
+	return simulation.Event{T: t, Type: simulation.EventAlarm, Message: message}
+}
+
+// SetAlarms replaces the alarms of the session, which start inactive.
+func (s *Session) SetAlarms(configs []AlarmConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.alarms = make([]*Alarm, len(configs))
+	for i, cfg := range configs {
+		s.alarms[i] = &Alarm{AlarmConfig: cfg, State: AlarmNormal, Since: s.last.T}
+	}
+}
+
+// Alarms returns the alarms of the session in their configured order.
+func (s *Session) Alarms() []Alarm {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Alarm, len(s.alarms))
+	for i, a := range s.alarms {
+		list[i] = *a
+	}
+	return list
+}
+
+// checkAlarms updates the alarms with the last sample, unshelving those
+// whose shelving ended by now, and adds their changes of state to its
+// events.
+func (s *Session) checkAlarms(now time.Time) {
+	for _, a := range s.alarms {
+		if a.State == AlarmShelved && !now.Before(*a.ShelvedUntil) {
+			s.last.Events = append(s.last.Events, s.unshelve(a, "Fin de la mise en veille de l'alarme "+a.Name))
+		}
+		if e, changed := a.update(s.last.T, s.last.SP, s.last.PV); changed {
+			s.last.Events = append(s.last.Events, e)
+		}
+	}
+}
+
+// alarm returns the alarm called name.
+func (s *Session) alarm(name string) (*Alarm, error) {
+	for _, a := range s.alarms {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return nil, ErrUnknownAlarm
+}
+
+// Acknowledge acknowledges an active alarm, or clears one that returned to
+// normal unacknowledged, and returns it.
+func (s *Session) Acknowledge(name string) (Alarm, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.alarm(name)
+	if err != nil {
+		return Alarm{}, err
+	}
+	switch a.State {
+	case AlarmUnacknowledged:
+		a.State = AlarmAcknowledged
+	case AlarmReturned:
+		a.State = AlarmNormal
+	default:
+		return *a, ErrNotAcknowledgeable
+	}
+	a.Since = s.last.T
+	s.pending = append(s.pending, a.event(s.last.T, "Alarme "+a.Name+" acquittée"))
+	return *a, nil
+}
+
+// Shelve suppresses an alarm for d, at most MaxShelve, whatever its state,
+// and returns it. Shelving a shelved alarm moves the end of its shelving.
+func (s *Session) Shelve(name string, d time.Duration) (Alarm, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.alarm(name)
+	if err != nil {
+		return Alarm{}, err
+	}
+	until := time.Now().Add(min(d, MaxShelve))
+	a.State, a.ShelvedUntil, a.Since = AlarmShelved, &until, s.last.T
+	s.pending = append(s.pending, a.event(s.last.T, fmt.Sprintf("Alarme %s mise en veille pour %s", a.Name, min(d, MaxShelve))))
+	return *a, nil
+}
+
+// Unshelve ends the shelving of an alarm and returns it, unacknowledged if
+// its condition is active.
+func (s *Session) Unshelve(name string) (Alarm, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.alarm(name)
+	if err != nil {
+		return Alarm{}, err
+	}
+	if a.State != AlarmShelved {
+		return *a, ErrNotShelved
+	}
+	s.pending = append(s.pending, s.unshelve(a, "Alarme "+a.Name+" sortie de veille"))
+	return *a, nil
+}
+
+// unshelve puts a shelved alarm back in service and returns the event.
+func (s *Session) unshelve(a *Alarm, message string) simulation.Event {
+	a.State, a.ShelvedUntil, a.Since = AlarmNormal, nil, s.last.T
+	if a.Active {
+		a.State = AlarmUnacknowledged
+	}
+	return a.event(s.last.T, message)
+}
@@ -0,0 +1,149 @@
+package live
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Breakpoint kinds, the condition that pauses the session.
+const (
+	BreakSaturated = "saturated" // controller output limited by uMin or uMax
+	BreakOvershoot = "overshoot" // measurement past the setpoint by Limit of the last setpoint step
+	BreakPVAbove   = "pvAbove"   // measurement at or above Limit
+	BreakPVBelow   = "pvBelow"   // measurement at or below Limit
+	BreakAlarm     = "alarm"     // an alarm of the session active
+)
+
+// BreakpointConfig configures a breakpoint of a session. Limit is a
+// fraction of the step for an overshoot, 0.1 for 10 %, a measurement for
+// pvAbove and pvBelow, and unused otherwise.
+type BreakpointConfig struct {
+	Name  string  `json:"name"`
+	Kind  string  `json:"kind"`
+	Limit float64 `json:"limit,omitempty"`
+}
+
+// CheckBreakpoints reports the invalid members of the breakpoint
+// configurations, with paths below /breakpoints.
+func CheckBreakpoints(configs []BreakpointConfig) []schema.Error {
+	var errs []schema.Error
+	for i, cfg := range configs {
+		path := fmt.Sprintf("/breakpoints/%d", i)
+		switch {
+		case cfg.Name == "":
+			errs = append(errs, schema.Error{Path: path + "/name", Message: "nom requis"})
+		case slices.ContainsFunc(configs[:i], func(c BreakpointConfig) bool { return c.Name == cfg.Name }):
+			errs = append(errs, schema.Error{Path: path + "/name", Message: fmt.Sprintf("point d'arrêt %q déjà défini", cfg.Name)})
+		}
+		if !slices.Contains([]string{BreakSaturated, BreakOvershoot, BreakPVAbove, BreakPVBelow, BreakAlarm}, cfg.Kind) {
+			errs = append(errs, schema.Error{Path: path + "/kind", Message: fmt.Sprintf("condition inconnue %q, attendu saturated, overshoot, pvAbove, pvBelow ou alarm", cfg.Kind)})
+		}
+		if cfg.Kind == BreakOvershoot && !(cfg.Limit > 0) {
+			errs = append(errs, schema.Error{Path: path + "/limit", Message: "le dépassement doit être strictement positif"})
+		}
+	}
+	return errs
+}
+
+// Breakpoint is a breakpoint of a session: the session pauses at the
+// sample where its condition becomes true, not while it stays true, so
+// that it can be resumed. Hits counts the pauses and LastHit is the
+// simulated time of the last one.
+type Breakpoint struct {
+	BreakpointConfig
+	Hits    int      `json:"hits"`
+	LastHit *float64 `json:"lastHit,omitempty"`
+
+	on bool
+	// from is the measurement when the setpoint last moved to sp, the
+	// start of the step an overshoot is measured on, and pv the last
+	// measurement.
+	from, sp, pv float64
+}
+
+// condition tells whether the condition of the breakpoint holds at the
+// sample, alarm telling whether an alarm is active.
+func (b *Breakpoint) condition(smp Sample, alarm bool) bool {
+
+	if smp.SP != b.sp {
+		b.from, b.sp = b.pv, smp.SP
+	}
+	b.pv = smp.PV
+	switch b.Kind {
+	case BreakSaturated:
+		return smp.State.Saturated
+	case BreakOvershoot:
+		step := b.sp - b.from
+		return step != 0 && (smp.PV-b.sp)/step >= b.Limit
+	case BreakPVAbove:
+		return smp.PV >= b.Limit
+	case BreakPVBelow:
+		return smp.PV <= b.Limit
+	}
+	return alarm
+}
+
+// message describes the pause at the sample.
+func (b *Breakpoint) message(smp Sample) string {
+	switch b.Kind {
+	case BreakSaturated:
+		return fmt.Sprintf("Point d'arrêt %s : sortie saturée à %g", b.Name, smp.U)
+	case BreakOvershoot:
+		return fmt.Sprintf("Point d'arrêt %s : dépassement de %.1f %% (mesure %g)", b.Name, 100*(smp.PV-b.sp)/(b.sp-b.from), smp.PV)
+	case BreakAlarm:
+		return fmt.Sprintf("Point d'arrêt %s : alarme active (mesure %g)", b.Name, smp.PV)
+	}
+	return fmt.Sprintf("Point d'arrêt %s : mesure %g", b.Name, smp.PV)
+}
+
+// SetBreakpoints replaces the breakpoints of the session. Their conditions
+// start false at the last sample. An overshoot is measured on the step
+// from rest to the setpoint for a session not started yet, on the next
+// setpoint step otherwise.
+func (s *Session) SetBreakpoints(configs []BreakpointConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from := s.last.SP
+	if s.last.T == 0 {
+		from = s.last.PV
+	}
+	s.breakpoints = make([]*Breakpoint, len(configs))
+	for i, cfg := range configs {
+		s.breakpoints[i] = &Breakpoint{BreakpointConfig: cfg, from: from, sp: s.last.SP, pv: s.last.PV}
+	}
+}
+
+// Breakpoints returns the breakpoints of the session in their configured
+// order.
+func (s *Session) Breakpoints() []Breakpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Breakpoint, len(s.breakpoints))
+	for i, b := range s.breakpoints {
+		list[i] = *b
+	}
+	return list
+}
+
+// checkBreakpoints evaluates the breakpoints at the last sample and pauses
+// the session on those whose condition became true, adding the pauses to
+// its events.
+func (s *Session) checkBreakpoints() {
+	alarm := s.alarmActive()
+	for _, b := range s.breakpoints {
+		on := b.condition(s.last, alarm)
+		if on && !b.on {
+			t := s.last.T
+			b.Hits++
+			b.LastHit = &t
+			s.paused = true
+			s.last.Events = append(s.last.Events, simulation.Event{T: t, Type: simulation.EventBreakpoint, Message: b.message(s.last)})
+		}
+		b.on = on
+	}
+}
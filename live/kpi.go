@@ -0,0 +1,184 @@
+package live
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// DefaultKPIWindows are the windows, in seconds, of the KPIs of a session
+// started without its own: a minute, ten minutes and an hour.
+var DefaultKPIWindows = []float64{60, 600, 3600}
+
+// MaxKPIWindows bounds the number of windows of a session.
+const MaxKPIWindows = 8
+
+// kpiBuckets is the number of buckets of a window: its KPIs roll by a
+// kpiBuckets-th of it, forgetting the oldest bucket at once.
+const kpiBuckets = 60
+
+// KPI are the performance indicators of a session over the last Window
+// seconds of simulated time, Span when the session is younger.
+//
+// TimeInAlarm is the fraction of the samples with an alarm active,
+// shelved or not, and WithinBand the fraction with the measurement within
+// each band of the scenario, in their order. ErrorMean and ErrorVariance
+// are those of the error SP − PV; Travel, the control effort, is the sum
+// of the moves |Δu| of the output.
+type KPI struct {
+	Window        float64   `json:"window"`
+	Span          float64   `json:"span"`
+	Samples       int       `json:"samples"`
+	TimeInAlarm   float64   `json:"timeInAlarm"`
+	WithinBand    []float64 `json:"withinBand"`
+	ErrorMean     float64   `json:"errorMean"`
+	ErrorVariance float64   `json:"errorVariance"`
+	Travel        float64   `json:"travel"`
+}
+
+// CheckKPIWindows reports the invalid windows, with paths below
+// /kpiWindows.
+func CheckKPIWindows(windows []float64) []schema.Error {
+	var errs []schema.Error
+	if len(windows) > MaxKPIWindows {
+		errs = append(errs, schema.Error{Path: "/kpiWindows", Message: fmt.Sprintf("au plus %d fenêtres", MaxKPIWindows)})
+	}
+	for i, w := range windows {
+		if !(w > 0) || math.IsInf(w, 0) {
+			errs = append(errs, schema.Error{Path: fmt.Sprintf("/kpiWindows/%d", i), Message: "la fenêtre doit être une durée strictement positive"})
+		}
+	}
+	return errs
+}
+
+// kpiBucket sums the samples of a kpiBuckets-th of a window.
+type kpiBucket struct {
+	index       int
+	n, alarm    int
+	band        []int
+	e, e2, move float64
+}
+
+// rollup keeps the buckets of one window, in a ring indexed by the number
+// of the bucket modulo kpiBuckets.
+type rollup struct {
+	window  float64
+	buckets [kpiBuckets]kpiBucket
+}
+
+// kpis rolls up the samples of a session over each of its windows. It is
+// guarded by the session mutex.
+type kpis struct {
+	rollups []*rollup
+	lastU   *float64
+}
+
+func newKPIs(windows []float64) kpis {
+	var k kpis
+	for _, w := range windows {
+		r := &rollup{window: w}
+		for i := range r.buckets {
+			r.buckets[i].index = -1
+		}
+		k.rollups = append(k.rollups, r)
+	}
+	return k
+}
+
+// record adds a sample, alarm telling whether an alarm is active at it.
+func (k *kpis) record(smp Sample, alarm bool) {
+
+	move := 0.0
+	if k.lastU != nil {
+		move = math.Abs(smp.U - *k.lastU)
+	}
+	u := smp.U
+	k.lastU = &u
+	e := float64(smp.SP - smp.PV)
+
+	for _, r := range k.rollups {
+		index := int(smp.T / (r.window / kpiBuckets))
+		b := &r.buckets[index%kpiBuckets]
+		if b.index != index {
+			*b = kpiBucket{index: index, band: make([]int, len(smp.Bands))}
+		}
+		b.n++
+		if alarm {
+			b.alarm++
+		}
+		for i, edges := range smp.Bands {
+			if i < len(b.band) && smp.PV >= edges.Low && smp.PV <= edges.High {
+				b.band[i]++
+			}
+		}
+		b.e += e
+		b.e2 += float64(e * e)
+		b.move += move
+	}
+}
+
+// at returns the KPIs of every window at the simulated time t, for a loop
+// stepping by dt, rounded for output, see numfmt.
+func (k *kpis) at(t, dt float64, bands int) []KPI {
+
+	list := make([]KPI, 0, len(k.rollups))
+	for _, r := range k.rollups {
+		kpi := KPI{Window: r.window, WithinBand: make([]float64, bands)}
+		current := int(t / (r.window / kpiBuckets))
+		var alarm int
+		var e, e2 float64
+		within := make([]int, bands)
+		for _, b := range r.buckets {
+			if b.index < 0 || b.index <= current-kpiBuckets || b.index > current {
+				continue
+			}
+			kpi.Samples += b.n
+			alarm += b.alarm
+			for i := range min(bands, len(b.band)) {
+				within[i] += b.band[i]
+			}
+			e += b.e
+			e2 += b.e2
+			kpi.Travel += b.move
+		}
+		if kpi.Samples > 0 {
+			n := float64(kpi.Samples)
+			kpi.Span = numfmt.Round(n * dt)
+			kpi.TimeInAlarm = numfmt.Round(float64(alarm) / n)
+			for i, c := range within {
+				kpi.WithinBand[i] = numfmt.Round(float64(c) / n)
+			}
+			kpi.ErrorMean = numfmt.Round(e / n)
+			kpi.ErrorVariance = numfmt.Round(math.Max(e2/n-float64(e/n*e/n), 0))
+			kpi.Travel = numfmt.Round(kpi.Travel)
+		}
+		list = append(list, kpi)
+	}
+	return list
+}
+
+// SetKPIWindows replaces the windows of the KPIs of the session, which
+// start anew, DefaultKPIWindows when windows is empty.
+func (s *Session) SetKPIWindows(windows []float64) {
+	if len(windows) == 0 {
+		windows = DefaultKPIWindows
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kpis = newKPIs(slices.Clone(windows))
+}
+
+// KPIs returns the KPIs of the session over each of its windows.
+func (s *Session) KPIs() []KPI {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.kpis.at(s.last.T, s.loop.Scenario.Dt, len(s.loop.Scenario.Bands))
+}
+
+// alarmActive tells whether an alarm of the session is active.
+func (s *Session) alarmActive() bool {
+	return slices.ContainsFunc(s.alarms, func(a *Alarm) bool { return a.Active })
+}
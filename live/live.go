@@ -0,0 +1,289 @@
+package live
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Tick is the wall-clock period at which a session catches up with real
+// time and flushes its sinks.
+const Tick = 50 * time.Millisecond
+
+// maxStepsPerTick bounds the work done in one tick when dt is very small.
+const maxStepsPerTick = 10000
+
+// Sample is one step of a live simulation. At is the absolute time of the
+// sample, T the simulated seconds since the start of the session.
+type Sample struct {
+	At time.Time `json:"ts"`
+	T  float64   `json:"t"`
+	SP float64   `json:"sp"`
+	PV float64   `json:"pv"`
+	U  float64   `json:"u"`
+	// Events are the parameter changes applied just before this sample,
+	// those of the loop during its step, such as gain schedule switches,
+	// the operator actions on its alarms and their changes of state.
+	Events []simulation.Event `json:"events,omitempty"`
+	// Bands are the edges of the bands of the scenario at this sample, in
+	// their order, for the plots to shade.
+	Bands []simulation.BandEdges `json:"bands,omitempty"`
+	// State is the state of the controller at the output U.
+	State simulation.ControllerState `json:"state"`
+}
+
+// Session runs a scenario in real time until it is stopped. Its parameters
+// can be changed while it runs.
+type Session struct {
+	ID string
+
+	mu      sync.Mutex
+	loop    *simulation.Loop
+	last    Sample
+	subs    map[chan []Sample]struct{}
+	sinks   []Sink
+	pending []simulation.Event
+	advisor advisor
+	origin  time.Time
+	// ticked is the wall-clock time of the last advance and due the
+	// simulated seconds the loop is due to have run by then, running
+	// speed times as fast as real time; steps counts the steps due.
+	ticked time.Time
+	due    float64
+	speed  float64
+	steps  int
+	// paused is set while the loop only moves by Step.
+	paused bool
+	// undo and redo are the parameter changes that can be undone and
+	// redone, the most recent last.
+	undo, redo  []Edit
+	alarms      []*Alarm
+	breakpoints []*Breakpoint
+	trend       []Sample
+	kpis        kpis
+
+	// stopOnce closes stop once, however many callers stop the session.
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Start launches a live session for the scenario; N is ignored, the
+// session runs until Stop is called. Every sample is written to the sinks.
+func Start(id string, sc simulation.Scenario, sinks ...Sink) *Session {
+	return StartAt(id, sc, time.Now(), sinks...)
+}
+
+// StartAt is Start with the samples timestamped from origin instead of the
+// current time, e.g. to line a session up with historian data being
+// replayed. The session still runs in real time.
+func StartAt(id string, sc simulation.Scenario, origin time.Time, sinks ...Sink) *Session {
+
+	loop := simulation.NewLoop(sc)
+	s := &Session{
+		ID:     id,
+		loop:   loop,
+		last:   Sample{At: origin, SP: sc.Sp, PV: numfmt.Round(loop.Measurement()), Bands: simulation.EdgesAt(sc.Bands, sc.Sp)},
+		subs:   make(map[chan []Sample]struct{}),
+		sinks:  sinks,
+		ticked: time.Now(),
+		speed:  1,
+		origin: origin,
+		kpis:   newKPIs(DefaultKPIWindows),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	go s.advise()
+	return s
+}
+
+func (s *Session) run() {
+
+	defer close(s.done)
+	ticker := time.NewTicker(Tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			for _, sink := range s.sinks {
+				if err := sink.Close(); err != nil {
+					log.Println("session", s.ID, ": fermeture du puits :", err)
+				}
+			}
+			return
+		case now := <-ticker.C:
+			s.advance(now)
+		}
+	}
+}
+
+// advance steps the loop up to the wall-clock time, scaled by the speed
+// of the session, and publishes the new samples.
+func (s *Session) advance(now time.Time) {
+
+	s.mu.Lock()
+	s.catchUp(now)
+	target := int(s.due / s.loop.Scenario.Dt)
+	n := min(target-s.steps, maxStepsPerTick)
+	batch := make([]Sample, 0, max(n, 0))
+	for i := 0; i < n && !s.paused; i++ {
+		batch = append(batch, s.step(now))
+	}
+	s.record(batch)
+	if s.paused {
+		// A breakpoint may have paused the loop within the batch: the
+		// time due stops at its sample.
+		s.steps += len(batch)
+		s.due = float64(s.steps) * s.loop.Scenario.Dt
+	} else {
+		// When the loop cannot keep up, drop the backlog rather than lag more.
+		s.steps = max(s.steps, target)
+	}
+	subs := s.subscribers()
+	s.mu.Unlock()
+
+	s.publish(batch, subs)
+}
+
+// step advances the loop by one time step and returns the new sample.
+func (s *Session) step(now time.Time) Sample {
+	sp := s.loop.Scenario.Sp
+	u := s.loop.Step()
+	s.last = Sample{At: simulation.Timestamp(s.origin, s.loop.T), T: numfmt.Round(s.loop.T), SP: sp, PV: numfmt.Round(s.loop.Measurement()), U: numfmt.Round(u), Events: append(s.pending, s.loop.TakeEvents()...)}
+	s.last.Bands = simulation.EdgesAt(s.loop.Scenario.Bands, sp)
+	s.last.State = roundState(s.loop.State())
+	s.pending = nil
+	s.checkAlarms(now)
+	s.checkBreakpoints()
+	s.kpis.record(s.last, s.alarmActive())
+	return s.last
+}
+
+// record keeps the new samples for the trend and the advisor.
+func (s *Session) record(batch []Sample) {
+	s.trend = append(s.trend, batch...)
+	if len(s.trend) > maxTrend {
+		s.trend = s.trend[len(s.trend)-maxTrend:]
+	}
+	s.advisor.record(batch)
+}
+
+// subscribers returns the channels of the subscribers, to publish to once
+// the session is unlocked.
+func (s *Session) subscribers() []chan []Sample {
+	subs := make([]chan []Sample, 0, len(s.subs))
+	for ch := range s.subs {
+		subs = append(subs, ch)
+	}
+	return subs
+}
+
+// publish writes the new samples to the sinks and sends them to the
+// subscribers.
+func (s *Session) publish(batch []Sample, subs []chan []Sample) {
+	if len(batch) == 0 {
+		return
+	}
+	for _, sink := range s.sinks {
+		if err := sink.Write(batch); err != nil {
+			log.Println("session", s.ID, ": écriture du puits :", err)
+		}
+	}
+	for _, ch := range subs {
+		select {
+		case ch <- batch:
+		default: // slow subscriber, drop the batch
+		}
+	}
+}
+
+// Snapshot returns the current scenario and the last sample.
+func (s *Session) Snapshot() (simulation.Scenario, Sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loop.Scenario, s.last
+}
+
+// Inspect returns the internal state of the loop of the session, as of
+// its last sample.
+func (s *Session) Inspect() simulation.LoopState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loop.Inspect()
+}
+
+// Recent returns the times, setpoints and measurements of the recent
+// samples, since the last parameter change, for statistics.
+func (s *Session) Recent() (T, SP, PV []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	window := s.advisor.window
+	T, SP, PV = make([]float64, len(window)), make([]float64, len(window)), make([]float64, len(window))
+	for i, smp := range window {
+		T[i], SP[i], PV[i] = smp.T, smp.SP, smp.PV
+	}
+	return T, SP, PV
+}
+
+// Update changes the parameters of the running loop, which can then be
+// undone. The time step cannot be changed once the session is started.
+// Changes leaving the scenario inconsistent, as reported by its Check, are
+// not applied and the inconsistencies are returned.
+func (s *Session) Update(change func(*simulation.Scenario)) (simulation.Scenario, []schema.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc := s.loop.Scenario
+	change(&sc)
+	sc.Dt = s.loop.Scenario.Dt
+	if errs := sc.Check(); len(errs) > 0 {
+		return s.loop.Scenario, errs
+	}
+	s.undo = append(s.undo, Edit{T: numfmt.Round(s.loop.T), Before: s.loop.Scenario, After: sc})
+	if len(s.undo) > maxUndo {
+		s.undo = s.undo[len(s.undo)-maxUndo:]
+	}
+	s.redo = nil
+	s.replace(sc)
+	return sc, nil
+}
+
+// replace runs the loop on with the parameters sc.
+func (s *Session) replace(sc simulation.Scenario) {
+	s.pending = append(s.pending, simulation.Changes(numfmt.Round(s.loop.T), s.loop.Scenario, sc)...)
+	s.loop.Update(sc)
+	s.advisor.reset()
+}
+
+// Subscribe returns a channel receiving the samples of each tick, and a
+// function to unsubscribe.
+func (s *Session) Subscribe() (<-chan []Sample, func()) {
+
+	ch := make(chan []Sample, 16)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}
+}
+
+// Stop ends the session, closes its sinks and waits for it to finish.
+func (s *Session) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// Done is closed once the session has stopped.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
@@ -0,0 +1,75 @@
+package live
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Errors returned by Pause, Resume and Step in the wrong state.
+var (
+	ErrPaused    = errors.New("la session est déjà en pause")
+	ErrNotPaused = errors.New("la session n'est pas en pause")
+)
+
+// Pause stops the loop where it is, for it to be stepped through with Step
+// as in a debugger. The simulated time stands still until Resume.
+func (s *Session) Pause() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return ErrPaused
+	}
+	s.paused = true
+	return nil
+}
+
+// Resume runs a paused loop on in real time, at the speed of the session,
+// from where it stands: the time spent paused is not caught up.
+func (s *Session) Resume() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.paused {
+		return ErrNotPaused
+	}
+	s.paused = false
+	s.ticked = time.Now()
+	return nil
+}
+
+// Paused tells whether the session is paused.
+func (s *Session) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// Step advances a paused loop by exactly one controller cycle, publishes
+// the sample as a tick would and returns it, with the state of the
+// controller behind its output.
+func (s *Session) Step() (Sample, error) {
+	s.mu.Lock()
+	if !s.paused {
+		s.mu.Unlock()
+		return Sample{}, ErrNotPaused
+	}
+	batch := []Sample{s.step(time.Now())}
+	s.record(batch)
+	s.steps++
+	s.due += s.loop.Scenario.Dt
+	subs := s.subscribers()
+	s.mu.Unlock()
+
+	s.publish(batch, subs)
+	return batch[0], nil
+}
+
+// roundState rounds the controller state for output, see numfmt.
+func roundState(st simulation.ControllerState) simulation.ControllerState {
+	st.Error, st.P, st.I, st.D = numfmt.Round(st.Error), numfmt.Round(st.P), numfmt.Round(st.I), numfmt.Round(st.D)
+	return st
+}
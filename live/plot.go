@@ -0,0 +1,75 @@
+package live
+
+import (
+	"io"
+	"slices"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// maxTrend bounds the samples a session keeps for its trend plot.
+const maxTrend = 5000
+
+// alarmColor draws the limits of the alarms on the trend plot.
+const alarmColor = "#ff7f0e"
+
+// Trend returns the recent samples of the session, at most maxTrend, the
+// oldest first.
+func (s *Session) Trend() []Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.trend)
+}
+
+// WritePlot draws the trend of the session to w in the format: the
+// setpoint and the measurement of its recent samples over its bands, the
+// limits of its alarms dashed, as a run is drawn by Result.WritePlot.
+func (s *Session) WritePlot(w io.Writer, format string, opts simulation.PlotOptions) error {
+
+	s.mu.Lock()
+	trend := slices.Clone(s.trend)
+	bands := s.loop.Scenario.Bands
+	alarms := make([]AlarmConfig, len(s.alarms))
+	for i, a := range s.alarms {
+		alarms[i] = a.AlarmConfig
+	}
+	s.mu.Unlock()
+
+	var res simulation.Result
+	for _, smp := range trend {
+		res.Time = append(res.Time, smp.T)
+		res.SP = append(res.SP, smp.SP)
+		res.PV = append(res.PV, smp.PV)
+	}
+	res.Bands = simulation.BandTraces(bands, res.SP)
+	f, err := res.Figure(opts)
+	if err != nil {
+		return err
+	}
+	for _, a := range alarms {
+		f.Series = append(f.Series, alarmSeries(a, res.Time, res.SP)...)
+	}
+	return opts.Render(w, f, format)
+}
+
+// alarmSeries returns the dashed lines of the limits of an alarm over the
+// times T: one for a high or low alarm, two around the setpoint SP for a
+// deviation alarm.
+func alarmSeries(a AlarmConfig, T, SP []float64) []chart.Series {
+
+	line := func(label string, at func(k int) float64) chart.Series {
+		Y := make([]float64, len(T))
+		for k := range T {
+			Y[k] = at(k)
+		}
+		return chart.Series{Label: label, X: T, Y: Y, Color: alarmColor, Width: 1, Dashes: []float64{2, 2}}
+	}
+	if a.Kind != AlarmDeviation {
+		return []chart.Series{line(a.Name, func(int) float64 { return a.Limit })}
+	}
+	return []chart.Series{
+		line(a.Name, func(k int) float64 { return SP[k] + a.Limit }),
+		line("", func(k int) float64 { return SP[k] - a.Limit }),
+	}
+}
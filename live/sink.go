@@ -0,0 +1,178 @@
+package live
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// Sink receives the samples of a live session, one batch per tick.
+type Sink interface {
+	Write(batch []Sample) error
+	Close() error
+}
+
+// SinkConfig selects and configures a sink.
+//
+//   - "influxdb": samples are POSTed in line protocol to URL, either on the
+//     1.x /write API (Database, Username/Password) or, when Org or Token is
+//     set, on the 2.x /api/v2/write API (Org, Bucket, Token);
+//   - "file": samples are appended to Path, below the storage directory, as
+//     line protocol (Format "line") or as SQL INSERT statements for a
+//     TimescaleDB hypertable (Format "sql").
+type SinkConfig struct {
+	Type        string `json:"type"`
+	URL         string `json:"url,omitempty"`
+	Database    string `json:"database,omitempty"`
+	Org         string `json:"org,omitempty"`
+	Bucket      string `json:"bucket,omitempty"`
+	Token       string `json:"token,omitempty"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Format      string `json:"format,omitempty"`
+	Measurement string `json:"measurement,omitempty"`
+}
+
+// identifier restricts measurement and table names, which are written
+// verbatim in line protocol and SQL.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewSink builds the configured sink for the session. Files are created
+// below dataDir.
+func NewSink(cfg SinkConfig, session, dataDir string) (Sink, error) {
+
+	if cfg.Measurement == "" {
+		cfg.Measurement = "regulation"
+	}
+	if !identifier.MatchString(cfg.Measurement) {
+		return nil, fmt.Errorf("nom de mesure invalide %q", cfg.Measurement)
+	}
+
+	switch cfg.Type {
+	case "influxdb":
+		u, err := url.Parse(strings.TrimRight(cfg.URL, "/"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("URL InfluxDB invalide %q", cfg.URL)
+		}
+		header := http.Header{"Content-Type": {"text/plain; charset=utf-8"}}
+		if cfg.Org != "" || cfg.Token != "" {
+			u = u.JoinPath("api", "v2", "write")
+			u.RawQuery = url.Values{"org": {cfg.Org}, "bucket": {cfg.Bucket}, "precision": {"ms"}}.Encode()
+			header.Set("Authorization", "Token "+cfg.Token)
+		} else {
+			u = u.JoinPath("write")
+			u.RawQuery = url.Values{"db": {cfg.Database}, "precision": {"ms"}}.Encode()
+			if cfg.Username != "" {
+				header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(cfg.Username+":"+cfg.Password)))
+			}
+		}
+		return &influxSink{url: u.String(), header: header, measurement: cfg.Measurement, session: session}, nil
+
+	case "file":
+		if cfg.Path == "" || !filepath.IsLocal(cfg.Path) {
+			return nil, fmt.Errorf("chemin de fichier invalide %q : il doit être relatif au répertoire de stockage", cfg.Path)
+		}
+		if cfg.Format != "line" && cfg.Format != "sql" {
+			return nil, fmt.Errorf("format de fichier inconnu %q (line ou sql)", cfg.Format)
+		}
+		path := filepath.Join(dataDir, cfg.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return &fileSink{f: f, sql: cfg.Format == "sql", measurement: cfg.Measurement, session: session}, nil
+	}
+	return nil, fmt.Errorf("type de puits inconnu %q", cfg.Type)
+}
+
+func formatFloat(v float64) string {
+	return numfmt.String(v)
+}
+
+// writeLines encodes the batch in InfluxDB line protocol.
+func writeLines(w io.Writer, measurement, session string, batch []Sample) {
+	for _, s := range batch {
+		fmt.Fprintf(w, "%s,session=%s sp=%s,pv=%s,u=%s,t=%s %d\n",
+			measurement, session, formatFloat(s.SP), formatFloat(s.PV), formatFloat(s.U), formatFloat(s.T),
+			s.At.UnixMilli())
+	}
+}
+
+type influxSink struct {
+	url         string
+	header      http.Header
+	measurement string
+	session     string
+}
+
+func (s *influxSink) Write(batch []Sample) error {
+
+	var buf bytes.Buffer
+	writeLines(&buf, s.measurement, s.session, batch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header = s.header.Clone()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("InfluxDB a répondu %s : %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func (s *influxSink) Close() error { return nil }
+
+type fileSink struct {
+	f           *os.File
+	sql         bool
+	measurement string
+	session     string
+}
+
+func (s *fileSink) Write(batch []Sample) error {
+
+	var buf bytes.Buffer
+	if !s.sql {
+		writeLines(&buf, s.measurement, s.session, batch)
+	} else {
+		fmt.Fprintf(&buf, "INSERT INTO %s (time, session, t, sp, pv, u) VALUES\n", s.measurement)
+		for i, smp := range batch {
+			sep := ",\n"
+			if i == len(batch)-1 {
+				sep = ";\n"
+			}
+			fmt.Fprintf(&buf, "  ('%s', '%s', %s, %s, %s, %s)%s",
+				smp.At.Format(time.RFC3339Nano), s.session,
+				formatFloat(smp.T), formatFloat(smp.SP), formatFloat(smp.PV), formatFloat(smp.U), sep)
+		}
+	}
+	_, err := s.f.Write(buf.Bytes())
+	return err
+}
+
+func (s *fileSink) Close() error { return s.f.Close() }
@@ -0,0 +1,49 @@
+package live
+
+import (
+	"fmt"
+	"time"
+)
+
+// Bounds of the speed of a session: from ten times slower to a thousand
+// times faster than real time.
+const (
+	MinSpeed = 0.1
+	MaxSpeed = 1000.0
+)
+
+// ErrSpeed is returned by SetSpeed for a speed out of bounds.
+var ErrSpeed = fmt.Errorf("facteur de vitesse hors de [%g, %g]", MinSpeed, MaxSpeed)
+
+// catchUp adds to the simulated time due the wall-clock time elapsed since
+// the last tick, at the current speed, nothing while the session is
+// paused.
+func (s *Session) catchUp(now time.Time) {
+	if !s.paused {
+		s.due += float64(now.Sub(s.ticked).Seconds() * s.speed)
+	}
+	s.ticked = now
+}
+
+// SetSpeed runs the session factor times as fast as real time from now
+// on, fast-forwarding slow plants or slowing fast loops down for
+// observation. At high speed with a small time step, the steps beyond
+// maxStepsPerTick per tick are dropped as when the loop cannot keep up.
+func (s *Session) SetSpeed(factor float64) error {
+	if !(factor >= MinSpeed && factor <= MaxSpeed) {
+		return ErrSpeed
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catchUp(time.Now())
+	s.speed = factor
+	return nil
+}
+
+// Speed returns the factor by which the session runs faster than real
+// time.
+func (s *Session) Speed() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.speed
+}
@@ -0,0 +1,64 @@
+package live
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// maxUndo bounds the number of parameter changes a session can undo.
+const maxUndo = 100
+
+// Errors returned by Undo and Redo when there is no change to take back.
+var (
+	ErrNothingToUndo = errors.New("aucune modification à annuler")
+	ErrNothingToRedo = errors.New("aucune modification à rétablir")
+)
+
+// Edit is a parameter change of a session, made at the simulated time T:
+// the parameters before and after it.
+type Edit struct {
+	T             float64
+	Before, After simulation.Scenario
+}
+
+// Undo restores the parameters in force before the last change, which
+// Redo then applies again, and returns that change. A change made after
+// an undo drops the changes that could be redone.
+func (s *Session) Undo() (Edit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.undo) == 0 {
+		return Edit{}, ErrNothingToUndo
+	}
+	e := s.undo[len(s.undo)-1]
+	s.undo = s.undo[:len(s.undo)-1]
+	s.replace(e.Before)
+	s.redo = append(s.redo, e)
+	return e, nil
+}
+
+// Redo applies again the last change undone and returns it.
+func (s *Session) Redo() (Edit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.redo) == 0 {
+		return Edit{}, ErrNothingToRedo
+	}
+	e := s.redo[len(s.redo)-1]
+	s.redo = s.redo[:len(s.redo)-1]
+	s.replace(e.After)
+	s.undo = append(s.undo, e)
+	return e, nil
+}
+
+// Edits returns the changes that can be undone and redone, the next one
+// to undo or redo last.
+func (s *Session) Edits() (undo, redo []Edit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.undo), slices.Clone(s.redo)
+}
@@ -0,0 +1,88 @@
+package numfmt
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp formats of CSV outputs.
+const (
+	TimeRFC3339  = "rfc3339"  // 2024-05-01T08:00:00.5Z
+	TimeDateTime = "datetime" // 2024-05-01 08:00:00.500, read as a date by spreadsheets
+	TimeUnix     = "unix"     // seconds since 1970-01-01 UTC
+)
+
+// CSV is the dialect of a CSV output, for it to open as it is in the
+// spreadsheets of any locale: French Excel expects semicolons between the
+// cells and decimal commas. The zero value writes commas, decimal points
+// and RFC 3339 timestamps.
+type CSV struct {
+	Comma   rune // ',' when zero
+	Decimal rune // '.' when zero
+	// Time is one of the timestamp formats, TimeRFC3339 when empty.
+	// Timestamps are written in UTC.
+	Time string
+}
+
+// FrenchCSV is the dialect of French spreadsheets.
+var FrenchCSV = CSV{Comma: ';', Decimal: ',', Time: TimeDateTime}
+
+// Check reports an unknown delimiter, decimal separator or timestamp
+// format.
+func (c CSV) Check() error {
+	switch {
+	case c.Comma != 0 && c.Comma != ',' && c.Comma != ';' && c.Comma != '\t':
+		return fmt.Errorf("séparateur de colonnes %q inconnu, attendu virgule, point-virgule ou tabulation", c.Comma)
+	case c.Decimal != 0 && c.Decimal != '.' && c.Decimal != ',':
+		return fmt.Errorf("séparateur décimal %q inconnu, attendu point ou virgule", c.Decimal)
+	case c.Time != "" && c.Time != TimeRFC3339 && c.Time != TimeDateTime && c.Time != TimeUnix:
+		return fmt.Errorf("format d'horodatage %q inconnu, attendu rfc3339, datetime ou unix", c.Time)
+	}
+	return nil
+}
+
+// Writer returns a CSV writer with the delimiter of the dialect. Cells
+// holding the delimiter, such as decimal commas between commas, are
+// quoted.
+func (c CSV) Writer(w io.Writer) *csv.Writer {
+	cw := csv.NewWriter(w)
+	if c.Comma != 0 {
+		cw.Comma = c.Comma
+	}
+	return cw
+}
+
+// Number formats v as String does, with the decimal separator of the
+// dialect.
+func (c CSV) Number(v float64) string {
+	return c.decimal(String(v))
+}
+
+// Timestamp formats t in the timestamp format of the dialect.
+func (c CSV) Timestamp(t time.Time) string {
+	t = t.UTC()
+	switch c.Time {
+	case TimeDateTime:
+		return c.decimal(t.Format("2006-01-02 15:04:05.000"))
+	case TimeUnix:
+		// Every digit down to the nanosecond is kept: as a float64 the
+		// seconds since 1970 would lose the microseconds.
+		s := strconv.FormatInt(t.Unix(), 10)
+		if ns := t.Nanosecond(); ns != 0 {
+			s += "." + strings.TrimRight(fmt.Sprintf("%09d", ns), "0")
+		}
+		return c.decimal(s)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func (c CSV) decimal(s string) string {
+	if c.Decimal == ',' {
+		return strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
@@ -0,0 +1,265 @@
+package numfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// NonFinite is how JSON outputs write NaN and infinities, for which JSON
+// has no numbers: encoding/json refuses them, so that a diverged run
+// could not be sent at all.
+type NonFinite string
+
+const (
+	// NonFiniteNull writes null, the output telling elsewhere that values
+	// are missing, such as the "diverged" status of a result.
+	NonFiniteNull NonFinite = "null"
+	// NonFiniteString writes the strings "NaN", "Infinity" and
+	// "-Infinity", as read by UnmarshalJSON, JavaScript's Number() and
+	// Python's float().
+	NonFiniteString NonFinite = "string"
+)
+
+// The non-finite values are marshaled as the largest floats, rewritten
+// afterwards. A rounded output never holds them, Round keeping Digits
+// digits only.
+var (
+	nanSentinel    = math.Nextafter(math.MaxFloat64, 0)
+	sentinelTokens = map[string]string{}
+)
+
+func init() {
+	for v, name := range map[float64]string{nanSentinel: "NaN", math.MaxFloat64: "Infinity", -math.MaxFloat64: "-Infinity"} {
+		b, _ := json.Marshal(v)
+		sentinelTokens[string(b)] = name
+	}
+}
+
+func toSentinel(v float64) (float64, bool) {
+	switch {
+	case math.IsNaN(v):
+		return nanSentinel, true
+	case math.IsInf(v, 1):
+		return math.MaxFloat64, true
+	case math.IsInf(v, -1):
+		return -math.MaxFloat64, true
+	}
+	return v, false
+}
+
+func fromSentinel(v float64) (float64, bool) {
+	switch v {
+	case nanSentinel:
+		return math.NaN(), true
+	case math.MaxFloat64:
+		return math.Inf(1), true
+	case -math.MaxFloat64:
+		return math.Inf(-1), true
+	}
+	return v, false
+}
+
+// MarshalJSON is json.Marshal writing the NaN and infinite float64 values
+// of v as mode tells, and returns how many there were.
+func MarshalJSON(v any, mode NonFinite) ([]byte, int, error) {
+
+	n := 0
+	rv, changed := mapFloats(reflect.ValueOf(v), func(f float64) (float64, bool) {
+		s, ok := toSentinel(f)
+		if ok {
+			n++
+		}
+		return s, ok
+	})
+	if !changed {
+		b, err := json.Marshal(v)
+		return b, 0, err
+	}
+	b, err := json.Marshal(rv.Interface())
+	if err != nil {
+		return nil, n, err
+	}
+	return rewriteTokens(b, func(token []byte) []byte {
+		name, ok := sentinelTokens[string(token)]
+		switch {
+		case !ok:
+			return nil
+		case mode == NonFiniteString:
+			return strconv.AppendQuote(nil, name)
+		}
+		return []byte("null")
+	}), n, nil
+}
+
+// UnmarshalJSON is json.Unmarshal also reading the strings of
+// NonFiniteString outputs into float64 values. A string field holding
+// exactly "NaN", "Infinity" or "-Infinity" cannot be read with it.
+func UnmarshalJSON(data []byte, v any) error {
+
+	if !bytes.Contains(data, []byte(`"NaN"`)) && !bytes.Contains(data, []byte(`Infinity"`)) {
+		return json.Unmarshal(data, v)
+	}
+	names := map[string]string{}
+	for token, name := range sentinelTokens {
+		names[strconv.Quote(name)] = token
+	}
+	data = rewriteTokens(data, func(token []byte) []byte {
+		if s, ok := names[string(token)]; ok {
+			return []byte(s)
+		}
+		return nil
+	})
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil
+	}
+	if out, changed := mapFloats(rv.Elem(), fromSentinel); changed {
+		rv.Elem().Set(out)
+	}
+	return nil
+}
+
+// rewriteTokens returns b with the numbers and strings replaced by
+// replace, which returns nil to keep a token.
+func rewriteTokens(b []byte, replace func(token []byte) []byte) []byte {
+
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); {
+		start := i
+		switch c := b[i]; {
+		case c == '"':
+			for i++; i < len(b) && b[i] != '"'; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		case c == '-' || c >= '0' && c <= '9':
+			for i < len(b) && bytes.IndexByte([]byte("+-.eE0123456789"), b[i]) >= 0 {
+				i++
+			}
+		default:
+			out = append(out, c)
+			i++
+			continue
+		}
+		i = min(i, len(b))
+		if r := replace(b[start:i]); r != nil {
+			out = append(out, r...)
+		} else {
+			out = append(out, b[start:i]...)
+		}
+	}
+	return out
+}
+
+// mapFloats returns v with its float64 values, followed through pointers,
+// interfaces, slices, arrays, maps and exported struct fields, replaced by
+// f, which reports whether it changed one. v is only copied where it
+// changes, and is returned as is when nothing does.
+func mapFloats(v reflect.Value, f func(float64) (float64, bool)) (reflect.Value, bool) {
+
+	switch v.Kind() {
+	case reflect.Float64:
+		x, ok := f(v.Float())
+		if !ok {
+			return v, false
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.SetFloat(x)
+		return out, true
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, false
+		}
+		e, ok := mapFloats(v.Elem(), f)
+		if !ok {
+			return v, false
+		}
+		p := reflect.New(e.Type())
+		p.Elem().Set(e)
+		return p, true
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		e, ok := mapFloats(v.Elem(), f)
+		if !ok {
+			return v, false
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(e)
+		return out, true
+
+	case reflect.Struct:
+		var out reflect.Value
+		for i := range v.NumField() {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			e, ok := mapFloats(v.Field(i), f)
+			if !ok {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.New(v.Type()).Elem()
+				out.Set(v)
+			}
+			out.Field(i).Set(e)
+		}
+		return out, out.IsValid()
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v, false
+		}
+		var out reflect.Value
+		for i := range v.Len() {
+			e, ok := mapFloats(v.Index(i), f)
+			if !ok {
+				continue
+			}
+			if !out.IsValid() {
+				if v.Kind() == reflect.Slice {
+					out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+					reflect.Copy(out, v)
+				} else {
+					out = reflect.New(v.Type()).Elem()
+					out.Set(v)
+				}
+			}
+			out.Index(i).Set(e)
+		}
+		return out, out.IsValid()
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v, false
+		}
+		var out reflect.Value
+		iter := v.MapRange()
+		for iter.Next() {
+			e, ok := mapFloats(iter.Value(), f)
+			if !ok {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeMapWithSize(v.Type(), v.Len())
+				for other := v.MapRange(); other.Next(); {
+					out.SetMapIndex(other.Key(), other.Value())
+				}
+			}
+			out.SetMapIndex(iter.Key(), e)
+		}
+		return out, out.IsValid()
+	}
+	return v, false
+}
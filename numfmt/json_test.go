@@ -0,0 +1,51 @@
+package numfmt
+
+import (
+	"math"
+	"testing"
+)
+
+// FuzzUnmarshalJSON reads arbitrary documents without panicking, and checks
+// that rounded series, as the outputs hold, survive a NonFiniteString round
+// trip with their NaN and infinities.
+func FuzzUnmarshalJSON(f *testing.F) {
+
+	for _, seed := range []string{
+		`[0, 1.5, -2e-300, 1e308]`,
+		`[1, "NaN", "Infinity", "-Infinity"]`,
+		`{"pv": ["NaN", 1], "status": "diverged"}`,
+		`[1.7976931348623157e308, -1.7976931348623157e308]`,
+		`"Infinity"`,
+		`[1, "NaN"`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+
+		var doc any
+		UnmarshalJSON(data, &doc)
+
+		var xs []float64
+		if UnmarshalJSON(data, &xs) != nil {
+			return
+		}
+		xs = Series(xs)
+		b, _, err := MarshalJSON(xs, NonFiniteString)
+		if err != nil {
+			t.Fatalf("MarshalJSON(%v): %v", xs, err)
+		}
+		var back []float64
+		if err := UnmarshalJSON(b, &back); err != nil {
+			t.Fatalf("UnmarshalJSON(%s): %v", b, err)
+		}
+		if len(back) != len(xs) {
+			t.Fatalf("%s read back as %d values, want %d", b, len(back), len(xs))
+		}
+		for i, x := range xs {
+			if back[i] != x && !(math.IsNaN(back[i]) && math.IsNaN(x)) {
+				t.Fatalf("value %d: %v read back as %v from %s", i, x, back[i], b)
+			}
+		}
+	})
+}
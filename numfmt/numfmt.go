@@ -0,0 +1,83 @@
+// Package numfmt makes the numbers sent to clients identical on every
+// platform: values are rounded to Digits significant digits, which hides
+// the last-bit differences left by the compiler and the CPU, and written in
+// the shortest form that reads back exactly.
+package numfmt
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Digits is the number of significant digits kept in every output.
+const Digits = 12
+
+// Round rounds v to Digits significant digits. Negative zero becomes 0,
+// NaN and infinities are returned unchanged.
+func Round(v float64) float64 {
+	if v == 0 {
+		return 0
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	r, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', Digits, 64), 64)
+	return r
+}
+
+// Series returns a rounded copy of xs.
+func Series(xs []float64) []float64 {
+	if xs == nil {
+		return nil
+	}
+	out := make([]float64, len(xs))
+	for i, x := range xs {
+		out[i] = Round(x)
+	}
+	return out
+}
+
+// String formats v rounded, for text outputs such as CSV.
+func String(v float64) string {
+	return strconv.FormatFloat(Round(v), 'g', -1, 64)
+}
+
+// Parse reads a number typed or pasted by a person: the decimal separator
+// may be a comma, as in French, and spaces may group the thousands, so
+// that "0,5", "1 234,5", "1.234,5", "1,234.5" and "2,5e-3" are all read.
+// When both separators appear, the last one is the decimal separator.
+// NaN and infinities are refused.
+func Parse(s string) (float64, error) {
+
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\t':
+			return -1
+		}
+		return r
+	}, s)
+
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent = s[:i], s[i:]
+	}
+	comma, dot := strings.LastIndex(mantissa, ","), strings.LastIndex(mantissa, ".")
+	switch {
+	case comma > dot:
+		mantissa = strings.ReplaceAll(mantissa, ".", "")
+		if strings.Count(mantissa, ",") > 1 {
+			return 0, fmt.Errorf("nombre invalide %q", s)
+		}
+		mantissa = strings.Replace(mantissa, ",", ".", 1)
+	case comma >= 0:
+		mantissa = strings.ReplaceAll(mantissa, ",", "")
+	}
+
+	v, err := strconv.ParseFloat(mantissa+exponent, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("nombre invalide %q", s)
+	}
+	return v, nil
+}
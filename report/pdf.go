@@ -0,0 +1,91 @@
+//go:build !nogonum
+
+package report
+
+import (
+	"image/color"
+	"io"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/chart/gonumplot"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/font"
+	"gonum.org/v1/plot/text"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgpdf"
+)
+
+// WritePDF writes the comparison as an A4 landscape PDF: the plot on the
+// first page, the table on the second.
+func (c Comparison) WritePDF(w io.Writer) error {
+
+	f, loc, err := c.figure()
+	if err != nil {
+		return err
+	}
+	p, err := gonumplot.Plot(f)
+	if err != nil {
+		return err
+	}
+
+	width, height := 297*vg.Millimeter, 210*vg.Millimeter
+	margin := 15 * vg.Millimeter
+	pdf := vgpdf.New(width, height)
+	p.Draw(draw.Crop(draw.New(pdf), margin, -margin, margin, -margin))
+	pdf.NextPage()
+
+	dc := draw.New(pdf)
+	// vgpdf registers the fonts without their style, so that bold faces
+	// cannot be selected: bold text is overprinted with a slight offset.
+	fill := func(size vg.Length, bold bool, clr color.Color, pt vg.Point, txt string) {
+		sty := text.Style{Color: clr, Font: font.Font{Typeface: "Liberation", Variant: "Sans", Size: size}, Handler: plot.DefaultTextHandler}
+		dc.FillText(sty, pt, txt)
+		if bold {
+			dc.FillText(sty, vg.Point{X: pt.X + size/40, Y: pt.Y}, txt)
+		}
+	}
+	black := color.Black
+	y := height - margin
+	fill(16, true, black, vg.Point{X: margin, Y: y - 16}, loc.ComparisonTitle)
+	y -= 28
+	fill(9, false, black, vg.Point{X: margin, Y: y - 9}, loc.Best)
+	y -= 24
+
+	rowHeight := vg.Length(16)
+	nameWidth := 40 * vg.Millimeter
+	colWidth := (width - 2*margin - nameWidth) / vg.Length(len(c.Runs))
+	x := func(i int) vg.Length { return margin + nameWidth + vg.Length(i)*colWidth }
+
+	fill(10, true, black, vg.Point{X: margin, Y: y - 12}, loc.Run)
+	for i, run := range c.Runs {
+		clr, _ := chart.ParseColor(simulation.ComparisonPalette[i])
+		fill(10, true, clr, vg.Point{X: x(i), Y: y - 12}, run.Label)
+	}
+	y -= rowHeight
+
+	params, metrics := c.Table()
+	for k, rows := range [][]Row{params, metrics} {
+		if k == 1 {
+			fill(10, true, black, vg.Point{X: margin, Y: y - 12}, loc.Metric)
+			y -= rowHeight
+		}
+		for _, row := range rows {
+			fill(10, false, black, vg.Point{X: margin, Y: y - 12}, row.Name)
+			for i, v := range row.Values {
+				best := row.Best != nil && row.Best[i]
+				if best {
+					x0, x1, y0, y1 := x(i)-2, x(i)+colWidth-4, y-rowHeight+1, y-1
+					dc.FillPolygon(color.RGBA{R: 0xd8, G: 0xf0, B: 0xd8, A: 0xff}, []vg.Point{{X: x0, Y: y0}, {X: x1, Y: y0}, {X: x1, Y: y1}, {X: x0, Y: y1}})
+				}
+				fill(10, best, black, vg.Point{X: x(i), Y: y - 12}, number(loc, v))
+			}
+			y -= rowHeight
+		}
+	}
+
+	_, err = pdf.WriteTo(w)
+	return err
+}
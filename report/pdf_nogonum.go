@@ -0,0 +1,15 @@
+//go:build nogonum
+
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+)
+
+// WritePDF is unavailable in builds leaving gonum/plot out.
+func (c Comparison) WritePDF(w io.Writer) error {
+	return fmt.Errorf("%w : rapport PDF, le serveur est compilé sans gonum/plot", chart.ErrUnsupported)
+}
@@ -0,0 +1,167 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Run is one of the compared runs.
+type Run struct {
+	Label    string
+	Scenario simulation.Scenario
+	Result   simulation.Result
+}
+
+// Comparison is a design review document comparing runs side by side: an
+// overlay of their responses and a table of their parameters and metrics.
+type Comparison struct {
+	Runs    []Run
+	Options simulation.PlotOptions
+}
+
+// Row is a line of the table. Best marks the runs with the lowest value of
+// a metric, all metrics being better when lower; it is unset when every
+// run has the same value.
+type Row struct {
+	Name   string
+	Values []float64
+	Best   []bool
+}
+
+// parameters are the scenario parameters listed above the metrics.
+var parameters = []string{"Sp", "K", "Tau", "P", "Ki", "Kd"}
+
+// plantMetrics are left out of the table when every run has zero, as for
+// runs of plants they do not apply to.
+var plantMetrics = map[string]bool{"cost": true, "iaeHeating": true, "iaeCooling": true, "levelDeviation": true, "outflowRate": true}
+
+// Table returns the parameters and the metrics of the runs.
+func (c Comparison) Table() (params, metrics []Row) {
+
+	for _, name := range parameters {
+		row := Row{Name: name}
+		for _, run := range c.Runs {
+			p, _ := run.Scenario.Param(name)
+			row.Values = append(row.Values, *p)
+		}
+		params = append(params, row)
+	}
+
+	for _, name := range simulation.MetricNames {
+		row := Row{Name: name, Best: make([]bool, len(c.Runs))}
+		zero := true
+		for _, run := range c.Runs {
+			v, _ := run.Result.Metrics.Get(name)
+			row.Values = append(row.Values, v)
+			zero = zero && v == 0
+		}
+		if zero && plantMetrics[name] {
+			continue
+		}
+		best := row.Values[0]
+		for _, v := range row.Values {
+			best = min(best, v)
+		}
+		if !allEqual(row.Values) {
+			for i, v := range row.Values {
+				row.Best[i] = v == best
+			}
+		}
+		metrics = append(metrics, row)
+	}
+	return params, metrics
+}
+
+func allEqual(values []float64) bool {
+	for _, v := range values {
+		if v != values[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// number formats v for the table with the decimal separator of loc.
+func number(loc simulation.Locale, v float64) string {
+	s := strconv.FormatFloat(v, 'g', 4, 64)
+	if loc.DecimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+func (c Comparison) figure() (chart.Figure, simulation.Locale, error) {
+	loc, err := simulation.FindLocale(c.Options.Locale)
+	if err != nil {
+		return chart.Figure{}, loc, err
+	}
+	labels := make([]string, len(c.Runs))
+	results := make([]simulation.Result, len(c.Runs))
+	for i, run := range c.Runs {
+		labels[i], results[i] = run.Label, run.Result
+	}
+	f, err := simulation.ComparisonPlot(labels, results, c.Options)
+	return f, loc, err
+}
+
+//go:embed report.html
+var page string
+
+var htmlTemplate = template.Must(template.New("report").Parse(page))
+
+// WriteHTML writes the comparison as a standalone HTML page, the plot
+// inlined as SVG.
+func (c Comparison) WriteHTML(w io.Writer) error {
+
+	f, loc, err := c.figure()
+	if err != nil {
+		return err
+	}
+	var svg bytes.Buffer
+	if err := chart.Render(&svg, f, c.Options.Backend, "svg", 720, 360); err != nil {
+		return err
+	}
+
+	type cell struct {
+		Text string
+		Best bool
+	}
+	type line struct {
+		Name  string
+		Cells []cell
+	}
+	lines := func(rows []Row) []line {
+		var out []line
+		for _, row := range rows {
+			l := line{Name: row.Name}
+			for i, v := range row.Values {
+				l.Cells = append(l.Cells, cell{Text: number(loc, v), Best: row.Best != nil && row.Best[i]})
+			}
+			out = append(out, l)
+		}
+		return out
+	}
+	type run struct {
+		Label, Color string
+	}
+	var runs []run
+	for i, r := range c.Runs {
+		runs = append(runs, run{r.Label, simulation.ComparisonPalette[i]})
+	}
+	params, metrics := c.Table()
+
+	return htmlTemplate.Execute(w, map[string]any{
+		"Locale":     loc,
+		"Plot":       template.HTML(svg.String()),
+		"Runs":       runs,
+		"Parameters": lines(params),
+		"Metrics":    lines(metrics),
+	})
+}
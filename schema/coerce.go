@@ -0,0 +1,51 @@
+package schema
+
+import "github.com/Ivan69-tech/PIDControllerResponse/units"
+
+// Coerce converts, in a decoded JSON document, the strings found where the
+// schema expects a number or a boolean, such as the values of an HTML form.
+// Numbers are read with parse; booleans accept "true", "false", "on" and
+// "off"; those of a number with a unit may carry it, see units.Split.
+// Values that do not convert are left for Validate to report.
+func (s *Schema) Coerce(doc any, parse func(string) (float64, error)) any {
+
+	switch x := doc.(type) {
+	case string:
+		switch s.Type {
+		case "number", "integer":
+			scale := 1.0
+			if s.Unit != "" {
+				number, sc, err := units.Split(x, s.Unit)
+				if err != nil {
+					return doc
+				}
+				x, scale = number, sc
+			}
+			if v, err := parse(x); err == nil {
+				return float64(v * scale)
+			}
+		case "boolean":
+			switch x {
+			case "true", "on":
+				return true
+			case "false", "off":
+				return false
+			}
+		}
+
+	case map[string]any:
+		for k, v := range x {
+			if child, ok := s.Properties[k]; ok {
+				x[k] = child.Coerce(v, parse)
+			}
+		}
+
+	case []any:
+		if s.Items != nil {
+			for i, item := range x {
+				x[i] = s.Items.Coerce(item, parse)
+			}
+		}
+	}
+	return doc
+}
@@ -0,0 +1,30 @@
+package schema
+
+// Defaults fills, in a decoded JSON document, the required properties that
+// are missing and have a default, in the objects found where the schema
+// expects them. Other missing properties are left to the zero values of
+// the decoding.
+func (s *Schema) Defaults(doc any) any {
+
+	switch x := doc.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := x[name]; !ok && s.Properties[name] != nil && s.Properties[name].Default != nil {
+				x[name] = s.Properties[name].Default
+			}
+		}
+		for k, v := range x {
+			if child, ok := s.Properties[k]; ok {
+				x[k] = child.Defaults(v)
+			}
+		}
+
+	case []any:
+		if s.Items != nil {
+			for i, item := range x {
+				x[i] = s.Items.Defaults(item)
+			}
+		}
+	}
+	return doc
+}
@@ -0,0 +1,113 @@
+package schema
+
+// Draft is the JSON Schema dialect declared by the published schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema used to describe the parameter
+// documents accepted by the simulator.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	// Unit is the unit of a number, see package units.
+	Unit string `json:"x-unit,omitempty"`
+}
+
+// Object returns an object schema with the given properties, rejecting
+// unknown ones.
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	closed := false
+	return &Schema{
+		Type:                 "object",
+		Properties:           properties,
+		Required:             required,
+		AdditionalProperties: &closed,
+	}
+}
+
+// Number returns a number schema with the given description.
+func Number(description string) *Schema {
+	return &Schema{Type: "number", Description: description}
+}
+
+// Integer returns an integer schema with the given description.
+func Integer(description string) *Schema {
+	return &Schema{Type: "integer", Description: description}
+}
+
+// String returns a string schema with the given description.
+func String(description string) *Schema {
+	return &Schema{Type: "string", Description: description}
+}
+
+// Array returns an array schema whose items follow the given schema.
+func Array(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// Boolean returns a boolean schema with the given description.
+func Boolean(description string) *Schema {
+	return &Schema{Type: "boolean", Description: description}
+}
+
+// AtLeast sets the minimum number of items of an array.
+func (s *Schema) AtLeast(n int) *Schema {
+	s.MinItems = &n
+	return s
+}
+
+// Min sets the inclusive lower bound.
+func (s *Schema) Min(v float64) *Schema {
+	s.Minimum = &v
+	return s
+}
+
+// Max sets the inclusive upper bound.
+func (s *Schema) Max(v float64) *Schema {
+	s.Maximum = &v
+	return s
+}
+
+// Above sets the exclusive lower bound.
+func (s *Schema) Above(v float64) *Schema {
+	s.ExclusiveMinimum = &v
+	return s
+}
+
+// Below sets the exclusive upper bound.
+func (s *Schema) Below(v float64) *Schema {
+	s.ExclusiveMaximum = &v
+	return s
+}
+
+// OneOf restricts the value to the given choices.
+func (s *Schema) OneOf(values ...any) *Schema {
+	s.Enum = values
+	return s
+}
+
+// WithDefault sets the value used when the field is omitted.
+func (s *Schema) WithDefault(v any) *Schema {
+	s.Default = v
+	return s
+}
+
+// In sets the unit of a number, in which Coerce also reads the strings
+// written with the unit and an SI prefix, as "20 kV".
+func (s *Schema) In(unit string) *Schema {
+	s.Unit = unit
+	return s
+}
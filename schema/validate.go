@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Error is a validation failure located by a JSON Pointer (RFC 6901).
+type Error struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	return e.Path + " : " + e.Message
+}
+
+// Validate checks a decoded JSON document (as produced by encoding/json
+// into an any) against the schema and returns every violation found.
+func (s *Schema) Validate(doc any) []Error {
+	var errs []Error
+	s.validate("", doc, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v any, errs *[]Error) {
+
+	fail := func(format string, args ...any) {
+		p := path
+		if p == "" {
+			p = "/"
+		}
+		*errs = append(*errs, Error{Path: p, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Type != "" && !hasType(v, s.Type) {
+		fail("type %s attendu, reçu %s", s.Type, typeName(v))
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, v) {
+		fail("valeur %v non autorisée, valeurs possibles : %v", v, s.Enum)
+	}
+
+	switch x := v.(type) {
+	case float64:
+		if s.Minimum != nil && x < *s.Minimum {
+			fail("doit être supérieur ou égal à %g, reçu %g", *s.Minimum, x)
+		}
+		if s.ExclusiveMinimum != nil && x <= *s.ExclusiveMinimum {
+			fail("doit être strictement supérieur à %g, reçu %g", *s.ExclusiveMinimum, x)
+		}
+		if s.Maximum != nil && x > *s.Maximum {
+			fail("doit être inférieur ou égal à %g, reçu %g", *s.Maximum, x)
+		}
+		if s.ExclusiveMaximum != nil && x >= *s.ExclusiveMaximum {
+			fail("doit être strictement inférieur à %g, reçu %g", *s.ExclusiveMaximum, x)
+		}
+
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := x[name]; !ok {
+				*errs = append(*errs, Error{Path: path + "/" + escape(name), Message: "champ obligatoire manquant"})
+			}
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child, ok := s.Properties[k]
+			switch {
+			case ok:
+				child.validate(path+"/"+escape(k), x[k], errs)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				*errs = append(*errs, Error{Path: path + "/" + escape(k), Message: "champ inconnu"})
+			}
+		}
+
+	case []any:
+		if s.MinItems != nil && len(x) < *s.MinItems {
+			fail("au moins %d élément(s) attendu(s), reçu %d", *s.MinItems, len(x))
+		}
+		if s.Items != nil {
+			for i, item := range x {
+				s.Items.validate(fmt.Sprintf("%s/%d", path, i), item, errs)
+			}
+		}
+	}
+}
+
+func hasType(v any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		x, ok := v.(float64)
+		return ok && x == math.Trunc(x)
+	case "null":
+		return v == nil
+	}
+	return true
+}
+
+func typeName(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if x == math.Trunc(x) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// escape encodes a property name as a JSON Pointer reference token.
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,155 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// MaxAccuracyLevels bounds the number of halvings of an accuracy study,
+// which costs 2^(levels+1) - 1 runs of the scenario.
+const MaxAccuracyLevels = 6
+
+// Accuracy is a dt-halving study of a scenario: run with its time step
+// halved again and again over the same duration, its measurement converges
+// at the order of the solver, which the study observes and uses to
+// recommend the time step reaching a target accuracy.
+type Accuracy struct {
+	Solver string          `json:"solver"`
+	Levels []AccuracyLevel `json:"levels"`
+	// Order is the convergence order observed between the finest levels,
+	// zero when the runs do not differ, do not converge, or converge at
+	// irregular orders.
+	Order float64 `json:"order"`
+	// Error estimates the largest error of the measurement at the time
+	// step of the scenario, by Richardson extrapolation.
+	Error float64 `json:"error"`
+	// Tolerance is the target accuracy, in units of the measurement, and
+	// RecommendedDt the largest time step reaching it, with RecommendedN
+	// the iterations covering the duration of the scenario. Both are zero
+	// when no order was observed.
+	Tolerance     float64 `json:"tolerance"`
+	RecommendedDt float64 `json:"recommendedDt"`
+	RecommendedN  float64 `json:"recommendedN"`
+	// Note explains why no order was observed.
+	Note string `json:"note,omitempty"`
+}
+
+// AccuracyLevel is one run of the study. Difference is the largest gap of
+// its measurement with that of the next level, half its time step, over
+// the samples of the scenario's time step; Order is the convergence order
+// observed from the previous level, zero for the first.
+type AccuracyLevel struct {
+	Dt         float64 `json:"dt"`
+	N          float64 `json:"N"`
+	Difference float64 `json:"difference,omitempty"`
+	Order      float64 `json:"order,omitempty"`
+}
+
+// Accuracy runs the scenario at its time step then at levels successive
+// halvings of it. A tolerance of zero is taken as 0.1 % of the largest
+// setpoint, or of the largest measurement when the setpoint stays at zero.
+func (sc Scenario) Accuracy(levels int, tolerance float64) (Accuracy, error) {
+
+	if levels < 2 || levels > MaxAccuracyLevels {
+		return Accuracy{}, fmt.Errorf("le nombre de divisions du pas doit être compris entre 2 et %d", MaxAccuracyLevels)
+	}
+	if tolerance < 0 {
+		return Accuracy{}, errors.New("la précision visée doit être positive")
+	}
+	if sc.Level != nil && sc.Level.Noise != nil && sc.Level.Noise.Std > 0 {
+		return Accuracy{}, errors.New("le bruit du débit d'entrée change avec le pas de temps : l'étude de convergence demande un scénario sans bruit")
+	}
+	if sc.Noise != nil && sc.Noise.Std > 0 {
+		return Accuracy{}, errors.New("le bruit de mesure change avec le pas de temps : l'étude de convergence demande un scénario sans bruit")
+	}
+
+	a := Accuracy{Solver: Solvers[0].Name}
+	// pv[i] is the measurement of level i at the times of the scenario's
+	// time step.
+	pv := make([][]float64, levels+1)
+	for i := range pv {
+		run := sc
+		scale := math.Ldexp(1, i)
+		run.Dt, run.N = sc.Dt/scale, sc.N*scale
+		a.Levels = append(a.Levels, AccuracyLevel{Dt: run.Dt, N: run.N})
+		res := run.Run()
+		if i == 0 {
+			a.Tolerance = tolerance
+			if tolerance == 0 {
+				a.Tolerance = 1e-3 * defaultScale(res)
+			}
+		}
+		for k := 0; k < len(res.PV); k += 1 << i {
+			pv[i] = append(pv[i], res.PV[k])
+		}
+	}
+
+	for i := 0; i < levels; i++ {
+		n := min(len(pv[i]), len(pv[i+1]))
+		for k := range n {
+			a.Levels[i].Difference = math.Max(a.Levels[i].Difference, math.Abs(pv[i][k]-pv[i+1][k]))
+		}
+		if i > 0 && a.Levels[i].Difference > 0 {
+			a.Levels[i].Order = math.Log2(a.Levels[i-1].Difference / a.Levels[i].Difference)
+		}
+	}
+
+	// The error of level i is C·dt_i^p; the gap with level i+1 removes
+	// the part 2^-p of it.
+	p := a.Levels[levels-1].Order
+	switch prev := a.Levels[levels-2].Order; {
+	case a.Levels[levels-1].Difference == 0:
+		a.Note = "les mesures ne dépendent pas du pas de temps"
+		return a, nil
+	case !(p > 0) || math.IsInf(p, 0):
+		a.Note = "les mesures ne convergent pas quand le pas diminue"
+		return a, nil
+	// Both orders exist from three levels on.
+	case levels > 2 && math.Abs(p-prev) > 0.25*math.Max(p, 1):
+		a.Note = fmt.Sprintf("ordres observés irréguliers, %.3g puis %.3g : une discontinuité, comme un échelon entre deux pas, domine l'écart", prev, p)
+		return a, nil
+	}
+	a.Order = p
+	gain := 1 - math.Pow(2, -p)
+	a.Error = a.Levels[levels-1].Difference / gain * math.Pow(2, p*float64(levels-1))
+	if a.Error > 0 && a.Tolerance > 0 {
+		a.RecommendedDt = sc.Dt * math.Pow(a.Tolerance/a.Error, 1/p)
+		a.RecommendedN = math.Ceil(sc.Dt * sc.N / a.RecommendedDt)
+	}
+	return a, nil
+}
+
+// defaultScale returns the magnitude the default tolerance is taken of.
+func defaultScale(res Result) float64 {
+	var sp, pv float64
+	for k := range res.PV {
+		if k < len(res.SP) {
+			sp = math.Max(sp, math.Abs(res.SP[k]))
+		}
+		pv = math.Max(pv, math.Abs(res.PV[k]))
+	}
+	switch {
+	case sp > 0:
+		return sp
+	case pv > 0:
+		return pv
+	}
+	return 1
+}
+
+// Rounded returns a copy rounded for output, see numfmt.
+func (a Accuracy) Rounded() Accuracy {
+	out := a
+	out.Levels = make([]AccuracyLevel, len(a.Levels))
+	for i, l := range a.Levels {
+		out.Levels[i] = AccuracyLevel{Dt: numfmt.Round(l.Dt), N: l.N, Difference: numfmt.Round(l.Difference), Order: numfmt.Round(l.Order)}
+	}
+	out.Order = numfmt.Round(a.Order)
+	out.Error = numfmt.Round(a.Error)
+	out.Tolerance = numfmt.Round(a.Tolerance)
+	out.RecommendedDt = numfmt.Round(a.RecommendedDt)
+	return out
+}
@@ -0,0 +1,83 @@
+package simulation
+
+import "math"
+
+// DefaultForgetting is the forgetting factor of the adaptive controller
+// when the scenario does not set one.
+const DefaultForgetting = 0.98
+
+// rlsCovarianceMax bounds the estimator covariance, which otherwise blows
+// up with the forgetting factor while the output is zero and nothing new is
+// learnt.
+const rlsCovarianceMax = 1e4
+
+// Adaptation is the trace of the adaptive controller: per sample, the
+// plant gain it estimated and the proportional gain it applied.
+type Adaptation struct {
+	K []float64 `json:"K"`
+	P []float64 `json:"P"`
+}
+
+// adaptive is a self-tuning regulator. With the time constant known, the
+// plant y[k+1] = y[k] + dt/Tau·(K·u[k] - y[k]) is linear in its gain K,
+// which a recursive least squares estimator tracks from the measurements.
+// The PID gains are scaled so that the loop gain stays the one tuned for
+// the nominal plant. The gain remains observable at steady state as long as
+// the output is not zero.
+type adaptive struct {
+	lambda  float64
+	r       float64 // dt/Tau
+	gain    float64 // estimated plant gain
+	cov     float64 // covariance of the estimate
+	nominal float64 // plant gain the gains were tuned for
+
+	y, u  float64 // previous measurement and output
+	ready bool
+}
+
+func newAdaptive(sc Scenario) *adaptive {
+	lambda := sc.Forgetting
+	if lambda == 0 {
+		lambda = DefaultForgetting
+	}
+	return &adaptive{
+		lambda:  lambda,
+		r:       sc.Dt / sc.Tau,
+		gain:    sc.K,
+		cov:     100,
+		nominal: sc.K,
+	}
+}
+
+// observe feeds the measurement reached after the previous output and
+// returns the current plant gain estimate.
+func (a *adaptive) observe(y float64) float64 {
+
+	if !a.ready {
+		return a.gain
+	}
+
+	phi := float64(a.r * a.u)
+	target := y - a.y + float64(a.r*a.y)
+	k := float64(a.cov*phi) / (a.lambda + float64(float64(phi*a.cov)*phi))
+	gain := a.gain + float64(k*(target-float64(phi*a.gain)))
+	a.cov = math.Min((a.cov-float64(float64(k*phi)*a.cov))/a.lambda, rlsCovarianceMax)
+
+	// A gain of the opposite sign would turn the controller into a
+	// positive feedback: keep the last estimate instead.
+	if gain*a.nominal > 0 {
+		a.gain = gain
+	}
+	return a.gain
+}
+
+// applied records the output applied from measurement y.
+func (a *adaptive) applied(y, u float64) {
+	a.y, a.u, a.ready = y, u, true
+}
+
+// scale returns the factor applied to the nominal gains, bounded to one
+// decade either way.
+func (a *adaptive) scale() float64 {
+	return math.Max(0.1, math.Min(10, a.nominal/a.gain))
+}
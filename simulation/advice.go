@@ -0,0 +1,131 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Kinds of Suggestion.
+const (
+	AdviceOscillating   = "oscillating"
+	AdviceSluggish      = "sluggish"
+	AdviceModelMismatch = "model-mismatch"
+)
+
+// Suggestion is a tuning change proposed from the recent behavior of a
+// loop. Changes holds the new values of the scenario parameters.
+type Suggestion struct {
+	ID      string             `json:"id"`
+	Kind    string             `json:"kind"`
+	Message string             `json:"message"`
+	Changes map[string]float64 `json:"changes"`
+}
+
+// adviceMinSamples is the shortest window Advise looks at.
+const adviceMinSamples = 20
+
+// Advise diagnoses the window T, SP, PV, U of a loop running sc and
+// returns at most one suggestion per kind, without IDs. The diagnostics
+// are, in order: sustained oscillation of the error, an error that decays
+// too slowly, and a plant whose identified gain or time constant differs
+// from the scenario's by more than 25 %.
+func Advise(sc Scenario, T, SP, PV, U []float64) []Suggestion {
+
+	n := len(T)
+	if n < adviceMinSamples || len(SP) != n || len(PV) != n || len(U) != n {
+		return nil
+	}
+
+	scale := 0.0
+	for _, sp := range SP {
+		scale = math.Max(scale, math.Abs(sp))
+	}
+	if scale == 0 {
+		scale = 1
+	}
+	band := SettlingBand * scale
+
+	var out []Suggestion
+
+	// Oscillation: the error crosses zero repeatedly, leaving the band in
+	// between.
+	crossings, sign := 0, 0.0
+	for i := range T {
+		e := SP[i] - PV[i]
+		if math.Abs(e) <= band {
+			continue
+		}
+		if s := math.Copysign(1, e); s != sign {
+			if sign != 0 {
+				crossings++
+			}
+			sign = s
+		}
+	}
+	if crossings >= 4 {
+		out = append(out, scaled(sc, AdviceOscillating, "la boucle oscille", 0.7, 0.5))
+	}
+
+	// Sluggishness: over at least a plant time constant the error keeps
+	// its sign and stays out of the band, with the output unsaturated.
+	if crossings == 0 && T[n-1]-T[0] >= sc.TimeConstant(T[n-1]) {
+		slow := true
+		for i := n / 2; i < n; i++ {
+			e := SP[i] - PV[i]
+			if math.Abs(e) <= band || math.Copysign(1, e) != sign || sc.atLimit(U[i]) {
+				slow = false
+				break
+			}
+		}
+		if slow {
+			out = append(out, scaled(sc, AdviceSluggish, "la boucle paraît lente", 1.4, 1.4))
+		}
+	}
+
+	// Model mismatch: retune for the identified plant, keeping the ratio
+	// of the gains to the nominal plant.
+	t := T[n-1]
+	K, Tau := sc.Gain(t), sc.TimeConstant(t)
+	// U[i] is applied from PV[i-1] to PV[i], so it is shifted by a sample.
+	if m, err := IdentifyFirstOrder(T[:n-1], U[1:], PV[:n-1]); err == nil && m.R2 >= 0.9 && m.K != 0 && K != 0 {
+		if math.Abs(m.K/K-1) > 0.25 || math.Abs(m.Tau/Tau-1) > 0.25 {
+			P := sc.P * K / m.K * m.Tau / Tau
+			Ki := sc.Ki * K / m.K
+			out = append(out, Suggestion{
+				Kind: AdviceModelMismatch,
+				Message: fmt.Sprintf("le procédé identifié (K = %g, Tau = %g) diffère du modèle (K = %g, Tau = %g) ; essayer Kp = %g et Ki = %g",
+					short(m.K), short(m.Tau), short(K), short(Tau), short(P), short(Ki)),
+				Changes: map[string]float64{"P": short(P), "Ki": short(Ki)},
+			})
+		}
+	}
+	return out
+}
+
+// scaled suggests multiplying P and Ki by the given factors.
+func scaled(sc Scenario, kind, diagnosis string, pFactor, iFactor float64) Suggestion {
+	msg := fmt.Sprintf("%s ; essayer Kp×%g", diagnosis, short(pFactor))
+	if iFactor != pFactor {
+		msg += fmt.Sprintf(" et Ki×%g", short(iFactor))
+	} else {
+		msg += " et Ki dans la même proportion"
+	}
+	return Suggestion{
+		Kind:    kind,
+		Message: msg,
+		Changes: map[string]float64{"P": short(sc.P * pFactor), "Ki": short(sc.Ki * iFactor)},
+	}
+}
+
+// atLimit reports whether u sits on one of the output limits.
+func (sc Scenario) atLimit(u float64) bool {
+	return (sc.UMin != nil && u <= *sc.UMin) || (sc.UMax != nil && u >= *sc.UMax)
+}
+
+// short rounds x to three significant digits, for proposed values that
+// are read by people.
+func short(x float64) float64 {
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(x, 'g', 3, 64), 64)
+	return v
+}
@@ -0,0 +1,134 @@
+package simulation
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	imagedraw "image/draw"
+	"image/gif"
+	"io"
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+)
+
+// MaxFrames bounds the number of frames of an animation.
+const MaxFrames = 120
+
+// Frame timing of the animations, in hundredths of a second: the last
+// frame is held so that the final response can be read before the loop.
+const (
+	frameDelay     = 10
+	lastFrameDelay = 200
+)
+
+// AnimateTrace writes to w an animated GIF drawing the response of r over
+// time in at most frames frames, for teaching material.
+func (r Result) AnimateTrace(w io.Writer, frames int, opts PlotOptions) error {
+
+	loc, theme, err := opts.style()
+	if err != nil {
+		return err
+	}
+	if len(r.Time) < 2 {
+		return fmt.Errorf("la réponse doit compter au moins deux échantillons")
+	}
+	frames = max(1, min(frames, MaxFrames, len(r.Time)-1))
+
+	var figures []chart.Figure
+	for f := 1; f <= frames; f++ {
+		k := 1 + f*(len(r.Time)-1)/frames
+		part := Result{Time: r.Time[:k], SP: r.SP[:k], PV: r.PV[:k]}
+		fig, err := part.Figure(opts)
+		if err != nil {
+			return err
+		}
+		fig.Title += fmt.Sprintf(" (t = %s s)", loc.number(r.Time[k-1]))
+		figures = append(figures, fig)
+	}
+	return writeGIF(w, figures, []Result{r}, theme, opts.Backend)
+}
+
+// AnimateSweep writes to w an animated GIF of the responses runs obtained
+// for the values of the scenario parameter param, one per frame, showing
+// how the response morphs as the parameter is swept.
+func AnimateSweep(w io.Writer, runs []Result, param string, values []float64, opts PlotOptions) error {
+
+	loc, theme, err := opts.style()
+	if err != nil {
+		return err
+	}
+	if len(runs) != len(values) || len(runs) == 0 || len(runs) > MaxFrames {
+		return fmt.Errorf("entre 1 et %d réponses attendues, une par valeur de %s", MaxFrames, param)
+	}
+
+	var figures []chart.Figure
+	for i, r := range runs {
+		fig, err := r.Figure(opts)
+		if err != nil {
+			return err
+		}
+		fig.Title += fmt.Sprintf(" (%s = %s)", param, loc.number(values[i]))
+		figures = append(figures, fig)
+	}
+	return writeGIF(w, figures, runs, theme, opts.Backend)
+}
+
+// writeGIF renders the figures with the backend as the frames of a
+// looping GIF, on the same axes, fitted to the series of runs.
+func writeGIF(w io.Writer, figures []chart.Figure, runs []Result, theme Theme, backend string) error {
+
+	xMax, yMin, yMax := 0.0, math.Inf(1), math.Inf(-1)
+	for _, r := range runs {
+		for _, t := range r.Time {
+			xMax = math.Max(xMax, t)
+		}
+		for _, ys := range [][]float64{r.SP, r.PV} {
+			for _, y := range ys {
+				if !math.IsNaN(y) && !math.IsInf(y, 0) {
+					yMin, yMax = math.Min(yMin, y), math.Max(yMax, y)
+				}
+			}
+		}
+	}
+	if yMin > yMax {
+		yMin, yMax = -1, 1
+	}
+	margin := math.Max(0.05*(yMax-yMin), 1e-9)
+
+	pal := theme.palette()
+	anim := &gif.GIF{}
+	for _, f := range figures {
+		f.XRange = &chart.Range{Min: 0, Max: xMax}
+		f.YRange = &chart.Range{Min: yMin - margin, Max: yMax + margin}
+
+		img, err := chart.Raster(f, backend, plotWidth, plotHeight, 96)
+		if err != nil {
+			return err
+		}
+		frame := image.NewPaletted(img.Bounds(), pal)
+		imagedraw.Draw(frame, frame.Rect, img, img.Bounds().Min, imagedraw.Src)
+
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, frameDelay)
+	}
+	anim.Delay[len(anim.Delay)-1] = lastFrameDelay
+	return gif.EncodeAll(w, anim)
+}
+
+// palette returns the colors of the frames: the background, and the
+// shades from the background to each color of the theme, so that the
+// antialiased lines and texts keep their look.
+func (t Theme) palette() color.Palette {
+	const shades = 16
+	bg := t.color(t.Background)
+	pal := color.Palette{bg}
+	for _, c := range []string{t.Foreground, t.Setpoint, t.Measurement} {
+		fg := t.color(c)
+		for i := 1; i <= shades; i++ {
+			blend := func(a, b uint8) uint8 { return uint8((int(a)*(shades-i) + int(b)*i) / shades) }
+			pal = append(pal, color.RGBA{R: blend(bg.R, fg.R), G: blend(bg.G, fg.G), B: blend(bg.B, fg.B), A: 0xff})
+		}
+	}
+	return pal
+}
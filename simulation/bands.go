@@ -0,0 +1,127 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// DefaultBandColor fills the bands with no color of their own.
+const DefaultBandColor = "#2ca02c"
+
+// Band is a region of the measurement plots shade for the operator: a
+// target band around the setpoint, ±Relative of it or ±Absolute in units
+// of the measurement, or the fixed region from Low to High.
+type Band struct {
+	Name     string   `json:"name"`
+	Relative float64  `json:"relative,omitempty"`
+	Absolute float64  `json:"absolute,omitempty"`
+	Low      *float64 `json:"low,omitempty"`
+	High     *float64 `json:"high,omitempty"`
+	// Color is written "#rrggbb" or "#rgb", DefaultBandColor when empty.
+	Color string `json:"color,omitempty"`
+}
+
+// BandsSchema describes the optional "bands" member of a scenario.
+var BandsSchema = schema.Array(schema.Object(map[string]*schema.Schema{
+	"name":     schema.String("Nom de la bande, affiché en légende"),
+	"relative": schema.Number("Demi-largeur autour de la consigne, en fraction de la consigne (0.01 pour ±1 %)").Above(0),
+	"absolute": schema.Number("Demi-largeur autour de la consigne, en unités de la mesure").Above(0),
+	"low":      schema.Number("Bord bas d'une région fixe"),
+	"high":     schema.Number("Bord haut d'une région fixe"),
+	"color":    schema.String("Couleur de remplissage, #rrggbb ou #rgb"),
+}, "name"))
+
+// BandEdges are the edges of a band at one sample.
+type BandEdges struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// BandTrace is a band along a run, its edges at every sample.
+type BandTrace struct {
+	Name  string    `json:"name"`
+	Color string    `json:"color"`
+	Low   []float64 `json:"low"`
+	High  []float64 `json:"high"`
+}
+
+// check reports a band that is not exactly one of the three forms.
+func (b Band) check(path string) []schema.Error {
+	var errs []schema.Error
+	forms := 0
+	for _, set := range []bool{b.Relative != 0, b.Absolute != 0, b.Low != nil || b.High != nil} {
+		if set {
+			forms++
+		}
+	}
+	switch {
+	case forms != 1:
+		errs = append(errs, schema.Error{Path: path, Message: "attendu exactement une forme : relative, absolute, ou low et high"})
+	case (b.Low == nil) != (b.High == nil):
+		errs = append(errs, schema.Error{Path: path, Message: "une région fixe demande low et high"})
+	case b.Low != nil && *b.Low >= *b.High:
+		errs = append(errs, schema.Error{Path: path + "/high", Message: fmt.Sprintf("doit être supérieur à low (%g)", *b.Low)})
+	}
+	if b.Color != "" {
+		if _, err := chart.ParseColor(b.Color); err != nil {
+			errs = append(errs, schema.Error{Path: path + "/color", Message: err.Error()})
+		}
+	}
+	return errs
+}
+
+// Edges returns the edges of the band for the setpoint sp.
+func (b Band) Edges(sp float64) BandEdges {
+	switch {
+	case b.Low != nil:
+		return BandEdges{Low: *b.Low, High: *b.High}
+	case b.Relative != 0:
+		half := float64(math.Abs(sp) * b.Relative)
+		return BandEdges{Low: sp - half, High: sp + half}
+	}
+	return BandEdges{Low: sp - b.Absolute, High: sp + b.Absolute}
+}
+
+// EdgesAt returns the edges of every band for the setpoint sp, rounded
+// for output, see numfmt; nil when there is none.
+func EdgesAt(bands []Band, sp float64) []BandEdges {
+	if len(bands) == 0 {
+		return nil
+	}
+	edges := make([]BandEdges, len(bands))
+	for i, b := range bands {
+		e := b.Edges(sp)
+		edges[i] = BandEdges{Low: numfmt.Round(e.Low), High: numfmt.Round(e.High)}
+	}
+	return edges
+}
+
+// BandTraces returns the bands along the setpoint series SP.
+func BandTraces(bands []Band, SP []float64) []BandTrace {
+	var traces []BandTrace
+	for _, b := range bands {
+		t := BandTrace{Name: b.Name, Color: b.Color, Low: make([]float64, len(SP)), High: make([]float64, len(SP))}
+		if t.Color == "" {
+			t.Color = DefaultBandColor
+		}
+		for k, sp := range SP {
+			e := b.Edges(sp)
+			t.Low[k], t.High[k] = e.Low, e.High
+		}
+		traces = append(traces, t)
+	}
+	return traces
+}
+
+// bandSeries returns the series shading the bands over the times T.
+func bandSeries(traces []BandTrace, T []float64) []chart.Series {
+	var series []chart.Series
+	for _, t := range traces {
+		series = append(series, chart.Series{Kind: chart.KindBand, Label: t.Name, X: T, Y: numfmt.Series(t.Low), Upper: numfmt.Series(t.High), Fill: t.Color})
+	}
+	return series
+}
@@ -0,0 +1,83 @@
+package simulation
+
+// Bounds is the range a parameter is usually set in, for the sliders of a
+// user interface: values outside stay accepted when the schema allows them.
+// Log asks for a logarithmic slider, the range spanning several decades.
+type Bounds struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Step float64 `json:"step"`
+	Log  bool    `json:"log,omitempty"`
+}
+
+// solverBounds are shared by every plant.
+var solverBounds = map[string]Bounds{
+	"dt":     {Min: 1e-4, Max: 1, Step: 1e-4, Log: true},
+	"N":      {Min: 10, Max: 100_000, Step: 10, Log: true},
+	"spRamp": {Min: 0.01, Max: 100, Step: 0.01, Log: true},
+	"theta":  {Min: 0, Max: 10, Step: 0.001},
+}
+
+// ParameterBounds are the bounds of the numeric parameters of a scenario,
+// by plant: the gains suited to a pH loop are orders of magnitude away
+// from those of a level loop.
+var ParameterBounds = map[string]map[string]Bounds{
+	PlantFirstOrder: {
+		"Sp":     {Min: 0, Max: 100, Step: 0.1},
+		"Tau":    {Min: 0.01, Max: 100, Step: 0.01, Log: true},
+		"K":      {Min: -10, Max: 10, Step: 0.01},
+		"kDrift": {Min: -0.1, Max: 0.1, Step: 0.001},
+		"P":      {Min: 0, Max: 50, Step: 0.01},
+		"Ki":     {Min: 0, Max: 100, Step: 0.01},
+		"Kd":     {Min: 0, Max: 10, Step: 0.01},
+	},
+	PlantStateSpace: {
+		"Sp": {Min: 0, Max: 10, Step: 0.01},
+		"P":  {Min: 0, Max: 20, Step: 0.01},
+		"Ki": {Min: 0, Max: 5, Step: 0.001},
+		"Kd": {Min: 0, Max: 10, Step: 0.01},
+	},
+	PlantHeatCool: {
+		"Sp":  {Min: -50, Max: 150, Step: 0.1},
+		"Tau": {Min: 0.01, Max: 100, Step: 0.01, Log: true},
+		"K":   {Min: 0, Max: 10, Step: 0.01},
+		"P":   {Min: 0, Max: 50, Step: 0.01},
+		"Ki":  {Min: 0, Max: 100, Step: 0.01},
+		"Kd":  {Min: 0, Max: 10, Step: 0.01},
+	},
+	PlantPH: {
+		"Sp":  {Min: 0, Max: 14, Step: 0.1},
+		"Tau": {Min: 1, Max: 100, Step: 0.1},
+		"K":   {Min: 1e-6, Max: 1e-3, Step: 1e-6, Log: true},
+		"P":   {Min: 1e-3, Max: 100, Step: 1e-3, Log: true},
+		"Ki":  {Min: 1e-4, Max: 10, Step: 1e-4, Log: true},
+		"Kd":  {Min: 0, Max: 10, Step: 0.01},
+	},
+	// The level controller is direct acting, its gains negative.
+	PlantLevel: {
+		"Sp":  {Min: 0, Max: 100, Step: 1},
+		"Tau": {Min: 10, Max: 1000, Step: 1},
+		"K":   {Min: 0.01, Max: 1, Step: 0.01},
+		"P":   {Min: -500, Max: 0, Step: 1},
+		"Ki":  {Min: -20, Max: 0, Step: 0.001},
+		"Kd":  {Min: -10, Max: 0, Step: 0.01},
+	},
+	// The reactive power plant works in var, the gains being ratios of
+	// inverter to POC reactive power.
+	PlantReactive: {
+		"Sp":  {Min: -2e6, Max: 2e6, Step: 1e3},
+		"Tau": {Min: 0.01, Max: 10, Step: 0.01, Log: true},
+		"K":   {Min: 0.5, Max: 1.5, Step: 0.01},
+		"P":   {Min: 0, Max: 5, Step: 0.01},
+		"Ki":  {Min: 0, Max: 50, Step: 0.01},
+		"Kd":  {Min: 0, Max: 1, Step: 0.001},
+	},
+}
+
+func init() {
+	for _, bounds := range ParameterBounds {
+		for name, b := range solverBounds {
+			bounds[name] = b
+		}
+	}
+}
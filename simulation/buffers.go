@@ -0,0 +1,70 @@
+package simulation
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// trajectory holds the series of a run. Evaluate, used by the sweep and
+// tuning engines, only keeps the metrics of a run, so its series are
+// recycled through trajectoryPool instead of being left to the collector.
+type trajectory struct {
+	time, sp, pv, u, p, i, d []float64
+}
+
+var trajectoryPool sync.Pool
+
+var bufferGets, bufferAllocs atomic.Int64
+
+// BufferStats returns the number of trajectory buffers taken from the pool
+// and, among them, the number that had to be allocated or grown.
+func BufferStats() (gets, allocs int64) {
+	return bufferGets.Load(), bufferAllocs.Load()
+}
+
+// getTrajectory returns empty series with room for n samples.
+func getTrajectory(n int) *trajectory {
+
+	bufferGets.Add(1)
+	tr, _ := trajectoryPool.Get().(*trajectory)
+	if tr == nil || cap(tr.time) < n {
+		bufferAllocs.Add(1)
+		return newTrajectory(n)
+	}
+	for _, s := range tr.series() {
+		*s = (*s)[:0]
+	}
+	return tr
+}
+
+func newTrajectory(n int) *trajectory {
+	tr := &trajectory{}
+	for _, s := range tr.series() {
+		*s = make([]float64, 0, n)
+	}
+	return tr
+}
+
+func (tr *trajectory) series() []*[]float64 {
+	return []*[]float64{&tr.time, &tr.sp, &tr.pv, &tr.u, &tr.p, &tr.i, &tr.d}
+}
+
+// result returns a Result whose series are those of tr.
+func (tr *trajectory) result() Result {
+	return Result{
+		Time:       tr.time,
+		SP:         tr.sp,
+		PV:         tr.pv,
+		U:          tr.u,
+		Components: Components{P: tr.p, I: tr.i, D: tr.d},
+	}
+}
+
+// recycle returns the series of res, which must not be used afterwards, to
+// the pool.
+func recycle(res Result) {
+	trajectoryPool.Put(&trajectory{
+		time: res.Time, sp: res.SP, pv: res.PV, u: res.U,
+		p: res.Components.P, i: res.Components.I, d: res.Components.D,
+	})
+}
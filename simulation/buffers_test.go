@@ -0,0 +1,32 @@
+package simulation
+
+import "testing"
+
+var benchScenario = Scenario{Sp: 10, Tau: 1, K: 1, P: 5, Ki: 10, Dt: 0.001, N: 10000}
+
+// BenchmarkEvaluate runs a scenario for its metrics only, its series
+// recycled through trajectoryPool; compare with BenchmarkSimulation.
+func BenchmarkEvaluate(b *testing.B) {
+	b.ReportAllocs()
+	gets, allocs := BufferStats()
+	for range b.N {
+		benchScenario.Evaluate()
+	}
+	reportBuffers(b, gets, allocs)
+}
+
+// BenchmarkSimulation runs the same scenario keeping its series.
+func BenchmarkSimulation(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		Simulation(benchScenario)
+	}
+}
+
+// reportBuffers reports the trajectory buffers taken from the pool and
+// allocated per operation since the given BufferStats.
+func reportBuffers(b *testing.B, gets0, allocs0 int64) {
+	gets, allocs := BufferStats()
+	b.ReportMetric(float64(gets-gets0)/float64(b.N), "buffers/op")
+	b.ReportMetric(float64(allocs-allocs0)/float64(b.N), "buffer-allocs/op")
+}
@@ -0,0 +1,107 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/units"
+)
+
+// Capability is the P–Q capability curve of the inverters, bounding the
+// reactive power they can produce or absorb for their active power and
+// their terminal voltage:
+//
+//	|Q| ≤ √((SMax·U)² - P²)
+//
+// U being the inverter terminal voltage per unit, since the inverter
+// current is what is limited. Curve, when set, further bounds |Q| by a
+// piecewise linear function of P, as the triangle or the D-shape of grid
+// codes, held beyond its first and last points.
+type Capability struct {
+	SMax  float64           `json:"Smax"`
+	Curve []CapabilityPoint `json:"curve,omitempty"`
+}
+
+// CapabilityPoint is a point of Capability.Curve: the largest |Q| at the
+// active power P.
+type CapabilityPoint struct {
+	P    float64 `json:"P"`
+	QMax float64 `json:"Qmax"`
+}
+
+// CapabilitySchema describes the "capability" member of the electrical
+// system.
+var CapabilitySchema = schema.Object(map[string]*schema.Schema{
+	"Smax": schema.Number("Puissance apparente assignée des onduleurs à la tension nominale (VA)").In(units.VoltAmpere).Above(0),
+	"curve": schema.Array(schema.Object(map[string]*schema.Schema{
+		"P":    schema.Number("Puissance active (W)").In(units.Watt),
+		"Qmax": schema.Number("Puissance réactive maximale en valeur absolue (var)").In(units.Var).Min(0),
+	}, "P", "Qmax")).AtLeast(1),
+}, "Smax")
+
+func (c *Capability) check() []schema.Error {
+	var errs []schema.Error
+	for i := 1; i < len(c.Curve); i++ {
+		if c.Curve[i].P <= c.Curve[i-1].P {
+			errs = append(errs, schema.Error{Path: fmt.Sprintf("/capability/curve/%d/P", i), Message: "les points doivent être triés par P strictement croissant"})
+		}
+	}
+	return errs
+}
+
+// QMax returns the largest |Q| of the inverters at the active power P and
+// the terminal voltage U per unit, zero beyond their rating.
+func (c *Capability) QMax(P, U float64) float64 {
+
+	S := float64(c.SMax * U)
+	q := math.Sqrt(math.Max(0, float64(S*S)-float64(P*P)))
+	if len(c.Curve) == 0 {
+		return q
+	}
+	curve := c.Curve
+	var limit float64
+	switch {
+	case P <= curve[0].P:
+		limit = curve[0].QMax
+	case P >= curve[len(curve)-1].P:
+		limit = curve[len(curve)-1].QMax
+	default:
+		for i := 1; i < len(curve); i++ {
+			if P <= curve[i].P {
+				a, b := curve[i-1], curve[i]
+				limit = a.QMax + float64((b.QMax-a.QMax)*(P-a.P))/(b.P-a.P)
+				break
+			}
+		}
+	}
+	return math.Min(q, limit)
+}
+
+// capabilityRun follows whether the capability limits the commands of a
+// run.
+type capabilityRun struct {
+	c       *Capability
+	limited bool
+}
+
+// limit returns the command Q bounded by the capability at time t, the
+// active power P and the terminal voltage U per unit, and the event of
+// the limitation starting or ending, if it does.
+func (r *capabilityRun) limit(t, Q, P, U float64) (float64, *Event) {
+
+	qmax := r.c.QMax(P, U)
+	limited := math.Abs(Q) > qmax
+	if limited {
+		Q = math.Copysign(qmax, Q)
+	}
+	if limited == r.limited {
+		return Q, nil
+	}
+	r.limited = limited
+	if limited {
+		return Q, &Event{T: t, Type: EventCapability,
+			Message: fmt.Sprintf("Puissance réactive limitée par le diagramme P–Q à %.0f var (P = %.0f W, U = %.3f pu)", Q, P, U)}
+	}
+	return Q, &Event{T: t, Type: EventCapability, Message: "Puissance réactive dans le diagramme P–Q"}
+}
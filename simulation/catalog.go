@@ -0,0 +1,226 @@
+package simulation
+
+import (
+	"slices"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Option describes a selectable building block of a simulation and the
+// parameters it reads from the request.
+type Option struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  *schema.Schema `json:"parameters"`
+}
+
+// Setpoint describes the setpoint shared by every scenario.
+var Setpoint = schema.Object(map[string]*schema.Schema{
+	"Sp":     schema.Number("Setpoint").WithDefault(10.0),
+	"spRamp": schema.Number("Vitesse maximale de la consigne de travail, en unités par seconde (absente : échelon)").Above(0),
+}, "Sp")
+
+// Controller names accepted in Scenario.Controller.
+const (
+	ControllerPID           = "pid"
+	ControllerExternalReset = "pid-external-reset"
+	ControllerAdaptive      = "pid-adaptive"
+)
+
+// Controllers lists the available controller types. They share their
+// parameters.
+var Controllers = []Option{
+	{
+		Name:        ControllerPID,
+		Description: "Régulateur PID parallèle (P + Ki∫e + Kd de/dt)",
+		Parameters: schema.Object(map[string]*schema.Schema{
+			"P":  schema.Number("Coefficient proportionnel").WithDefault(5.0),
+			"Ki": schema.Number("Coefficient intégral").WithDefault(10.0),
+			"Kd": schema.Number("Coefficient dérivé").WithDefault(0.0),
+
+			"uMin":                   schema.Number("Saturation basse de la sortie (absente : pas de limite)"),
+			"uMax":                   schema.Number("Saturation haute de la sortie (absente : pas de limite)"),
+			"iMax":                   schema.Number("Limite de |Ki·∫e dt|, l'intégrale est bornée en conséquence").Min(0),
+			"dMax":                   schema.Number("Limite de |Kd·de/dt|").Min(0),
+			"conditionalIntegration": schema.Boolean("Geler l'intégration quand la sortie est saturée dans le sens de l'erreur").WithDefault(false),
+			"backCalculation":        schema.Number("Constante de temps de poursuite Tt (s) de l'anti-emballement par recalcul : quand la sortie est saturée, l'écart entre sortie saturée et calculée est réinjecté dans l'intégrale au rythme 1/Tt (absente : pas de recalcul)").Above(0),
+		}, "P", "Ki", "Kd"),
+	},
+	{
+		Name:        ControllerExternalReset,
+		Description: "PID à reset externe : l'action intégrale est un retard du premier ordre (Ti = P / Ki) de la sortie appliquée, réinjecté positivement, ce qui évite l'emballement de l'intégrale",
+	},
+}
+
+func init() {
+	Controllers[1].Parameters = Controllers[0].Parameters
+
+	adaptive := schema.Object(map[string]*schema.Schema{
+		"forgetting": schema.Number("Facteur d'oubli de l'estimateur (1 : pas d'oubli)").Above(0).Max(1).WithDefault(DefaultForgetting),
+	}, Controllers[0].Parameters.Required...)
+	for name, prop := range Controllers[0].Parameters.Properties {
+		adaptive.Properties[name] = prop
+	}
+	Controllers = append(Controllers, Option{
+		Name:        ControllerAdaptive,
+		Description: "PID adaptatif : le gain du procédé est estimé en ligne par moindres carrés récursifs et les gains sont corrigés pour garder le gain de boucle du réglage nominal",
+		Parameters:  adaptive,
+	})
+}
+
+// Plant names accepted in Scenario.Plant.
+const (
+	PlantFirstOrder = "first-order"
+	PlantStateSpace = "state-space"
+	PlantHeatCool   = "heat-cool"
+	PlantPH         = "ph"
+	PlantLevel      = "level"
+	PlantReactive   = "reactive"
+)
+
+// Plants lists the available process models.
+var Plants = []Option{
+	{
+		Name:        PlantFirstOrder,
+		Description: "Système du premier ordre K / (1 + Tau s)",
+		Parameters: schema.Object(map[string]*schema.Schema{
+			"Tau": schema.Number("Constante de temps Tau").Above(0).WithDefault(1.0),
+			"K":   schema.Number("Gain K").WithDefault(1.0),
+
+			"kDrift": schema.Number("Dérive du gain K par seconde").WithDefault(0.0),
+		}, "Tau", "K"),
+	},
+	{
+		Name:        PlantStateSpace,
+		Description: "Système linéaire d'état dx/dt = A x + B u, y = C x + D u ; la commande est la première entrée et la mesure la première sortie. K et Tau sont ignorés",
+		Parameters: schema.Object(map[string]*schema.Schema{
+			"stateSpace": StateSpaceSchema,
+		}, "stateSpace"),
+	},
+	{
+		Name:        PlantHeatCool,
+		Description: "Procédé de chauffage/refroidissement à deux actionneurs : une commande positive chauffe selon K et Tau, une commande négative refroidit selon cooling.K et cooling.Tau",
+		Parameters: schema.Object(map[string]*schema.Schema{
+			"Tau":     schema.Number("Constante de temps Tau en chauffe").Above(0).WithDefault(1.0),
+			"K":       schema.Number("Gain K en chauffe").WithDefault(1.0),
+			"cooling": CoolingSchema,
+		}, "Tau", "K", "cooling"),
+	},
+	{
+		Name:        PlantPH,
+		Description: "Neutralisation d'un acide fort par une base forte : la mesure est le pH, lu sur la courbe de titrage d'un premier ordre (K, Tau) du dosage, très non linéaire autour de la neutralité",
+		Parameters: schema.Object(map[string]*schema.Schema{
+			"Tau": schema.Number("Constante de temps du mélange").Above(0).WithDefault(10.0),
+			"K":   schema.Number("Concentration de réactif apportée par unité de commande (mol/L)").WithDefault(2e-5),
+			"ph":  PHSchema,
+		}, "Tau", "K", "ph"),
+	},
+	{
+		Name:        PlantLevel,
+		Description: "Niveau d'un bac, intégrateur : le débit entrant, nominal, programmé ou aléatoire, est la perturbation et la commande ouvre la vanne de sortie, le régulateur est donc à action directe (gains négatifs)",
+		Parameters: schema.Object(map[string]*schema.Schema{
+			"Tau":   schema.Number("Section du bac : volume par unité de niveau").Above(0).WithDefault(100.0),
+			"K":     schema.Number("Débit sortant par unité de commande").Above(0).WithDefault(0.1),
+			"level": LevelSchema,
+		}, "Tau", "K", "level"),
+	},
+	{
+		Name:        PlantReactive,
+		Description: "Régulation de la puissance réactive au point de connexion (POC) : la commande est la consigne de puissance réactive des onduleurs, qui la suivent selon K et Tau, et la mesure la puissance réactive au POC, diminuée de celle absorbée par le système électrique au courant de la puissance active, la perturbation ; avec electrical.droop, la consigne suit la tension au POC",
+		Parameters: schema.Object(map[string]*schema.Schema{
+			"Tau":        schema.Number("Constante de temps de réponse des onduleurs").Above(0).WithDefault(0.2),
+			"K":          schema.Number("Gain des onduleurs").Above(0).WithDefault(1.0),
+			"electrical": ElectricalSchema,
+		}, "Tau", "K", "electrical"),
+	},
+}
+
+// Solvers lists the available time integration schemes.
+var Solvers = []Option{
+	{
+		Name:        "euler",
+		Description: "Intégration d'Euler explicite à pas fixe",
+		Parameters: schema.Object(map[string]*schema.Schema{
+			"dt": schema.Number("Pas de temps").Above(0).WithDefault(0.001),
+			"N":  schema.Integer("Nombre d'itérations").Min(1).WithDefault(1000),
+		}, "dt", "N"),
+	},
+}
+
+// TuningRules lists the available automatic tuning rules, applied by
+// ApplyRule to the model they read.
+var TuningRules = []Option{
+	{
+		Name:        RuleSIMC,
+		Description: "Règle SIMC de Skogestad, PI pour τc = θ : robuste, recommandée par défaut",
+		Parameters:  DelayedModelSchema,
+	},
+	{
+		Name:        RuleZieglerNicholsPI,
+		Description: "Ziegler-Nichols, courbe de réaction, PI : amortissement d'un quart",
+		Parameters:  DelayedModelSchema,
+	},
+	{
+		Name:        RuleZieglerNicholsPID,
+		Description: "Ziegler-Nichols, courbe de réaction, PID : amortissement d'un quart",
+		Parameters:  DelayedModelSchema,
+	},
+}
+
+// ScenarioSchema returns the schema of the scenario document posted to
+// /sendData: the controller type, the setpoint plus the parameters of the
+// controller, the first-order plant and the solver, the plant type with
+// the optional state-space model, and the optional stop conditions, plant
+// variation, recipe, cost rates, measurement noise and applied tuning rule.
+func ScenarioSchema() *schema.Schema {
+
+	parts := []*schema.Schema{Setpoint}
+	for _, c := range Controllers {
+		parts = append(parts, c.Parameters)
+	}
+	parts = append(parts, Plants[0].Parameters, Solvers[0].Parameters)
+
+	s := schema.Object(map[string]*schema.Schema{})
+	for _, part := range parts {
+		for name, prop := range part.Properties {
+			s.Properties[name] = prop
+		}
+		for _, name := range part.Required {
+			if !slices.Contains(s.Required, name) {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+
+	names := make([]any, len(Controllers))
+	for i, c := range Controllers {
+		names[i] = c.Name
+	}
+	s.Properties["controller"] = schema.String("Type de régulateur").OneOf(names...).WithDefault(ControllerPID)
+	plants := make([]any, len(Plants))
+	for i, p := range Plants {
+		plants[i] = p.Name
+	}
+	s.Properties["plant"] = schema.String("Type de procédé").OneOf(plants...).WithDefault(PlantFirstOrder)
+	s.Properties["stateSpace"] = StateSpaceSchema
+	s.Properties["cooling"] = CoolingSchema
+	s.Properties["ph"] = PHSchema
+	s.Properties["level"] = LevelSchema
+	s.Properties["electrical"] = ElectricalSchema
+	s.Properties["schedule"] = ScheduleSchema
+	s.Properties["stop"] = StopSchema
+	s.Properties["variation"] = VariationSchema
+	s.Properties["recipe"] = RecipeSchema
+	s.Properties["drive"] = DriveSchema
+	s.Properties["costs"] = CostSchema
+	s.Properties["manual"] = ManualSchema
+	s.Properties["noise"] = NoiseSchema
+	s.Properties["theta"] = schema.Number("Retard pur θ du procédé (s) : le procédé reçoit la commande u(t − θ), interpolée entre deux pas lorsque θ n'est pas un multiple de dt").Min(0).WithDefault(0.0)
+	s.Properties["tuning"] = TuningSchema
+	s.Properties["bands"] = BandsSchema
+
+	s.Schema = schema.Draft
+	s.ID = "/api/v1/schemas/scenario.json"
+	s.Title = "Scénario de simulation"
+	return s
+}
@@ -0,0 +1,48 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// ComparisonPalette colors the runs of a comparison in turn; it bounds the
+// number of runs compared at once.
+var ComparisonPalette = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+// ComparisonPlot overlays the responses of several runs, labelled in the
+// legend: the measurement of each run as a solid line, its setpoint dashed
+// in the same color.
+func ComparisonPlot(labels []string, results []Result, opts PlotOptions) (chart.Figure, error) {
+
+	if len(results) > len(ComparisonPalette) {
+		return chart.Figure{}, fmt.Errorf("au plus %d simulations peuvent être comparées", len(ComparisonPalette))
+	}
+	loc, theme, err := opts.style()
+	if err != nil {
+		return chart.Figure{}, err
+	}
+
+	f := chart.Figure{
+		Title:        loc.ComparisonTitle,
+		XLabel:       loc.Time,
+		YLabel:       loc.Value,
+		Style:        theme.style(),
+		DecimalComma: loc.DecimalComma,
+		Legend:       true,
+	}
+	for k, r := range results {
+		for _, Y := range [][]float64{r.SP, r.PV} {
+			if len(Y) != len(r.Time) {
+				return chart.Figure{}, fmt.Errorf("Erreur dans le tracé, X et Y ne sont pas de la même taille")
+			}
+		}
+		T, color := numfmt.Series(r.Time), ComparisonPalette[k]
+		f.Series = append(f.Series,
+			chart.Series{X: T, Y: numfmt.Series(r.SP), Color: color, Width: theme.LineWidth / 2, Dashes: []float64{4 * theme.LineWidth, 3 * theme.LineWidth}},
+			chart.Series{Label: labels[k], X: T, Y: numfmt.Series(r.PV), Color: color, Width: theme.LineWidth},
+		)
+	}
+	return f, nil
+}
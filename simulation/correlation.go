@@ -0,0 +1,122 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// Peak is the strongest correlation of a cross-correlation, by absolute
+// value, and its lag.
+type Peak struct {
+	Lag float64 `json:"lag"`
+	R   float64 `json:"r"`
+}
+
+// Correlation is the normalized cross-correlation between a disturbance and
+// the measurement PV and the controller output OP, for lags in seconds. At
+// a positive lag the loop follows the disturbance: a strong PV correlation
+// there shows a disturbance measured before it upsets the loop, which
+// feedforward could cancel, while the OP correlation shows how much of it
+// feedback already rejects.
+type Correlation struct {
+	Lags   []float64 `json:"lags"`
+	PV     []float64 `json:"pv"`
+	OP     []float64 `json:"op,omitempty"`
+	PeakPV Peak      `json:"peakPV"`
+	PeakOP *Peak     `json:"peakOP,omitempty"`
+}
+
+// CrossCorrelation correlates the disturbance d with pv and, when given,
+// op, all sampled uniformly at times T, for lags from -maxLag to maxLag
+// seconds.
+func CrossCorrelation(T, d, pv, op []float64, maxLag float64) (Correlation, error) {
+
+	n := len(T)
+	if n < 3 || len(d) != n || len(pv) != n || (op != nil && len(op) != n) {
+		return Correlation{}, fmt.Errorf("les séries doivent compter le même nombre d'échantillons, au moins 3")
+	}
+	dt := T[1] - T[0]
+	for k := 1; k < n; k++ {
+		if !(math.Abs(T[k]-T[k-1]-dt) <= 1e-3*dt) {
+			return Correlation{}, fmt.Errorf("les échantillons doivent être régulièrement espacés (t = %g s)", T[k])
+		}
+	}
+	lags := int(maxLag / dt)
+	if lags < 0 || lags >= n {
+		return Correlation{}, fmt.Errorf("le décalage maximal doit être compris entre 0 et %g s", float64(n-1)*dt)
+	}
+
+	c := Correlation{}
+	for k := -lags; k <= lags; k++ {
+		c.Lags = append(c.Lags, float64(k)*dt)
+	}
+	c.PV = correlate(d, pv, lags)
+	c.PeakPV = peak(c.Lags, c.PV)
+	if op != nil {
+		c.OP = correlate(d, op, lags)
+		p := peak(c.Lags, c.OP)
+		c.PeakOP = &p
+	}
+	return c, nil
+}
+
+// correlate returns the correlation coefficients of x[t] and y[t+k] for k
+// from -lags to lags, over the overlapping samples; they are zero when
+// either series is constant.
+func correlate(x, y []float64, lags int) []float64 {
+
+	mean := func(v []float64) float64 {
+		s := 0.0
+		for _, a := range v {
+			s += a
+		}
+		return s / float64(len(v))
+	}
+	mx, my := mean(x), mean(y)
+	var sx, sy float64
+	for i := range x {
+		sx += float64((x[i] - mx) * (x[i] - mx))
+		sy += float64((y[i] - my) * (y[i] - my))
+	}
+	norm := math.Sqrt(float64(sx * sy))
+
+	r := make([]float64, 0, 2*lags+1)
+	for k := -lags; k <= lags; k++ {
+		s := 0.0
+		for i := max(0, -k); i < len(x) && i+k < len(y); i++ {
+			s += float64((x[i] - mx) * (y[i+k] - my))
+		}
+		if norm == 0 {
+			r = append(r, 0)
+		} else {
+			r = append(r, s/norm)
+		}
+	}
+	return r
+}
+
+func peak(lags, r []float64) Peak {
+	var p Peak
+	for i, v := range r {
+		if math.Abs(v) > math.Abs(p.R) {
+			p = Peak{Lag: lags[i], R: v}
+		}
+	}
+	return p
+}
+
+// Rounded returns a copy rounded for output, see numfmt.
+func (c Correlation) Rounded() Correlation {
+	out := Correlation{
+		Lags:   numfmt.Series(c.Lags),
+		PV:     numfmt.Series(c.PV),
+		OP:     numfmt.Series(c.OP),
+		PeakPV: Peak{Lag: numfmt.Round(c.PeakPV.Lag), R: numfmt.Round(c.PeakPV.R)},
+	}
+	if c.PeakOP != nil {
+		out.PeakOP = &Peak{Lag: numfmt.Round(c.PeakOP.Lag), R: numfmt.Round(c.PeakOP.R)}
+	}
+	return out
+}
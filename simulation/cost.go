@@ -0,0 +1,85 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// CostRates are the prices used to account for a run in money rather than
+// in dynamic metrics. Every rate is optional.
+type CostRates struct {
+	Currency string `json:"currency,omitempty"`
+	// Energy is the price of one unit of controller output held for an
+	// hour, i.e. per unit of ∫|u| dt in hours.
+	Energy float64 `json:"energy,omitempty"`
+	// AboveSetpoint and BelowSetpoint are the prices of one unit of
+	// measurement above (below) the setpoint for an hour, e.g. € per
+	// degree-hour of overheating.
+	AboveSetpoint float64 `json:"aboveSetpoint,omitempty"`
+	BelowSetpoint float64 `json:"belowSetpoint,omitempty"`
+	// Moves is the price of one unit of output travel Σ|Δu|, for actuator
+	// wear.
+	Moves float64 `json:"moves,omitempty"`
+}
+
+// Cost is the accumulated cost of a run, per item and in total.
+type Cost struct {
+	Currency      string  `json:"currency"`
+	Energy        float64 `json:"energy"`
+	AboveSetpoint float64 `json:"aboveSetpoint"`
+	BelowSetpoint float64 `json:"belowSetpoint"`
+	Moves         float64 `json:"moves"`
+	Total         float64 `json:"total"`
+}
+
+// CostSchema describes the optional "costs" member of a scenario.
+var CostSchema = schema.Object(map[string]*schema.Schema{
+	"currency":      schema.String("Unité monétaire des coûts").WithDefault("€"),
+	"energy":        schema.Number("Prix d'une unité de sortie pendant une heure (∫|u| dt)").Min(0),
+	"aboveSetpoint": schema.Number("Prix d'une unité de mesure au-dessus de la consigne pendant une heure").Min(0),
+	"belowSetpoint": schema.Number("Prix d'une unité de mesure sous la consigne pendant une heure").Min(0),
+	"moves":         schema.Number("Prix d'une unité de déplacement de la sortie (Σ|Δu|)").Min(0),
+})
+
+// Account prices the series of a result, integrated with the same
+// rectangle rule as the metrics.
+func (rates CostRates) Account(r Result) Cost {
+
+	c := Cost{Currency: rates.Currency}
+	if c.Currency == "" {
+		c.Currency = "€"
+	}
+
+	var energy, above, below, moves kahanSum
+	for i := 1; i < len(r.Time); i++ {
+		hours := (r.Time[i] - r.Time[i-1]) / 3600
+		if i-1 < len(r.U) {
+			energy.add(float64(math.Abs(r.U[i-1]) * hours))
+		}
+		if i < len(r.U) {
+			moves.add(math.Abs(r.U[i] - r.U[i-1]))
+		}
+		e := r.PV[i] - r.SP[i]
+		above.add(float64(math.Max(e, 0) * hours))
+		below.add(float64(math.Max(-e, 0) * hours))
+	}
+
+	c.Energy = float64(rates.Energy * energy.value())
+	c.AboveSetpoint = float64(rates.AboveSetpoint * above.value())
+	c.BelowSetpoint = float64(rates.BelowSetpoint * below.value())
+	c.Moves = float64(rates.Moves * moves.value())
+	c.Total = c.Energy + c.AboveSetpoint + c.BelowSetpoint + c.Moves
+	return c
+}
+
+// Rounded returns the cost rounded for output, see numfmt.
+func (c Cost) Rounded() Cost {
+	c.Energy = numfmt.Round(c.Energy)
+	c.AboveSetpoint = numfmt.Round(c.AboveSetpoint)
+	c.BelowSetpoint = numfmt.Round(c.BelowSetpoint)
+	c.Moves = numfmt.Round(c.Moves)
+	c.Total = numfmt.Round(c.Total)
+	return c
+}
@@ -0,0 +1,208 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Decoupled is the decoupled control of the active and reactive powers of
+// an inverter: one PID sets the d-axis current, a proxy of P, the other
+// the q-axis current, a proxy of Q. The current loops of the inverter are
+// first-order lags of time constant Tau, which the voltage ωL·i across the
+// output inductance couples:
+//
+//	Tau·dP/dt = uP - P + Coupling·Q
+//	Tau·dQ/dt = uQ - Q - Coupling·P
+//
+// the powers and the commands being per unit of the rating. Each axis
+// steps from zero to its setpoint Sp at its time At, so that the reaction
+// of the other axis shows the coupling.
+type Decoupled struct {
+	Tau      float64       `json:"Tau"`
+	Coupling float64       `json:"coupling"`
+	P        DecoupledAxis `json:"P"`
+	Q        DecoupledAxis `json:"Q"`
+	Dt       float64       `json:"dt"`
+	N        float64       `json:"N"`
+}
+
+// DecoupledAxis is the setpoint step and the PID gains of one axis.
+type DecoupledAxis struct {
+	Sp float64 `json:"Sp"`
+	At float64 `json:"at"`
+	P  float64 `json:"P"`
+	Ki float64 `json:"Ki"`
+	Kd float64 `json:"Kd"`
+}
+
+// DefaultDecoupled is the standard demonstration: the integral time of
+// the PIs cancels the lag of the current loops, closing each loop with a
+// 40 ms time constant, and Q steps once P has settled.
+var DefaultDecoupled = Decoupled{
+	Tau:      0.02,
+	Coupling: 0.3,
+	P:        DecoupledAxis{Sp: 1, At: 0.1, P: 0.5, Ki: 25},
+	Q:        DecoupledAxis{Sp: 0.5, At: 0.6, P: 0.5, Ki: 25},
+	Dt:       0.001,
+	N:        1200,
+}
+
+// DecoupledSchema describes a decoupled control demonstration, whose
+// missing members are those of DefaultDecoupled.
+var DecoupledSchema = func() *schema.Schema {
+	axis := func(name string, a DecoupledAxis) *schema.Schema {
+		return schema.Object(map[string]*schema.Schema{
+			"Sp": schema.Number("Consigne de " + name + " après l'échelon (pu)").WithDefault(a.Sp),
+			"at": schema.Number("Instant de l'échelon de consigne (s)").Min(0).WithDefault(a.At),
+			"P":  schema.Number("Gain proportionnel").WithDefault(a.P),
+			"Ki": schema.Number("Gain intégral").WithDefault(a.Ki),
+			"Kd": schema.Number("Gain dérivé").WithDefault(a.Kd),
+		})
+	}
+	d := DefaultDecoupled
+	return schema.Object(map[string]*schema.Schema{
+		"Tau":      schema.Number("Constante de temps des boucles de courant de l'onduleur (s)").Above(0).WithDefault(d.Tau),
+		"coupling": schema.Number("Couplage ωL entre les axes d et q, en pu").WithDefault(d.Coupling),
+		"P":        axis("la puissance active", d.P),
+		"Q":        axis("la puissance réactive", d.Q),
+		"dt":       schema.Number("Pas de temps (s)").Above(0).WithDefault(d.Dt),
+		"N":        schema.Integer("Nombre d'itérations").Min(1).Max(1e6).WithDefault(d.N),
+	})
+}()
+
+// Check reports the inconsistencies the schema cannot express.
+func (d *Decoupled) Check() []schema.Error {
+	if d.Dt >= d.Tau {
+		return []schema.Error{{Path: "/dt", Message: "le pas de temps doit être inférieur à Tau pour que l'intégration reste stable"}}
+	}
+	return nil
+}
+
+// DecoupledResult compares the runs without and with the decoupling
+// feedforward, which adds -Coupling·Q to uP and Coupling·P to uQ so that
+// each PID only sees its own axis.
+type DecoupledResult struct {
+	Feedback    DecoupledRun `json:"feedback"`
+	Feedforward DecoupledRun `json:"feedforward"`
+}
+
+// DecoupledRun is one run of the demonstration: both loops and how much
+// each step disturbed the other axis.
+type DecoupledRun struct {
+	Time     []float64      `json:"time"`
+	P        DecoupledLoop  `json:"P"`
+	Q        DecoupledLoop  `json:"Q"`
+	Coupling CouplingErrors `json:"coupling"`
+}
+
+// DecoupledLoop is the setpoint, the measurement and the output of the
+// PID of one axis, the feedforward excluded, and their metrics.
+type DecoupledLoop struct {
+	SP      []float64 `json:"SP"`
+	PV      []float64 `json:"PV"`
+	U       []float64 `json:"U"`
+	Metrics Metrics   `json:"metrics"`
+}
+
+// CouplingErrors are the deviations of an axis from its setpoint caused by
+// the step of the other one, over the samples from that step to the next
+// step of either axis: PonQ is the effect of the step of P on Q, QonP that
+// of the step of Q on P. Simultaneous steps leave them at zero.
+type CouplingErrors struct {
+	PonQ CouplingError `json:"PonQ"`
+	QonP CouplingError `json:"QonP"`
+}
+
+// CouplingError is the peak of |e| and its integral over a window.
+type CouplingError struct {
+	Peak float64 `json:"peak"`
+	IAE  float64 `json:"iae"`
+}
+
+// Run simulates the demonstration without then with the feedforward.
+func (d Decoupled) Run() DecoupledResult {
+	return DecoupledResult{Feedback: d.run(false), Feedforward: d.run(true)}
+}
+
+func (d Decoupled) run(feedforward bool) DecoupledRun {
+
+	n := int(d.N)
+	r := DecoupledRun{
+		Time: make([]float64, n),
+		P:    DecoupledLoop{SP: make([]float64, n), PV: make([]float64, n), U: make([]float64, n)},
+		Q:    DecoupledLoop{SP: make([]float64, n), PV: make([]float64, n), U: make([]float64, n)},
+	}
+	pidP := NewPID(d.P.P, d.P.Ki, d.P.Kd)
+	pidQ := NewPID(d.Q.P, d.Q.Ki, d.Q.Kd)
+	var P, Q float64
+	for k := range n {
+		t := float64(k) * d.Dt
+		spP, spQ := d.P.setpoint(t), d.Q.setpoint(t)
+		uP := pidP.Compute(spP, P, d.Dt)
+		uQ := pidQ.Compute(spQ, Q, d.Dt)
+		r.Time[k] = t
+		r.P.SP[k], r.P.PV[k], r.P.U[k] = spP, P, uP
+		r.Q.SP[k], r.Q.PV[k], r.Q.U[k] = spQ, Q, uQ
+
+		cP, cQ := float64(d.Coupling*Q), -float64(d.Coupling*P)
+		if feedforward {
+			uP, uQ = uP-cP, uQ-cQ
+		}
+		P, Q = DynamicResponse(uP+cP, P, d.Dt, d.Tau, 1), DynamicResponse(uQ+cQ, Q, d.Dt, d.Tau, 1)
+	}
+	r.P.Metrics = TrackingMetrics(r.Time, r.P.SP, r.P.PV, r.P.U)
+	r.Q.Metrics = TrackingMetrics(r.Time, r.Q.SP, r.Q.PV, r.Q.U)
+	r.Coupling.PonQ = couplingError(r.Time, r.Q, d.P.At, d.Q.At)
+	r.Coupling.QonP = couplingError(r.Time, r.P, d.Q.At, d.P.At)
+	return r
+}
+
+// setpoint returns the setpoint of the axis at time t.
+func (a DecoupledAxis) setpoint(t float64) float64 {
+	if t < a.At {
+		return 0
+	}
+	return a.Sp
+}
+
+// couplingError measures the deviation of loop from its setpoint from the
+// step at time from until the step at time next, or the end of the run
+// when next is not after from.
+func couplingError(T []float64, loop DecoupledLoop, from, next float64) CouplingError {
+
+	var c CouplingError
+	if next == from {
+		return c
+	}
+	for k := 1; k < len(T); k++ {
+		if T[k] < from || next > from && T[k] >= next {
+			continue
+		}
+		e := math.Abs(loop.SP[k] - loop.PV[k])
+		c.Peak = math.Max(c.Peak, e)
+		c.IAE += float64(e * (T[k] - T[k-1]))
+	}
+	return c
+}
+
+// Rounded returns a copy rounded for output, see numfmt.
+func (r DecoupledResult) Rounded() DecoupledResult {
+	return DecoupledResult{Feedback: r.Feedback.rounded(), Feedforward: r.Feedforward.rounded()}
+}
+
+func (r DecoupledRun) rounded() DecoupledRun {
+	loop := func(l DecoupledLoop) DecoupledLoop {
+		return DecoupledLoop{SP: numfmt.Series(l.SP), PV: numfmt.Series(l.PV), U: numfmt.Series(l.U), Metrics: l.Metrics.Rounded()}
+	}
+	errs := func(c CouplingError) CouplingError {
+		return CouplingError{Peak: numfmt.Round(c.Peak), IAE: numfmt.Round(c.IAE)}
+	}
+	return DecoupledRun{
+		Time:     numfmt.Series(r.Time),
+		P:        loop(r.P),
+		Q:        loop(r.Q),
+		Coupling: CouplingErrors{PonQ: errs(r.Coupling.PonQ), QonP: errs(r.Coupling.QonP)},
+	}
+}
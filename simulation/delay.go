@@ -0,0 +1,76 @@
+package simulation
+
+import "math"
+
+// maxDelaySteps bounds the memory of a dead time, 8 MiB of outputs: Check
+// rejects longer dead times, and a delay line clamps them should a
+// scenario escape it, as one patched through Param.
+const maxDelaySteps = 1 << 20
+
+// delayLine is the dead time of the plant: it holds the last controller
+// outputs so that the plant receives u(t − Theta). A dead time that is not
+// a multiple of dt is interpolated linearly between the two outputs around
+// t − Theta. Before t = Theta the plant receives 0, the output at rest.
+type delayLine struct {
+	// past are the outputs by age, past[next] the oldest, in a ring of
+	// steps+2 so that the two outputs around t − Theta are both kept.
+	past []float64
+	next int
+	// steps and frac split Theta/dt into its whole and fractional parts.
+	steps int
+	frac  float64
+	// out is the output the plant received at the last step.
+	out float64
+}
+
+// newDelayLine returns the delay line of the dead time theta, or nil when
+// there is none.
+func newDelayLine(theta, dt float64) *delayLine {
+	if theta <= 0 {
+		return nil
+	}
+	n := min(theta/dt, maxDelaySteps)
+	steps := int(math.Floor(n))
+	// Theta given as a multiple of dt must not pick up a fraction from the
+	// rounding of the division.
+	frac := n - float64(steps)
+	if math.Abs(n-math.Round(n)) < 1e-9 {
+		steps, frac = int(math.Round(n)), 0
+	}
+	return &delayLine{past: make([]float64, steps+2), steps: steps, frac: frac}
+}
+
+// push records the output u of the current step and returns the one the
+// plant receives.
+func (d *delayLine) push(u float64) float64 {
+	if d == nil {
+		return u
+	}
+	d.past[d.next] = u
+	d.next = (d.next + 1) % len(d.past)
+	d.out = float64((1-d.frac)*d.at(d.steps)) + float64(d.frac*d.at(d.steps+1))
+	return d.out
+}
+
+// at returns the output recorded age steps ago, 0 for the last one.
+func (d *delayLine) at(age int) float64 {
+	n := len(d.past)
+	return d.past[((d.next-1-age)%n+n)%n]
+}
+
+// resize returns the delay line of the dead time theta keeping the
+// outputs recorded, as when the dead time of a running loop changes.
+// Outputs older than those kept are taken as the oldest known.
+func (d *delayLine) resize(theta, dt float64) *delayLine {
+	r := newDelayLine(theta, dt)
+	if d == nil || r == nil {
+		return r
+	}
+	n := len(r.past)
+	for age := n - 1; age >= 0; age-- {
+		r.past[r.next] = d.at(min(age, len(d.past)-1))
+		r.next = (r.next + 1) % n
+	}
+	r.out = d.out
+	return r
+}
@@ -0,0 +1,134 @@
+package simulation
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// MaxBins bounds the number of bins of a histogram.
+const MaxBins = 200
+
+// Bin is one class of a histogram: the samples whose value is in
+// [From, To), the last bin including To.
+type Bin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// Distribution describes the deviation PV - SP of the controlled variable
+// around its setpoint over a steady-state window, as control-performance
+// reports do for noisy loops.
+type Distribution struct {
+	From    float64 `json:"from"` // start of the window (s)
+	To      float64 `json:"to"`   // end of the window (s)
+	Samples int     `json:"samples"`
+	Mean    float64 `json:"mean"`
+	Std     float64 `json:"std"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Bins    []Bin   `json:"bins"`
+}
+
+// SteadyStart returns the start of the steady-state window of the result:
+// the settling time when the response settled, the middle of the run
+// otherwise.
+func (r Result) SteadyStart() float64 {
+	if r.Metrics.Settled {
+		return r.Metrics.SettlingTime
+	}
+	if len(r.Time) == 0 {
+		return 0
+	}
+	return r.Time[len(r.Time)-1] / 2
+}
+
+// Deviation computes the distribution of PV - SP over the samples from time
+// from on, in the given number of bins of equal width.
+func Deviation(T, SP, PV []float64, from float64, bins int) (Distribution, error) {
+
+	if bins < 1 || bins > MaxBins {
+		return Distribution{}, fmt.Errorf("le nombre de classes doit être compris entre 1 et %d", MaxBins)
+	}
+	n := min(len(T), len(SP), len(PV))
+	var dev []float64
+	d := Distribution{From: from, Min: math.Inf(1), Max: math.Inf(-1)}
+	for k := 0; k < n; k++ {
+		e := PV[k] - SP[k]
+		if T[k] < from || math.IsNaN(e) || math.IsInf(e, 0) {
+			continue
+		}
+		dev = append(dev, e)
+		d.To = T[k]
+		d.Mean += e
+		d.Min, d.Max = math.Min(d.Min, e), math.Max(d.Max, e)
+	}
+	if len(dev) == 0 {
+		return Distribution{}, fmt.Errorf("aucun échantillon après t = %g s", from)
+	}
+	d.Samples = len(dev)
+	d.Mean /= float64(len(dev))
+	for _, e := range dev {
+		d.Std += float64((e - d.Mean) * (e - d.Mean))
+	}
+	d.Std = math.Sqrt(d.Std / float64(len(dev)))
+
+	width := (d.Max - d.Min) / float64(bins)
+	if width == 0 {
+		width = 1
+	}
+	d.Bins = make([]Bin, bins)
+	for i := range d.Bins {
+		d.Bins[i].From = d.Min + float64(i)*width
+		d.Bins[i].To = d.Min + float64(i+1)*width
+	}
+	for _, e := range dev {
+		i := min(int((e-d.Min)/width), bins-1)
+		d.Bins[i].Count++
+	}
+	return d, nil
+}
+
+// Rounded returns a copy rounded for output, see numfmt.
+func (d Distribution) Rounded() Distribution {
+	d.From, d.To = numfmt.Round(d.From), numfmt.Round(d.To)
+	d.Mean, d.Std = numfmt.Round(d.Mean), numfmt.Round(d.Std)
+	d.Min, d.Max = numfmt.Round(d.Min), numfmt.Round(d.Max)
+	bins := make([]Bin, len(d.Bins))
+	for i, b := range d.Bins {
+		bins[i] = Bin{From: numfmt.Round(b.From), To: numfmt.Round(b.To), Count: b.Count}
+	}
+	d.Bins = bins
+	return d
+}
+
+// WritePlot draws the histogram to w in the format, as Result.WritePlot,
+// with the mean and the standard deviation in the title.
+func (d Distribution) WritePlot(w io.Writer, format string, opts PlotOptions) error {
+
+	loc, theme, err := opts.style()
+	if err != nil {
+		return err
+	}
+
+	bars := chart.Series{Kind: chart.KindBars, Color: theme.Foreground, Fill: theme.Measurement, Width: theme.LineWidth / 2}
+	for i, b := range d.Bins {
+		if i == 0 {
+			bars.X = append(bars.X, b.From)
+		}
+		bars.X = append(bars.X, b.To)
+		bars.Y = append(bars.Y, float64(b.Count))
+	}
+	return opts.Render(w, chart.Figure{
+		Title:        fmt.Sprintf("%s (μ = %s, σ = %s)", loc.HistogramTitle, loc.number(d.Mean), loc.number(d.Std)),
+		XLabel:       loc.Deviation,
+		YLabel:       loc.Count,
+		Style:        theme.style(),
+		DecimalComma: loc.DecimalComma,
+		Series:       []chart.Series{bars},
+	}, format)
+}
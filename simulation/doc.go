@@ -0,0 +1,10 @@
+// Package simulation simulates PID control loops: the controller (PID),
+// the plant models, selected by Scenario.Plant, and the metrics of their
+// response (Result, Metrics).
+//
+// The module follows semantic versioning. Within a major version, the
+// exported API of this package keeps compiling and keeps its meaning:
+// fields, functions and options may be added, and the JSON encoding of
+// Scenario and Result only gains optional members. Simulated values only
+// change to fix a bug, noted in the release notes.
+package simulation
@@ -0,0 +1,106 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Drive replays a recorded operating profile, such as a day of ambient
+// temperature, through the run: SP replaces the setpoint Sp and
+// Disturbance is a load added to the controller output at the plant input.
+type Drive struct {
+	SP          *Profile `json:"sp,omitempty"`
+	Disturbance *Profile `json:"disturbance,omitempty"`
+}
+
+// DriveSchema describes the optional "drive" member of a scenario.
+var DriveSchema = schema.Object(map[string]*schema.Schema{
+	"sp":          profileSchema("Consigne imposée au cours du temps, remplace Sp"),
+	"disturbance": profileSchema("Perturbation de charge ajoutée à la commande en entrée du procédé"),
+})
+
+// DriveFromSeries builds a drive from sampled series, as read from a CSV
+// upload; a nil series leaves its profile unset. Times are shifted so that
+// the first sample is at t = 0.
+func DriveFromSeries(t, sp, disturbance []float64) (Drive, error) {
+	var d Drive
+	if len(t) == 0 {
+		return d, fmt.Errorf("aucun échantillon")
+	}
+	profile := func(values []float64) *Profile {
+		if values == nil {
+			return nil
+		}
+		p := &Profile{Points: make([]Breakpoint, len(t))}
+		for i := range t {
+			p.Points[i] = Breakpoint{T: t[i] - t[0], Value: values[i]}
+		}
+		return p
+	}
+	d.SP, d.Disturbance = profile(sp), profile(disturbance)
+	if d.SP == nil && d.Disturbance == nil {
+		return d, fmt.Errorf("ni consigne ni perturbation à rejouer")
+	}
+	return d, nil
+}
+
+// check reports the problems of the drive that the schema cannot express.
+func (d *Drive) check(recipe bool) []schema.Error {
+	var errs []schema.Error
+	if d.SP != nil {
+		errs = append(errs, d.SP.check("/drive/sp", false)...)
+		if recipe {
+			errs = append(errs, schema.Error{Path: "/drive/sp", Message: "incompatible avec une recette, qui impose déjà la consigne"})
+		}
+	}
+	if d.Disturbance != nil {
+		errs = append(errs, d.Disturbance.check("/drive/disturbance", false)...)
+	}
+	return errs
+}
+
+// follow sets the setpoint of the loop to the driven one at the current
+// time.
+func (d *Drive) follow(l *Loop) {
+	if d != nil && d.SP != nil {
+		l.Scenario.Sp = d.SP.At(l.T)
+	}
+}
+
+// load returns the disturbance at time t.
+func (d *Drive) load(t float64) float64 {
+	if d == nil || d.Disturbance == nil {
+		return 0
+	}
+	return d.Disturbance.At(t)
+}
+
+// watch follows the segments of the disturbance from time t.
+func (d *Drive) watch(t float64) *profileWatch {
+	if d == nil {
+		return nil
+	}
+	return newProfileWatch(d.Disturbance, t)
+}
+
+// Shape sets how both profiles are interpolated and extrapolated, empty
+// strings keeping the defaults.
+func (d *Drive) Shape(interpolation, extrapolation string) error {
+	switch interpolation {
+	case "", InterpolationLinear, InterpolationHold, InterpolationSpline:
+	default:
+		return fmt.Errorf("interpolation inconnue %q", interpolation)
+	}
+	switch extrapolation {
+	case "", ExtrapolationHold, ExtrapolationLinear, ExtrapolationPeriodic:
+	default:
+		return fmt.Errorf("extrapolation inconnue %q", extrapolation)
+	}
+	for _, p := range []*Profile{d.SP, d.Disturbance} {
+		if p != nil {
+			p.Interpolation, p.Extrapolation = interpolation, extrapolation
+		}
+	}
+	return nil
+}
@@ -0,0 +1,526 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/units"
+)
+
+// ElectricalSystem represents the parameters of the electrical system
+// between the inverters and the point of connection (POC).
+type ElectricalSystem struct {
+	L    float64 `json:"L"`    // Inductance in henrys
+	C    float64 `json:"C"`    // Capacitance in farads, none when zero
+	R    float64 `json:"R"`    // Resistance in ohms
+	F    float64 `json:"f"`    // Frequency in hertz
+	UPoc float64 `json:"UPoc"` // Voltage at the POC in volts
+
+	// Blocks are the transformers and cables of the collection grid, in
+	// order from the inverters, behind the lumped L, R and C.
+	Blocks []Block `json:"blocks,omitempty"`
+}
+
+// Block types accepted in Block.Type.
+const (
+	BlockTransformer = "transformer"
+	BlockCable       = "cable"
+)
+
+// Block is a series impedance R + j X between the inverters and the POC,
+// in ohms on the POC side of the transformers that follow it.
+//
+// A transformer has its impedance on its inverter side, whose voltage is
+// Tap times that of its POC side. A cable is a PI section, half of its
+// shunt susceptance B, in siemens, at each end.
+type Block struct {
+	Type string  `json:"type"`
+	Name string  `json:"name,omitempty"`
+	R    float64 `json:"R"`
+	X    float64 `json:"X"`
+	// Tap is the ratio of a transformer, 1 when zero.
+	Tap float64 `json:"tap,omitempty"`
+	B   float64 `json:"B,omitempty"`
+}
+
+// label returns the name of the block, or its type and rank.
+func (b Block) label(i int) string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return fmt.Sprintf("%s %d", b.Type, i+1)
+}
+
+func (b Block) ratio() float64 {
+	if b.Type != BlockTransformer || b.Tap == 0 {
+		return 1
+	}
+	return b.Tap
+}
+
+// BlockSchema describes a block of the collection grid.
+var BlockSchema = schema.Object(map[string]*schema.Schema{
+	"type": schema.String("Type de bloc").OneOf(BlockTransformer, BlockCable),
+	"name": schema.String("Nom du bloc, repris dans les pertes"),
+	"R":    schema.Number("Résistance série (Ω)").In(units.Ohm).Min(0).WithDefault(0.0),
+	"X":    schema.Number("Réactance série (Ω)").In(units.Ohm).WithDefault(0.0),
+	"tap":  schema.Number("Rapport du transformateur, tension côté onduleurs sur tension côté POC").Above(0).WithDefault(1.0),
+	"B":    schema.Number("Susceptance shunt totale du câble (S), moitié à chaque extrémité").In(units.Siemens).Min(0).WithDefault(0.0),
+}, "type")
+
+// DefaultElectricalSystem is the collection grid of the reactive power
+// presets: a 2.8 mH series inductance at 50 Hz under 6.7 kV.
+var DefaultElectricalSystem = ElectricalSystem{
+	L:    2.8e-3,
+	C:    0,
+	R:    0,
+	F:    50,
+	UPoc: 6700,
+}
+
+// ComputeImpedance calculates the impedance of the system
+func (sys *ElectricalSystem) ComputeImpedance() (float64, float64) {
+
+	X_L := 2 * math.Pi * sys.F * sys.L
+
+	X_C := 1 / (2 * math.Pi * sys.F * sys.C)
+
+	Z := math.Sqrt(math.Pow(sys.R, 2) + math.Pow(X_L-X_C, 2))
+	theta := math.Atan2(X_L-X_C, sys.R)
+
+	return Z, theta
+}
+
+// ComputeReactivePowerSys calculates the reactive power Q of the system
+// crossed by the current I
+func (sys *ElectricalSystem) ComputeReactivePowerSys(I float64) float64 {
+	Q_L, Q_C := sys.reactivePowers(I)
+	return Q_C - Q_L
+}
+
+// reactivePowers returns the reactive powers absorbed by the inductance
+// and generated by the capacitance of the system crossed by the current I.
+func (sys *ElectricalSystem) reactivePowers(I float64) (Q_L, Q_C float64) {
+
+	X_L := 2 * math.Pi * sys.F * sys.L
+	var X_C float64
+
+	if sys.C == 0 {
+		X_C = 0
+	} else {
+		X_C = 1 / (2 * math.Pi * sys.F * sys.C)
+	}
+
+	Q_L = math.Pow(I, 2) * X_L
+	Q_C = math.Pow(I, 2) * X_C
+	return Q_L, Q_C
+}
+
+// ComputeS calculates the apparent power of the inverters
+func (sys *ElectricalSystem) ComputeS(Pond, Qond float64) float64 {
+	S := math.Sqrt(math.Pow(Pond, 2) + math.Pow(Qond, 2))
+	return S
+}
+
+// ComputeQPoc calculates the reactive power at the POC for the active and
+// reactive powers of the inverters
+func (sys *ElectricalSystem) ComputeQPoc(Pond, Qond float64) float64 {
+	return sys.ComputeDetail(Pond, Qond).QPoc
+}
+
+// ComputeDetail returns the intermediate values of ComputeQPoc, see
+// Detail.
+func (sys *ElectricalSystem) ComputeDetail(Pond, Qond float64) Detail {
+	return sys.ComputeFlow(Pond, Qond).Detail
+}
+
+// Detail is how the reactive power at the POC follows from the powers of
+// the inverters: their apparent power S in VA, their current I in A, the
+// reactive powers Q_L absorbed by the inductance and Q_C generated by the
+// capacitance of the lumped system, Qsys = Q_C - Q_L, and QPoc in var,
+// which also includes the blocks.
+type Detail struct {
+	S    float64 `json:"S"`
+	I    float64 `json:"I"`
+	Q_L  float64 `json:"Q_L"`
+	Q_C  float64 `json:"Q_C"`
+	Qsys float64 `json:"Qsys"`
+	QPoc float64 `json:"QPoc"`
+}
+
+// Rounded returns a copy rounded for output, see numfmt.
+func (d Detail) Rounded() Detail {
+	return Detail{
+		S: numfmt.Round(d.S), I: numfmt.Round(d.I),
+		Q_L: numfmt.Round(d.Q_L), Q_C: numfmt.Round(d.Q_C),
+		Qsys: numfmt.Round(d.Qsys), QPoc: numfmt.Round(d.QPoc),
+	}
+}
+
+// DebugLog, when not nil, receives the Detail of every power flow, as the
+// regulation server writes it with -debug-electrical.
+var DebugLog *log.Logger
+
+// Flow is the power flow from the inverters to the POC, in W and var.
+type Flow struct {
+	PPoc   float64 `json:"PPoc"`
+	QPoc   float64 `json:"QPoc"`
+	Losses []Loss  `json:"losses"`
+	// Voltages is the voltage profile from the inverters to the POC.
+	Voltages    []Bus       `json:"voltages"`
+	Convergence Convergence `json:"convergence"`
+	Detail      Detail      `json:"detail"`
+}
+
+// Loss is what a part of the system takes from the power flow: the active
+// power it dissipates and the reactive power it absorbs, negative when it
+// generates more than it absorbs, as a long cable.
+type Loss struct {
+	Block string  `json:"block"`
+	P     float64 `json:"P"`
+	Q     float64 `json:"Q"`
+}
+
+// Bus is a node of the voltage profile: the inverter terminals, named
+// BusInverters, then the POC end of every part of the system, named after
+// it, the last one being the POC.
+type Bus struct {
+	Name string  `json:"name"`
+	U    float64 `json:"U"`
+	// PU is U per unit of the nominal voltage of the bus: UPoc times the
+	// taps of the transformers between the bus and the POC.
+	PU float64 `json:"pu"`
+}
+
+const (
+	// lumpedLoss names the loss of the lumped L, R and C.
+	lumpedLoss = "system"
+	// BusInverters names the bus of the inverter terminals.
+	BusInverters = "inverters"
+)
+
+// Power flow iterations: the bus voltages are updated until they move by
+// less than flowTolerance per unit, at most flowIterations times.
+const (
+	flowTolerance  = 1e-9
+	flowIterations = 50
+)
+
+// Convergence reports the iterations of a power flow. Residual is the
+// largest change of a bus voltage at the last iteration, per unit; a flow
+// that did not converge, near the collapse of the voltage, is that of the
+// last iteration.
+type Convergence struct {
+	Iterations int     `json:"iterations"`
+	Residual   float64 `json:"residual"`
+	Converged  bool    `json:"converged"`
+}
+
+// ComputeFlow calculates the powers at the POC for the powers of the
+// inverters, the losses of the lumped system then of every block, and the
+// voltages of the buses, the POC being at UPoc.
+//
+// The current of each part is its apparent power over the voltage of its
+// inverter end, and its voltage drop (R·P + X·Q) / U: starting from the
+// nominal voltages, UPoc times the taps of the transformers between the
+// part and the POC, passes alternate between the powers from the
+// inverters and the voltages from the POC until the voltages settle.
+func (sys *ElectricalSystem) ComputeFlow(Pond, Qond float64) Flow {
+	return sys.computeFlow(Pond, Qond, nil, sys.UPoc)
+}
+
+// computeFlow is ComputeFlow with the grid at the voltage uGrid, the POC
+// voltage rising from it through the grid impedance of droop when it is
+// not nil, see Electrical.Voltage.
+func (sys *ElectricalSystem) computeFlow(Pond, Qond float64, droop *Droop, uGrid float64) Flow {
+
+	nominal := sys.nominals()
+	U := slices.Clone(nominal)
+	var flow Flow
+	for flow.Convergence.Iterations < flowIterations {
+		next := sys.flowPass(Pond, Qond, droop, uGrid, U, nominal)
+		next.Convergence.Iterations = flow.Convergence.Iterations + 1
+		for k, b := range next.Voltages {
+			next.Convergence.Residual = max(next.Convergence.Residual, math.Abs(b.U-U[k])/nominal[k])
+			U[k] = b.U
+		}
+		flow = next
+		residual := flow.Convergence.Residual
+		if residual < flowTolerance || math.IsNaN(residual) {
+			break
+		}
+	}
+	flow.Convergence.Converged = flow.Convergence.Residual < flowTolerance
+	if DebugLog != nil {
+		d := flow.Detail
+		DebugLog.Printf("Pond = %.6g W, Qond = %.6g var : S = %.6g VA, I = %.6g A, Q_L = %.6g var, Q_C = %.6g var, Qsys = %.6g var, QPoc = %.6g var, %d itération(s)",
+			Pond, Qond, d.S, d.I, d.Q_L, d.Q_C, d.Qsys, d.QPoc, flow.Convergence.Iterations)
+	}
+	return flow
+}
+
+// nominals returns the nominal voltages of the buses, UPoc times the taps
+// of the transformers between the bus and the POC: nominal[k] is that of
+// the inverter end of the lumped system for k = 0, of block k-1
+// otherwise, then the POC.
+func (sys *ElectricalSystem) nominals() []float64 {
+	n := len(sys.Blocks)
+	nominal := make([]float64, n+2)
+	nominal[n+1] = sys.UPoc
+	for i := n - 1; i >= 0; i-- {
+		nominal[i+1] = nominal[i+2] * sys.Blocks[i].ratio()
+	}
+	nominal[0] = nominal[1]
+	return nominal
+}
+
+// flowPass computes the powers with the bus voltages U, then the voltages
+// from the POC back to the inverters.
+func (sys *ElectricalSystem) flowPass(Pond, Qond float64, droop *Droop, uGrid float64, U, nominal []float64) Flow {
+
+	n := len(sys.Blocks)
+	// The voltage drop of a part is taken with the powers at its POC end,
+	// kept in drops until the voltage at the POC is known.
+	type drop struct{ R, X, P, Q float64 }
+	drops := make([]drop, 0, n+1)
+
+	P, Q := Pond, Qond
+	S := sys.ComputeS(P, Q)
+	I := S / U[0]
+	Q_L, Q_C := sys.reactivePowers(I)
+	Qsys := Q_C - Q_L
+	flow := Flow{Losses: make([]Loss, 0, n+1), Detail: Detail{S: S, I: I, Q_L: Q_L, Q_C: Q_C, Qsys: Qsys}}
+	flow.Losses = append(flow.Losses, Loss{Block: lumpedLoss, P: float64(I * I * sys.R), Q: -Qsys})
+	P, Q = P-flow.Losses[0].P, Q+Qsys
+	X := 0.0
+	if I != 0 {
+		X = -Qsys / (I * I)
+	}
+	drops = append(drops, drop{sys.R, X, P, Q})
+
+	for i, b := range sys.Blocks {
+		// The cable ends are buses i+1 and i+2; a transformer, whose ideal
+		// ratio is at its POC end, has no shunt.
+		send := float64(U[i+1]*U[i+1]*b.B) / 2
+		Q += send
+		I := sys.ComputeS(P, Q) / U[i+1]
+		receive := float64(U[i+2]*U[i+2]*b.B) / 2
+		loss := Loss{Block: b.label(i), P: float64(I * I * b.R), Q: float64(I*I*b.X) - send - receive}
+		P, Q = P-loss.P, Q-float64(I*I*b.X)
+		drops = append(drops, drop{b.R, b.X, P, Q})
+		Q += receive
+		flow.Losses = append(flow.Losses, loss)
+	}
+	flow.PPoc, flow.QPoc = P, Q
+	flow.Detail.QPoc = Q
+
+	// From the POC back to the inverters, each bus being the POC end of a
+	// part.
+	V := droop.voltage(uGrid, P, Q)
+	flow.Voltages = make([]Bus, n+2)
+	for i := n; i >= 0; i-- {
+		flow.Voltages[i+1] = Bus{Name: flow.Losses[i].Block, U: V, PU: V / nominal[i+1]}
+		if i > 0 {
+			V *= sys.Blocks[i-1].ratio()
+		}
+		d := drops[i]
+		V += float64(d.R*d.P+d.X*d.Q) / V
+	}
+	flow.Voltages[0] = Bus{Name: BusInverters, U: V, PU: V / nominal[0]}
+	return flow
+}
+
+// Rounded returns a copy of the flow rounded for output, see numfmt.
+func (f Flow) Rounded() Flow {
+	out := Flow{PPoc: numfmt.Round(f.PPoc), QPoc: numfmt.Round(f.QPoc), Losses: roundLosses(f.Losses), Convergence: f.Convergence, Detail: f.Detail.Rounded()}
+	out.Convergence.Residual = numfmt.Round(f.Convergence.Residual)
+	for _, b := range f.Voltages {
+		out.Voltages = append(out.Voltages, Bus{Name: b.Name, U: numfmt.Round(b.U), PU: numfmt.Round(b.PU)})
+	}
+	return out
+}
+
+func roundLosses(losses []Loss) []Loss {
+	if losses == nil {
+		return nil
+	}
+	out := make([]Loss, len(losses))
+	for i, l := range losses {
+		out[i] = Loss{Block: l.Block, P: numfmt.Round(l.P), Q: numfmt.Round(l.Q)}
+	}
+	return out
+}
+
+// Electrical is the reactive power regulation at the POC of a PlantReactive
+// plant: the controller sets the reactive power of the inverters, which
+// follows it with the first-order lag (K, Tau), and measures the reactive
+// power at the POC, the inverters' minus what the system absorbs for the
+// current of their active power Pond, the disturbance.
+type Electrical struct {
+	ElectricalSystem
+	// Pond is the active power of the inverters in watts; Profile, when
+	// set, replaces it by a scheduled active power.
+	Pond    float64  `json:"Pond"`
+	Profile *Profile `json:"profile,omitempty"`
+	// Droop, when set, moves the setpoint with the POC voltage.
+	Droop *Droop `json:"droop,omitempty"`
+	// TapChanger, when set, moves the tap of a transformer to regulate a
+	// bus voltage.
+	TapChanger *TapChanger `json:"tapChanger,omitempty"`
+	// Capability, when set, bounds the reactive power commanded to the
+	// inverters.
+	Capability *Capability `json:"capability,omitempty"`
+	// Grid are the disturbances of the grid injected during the run.
+	Grid []GridEvent `json:"grid,omitempty"`
+}
+
+// Droop is a Q(U) characteristic: beyond the deadband around the nominal
+// voltage of Bus, the POC when empty, the reactive power setpoint drops
+// by Slope var for every volt above, and rises for every volt below. The
+// POC voltage rises by the powers injected through the grid impedance
+// RGrid + j XGrid: U = UPoc + (RGrid·P + XGrid·Q) / UPoc.
+type Droop struct {
+	Slope    float64 `json:"slope"`
+	Deadband float64 `json:"deadband"`
+	RGrid    float64 `json:"RGrid"`
+	XGrid    float64 `json:"XGrid"`
+	Bus      string  `json:"bus,omitempty"`
+}
+
+// ElectricalSchema describes the "electrical" member of a scenario.
+var ElectricalSchema = schema.Object(map[string]*schema.Schema{
+	"L":       schema.Number("Inductance série entre onduleurs et POC (H)").In(units.Henry).Min(0).WithDefault(DefaultElectricalSystem.L),
+	"C":       schema.Number("Capacité série entre onduleurs et POC (F), aucune si nulle").In(units.Farad).Min(0).WithDefault(DefaultElectricalSystem.C),
+	"R":       schema.Number("Résistance série entre onduleurs et POC (Ω)").In(units.Ohm).Min(0).WithDefault(DefaultElectricalSystem.R),
+	"f":       schema.Number("Fréquence du réseau (Hz)").In(units.Hertz).Above(0).WithDefault(DefaultElectricalSystem.F),
+	"UPoc":    schema.Number("Tension au POC (V)").In(units.Volt).Above(0).WithDefault(DefaultElectricalSystem.UPoc),
+	"blocks":  schema.Array(BlockSchema),
+	"Pond":    schema.Number("Puissance active des onduleurs (W)").In(units.Watt).WithDefault(1e6),
+	"profile": profileSchema("Puissance active programmée des onduleurs, remplace Pond"),
+	"droop": schema.Object(map[string]*schema.Schema{
+		"slope":    schema.Number("Pente de la caractéristique Q(U) (var/V)").In(units.VarPerVolt).Min(0),
+		"deadband": schema.Number("Bande morte autour de UPoc (V)").In(units.Volt).Min(0).WithDefault(0.0),
+		"RGrid":    schema.Number("Résistance du réseau vue du POC (Ω)").In(units.Ohm).Min(0).WithDefault(0.0),
+		"XGrid":    schema.Number("Réactance du réseau vue du POC (Ω)").In(units.Ohm).Min(0),
+		"bus":      schema.String("Nœud dont la tension déplace la consigne : inverters, system ou le nom d'un bloc ; le POC par défaut"),
+	}, "slope", "XGrid"),
+	"tapChanger": TapChangerSchema,
+	"capability": CapabilitySchema,
+	"grid":       schema.Array(GridEventSchema),
+}, "L", "f", "UPoc", "Pond")
+
+// Check reports the inconsistencies the schema cannot express, with paths
+// within the electrical member.
+func (e *Electrical) Check() []schema.Error {
+	var errs []schema.Error
+	if e.Profile != nil {
+		errs = append(errs, e.Profile.check("/profile", false)...)
+	}
+	if e.Droop != nil && e.Droop.Bus != "" && !slices.Contains(e.busNames(), e.Droop.Bus) {
+		errs = append(errs, schema.Error{Path: "/droop/bus", Message: fmt.Sprintf("nœud inconnu %q, nœuds possibles : %v", e.Droop.Bus, e.busNames())})
+	}
+	if e.TapChanger != nil {
+		errs = append(errs, e.TapChanger.check(e)...)
+	}
+	if e.Capability != nil {
+		errs = append(errs, e.Capability.check()...)
+	}
+	errs = append(errs, checkGridEvents(e.Grid)...)
+	errs = append(errs, e.checkMagnitudes()...)
+	return errs
+}
+
+// busNames returns the names of the buses of the voltage profile, from
+// the inverters to the POC.
+func (sys *ElectricalSystem) busNames() []string {
+	names := []string{BusInverters, lumpedLoss}
+	for i, b := range sys.Blocks {
+		names = append(names, b.label(i))
+	}
+	return names
+}
+
+// nominalVoltage returns the nominal voltage of the bus of the given name,
+// see busNames.
+func (sys *ElectricalSystem) nominalVoltage(name string) float64 {
+	nominal := sys.nominals()
+	// The buses after the inverters are the POC ends of the parts.
+	if k := slices.Index(sys.busNames(), name); k > 0 {
+		return nominal[k]
+	}
+	return nominal[0]
+}
+
+// bus returns the bus of the given name, the POC when name is empty.
+func (f Flow) bus(name string) Bus {
+	for _, b := range f.Voltages {
+		if b.Name == name {
+			return b
+		}
+	}
+	return f.Voltages[len(f.Voltages)-1]
+}
+
+// power returns the active power of the inverters at time t.
+func (e *Electrical) power(t float64) float64 {
+	if e.Profile != nil {
+		return e.Profile.At(t)
+	}
+	return e.Pond
+}
+
+// flow returns the power flow at time t for the reactive power Qond of
+// the inverters, whose QPoc is the measurement, under the grid events.
+func (e *Electrical) flow(t, Qond float64) Flow {
+	g := e.grid(t)
+	P, Q := e.power(t), Qond
+	if g.angle != 0 {
+		sin, cos := math.Sincos(g.angle)
+		P, Q = float64(P*cos)-float64(Q*sin), float64(P*sin)+float64(Q*cos)
+	}
+	sys := e.ElectricalSystem.at(g.frequency)
+	return sys.computeFlow(P, Q, e.Droop, float64(e.UPoc*g.voltage))
+}
+
+// Voltage returns the POC voltage for the active power P and the reactive
+// power Q at the POC, UPoc without droop.
+func (e *Electrical) Voltage(P, Q float64) float64 {
+	return e.Droop.voltage(e.UPoc, P, Q)
+}
+
+// voltage returns the POC voltage for the grid voltage U, U when d is nil.
+func (d *Droop) voltage(U, P, Q float64) float64 {
+	if d == nil {
+		return U
+	}
+	return U + float64(d.RGrid*P+d.XGrid*Q)/U
+}
+
+// setpoint returns the reactive power setpoint for the target Sp and the
+// voltages of f, moved by the droop.
+func (e *Electrical) setpoint(Sp float64, f Flow) float64 {
+	if e.Droop == nil {
+		return Sp
+	}
+	b := f.bus(e.Droop.Bus)
+	dU := b.U - b.U/b.PU
+	switch {
+	case dU > e.Droop.Deadband:
+		dU -= e.Droop.Deadband
+	case dU < -e.Droop.Deadband:
+		dU += e.Droop.Deadband
+	default:
+		dU = 0
+	}
+	return Sp - float64(e.Droop.Slope*dU)
+}
+
+// add records the voltages of f at both ends of the system.
+func (v *Voltage) add(f Flow) {
+	v.Inverters = append(v.Inverters, f.Voltages[0].U)
+	v.POC = append(v.POC, f.Voltages[len(f.Voltages)-1].U)
+}
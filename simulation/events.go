@@ -0,0 +1,85 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+)
+
+// EventType classifies the events of a run.
+type EventType string
+
+const (
+	EventSetpoint EventType = "setpoint" // setpoint changed
+	EventGains    EventType = "gains"    // controller gains changed
+	EventSettled  EventType = "settled"  // measurement entered the settling band for good
+	EventDiverged EventType = "diverged" // measurement became infinite or NaN
+	EventStop     EventType = "stop"     // a stop condition ended the run
+
+	EventPhase       EventType = "phase"       // a recipe phase started
+	EventSaturated   EventType = "saturated"   // output reached UMin or UMax
+	EventUnsaturated EventType = "unsaturated" // output left its limits
+	EventMode        EventType = "mode"        // switch between manual and auto
+	EventTap         EventType = "tap"         // a tap changer moved its tap
+	EventCapability  EventType = "capability"  // the P–Q capability started or stopped limiting the output
+	EventGrid        EventType = "grid"        // a grid event was injected
+	EventAlarm       EventType = "alarm"       // a live alarm changed state or was acknowledged, shelved or unshelved
+	EventBreakpoint  EventType = "breakpoint"  // a breakpoint paused a live session
+	EventSchedule    EventType = "schedule"    // the measurement entered another segment of the gain schedule
+	EventDisturbance EventType = "disturbance" // a step or segment of a disturbance profile started
+)
+
+// Event is something notable that happened at time T of a run, for plots
+// to mark and reports to narrate.
+type Event struct {
+	T       float64   `json:"t"`
+	Type    EventType `json:"type"`
+	Message string    `json:"message"`
+}
+
+// events lists what can be read from a finished result.
+func events(r Result) []Event {
+
+	list := []Event{}
+	for k, y := range r.PV {
+		if math.IsNaN(y) || math.IsInf(y, 0) {
+			list = append(list, Event{T: r.Time[k], Type: EventDiverged, Message: "La mesure diverge"})
+			break
+		}
+	}
+	if r.Metrics.Settled {
+		list = append(list, Event{T: r.Metrics.SettlingTime, Type: EventSettled,
+			Message: fmt.Sprintf("Mesure stabilisée à ±%g %% de la consigne", SettlingBand*100)})
+	}
+	if r.StoppedBy != nil {
+		list = append(list, Event{T: r.StoppedBy.Time, Type: EventStop,
+			Message: fmt.Sprintf("Arrêt sur la condition %s", r.StoppedBy.Condition)})
+	}
+	return list
+}
+
+func saturationEvent(t float64, saturated bool, u float64) Event {
+	if saturated {
+		return Event{T: t, Type: EventSaturated, Message: fmt.Sprintf("Sortie saturée à %g", u)}
+	}
+	return Event{T: t, Type: EventUnsaturated, Message: "Sortie hors saturation"}
+}
+
+// disturbanceEvent is the event of a load disturbance, or the inflow of a
+// level plant, entering the segment of its profile starting at b.
+func disturbanceEvent(t float64, what string, b Breakpoint) Event {
+	return Event{T: t, Type: EventDisturbance, Message: fmt.Sprintf("%s : %g à partir du point t = %g s du profil", what, b.Value, b.T)}
+}
+
+// Changes returns the events of replacing the parameters old by new while a
+// loop runs.
+func Changes(t float64, old, new Scenario) []Event {
+	var list []Event
+	if new.Sp != old.Sp {
+		list = append(list, Event{T: t, Type: EventSetpoint, Message: fmt.Sprintf("Consigne %g → %g", old.Sp, new.Sp)})
+	}
+	if new.P != old.P || new.Ki != old.Ki || new.Kd != old.Kd {
+		list = append(list, Event{T: t, Type: EventGains,
+			Message: fmt.Sprintf("Gains (P, Ki, Kd) (%g, %g, %g) → (%g, %g, %g)", old.P, old.Ki, old.Kd, new.P, new.Ki, new.Kd)})
+	}
+	return list
+}
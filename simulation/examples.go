@@ -0,0 +1,176 @@
+package simulation
+
+import "fmt"
+
+// Tuning is a reference tuning of a preset, computed by a textbook rule
+// from the model of the plant, and explained.
+type Tuning struct {
+	Rule        string       `json:"rule"`
+	P           float64      `json:"P"`
+	Ki          float64      `json:"Ki"`
+	Kd          float64      `json:"Kd"`
+	Explanation *Explanation `json:"explanation,omitempty"`
+}
+
+// Difficulty tiers of the example library, from the easiest plant to
+// control.
+const (
+	TierLagDominant = iota + 1
+	TierBalanced
+	TierDeadTimeDominant
+	TierInverseResponse
+	TierIntegrating
+)
+
+// padeDelay returns the coefficients a1, a0 of the second-order Padé
+// approximant of a dead time theta, (s² - a1·s + a0) / (s² + a1·s + a0),
+// written 1 - 2·a1·s / (s² + a1·s + a0).
+func padeDelay(theta float64) (a1, a0 float64) {
+	return 6 / theta, 12 / (theta * theta)
+}
+
+// delayedPlant returns the state-space model of the dead time theta,
+// approximated by padeDelay, followed by the lag K / (1 + T·s), or by the
+// integrator K / s when T is zero. The states are those of the delay then
+// the measurement.
+func delayedPlant(K, T, theta float64) *StateSpace {
+	a1, a0 := padeDelay(theta)
+	// The lag reads w = u - 2·a1·x2, the output of the delay.
+	gain, leak := K, 0.0
+	if T > 0 {
+		gain, leak = K/T, 1/T
+	}
+	return &StateSpace{
+		States: 3, Inputs: 1, Outputs: 1,
+		A: []float64{
+			0, 1, 0,
+			-a0, -a1, 0,
+			0, -2 * a1 * gain, -leak,
+		},
+		B: []float64{0, 1, gain},
+		C: []float64{0, 0, 1},
+	}
+}
+
+// inverseResponsePlant returns the state-space model of
+// K·(1 - beta·s) / ((1 + T1·s)(1 + T2·s)), whose step response first moves
+// the wrong way.
+func inverseResponsePlant(K, T1, T2, beta float64) *StateSpace {
+	c0, c1 := 1/(T1*T2), (T1+T2)/(T1*T2)
+	return &StateSpace{
+		States: 2, Inputs: 1, Outputs: 1,
+		A: []float64{0, 1, -c0, -c1},
+		B: []float64{0, 1},
+		C: []float64{K * c0, -K * beta * c0},
+	}
+}
+
+// simc returns the SIMC PI tuning (Skogestad) of the plant
+// K·e^(-theta·s) / (1 + T·s), or K·e^(-theta·s) / s when T is zero, for the
+// recommended closed-loop time constant tauC = theta.
+func simc(K, T, theta float64) Tuning {
+	tauC := theta
+	x := explain(K, T, theta)
+	x.step("τc", "θ", tauC)
+	var kc, ti float64
+	if T > 0 {
+		kc = T / (K * (tauC + theta))
+		ti = min(T, 4*(tauC+theta))
+		x.step("Kc", "T / (K·(τc + θ))", kc)
+		x.step("Ti", "min(T, 4·(τc + θ))", ti)
+	} else {
+		kc = 1 / (K * (tauC + theta))
+		ti = 4 * (tauC + theta)
+		x.step("Kc", "1 / (K·(τc + θ))", kc)
+		x.step("Ti", "4·(τc + θ)", ti)
+	}
+	x.step("P", "Kc", kc)
+	x.step("Ki", "Kc / Ti", kc/ti)
+	x.Assumptions = append(x.Assumptions,
+		"la constante de temps en boucle fermée τc est prise égale au retard, le compromis recommandé entre rapidité et robustesse (marge de gain d'environ 3)",
+		"régulateur PI parallèle, sans action dérivée")
+	if ti < T {
+		x.Assumptions = append(x.Assumptions, "T dépasse 4·(τc + θ) : Ti est raccourci pour rejeter les perturbations de charge en entrée du procédé")
+	}
+	x.within(0, 0, "la règle s'applique quel que soit θ/T ; au-delà de 1 le retard domine et la réponse est lente par construction")
+	return Tuning{Rule: "SIMC (τc = θ)", P: short(kc), Ki: short(kc / ti), Explanation: x}
+}
+
+// zieglerNichols returns the tuning of the Ziegler-Nichols reaction curve
+// method for the same plants as simc, a being the intercept K·theta/T of
+// the tangent, or K·theta for the integrator: the PID Kc = 1.2 / a,
+// Ti = 2·theta, Td = theta/2, or without derivative the PI Kc = 0.9 / a,
+// Ti = 3.33·theta.
+func zieglerNichols(K, T, theta float64, derivative bool) Tuning {
+	x := explain(K, T, theta)
+	a := K * theta
+	if T > 0 {
+		a /= T
+		x.step("a", "K·θ / T", a)
+		x.within(0.1, 1, "la méthode de la courbe de réaction vise les procédés où θ/T est compris entre 0,1 et 1 : en deçà les gains sont excessifs, au-delà le retard domine et la réponse oscille")
+	} else {
+		x.step("a", "K·θ", a)
+		x.within(0, 1, "procédé intégrateur : la pente K de la courbe de réaction remplace K / T")
+	}
+	x.Assumptions = append(x.Assumptions,
+		"la réponse visée s'amortit d'un quart à chaque période : rapide mais oscillante et peu robuste aux erreurs de modèle",
+		"le modèle est la tangente au point d'inflexion de la réponse indicielle")
+	if !derivative {
+		kc := 0.9 / a
+		x.step("Kc", "0,9 / a", kc)
+		x.step("Ti", "3,33·θ", 3.33*theta)
+		x.step("P", "Kc", kc)
+		x.step("Ki", "Kc / Ti", kc/(3.33*theta))
+		return Tuning{Rule: "Ziegler-Nichols PI (courbe de réaction)", P: short(kc), Ki: short(kc / (3.33 * theta)), Explanation: x}
+	}
+	kc := 1.2 / a
+	x.step("Kc", "1,2 / a", kc)
+	x.step("Ti", "2·θ", 2*theta)
+	x.step("Td", "θ / 2", theta/2)
+	x.step("P", "Kc", kc)
+	x.step("Ki", "Kc / Ti", kc/(2*theta))
+	x.step("Kd", "Kc·Td", kc*theta/2)
+	x.Assumptions = append(x.Assumptions, "la dérivée agit sur l'erreur sans filtre : un bruit de mesure passe amplifié par Kd sur la commande")
+	return Tuning{Rule: "Ziegler-Nichols PID (courbe de réaction)", P: short(kc), Ki: short(kc / (2 * theta)), Kd: short(kc * theta / 2), Explanation: x}
+}
+
+// examplePresets returns the library of example plants, by difficulty
+// tier, each run with the first of its reference tunings. The FOPDT plants
+// have a unit gain and a total time constant T + theta of 11 s.
+func examplePresets() []Preset {
+
+	example := func(tier int, name, description string, ss *StateSpace, horizon float64, tunings ...Tuning) Preset {
+		sc := Scenario{
+			Sp: 1, K: 1, Tau: 1, Dt: 0.01, N: float64(int(horizon / 0.01)),
+			Plant: PlantStateSpace, StateSpace: ss,
+			P: tunings[0].P, Ki: tunings[0].Ki, Kd: tunings[0].Kd,
+			Tuning: &tunings[0],
+		}
+		return Preset{Name: name, Description: description, Tier: tier, Tunings: tunings, Scenario: sc}
+	}
+	fopdt := func(tier int, name, label string, T, theta float64) Preset {
+		description := fmt.Sprintf("%s (premier ordre retardé 1·e^(-%g s) / (1 + %g s), θ/T = %g)", label, theta, T, theta/T)
+		return example(tier, name, description, delayedPlant(1, T, theta), 8*(T+theta), simc(1, T, theta), zieglerNichols(1, T, theta, true))
+	}
+
+	// The inverse response is tuned as the FOPDT of the half rule: the
+	// zero and half the smallest lag count as dead time.
+	const T1, T2, beta = 5.0, 1.0, 2.0
+	inverseT, inverseTheta := T1+T2/2, beta+T2/2
+
+	return []Preset{
+		fopdt(TierLagDominant, "example-lag-dominant", "Procédé dominé par sa constante de temps, le plus facile à régler", 10, 1),
+		fopdt(TierBalanced, "example-balanced", "Procédé équilibré, retard pur et constante de temps comparables", 5.5, 5.5),
+		fopdt(TierDeadTimeDominant, "example-dead-time-dominant", "Procédé dominé par le retard pur : le régulateur ne voit l'effet de sa commande qu'après θ", 1, 10),
+		example(TierInverseResponse, "example-inverse-response",
+			fmt.Sprintf("Réponse inverse 1·(1 - %g s) / ((1 + %g s)(1 + %g s)) : la mesure part d'abord dans le mauvais sens, comme le niveau d'un ballon de chaudière", beta, T1, T2),
+			inverseResponsePlant(1, T1, T2, beta), 60,
+			// The derivative of the PID rule amplifies the inverse response
+			// until the loop diverges.
+			simc(1, inverseT, inverseTheta), zieglerNichols(1, inverseT, inverseTheta, false)),
+		example(TierIntegrating, "example-integrating",
+			"Procédé intégrateur retardé 0,1·e^(-2 s) / s : sans équilibre naturel, toute erreur de commande fait dériver la mesure",
+			delayedPlant(0.1, 0, 2), 100,
+			simc(0.1, 0, 2), zieglerNichols(0.1, 0, 2, true)),
+	}
+}
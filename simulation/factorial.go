@@ -0,0 +1,168 @@
+package simulation
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"slices"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// FactorialMetrics are the metrics of Scenario.Factorial by default.
+var FactorialMetrics = []string{"iae", "overshoot", "settling", "effort", "umax"}
+
+// Factorial is a full two-level factorial experiment on the non-idealities
+// of a scenario: every combination of them switched on or off, run with
+// the same tuning, and the main effect of each on the metrics, ranked as
+// in an analysis of variance by the share of the variance of the metric
+// it explains.
+type Factorial struct {
+	NonIdealities []string `json:"nonIdealities"`
+	Metrics       []string `json:"metrics"`
+	// Runs are the 2^k combinations, from the ideal plant to the
+	// scenario itself.
+	Runs []FactorialRun `json:"runs"`
+	Rows []FactorialRow `json:"rows"`
+	// Priorities rank the non-idealities by the largest share of the
+	// variance of a metric they degrade: the first is the one to model
+	// first.
+	Priorities []Priority `json:"priorities"`
+}
+
+// FactorialRun is one combination: the non-idealities switched on and the
+// metrics of its run, following Factorial.Metrics.
+type FactorialRun struct {
+	On     []string  `json:"on"`
+	Values []float64 `json:"values"`
+}
+
+// FactorialRow is the analysis of one metric, its slices following
+// Factorial.NonIdealities.
+type FactorialRow struct {
+	Metric string `json:"metric"`
+	// Effects are the main effects: the mean of the metric over the runs
+	// with the non-ideality on, minus the mean with it off. Every metric
+	// being better lower, a positive effect is a degradation.
+	Effects []float64 `json:"effects"`
+	// Shares are the fractions of the variance of the metric over the
+	// runs explained by each main effect, the rest coming from their
+	// interactions; zero when the metric does not vary.
+	Shares []float64 `json:"shares"`
+	// Worst is the non-ideality degrading the metric most, empty when
+	// none degrades it.
+	Worst string `json:"worst,omitempty"`
+}
+
+// Priority is a non-ideality with the metric it degrades most, by share
+// of its variance; Metric is empty when it degrades none.
+type Priority struct {
+	NonIdeality string  `json:"nonIdeality"`
+	Metric      string  `json:"metric,omitempty"`
+	Effect      float64 `json:"effect"`
+	Share       float64 `json:"share"`
+}
+
+// FactorialRuns returns the number of runs of Factorial for the scenario,
+// to weigh its cost.
+func (sc Scenario) FactorialRuns() int {
+	_, names := sc.Ideal()
+	return 1 << len(names)
+}
+
+// Factorial runs every combination of the non-idealities of the scenario,
+// see Ideal, and analyses the metrics, FactorialMetrics when none are
+// given.
+func (sc Scenario) Factorial(metrics []string) (Factorial, error) {
+
+	if len(metrics) == 0 {
+		metrics = FactorialMetrics
+	}
+	for _, name := range metrics {
+		if _, err := (Metrics{}).Get(name); err != nil {
+			return Factorial{}, err
+		}
+	}
+	_, names := sc.Ideal()
+	if len(names) == 0 {
+		return Factorial{}, errors.New("le scénario n'a aucune non-idéalité à comparer : bruit, limites de sortie ou retard")
+	}
+
+	f := Factorial{NonIdealities: names, Metrics: metrics}
+	n := 1 << len(names)
+	for k := range n {
+		run := FactorialRun{On: []string{}, Values: make([]float64, len(metrics))}
+		for j, name := range names {
+			if k&(1<<j) != 0 {
+				run.On = append(run.On, name)
+			}
+		}
+		m := sc.keeping(func(name string) bool { return slices.Contains(run.On, name) }).Run().Metrics
+		for i, name := range metrics {
+			run.Values[i], _ = m.Get(name)
+		}
+		f.Runs = append(f.Runs, run)
+	}
+
+	for i, metric := range metrics {
+		row := FactorialRow{Metric: metric, Effects: make([]float64, len(names)), Shares: make([]float64, len(names))}
+		mean := 0.0
+		for _, run := range f.Runs {
+			mean += run.Values[i] / float64(n)
+		}
+		total := 0.0
+		for _, run := range f.Runs {
+			total += float64((run.Values[i] - mean) * (run.Values[i] - mean))
+		}
+		worst := 0.0
+		for j, name := range names {
+			for k, run := range f.Runs {
+				if k&(1<<j) != 0 {
+					row.Effects[j] += run.Values[i] / float64(n/2)
+				} else {
+					row.Effects[j] -= run.Values[i] / float64(n/2)
+				}
+			}
+			// The sum of squares of a main effect of a 2^k design is
+			// N·effect²/4.
+			if total > 0 {
+				row.Shares[j] = math.Min(float64(float64(n)*row.Effects[j]*row.Effects[j])/4/total, 1)
+			}
+			if row.Effects[j] > worst {
+				worst, row.Worst = row.Effects[j], name
+			}
+		}
+		f.Rows = append(f.Rows, row)
+	}
+
+	for j, name := range names {
+		p := Priority{NonIdeality: name}
+		for _, row := range f.Rows {
+			if row.Effects[j] > 0 && (p.Metric == "" || row.Shares[j] > p.Share) {
+				p.Metric, p.Effect, p.Share = row.Metric, row.Effects[j], row.Shares[j]
+			}
+		}
+		f.Priorities = append(f.Priorities, p)
+	}
+	slices.SortStableFunc(f.Priorities, func(a, b Priority) int { return cmp.Compare(b.Share, a.Share) })
+	return f, nil
+}
+
+// Rounded returns a copy of f rounded for output, see numfmt.
+func (f Factorial) Rounded() Factorial {
+	out := Factorial{NonIdealities: f.NonIdealities, Metrics: f.Metrics}
+	for _, run := range f.Runs {
+		run.Values = numfmt.Series(run.Values)
+		out.Runs = append(out.Runs, run)
+	}
+	for _, row := range f.Rows {
+		row.Effects = numfmt.Series(row.Effects)
+		row.Shares = numfmt.Series(row.Shares)
+		out.Rows = append(out.Rows, row)
+	}
+	for _, p := range f.Priorities {
+		p.Effect, p.Share = numfmt.Round(p.Effect), numfmt.Round(p.Share)
+		out.Priorities = append(out.Priorities, p)
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package simulation
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata/golden")
+
+func ptr(v float64) *float64 { return &v }
+
+// goldenScenarios are run by TestGolden, whose outputs must be the same
+// bytes on every OS and architecture.
+var goldenScenarios = map[string]Scenario{
+	"first-order": {Sp: 10, Tau: 1, K: 1, P: 5, Ki: 10, Dt: 0.01, N: 300},
+	"dead-time": {Sp: 1, Tau: 2, K: 1.5, P: 1.2, Ki: 0.8, Kd: 0.1, Dt: 0.02, N: 400,
+		Theta: 0.31, Limits: Limits{UMin: ptr(0), UMax: ptr(1.5), BackCalculation: ptr(1)}},
+	"unstable": {Sp: 1, Tau: 1, K: -1, P: 50, Ki: 100, Dt: 0.01, N: 1500},
+}
+
+// TestGolden compares the JSON, CSV and SVG outputs of goldenScenarios
+// with testdata/golden byte for byte. Run go test -run TestGolden -update
+// after a deliberate change of the outputs.
+func TestGolden(t *testing.T) {
+
+	for name, sc := range goldenScenarios {
+		t.Run(name, func(t *testing.T) {
+
+			res := Simulation(sc).Rounded()
+
+			b, _, err := numfmt.MarshalJSON(res, numfmt.NonFiniteString)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name+".json", b)
+
+			var csv bytes.Buffer
+			if err := res.WriteCSV(&csv, numfmt.CSV{}); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name+".csv", csv.Bytes())
+
+			var svg bytes.Buffer
+			if err := res.WritePlot(&svg, "svg", PlotOptions{Backend: "svg"}); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name+".svg", svg.Bytes())
+		})
+	}
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file, run go test -run TestGolden -update if the change is deliberate", name)
+	}
+}
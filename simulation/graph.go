@@ -2,74 +2,158 @@ package simulation
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg"
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
 )
 
-func MultipleLine(X []float64, Ys [][]float64, name string) {
+// Size of the plots, in points.
+const (
+	plotWidth  = 576
+	plotHeight = 288
+)
+
+func MultipleLine(X []float64, Ys [][]float64, name string) error {
 
 	for _, Y := range Ys {
 		if len(X) != len(Y) {
-			fmt.Errorf("Erreur dans le tracé, X et Y ne sont pas de la même taille")
-			return
+			return fmt.Errorf("Erreur dans le tracé, X et Y ne sont pas de la même taille")
 		}
 	}
 
-	p := plot.New()
-
+	f := chart.Figure{Title: "Plot des données X et Y", XLabel: "X", YLabel: "Y"}
 	for _, Y := range Ys {
-		points := make(plotter.XYs, len(X))
-		for i := range X {
-			points[i].X = float64(X[i])
-			points[i].Y = Y[i]
-		}
+		f.Series = append(f.Series, chart.Series{X: numfmt.Series(X), Y: numfmt.Series(Y)})
+	}
+	return save(name, f, "")
+}
 
-		line, err := plotter.NewLine(points)
-		if err != nil {
-			panic(err)
-		}
+func Line(X []float64, Y []float64, name string) error {
 
-		p.Add(line)
+	if len(X) != len(Y) {
+		return fmt.Errorf("Erreur dans le tracé, X et Y ne sont pas de la même taille")
 	}
 
-	p.Title.Text = "Plot des données X et Y"
-	p.X.Label.Text = "X"
-	p.Y.Label.Text = "Y"
+	f := chart.Figure{Title: "Plot des données X et Y", XLabel: "X", YLabel: "Y"}
+	f.Series = append(f.Series, chart.Series{X: numfmt.Series(X), Y: numfmt.Series(Y)})
+	return save(name, f, "")
+}
 
-	if err := p.Save(8*vg.Inch, 4*vg.Inch, name); err != nil {
-		panic(err)
+// save draws f with the backend to the file name, whose extension gives
+// the format.
+func save(name string, f chart.Figure, backend string) error {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := chart.Render(file, f, backend, format, plotWidth, plotHeight); err != nil {
+		file.Close()
+		return err
 	}
+	return file.Close()
 }
 
-func Line(X []float64, Y []float64, name string) {
+// PlotOptions are the presentation options of the plots of a result.
+type PlotOptions struct {
+	// Locale is the name of one of the Locales, LocaleFrench when empty.
+	Locale string `json:"locale,omitempty"`
+	// Theme is the name of one of the Themes, ThemeLight when empty. The
+	// colors and sizes set below replace those of the theme.
+	Theme       string  `json:"theme,omitempty"`
+	Background  string  `json:"background,omitempty"`
+	Foreground  string  `json:"foreground,omitempty"`
+	Setpoint    string  `json:"setpoint,omitempty"`
+	Measurement string  `json:"measurement,omitempty"`
+	FontSize    float64 `json:"fontSize,omitempty"`
+	LineWidth   float64 `json:"lineWidth,omitempty"`
+	// Backend is the name of one of the chart backends, see chart.Find.
+	Backend string `json:"backend,omitempty"`
+}
 
-	if len(X) != len(Y) {
-		fmt.Errorf("Erreur dans le tracé, X et Y ne sont pas de la même taille")
-		return
+// Check reports the first invalid option.
+func (opts PlotOptions) Check() error {
+	if opts.Backend != "" && chart.Formats(opts.Backend) == nil {
+		return fmt.Errorf("moteur de tracé inconnu %q, attendu l'un de %s", opts.Backend, strings.Join(chart.Backends(), ", "))
 	}
+	_, _, err := opts.style()
+	return err
+}
 
-	p := plot.New()
+// CheckFormat reports whether the backend of the options draws the format,
+// "gif" standing for the images of animations.
+func (opts PlotOptions) CheckFormat(format string) error {
+	_, err := chart.Find(opts.Backend, format)
+	return err
+}
+
+// Render draws f to w in the format with the backend of the options, at
+// the size of the plots of results.
+func (opts PlotOptions) Render(w io.Writer, f chart.Figure, format string) error {
+	return chart.Render(w, f, opts.Backend, format, plotWidth, plotHeight)
+}
 
-	points := make(plotter.XYs, len(X))
-	for i := range X {
-		points[i].X = float64(X[i])
-		points[i].Y = Y[i]
+// Plot draws the setpoint and the measurement of a result to the file
+// name, whose extension gives the format.
+func (r Result) Plot(name string, opts PlotOptions) error {
+	f, err := r.Figure(opts)
+	if err != nil {
+		return err
 	}
+	return save(name, f, opts.Backend)
+}
 
-	line, err := plotter.NewLine(points)
+// WritePlot draws the setpoint and the measurement of a result to w in the
+// format, "svg" or "png" for instance.
+func (r Result) WritePlot(w io.Writer, format string, opts PlotOptions) error {
+	f, err := r.Figure(opts)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	return opts.Render(w, f, format)
+}
 
-	p.Add(line)
+// style returns the locale and the theme of the options.
+func (opts PlotOptions) style() (Locale, Theme, error) {
+	loc, err := FindLocale(opts.Locale)
+	if err != nil {
+		return loc, Theme{}, err
+	}
+	theme, err := opts.theme()
+	return loc, theme, err
+}
 
-	p.Title.Text = "Plot des données X et Y"
-	p.X.Label.Text = "X"
-	p.Y.Label.Text = "Y"
+// Figure returns the plot of the setpoint and the measurement of a
+// result, over its bands, for callers adding series of their own before
+// drawing it with PlotOptions.Render.
+func (r Result) Figure(opts PlotOptions) (chart.Figure, error) {
 
-	if err := p.Save(8*vg.Inch, 4*vg.Inch, name); err != nil {
-		panic(err)
+	loc, theme, err := opts.style()
+	if err != nil {
+		return chart.Figure{}, err
 	}
+	for _, Y := range [][]float64{r.SP, r.PV} {
+		if len(Y) != len(r.Time) {
+			return chart.Figure{}, fmt.Errorf("Erreur dans le tracé, X et Y ne sont pas de la même taille")
+		}
+	}
+
+	T := numfmt.Series(r.Time)
+	return chart.Figure{
+		Title:        loc.Title,
+		XLabel:       loc.Time,
+		YLabel:       loc.Value,
+		Style:        theme.style(),
+		DecimalComma: loc.DecimalComma,
+		Legend:       true,
+		// The bands go first, under the setpoint and the measurement.
+		Series: append(bandSeries(r.Bands, T),
+			chart.Series{Label: loc.Setpoint, X: T, Y: numfmt.Series(r.SP), Color: theme.Setpoint, Width: theme.LineWidth, Dashes: []float64{4 * theme.LineWidth, 3 * theme.LineWidth}},
+			chart.Series{Label: loc.Measurement, X: T, Y: numfmt.Series(r.PV), Color: theme.Measurement, Width: theme.LineWidth},
+		),
+	}, nil
 }
@@ -0,0 +1,205 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Grid event types accepted in GridEvent.Type.
+const (
+	GridVoltageDip    = "voltage-dip"
+	GridFrequencyRamp = "frequency-ramp"
+	GridPhaseJump     = "phase-jump"
+)
+
+// GridEvent is a disturbance of the grid behind the POC, injected into a
+// reactive power scenario to test how the control rides through it:
+//
+//   - a voltage dip lowers the grid voltage by Depth of UPoc, a swell when
+//     negative, during Duration, then recovers linearly in Recovery;
+//   - a frequency ramp moves the frequency by Rate Hz/s during Duration and
+//     holds it, changing the reactances of the system;
+//   - a phase jump shifts the grid voltage by Angle degrees, which the
+//     inverters catch up with the time constant Duration, their powers
+//     being rotated by the remaining angle meanwhile.
+//
+// Preset names one of the GridEvents, whose parameters are then used, At
+// only being taken from the scenario.
+type GridEvent struct {
+	Preset   string  `json:"preset,omitempty"`
+	Type     string  `json:"type,omitempty"`
+	At       float64 `json:"at"`
+	Duration float64 `json:"duration,omitempty"`
+	Depth    float64 `json:"depth,omitempty"`
+	Recovery float64 `json:"recovery,omitempty"`
+	Rate     float64 `json:"rate,omitempty"`
+	Angle    float64 `json:"angle,omitempty"`
+}
+
+// GridEventPreset is a ready-made grid event, selectable by name.
+type GridEventPreset struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Event       GridEvent `json:"event"`
+}
+
+// GridEvents lists the predefined grid events.
+var GridEvents = []GridEventPreset{
+	{
+		Name:        "dip-50-150ms",
+		Description: "Creux de tension de 50 % pendant 150 ms",
+		Event:       GridEvent{Type: GridVoltageDip, Depth: 0.5, Duration: 0.15},
+	},
+	{
+		Name:        "dip-85-250ms",
+		Description: "Creux de tension de 85 % pendant 250 ms, puis retour à la tension nominale en 1,5 s, le gabarit usuel de tenue aux creux",
+		Event:       GridEvent{Type: GridVoltageDip, Depth: 0.85, Duration: 0.25, Recovery: 1.5},
+	},
+	{
+		Name:        "swell-10-1s",
+		Description: "Surtension de 10 % pendant 1 s",
+		Event:       GridEvent{Type: GridVoltageDip, Depth: -0.1, Duration: 1},
+	},
+	{
+		Name:        "rocof-1hz",
+		Description: "Baisse de fréquence de 1 Hz/s pendant 1 s",
+		Event:       GridEvent{Type: GridFrequencyRamp, Rate: -1, Duration: 1},
+	},
+	{
+		Name:        "rocof-2hz-500ms",
+		Description: "Hausse de fréquence de 2 Hz/s pendant 500 ms",
+		Event:       GridEvent{Type: GridFrequencyRamp, Rate: 2, Duration: 0.5},
+	},
+	{
+		Name:        "phase-jump-20",
+		Description: "Saut de phase de 20°, rattrapé par les onduleurs en 100 ms",
+		Event:       GridEvent{Type: GridPhaseJump, Angle: 20, Duration: 0.1},
+	},
+	{
+		Name:        "phase-jump-60",
+		Description: "Saut de phase de 60°, rattrapé par les onduleurs en 100 ms",
+		Event:       GridEvent{Type: GridPhaseJump, Angle: 60, Duration: 0.1},
+	},
+}
+
+// FindGridEvent returns the predefined grid event with the given name.
+func FindGridEvent(name string) (GridEventPreset, bool) {
+	for _, p := range GridEvents {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return GridEventPreset{}, false
+}
+
+// GridEventSchema describes a grid event of the electrical system.
+var GridEventSchema = func() *schema.Schema {
+	names := make([]any, len(GridEvents))
+	for i, p := range GridEvents {
+		names[i] = p.Name
+	}
+	return schema.Object(map[string]*schema.Schema{
+		"preset":   schema.String("Événement prédéfini, dont les paramètres remplacent ceux donnés hormis at").OneOf(names...),
+		"type":     schema.String("Type d'événement").OneOf(GridVoltageDip, GridFrequencyRamp, GridPhaseJump),
+		"at":       schema.Number("Instant de l'événement (s)").Min(0),
+		"duration": schema.Number("Durée du creux ou de la rampe, constante de temps du rattrapage d'un saut de phase (s)").Min(0),
+		"depth":    schema.Number("Profondeur du creux en fraction de UPoc, négative pour une surtension").Max(1),
+		"recovery": schema.Number("Durée du retour linéaire à la tension nominale après le creux (s)").Min(0),
+		"rate":     schema.Number("Vitesse de variation de la fréquence (Hz/s)"),
+		"angle":    schema.Number("Saut de phase (degrés)"),
+	}, "at")
+}()
+
+// resolved returns the event with the parameters of its preset.
+func (g GridEvent) resolved() GridEvent {
+	if p, ok := FindGridEvent(g.Preset); ok {
+		at := g.At
+		g = p.Event
+		g.Preset, g.At = p.Name, at
+	}
+	return g
+}
+
+func checkGridEvents(events []GridEvent) []schema.Error {
+	var errs []schema.Error
+	for i, g := range events {
+		if g.Preset == "" && g.Type == "" {
+			errs = append(errs, schema.Error{Path: fmt.Sprintf("/grid/%d", i), Message: "type ou preset requis"})
+		}
+	}
+	return errs
+}
+
+// gridState is the grid behind the POC at some time: its voltage per unit
+// of UPoc, its frequency, and the angle by which the inverters lag a phase
+// jump, in radians.
+type gridState struct {
+	voltage, frequency, angle float64
+}
+
+// grid returns the state of the grid at time t, the effects of the events
+// adding up.
+func (e *Electrical) grid(t float64) gridState {
+
+	s := gridState{voltage: 1, frequency: e.F}
+	for _, g := range e.Grid {
+		g = g.resolved()
+		if t < g.At {
+			continue
+		}
+		dt := t - g.At
+		switch g.Type {
+		case GridVoltageDip:
+			switch {
+			case dt < g.Duration:
+				s.voltage -= g.Depth
+			case dt < g.Duration+g.Recovery:
+				s.voltage -= g.Depth * (1 - (dt-g.Duration)/g.Recovery)
+			}
+		case GridFrequencyRamp:
+			s.frequency += float64(g.Rate * math.Min(dt, g.Duration))
+		case GridPhaseJump:
+			angle := g.Angle * math.Pi / 180
+			if g.Duration > 0 {
+				angle *= math.Exp(-dt / g.Duration)
+			} else {
+				angle = 0
+			}
+			s.angle += angle
+		}
+	}
+	return s
+}
+
+// gridEvents returns the events marking the start of the grid events.
+func gridEvents(events []GridEvent) []Event {
+	var list []Event
+	for _, g := range events {
+		g = g.resolved()
+		name := g.Type
+		if g.Preset != "" {
+			name = g.Preset
+		}
+		list = append(list, Event{T: g.At, Type: EventGrid, Message: fmt.Sprintf("Événement réseau %s", name)})
+	}
+	return list
+}
+
+// at returns the system at the frequency f, its reactances scaled from its
+// frequency F, the blocks copied.
+func (sys ElectricalSystem) at(f float64) ElectricalSystem {
+	if f == sys.F {
+		return sys
+	}
+	ratio := f / sys.F
+	sys.Blocks = slices.Clone(sys.Blocks)
+	for i := range sys.Blocks {
+		sys.Blocks[i].X *= ratio
+		sys.Blocks[i].B *= ratio
+	}
+	sys.F = f
+	return sys
+}
@@ -0,0 +1,94 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Cooling is the cooling side of a PlantHeatCool plant: a negative output
+// drives the measurement through the gain K and time constant Tau, usually
+// different from those of heating, as with an electric heater and a water
+// cooler. A symmetric tuning is then too slow on one side or oscillates on
+// the other.
+type Cooling struct {
+	K   float64 `json:"K"`
+	Tau float64 `json:"Tau"`
+}
+
+// CoolingSchema describes the "cooling" member of a scenario.
+var CoolingSchema = schema.Object(map[string]*schema.Schema{
+	"K":   schema.Number("Gain en refroidissement, appliqué à une commande négative").WithDefault(1.0),
+	"Tau": schema.Number("Constante de temps en refroidissement").Above(0).WithDefault(1.0),
+}, "K", "Tau")
+
+// DirectionMetrics summarize one direction of a heating/cooling response:
+// the error while the measurement is on the side that direction corrects,
+// heating below the setpoint and cooling above it, and the output spent in
+// that direction.
+type DirectionMetrics struct {
+	IAE          float64 `json:"iae"`          // integral of |e| on that side of the setpoint
+	MaxDeviation float64 `json:"maxDeviation"` // largest |e| on that side
+	Effort       float64 `json:"effort"`       // integral of u² with u in that direction
+	Time         float64 `json:"time"`         // time with the output in that direction
+}
+
+// directionMetrics computes the heating and cooling metrics of a response,
+// with the conventions of computeMetrics.
+func directionMetrics(T, SP, Y, U []float64) (heating, cooling *DirectionMetrics) {
+
+	heating, cooling = &DirectionMetrics{}, &DirectionMetrics{}
+	if len(T) < 2 || len(SP) != len(T) || len(Y) != len(T) || len(U) != len(T) {
+		return heating, cooling
+	}
+	// The integrals of each direction, compensated, see kahanSum.
+	type sums struct{ iae, effort, time kahanSum }
+	var hs, cs sums
+	for i := 1; i < len(T); i++ {
+		dt := T[i] - T[i-1]
+		if e := SP[i] - Y[i]; e > 0 {
+			hs.iae.add(float64(e * dt))
+			heating.MaxDeviation = math.Max(heating.MaxDeviation, e)
+		} else if e < 0 {
+			cs.iae.add(float64(-e * dt))
+			cooling.MaxDeviation = math.Max(cooling.MaxDeviation, -e)
+		}
+		u := U[i-1]
+		if u > 0 {
+			hs.effort.add(float64(u * u * dt))
+			hs.time.add(dt)
+		} else if u < 0 {
+			cs.effort.add(float64(u * u * dt))
+			cs.time.add(dt)
+		}
+	}
+	heating.IAE, heating.Effort, heating.Time = hs.iae.value(), hs.effort.value(), hs.time.value()
+	cooling.IAE, cooling.Effort, cooling.Time = cs.iae.value(), cs.effort.value(), cs.time.value()
+	return heating, cooling
+}
+
+func (d *DirectionMetrics) rounded() *DirectionMetrics {
+	if d == nil {
+		return nil
+	}
+	return &DirectionMetrics{
+		IAE:          numfmt.Round(d.IAE),
+		MaxDeviation: numfmt.Round(d.MaxDeviation),
+		Effort:       numfmt.Round(d.Effort),
+		Time:         numfmt.Round(d.Time),
+	}
+}
+
+// worst returns the worst of d and o, metric by metric, for WorstCase.
+func (d *DirectionMetrics) worst(o *DirectionMetrics) *DirectionMetrics {
+	if d == nil || o == nil {
+		return d
+	}
+	return &DirectionMetrics{
+		IAE:          max(d.IAE, o.IAE),
+		MaxDeviation: max(d.MaxDeviation, o.MaxDeviation),
+		Effort:       max(d.Effort, o.Effort),
+		Time:         max(d.Time, o.Time),
+	}
+}
@@ -0,0 +1,134 @@
+package simulation
+
+import "github.com/Ivan69-tech/PIDControllerResponse/chart"
+
+// Non-idealities a scenario can carry on top of its ideal linear plant.
+const (
+	NonIdealNoise      = "noise"      // noise of the measurement, and of the inflow of a level
+	NonIdealSaturation = "saturation" // output limits uMin and uMax
+	NonIdealDelay      = "delay"      // dead time Theta of the plant
+)
+
+// nonIdealities remove each non-ideality from a scenario, telling whether
+// it had it, in the order of Dual.NonIdealities.
+var nonIdealities = []struct {
+	name   string
+	remove func(sc *Scenario) bool
+}{
+	{NonIdealNoise, func(sc *Scenario) bool {
+		had := sc.Noise != nil && sc.Noise.Std != 0
+		sc.Noise = nil
+		if sc.Level != nil && sc.Level.Noise != nil {
+			level := *sc.Level
+			had = had || level.Noise.Std != 0
+			level.Noise = nil
+			sc.Level = &level
+		}
+		return had
+	}},
+	{NonIdealSaturation, func(sc *Scenario) bool {
+		had := sc.UMin != nil || sc.UMax != nil
+		sc.UMin, sc.UMax = nil, nil
+		return had
+	}},
+	{NonIdealDelay, func(sc *Scenario) bool {
+		had := sc.Theta != 0
+		sc.Theta = 0
+		return had
+	}},
+}
+
+// Dual runs the same tuning on the ideal plant and with the
+// non-idealities of the scenario, for their impact to show on the overlay
+// of the two responses.
+type Dual struct {
+	// NonIdealities lists those of the scenario, which the ideal run
+	// leaves out.
+	NonIdealities []string `json:"nonIdealities"`
+	Ideal         Result   `json:"ideal"`
+	Actual        Result   `json:"actual"`
+	// Effects isolate each non-ideality when there are several: the ideal
+	// run with only that one put back.
+	Effects []Effect `json:"effects,omitempty"`
+}
+
+// Effect is the ideal run with the single non-ideality NonIdeality.
+type Effect struct {
+	NonIdeality string `json:"nonIdeality"`
+	Result
+}
+
+// Ideal returns the scenario without its non-idealities, and the names
+// of those it had.
+func (sc Scenario) Ideal() (Scenario, []string) {
+	names := []string{}
+	for _, n := range nonIdealities {
+		if n.remove(&sc) {
+			names = append(names, n.name)
+		}
+	}
+	return sc, names
+}
+
+// DualRuns returns the number of runs of Dual for the scenario, to weigh
+// its cost.
+func (sc Scenario) DualRuns() int {
+	_, names := sc.Ideal()
+	if len(names) > 1 {
+		return 2 + len(names)
+	}
+	return 2
+}
+
+// Dual runs the scenario and its ideal counterpart, see Ideal.
+func (sc Scenario) Dual() Dual {
+
+	ideal, names := sc.Ideal()
+	d := Dual{NonIdealities: names, Ideal: ideal.Run(), Actual: sc.Run()}
+	if len(names) < 2 {
+		return d
+	}
+	for _, name := range names {
+		only := sc.keeping(func(n string) bool { return n == name })
+		d.Effects = append(d.Effects, Effect{NonIdeality: name, Result: only.Run()})
+	}
+	return d
+}
+
+// keeping returns the scenario without the non-idealities for which keep
+// is false.
+func (sc Scenario) keeping(keep func(name string) bool) Scenario {
+	for _, n := range nonIdealities {
+		if !keep(n.name) {
+			n.remove(&sc)
+		}
+	}
+	return sc
+}
+
+// Rounded returns the runs rounded for output, see numfmt.
+func (d Dual) Rounded() Dual {
+	d.Ideal, d.Actual = d.Ideal.Rounded(), d.Actual.Rounded()
+	effects := make([]Effect, len(d.Effects))
+	for i, e := range d.Effects {
+		effects[i] = Effect{NonIdeality: e.NonIdeality, Result: e.Result.Rounded()}
+	}
+	d.Effects = effects
+	return d
+}
+
+// Figure overlays the ideal and the actual responses, and those of the
+// effects, see ComparisonPlot.
+func (d Dual) Figure(opts PlotOptions) (chart.Figure, error) {
+	loc, err := FindLocale(opts.Locale)
+	if err != nil {
+		return chart.Figure{}, err
+	}
+	labels := []string{loc.Ideal, loc.Actual}
+	results := []Result{d.Ideal, d.Actual}
+	for _, e := range d.Effects {
+		labels = append(labels, loc.Ideal+" + "+e.NonIdeality)
+		results = append(results, e.Result)
+	}
+	return ComparisonPlot(labels, results, opts)
+}
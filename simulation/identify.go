@@ -0,0 +1,112 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+)
+
+// FirstOrderModel is a first-order plant identified from loop data.
+type FirstOrderModel struct {
+	K      float64 `json:"K"`
+	Tau    float64 `json:"Tau"`
+	Offset float64 `json:"offset"` // PV reached with a zero controller output
+	R2     float64 `json:"r2"`     // coefficient of determination of the fit of dPV/dt
+}
+
+// IdentifyFirstOrder fits Tau·dy/dt = K·u − y + offset to the controller
+// output U and measurement Y sampled at times T, by least squares on the
+// finite differences of Y.
+func IdentifyFirstOrder(T, U, Y []float64) (FirstOrderModel, error) {
+
+	var m FirstOrderModel
+	if len(T) < 3 || len(U) != len(T) || len(Y) != len(T) {
+		return m, fmt.Errorf("séries de temps, de commande et de mesure de même longueur (au moins 3) nécessaires")
+	}
+
+	// Regress dy/dt on [u, y, 1]: dy/dt = a·u + b·y + c.
+	var A [3][3]float64
+	var rhs [3]float64
+	var ds []float64
+	var xs [][3]float64
+	for k := 0; k+1 < len(T); k++ {
+		dt := T[k+1] - T[k]
+		if dt <= 0 {
+			return m, fmt.Errorf("les temps doivent être strictement croissants (échantillon %d)", k+1)
+		}
+		d := (Y[k+1] - Y[k]) / dt
+		x := [3]float64{U[k], Y[k], 1}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				A[i][j] += x[i] * x[j]
+			}
+			rhs[i] += x[i] * d
+		}
+		ds = append(ds, d)
+		xs = append(xs, x)
+	}
+
+	coef, err := solve3(A, rhs)
+	if err != nil {
+		return m, fmt.Errorf("données insuffisamment excitées pour identifier le procédé : %w", err)
+	}
+	a, b, c := coef[0], coef[1], coef[2]
+	if b >= 0 {
+		return m, fmt.Errorf("le procédé identifié n'est pas stable (pôle %g ≥ 0)", -b)
+	}
+
+	m.Tau = -1 / b
+	m.K = a * m.Tau
+	m.Offset = c * m.Tau
+
+	var mean, ssTot, ssRes float64
+	for _, d := range ds {
+		mean += d
+	}
+	mean /= float64(len(ds))
+	for i, d := range ds {
+		fit := a*xs[i][0] + b*xs[i][1] + c
+		ssRes += (d - fit) * (d - fit)
+		ssTot += (d - mean) * (d - mean)
+	}
+	if ssTot > 0 {
+		m.R2 = 1 - ssRes/ssTot
+	}
+	return m, nil
+}
+
+// solve3 solves the 3×3 linear system by Gaussian elimination with partial
+// pivoting.
+func solve3(A [3][3]float64, b [3]float64) ([3]float64, error) {
+
+	for col := 0; col < 3; col++ {
+		pivot := col
+		for r := col + 1; r < 3; r++ {
+			if math.Abs(A[r][col]) > math.Abs(A[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(A[pivot][col]) < 1e-12 {
+			return b, fmt.Errorf("système singulier")
+		}
+		A[col], A[pivot] = A[pivot], A[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for r := col + 1; r < 3; r++ {
+			f := A[r][col] / A[col][col]
+			for c := col; c < 3; c++ {
+				A[r][c] -= f * A[col][c]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+
+	var x [3]float64
+	for r := 2; r >= 0; r-- {
+		x[r] = b[r]
+		for c := r + 1; c < 3; c++ {
+			x[r] -= A[r][c] * x[c]
+		}
+		x[r] /= A[r][r]
+	}
+	return x, nil
+}
@@ -0,0 +1,114 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+)
+
+// Industry loop classes accepted in IndustryProfile.Loop.
+const (
+	LoopFlow        = "flow"
+	LoopPressure    = "pressure"
+	LoopTemperature = "temperature"
+	LoopLevel       = "level"
+)
+
+// IndustryProfile is a typical loop of the process industry, with the
+// orders of magnitude met on site: the plant Gain·e^(-DeadTime·s) /
+// (1 + TimeConstant·s), or Gain·e^(-DeadTime·s) / s for a level, in units
+// of the measurement per percent of controller output, the noise of its
+// transmitter and the scan period of its controller, which is the time
+// step of the run. The outflow of a level draws the equivalent of 50 % of
+// output, which the inflow opened by the controller balances.
+type IndustryProfile struct {
+	Loop        string `json:"loop"`
+	Description string `json:"description"`
+	// Unit is the unit of the measurement, Gain being in Unit per %, and
+	// in Unit per second per % for a level.
+	Unit         string  `json:"unit"`
+	Gain         float64 `json:"gain"`
+	TimeConstant float64 `json:"timeConstant"`
+	DeadTime     float64 `json:"deadTime"`
+	// Noise is the standard deviation of the measurement noise, in Unit.
+	Noise      float64 `json:"noise"`
+	SampleTime float64 `json:"sampleTime"`
+	// Sp is the setpoint stepped to from rest, and Horizon the simulated
+	// duration, in seconds.
+	Sp      float64 `json:"Sp"`
+	Horizon float64 `json:"horizon"`
+}
+
+// IndustryProfiles lists the typical loops, from the fastest.
+var IndustryProfiles = []IndustryProfile{
+	{
+		Loop:        LoopFlow,
+		Description: "Débit de liquide par vanne de régulation : rapide, quasi sans retard, mesure bruitée par la turbulence",
+		Unit:        "m³/h", Gain: 1.2, TimeConstant: 2, DeadTime: 0.5,
+		Noise: 0.5, SampleTime: 0.1, Sp: 60, Horizon: 25,
+	},
+	{
+		Loop:        LoopPressure,
+		Description: "Pression de gaz d'un réservoir tampon : la capacité du volume domine, mesure peu bruitée",
+		Unit:        "bar", Gain: 0.08, TimeConstant: 10, DeadTime: 1,
+		Noise: 0.02, SampleTime: 0.1, Sp: 5, Horizon: 110,
+	},
+	{
+		Loop:        LoopTemperature,
+		Description: "Température d'un réacteur à double enveloppe : lente, avec le retard du transfert de chaleur et de la sonde",
+		Unit:        "°C", Gain: 0.8, TimeConstant: 300, DeadTime: 30,
+		Noise: 0.1, SampleTime: 1, Sp: 40, Horizon: 3300,
+	},
+	{
+		Loop:        LoopLevel,
+		Description: "Niveau d'un bac, intégrateur : la commande règle l'écart entre débit entrant et sortant, la surface du liquide agitée bruite la mesure",
+		Unit:        "%", Gain: 0.01, DeadTime: 3,
+		Noise: 0.3, SampleTime: 0.5, Sp: 50, Horizon: 300,
+	},
+}
+
+// Tuning returns the SIMC PI tuning of the profile, see simc.
+func (p IndustryProfile) Tuning() Tuning {
+	return simc(p.Gain, p.TimeConstant, p.DeadTime)
+}
+
+// Scenario returns the run of the profile: a setpoint step from rest under
+// its tuning, the output limited to 0–100 % with conditional integration,
+// the dead time approximated as in the example library.
+func (p IndustryProfile) Scenario() Scenario {
+	t := p.Tuning()
+	uMin, uMax := 0.0, 100.0
+	sc := Scenario{
+		Sp: p.Sp, K: 1, Tau: 1,
+		P: t.P, Ki: t.Ki, Kd: t.Kd, Tuning: &t,
+		Dt: p.SampleTime, N: math.Round(p.Horizon / p.SampleTime),
+		Plant: PlantStateSpace, StateSpace: delayedPlant(p.Gain, p.TimeConstant, p.DeadTime),
+		Noise:  &Noise{Std: p.Noise, Seed: 1},
+		Limits: Limits{UMin: &uMin, UMax: &uMax, ConditionalIntegration: true},
+	}
+	if p.TimeConstant == 0 {
+		sc.Drive = &Drive{Disturbance: &Profile{Points: []Breakpoint{{T: 0, Value: -50}}}}
+	}
+	return sc
+}
+
+// industryPresets returns the preset of every profile, named after its
+// loop class.
+func industryPresets() []Preset {
+	var presets []Preset
+	for _, p := range IndustryProfiles {
+		profile := p
+		description := fmt.Sprintf("%s ; gain %g %s/%%, constante de temps %g s, ", p.Description, p.Gain, p.Unit, p.TimeConstant)
+		if p.TimeConstant == 0 {
+			description = fmt.Sprintf("%s ; gain %g %s/s par %% de commande, ", p.Description, p.Gain, p.Unit)
+		}
+		description += fmt.Sprintf("retard %g s, bruit %g %s, période d'échantillonnage %g s", p.DeadTime, p.Noise, p.Unit, p.SampleTime)
+		presets = append(presets, Preset{
+			Name:        "industry-" + p.Loop,
+			Description: description,
+			Tunings:     []Tuning{p.Tuning()},
+			Industry:    &profile,
+			Scenario:    p.Scenario(),
+		})
+	}
+	return presets
+}
@@ -0,0 +1,28 @@
+package simulation
+
+import "math"
+
+// kahanSum is a sum compensated for the rounding of its additions, in the
+// Neumaier form of Kahan summation: the low-order bits every addition
+// drops are kept apart and added back, so that hours of simulated time at
+// small steps, millions of terms much smaller than the sum, stay exact to
+// the last digits instead of drifting.
+type kahanSum struct {
+	sum, c float64
+}
+
+// add adds x to the sum.
+func (k *kahanSum) add(x float64) {
+	t := k.sum + x
+	if math.Abs(k.sum) >= math.Abs(x) {
+		k.c += (k.sum - t) + x
+	} else {
+		k.c += (x - t) + k.sum
+	}
+	k.sum = t
+}
+
+// value returns the compensated sum.
+func (k kahanSum) value() float64 {
+	return k.sum + k.c
+}
@@ -0,0 +1,126 @@
+package simulation
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Level is a tank whose level is held by the outflow valve, opened by the
+// controller, against the inflow, the disturbance. The tank integrates:
+// dh/dt = (inflow - K·u) / Tau, Tau being the cross-section and K the
+// outflow at full output. Opening the valve lowers the level, so the
+// controller is direct-acting, with negative gains.
+type Level struct {
+	// Initial is the level at t = 0.
+	Initial float64 `json:"initial"`
+	// Inflow is the nominal inflow; Profile, when set, replaces it by a
+	// scheduled inflow.
+	Inflow  float64  `json:"inflow"`
+	Profile *Profile `json:"profile,omitempty"`
+	// Noise adds a random deviation to the inflow.
+	Noise *InflowNoise `json:"noise,omitempty"`
+}
+
+// InflowNoise is a random inflow deviation: white noise filtered by a
+// first-order lag of time constant Tau, of standard deviation Std. The same
+// seed gives the same run.
+type InflowNoise struct {
+	Std  float64 `json:"std"`
+	Tau  float64 `json:"Tau"`
+	Seed uint64  `json:"seed"`
+}
+
+// LevelSchema describes the "level" member of a scenario.
+var LevelSchema = schema.Object(map[string]*schema.Schema{
+	"initial": schema.Number("Niveau initial").WithDefault(0.0),
+	"inflow":  schema.Number("Débit entrant nominal").Min(0).WithDefault(1.0),
+	"profile": profileSchema("Débit entrant programmé, remplace inflow"),
+	"noise": schema.Object(map[string]*schema.Schema{
+		"std":  schema.Number("Écart type de la perturbation aléatoire du débit entrant").Min(0),
+		"Tau":  schema.Number("Constante de temps du filtre de la perturbation").Above(0).WithDefault(10.0),
+		"seed": schema.Integer("Graine du générateur aléatoire").Min(0).WithDefault(1),
+	}, "std", "Tau"),
+}, "inflow")
+
+// levelRun is the inflow of a level plant during a run.
+type levelRun struct {
+	level *Level
+	rng   *rand.Rand
+	noise float64
+	// q is the inflow at the current time.
+	q float64
+}
+
+func newLevelRun(l *Level) *levelRun {
+	r := &levelRun{level: l}
+	if l.Noise != nil {
+		r.rng = rand.New(rand.NewPCG(l.Noise.Seed, l.Noise.Seed))
+	}
+	r.q = r.inflow(0)
+	return r
+}
+
+// advance moves the inflow to time t, dt after the current one.
+func (r *levelRun) advance(t, dt float64) {
+	if n := r.level.Noise; n != nil && n.Std > 0 {
+		a := math.Exp(-dt / n.Tau)
+		r.noise = float64(a*r.noise) + float64(float64(n.Std*math.Sqrt(1-float64(a*a)))*r.rng.NormFloat64())
+	}
+	r.q = r.inflow(t)
+}
+
+// inflow returns the inflow at time t, which cannot be negative.
+func (r *levelRun) inflow(t float64) float64 {
+	q := r.level.Inflow
+	if r.level.Profile != nil {
+		q = r.level.Profile.At(t)
+	}
+	return math.Max(0, q+r.noise)
+}
+
+// LevelMetrics summarize an averaging level control: how far the level
+// moved and how abruptly the outflow, passed on downstream, changed.
+type LevelMetrics struct {
+	MaxDeviation   float64 `json:"maxDeviation"`   // largest |setpoint - level|
+	MaxOutflowRate float64 `json:"maxOutflowRate"` // largest |d(K·u)/dt|
+}
+
+// levelMetrics computes the level metrics of a response of gain K.
+func levelMetrics(T, SP, Y, U []float64, K float64) *LevelMetrics {
+	m := &LevelMetrics{}
+	if len(T) < 2 || len(SP) != len(T) || len(Y) != len(T) || len(U) != len(T) {
+		return m
+	}
+	for i := range T {
+		m.MaxDeviation = math.Max(m.MaxDeviation, math.Abs(SP[i]-Y[i]))
+		if i > 0 {
+			rate := float64(K*(U[i]-U[i-1])) / (T[i] - T[i-1])
+			m.MaxOutflowRate = math.Max(m.MaxOutflowRate, math.Abs(rate))
+		}
+	}
+	return m
+}
+
+func (m *LevelMetrics) rounded() *LevelMetrics {
+	if m == nil {
+		return nil
+	}
+	return &LevelMetrics{
+		MaxDeviation:   numfmt.Round(m.MaxDeviation),
+		MaxOutflowRate: numfmt.Round(m.MaxOutflowRate),
+	}
+}
+
+// worst returns the worst of m and o, metric by metric, for WorstCase.
+func (m *LevelMetrics) worst(o *LevelMetrics) *LevelMetrics {
+	if m == nil || o == nil {
+		return m
+	}
+	return &LevelMetrics{
+		MaxDeviation:   max(m.MaxDeviation, o.MaxDeviation),
+		MaxOutflowRate: max(m.MaxOutflowRate, o.MaxOutflowRate),
+	}
+}
@@ -0,0 +1,115 @@
+package simulation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// Locale is the language of the plot labels and the decimal separator of
+// their tick labels.
+type Locale struct {
+	Title, Time, Value, Setpoint, Measurement string
+	// Labels of the phase-plane plot.
+	PhaseTitle, Error, ErrorRate string
+	// Labels of the deviation histogram.
+	HistogramTitle, Deviation, Count string
+	// Labels of the impedance scan.
+	ScanTitle, Frequency, Impedance, Resonance string
+	// Labels of the comparison report.
+	ComparisonTitle, Run, Metric, Best string
+	// Labels of the ideal and actual runs of a dual simulation.
+	Ideal, Actual string
+	// DecimalComma writes the tick labels "0,5" instead of "0.5".
+	DecimalComma bool
+}
+
+// Locale names accepted in PlotOptions.Locale.
+const (
+	LocaleFrench  = "fr"
+	LocaleEnglish = "en"
+)
+
+// Locales lists the available plot locales by name.
+var Locales = map[string]Locale{
+	LocaleFrench: {
+		Title:       "Réponse du régulateur PID",
+		Time:        "Temps (s)",
+		Value:       "Valeur",
+		Setpoint:    "Consigne",
+		Measurement: "Mesure",
+		PhaseTitle:  "Plan de phase de l'erreur",
+		Error:       "Erreur e",
+		ErrorRate:   "de/dt (1/s)",
+
+		HistogramTitle: "Distribution de l'écart à la consigne en régime établi",
+		Deviation:      "Écart mesure - consigne",
+		Count:          "Échantillons",
+
+		ScanTitle: "Impédance vue du POC",
+		Frequency: "Fréquence (Hz)",
+		Impedance: "|Z| (Ω)",
+		Resonance: "Résonances",
+
+		ComparisonTitle: "Comparaison des simulations",
+		Run:             "Simulation",
+		Metric:          "Indicateur",
+		Best:            "Meilleure valeur de chaque indicateur en gras",
+
+		Ideal:  "Procédé idéal",
+		Actual: "Avec non-idéalités",
+
+		DecimalComma: true,
+	},
+	LocaleEnglish: {
+		Title:       "PID controller response",
+		Time:        "Time (s)",
+		Value:       "Value",
+		Setpoint:    "Setpoint",
+		Measurement: "Measurement",
+		PhaseTitle:  "Error phase plane",
+		Error:       "Error e",
+		ErrorRate:   "de/dt (1/s)",
+
+		HistogramTitle: "Steady-state deviation from the setpoint",
+		Deviation:      "Measurement - setpoint",
+		Count:          "Samples",
+
+		ScanTitle: "Impedance seen from the POC",
+		Frequency: "Frequency (Hz)",
+		Impedance: "|Z| (Ω)",
+		Resonance: "Resonances",
+
+		ComparisonTitle: "Run comparison",
+		Run:             "Run",
+		Metric:          "Metric",
+		Best:            "Best value of each metric in bold",
+
+		Ideal:  "Ideal plant",
+		Actual: "With non-idealities",
+	},
+}
+
+// FindLocale returns the locale with the given name, the French one, as
+// the web UI, when name is empty.
+func FindLocale(name string) (Locale, error) {
+	if name == "" {
+		name = LocaleFrench
+	}
+	loc, ok := Locales[name]
+	if !ok {
+		return Locale{}, fmt.Errorf("langue inconnue %q, attendu %q ou %q", name, LocaleFrench, LocaleEnglish)
+	}
+	return loc, nil
+}
+
+// number formats v for a label, with the decimal separator of the locale.
+func (loc Locale) number(v float64) string {
+	s := strconv.FormatFloat(numfmt.Round(v), 'g', 4, 64)
+	if loc.DecimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
@@ -0,0 +1,82 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/units"
+)
+
+// magnitude bounds the absolute value of a quantity of the electrical
+// system to those met on real plants: beyond them, the value was most
+// likely written in the wrong unit, such as kV or MW where V or W are
+// expected. A zero value, meaning none for the optional quantities, and
+// Min zero are not bounded below.
+type magnitude struct {
+	path     string
+	v        float64
+	unit     string
+	min, max float64
+}
+
+func (m magnitude) check() []schema.Error {
+	a := math.Abs(m.v)
+	if (a == 0 || a >= m.min) && a <= m.max {
+		return nil
+	}
+	expected := "au plus " + units.Format(m.max, m.unit)
+	if m.min > 0 {
+		expected = fmt.Sprintf("entre %s et %s", units.Format(m.min, m.unit), units.Format(m.max, m.unit))
+	}
+	return []schema.Error{{Path: m.path, Message: fmt.Sprintf("%s invraisemblable, attendu %s : vérifier l'unité", units.Format(m.v, m.unit), expected)}}
+}
+
+// checkMagnitudes reports the quantities of e out of their plausible
+// magnitudes.
+func (e *Electrical) checkMagnitudes() []schema.Error {
+
+	list := []magnitude{
+		{"/UPoc", e.UPoc, units.Volt, 100, 1e6},
+		{"/f", e.F, units.Hertz, 1, 1e3},
+		{"/L", e.L, units.Henry, 0, 1},
+		{"/C", e.C, units.Farad, 1e-9, 1},
+		{"/R", e.R, units.Ohm, 0, 1e3},
+		{"/Pond", e.Pond, units.Watt, 100, 1e10},
+	}
+	for i, b := range e.Blocks {
+		path := "/blocks/" + strconv.Itoa(i)
+		list = append(list,
+			magnitude{path + "/R", b.R, units.Ohm, 0, 1e4},
+			magnitude{path + "/X", b.X, units.Ohm, 0, 1e4},
+			magnitude{path + "/B", b.B, units.Siemens, 0, 1},
+			magnitude{path + "/tap", b.Tap, "", 0.01, 100},
+		)
+	}
+	if d := e.Droop; d != nil {
+		list = append(list,
+			magnitude{"/droop/RGrid", d.RGrid, units.Ohm, 0, 1e4},
+			magnitude{"/droop/XGrid", d.XGrid, units.Ohm, 0, 1e4},
+		)
+	}
+	if c := e.Capability; c != nil {
+		list = append(list, magnitude{"/capability/Smax", c.SMax, units.VoltAmpere, 100, 1e10})
+	}
+	// The target of the tap changer is in volts, not per unit: within
+	// half of the nominal voltage of its bus.
+	if tc := e.TapChanger; tc != nil && len(tc.check(e)) == 0 {
+		bus := BusInverters
+		if tc.Bus != "" {
+			bus = tc.Bus
+		}
+		U := e.nominalVoltage(bus)
+		list = append(list, magnitude{"/tapChanger/target", tc.Target, units.Volt, U / 2, 1.5 * U})
+	}
+
+	var errs []schema.Error
+	for _, m := range list {
+		errs = append(errs, m.check()...)
+	}
+	return errs
+}
@@ -0,0 +1,46 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Manual is the manual mode a run starts in: the operator holds the
+// controller output at Output until time Until, when the loop switches to
+// auto. The PID tracks the manual output, so that its own output continues
+// from it.
+//
+// With TrackPV the setpoint follows the measurement while in manual, as on
+// most DCS, so that there is no error to act on at the switch. The working
+// setpoint then returns to Sp, along the ramp when SpRamp is set and in one
+// step otherwise.
+type Manual struct {
+	Output  float64 `json:"output"`
+	Until   float64 `json:"until"`
+	TrackPV bool    `json:"trackPV,omitempty"`
+}
+
+// ManualSchema describes the optional "manual" member of a scenario.
+var ManualSchema = schema.Object(map[string]*schema.Schema{
+	"output":  schema.Number("Sortie imposée en manuel"),
+	"until":   schema.Number("Instant du passage en automatique (s)").Min(0),
+	"trackPV": schema.Boolean("La consigne suit la mesure en manuel, pour un passage en automatique sans à-coup").WithDefault(false),
+}, "output", "until")
+
+func manualEvent(m *Manual) Event {
+	tracking := "consigne fixe"
+	if m.TrackPV {
+		tracking = "consigne suivant la mesure"
+	}
+	return Event{T: 0, Type: EventMode, Message: fmt.Sprintf("Mode manuel, sortie %g, %s", m.Output, tracking)}
+}
+
+// autoEvent reports the switch to auto at time t, where the measurement is
+// pv and its gap to Sp is gap.
+func autoEvent(t float64, m *Manual, pv, gap float64) Event {
+	if m.TrackPV {
+		return Event{T: t, Type: EventMode, Message: fmt.Sprintf("Passage en automatique, suivi de la mesure actif : consigne à %.3g, sans écart au basculement", pv)}
+	}
+	return Event{T: t, Type: EventMode, Message: fmt.Sprintf("Passage en automatique sans suivi de la mesure, écart de %.3g au basculement", gap)}
+}
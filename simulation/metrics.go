@@ -0,0 +1,156 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// SettlingBand is the tolerance band, as a fraction of the step size, used
+// for the settling time.
+const SettlingBand = 0.02
+
+// MetricNames lists the names accepted by Metrics.Get.
+var MetricNames = []string{"iae", "ise", "itae", "overshoot", "settling", "effort", "umax", "cost", "iaeHeating", "iaeCooling", "levelDeviation", "outflowRate"}
+
+// Metrics summarizes a step response.
+type Metrics struct {
+	IAE          float64 `json:"iae"`       // integral of |e|
+	ISE          float64 `json:"ise"`       // integral of e²
+	ITAE         float64 `json:"itae"`      // integral of t·|e|
+	Overshoot    float64 `json:"overshoot"` // peak beyond the setpoint, in % of the step
+	SettlingTime float64 `json:"settling"`  // time after which the response stays within SettlingBand
+	Settled      bool    `json:"settled"`   // false when the response leaves the band at the end of the run
+	Effort       float64 `json:"effort"`    // integral of u², zero when U is not provided
+	MaxU         float64 `json:"umax"`      // peak |u|, zero when U is not provided
+	Cost         float64 `json:"cost"`      // total cost of the run, zero without cost rates
+
+	// Heating and Cooling split the response by direction, for the
+	// heat-cool plant.
+	Heating *DirectionMetrics `json:"heating,omitempty"`
+	Cooling *DirectionMetrics `json:"cooling,omitempty"`
+	// Level is set for the level plant.
+	Level *LevelMetrics `json:"level,omitempty"`
+}
+
+// ComputeMetrics evaluates the response Y sampled at times T against the
+// setpoint Sp. The step is taken from the initial measurement to Sp. U is
+// the controller output aligned with T; it may be nil.
+func ComputeMetrics(T, Y, U []float64, Sp float64) Metrics {
+	return computeMetrics(T, Y, U, func(int) float64 { return Sp })
+}
+
+// TrackingMetrics is ComputeMetrics for a setpoint varying along SP, as in
+// recipes. The error is taken against the setpoint of each sample and the
+// step from the initial measurement to the final setpoint.
+func TrackingMetrics(T, SP, Y, U []float64) Metrics {
+	if len(SP) != len(T) {
+		return Metrics{}
+	}
+	return computeMetrics(T, Y, U, func(i int) float64 { return SP[i] })
+}
+
+func computeMetrics(T, Y, U []float64, sp func(int) float64) Metrics {
+
+	var m Metrics
+	if len(T) < 2 || len(T) != len(Y) {
+		return m
+	}
+
+	step := sp(len(T)-1) - Y[0]
+	band := math.Abs(step) * SettlingBand
+	peak := 0.0
+	lastOut := -1
+	// The integrals are compensated, see kahanSum.
+	var iae, ise, itae, effort kahanSum
+
+	for i := 1; i < len(T); i++ {
+		dt := T[i] - T[i-1]
+		e := sp(i) - Y[i]
+		// Products are rounded before summing, see PID.Compute.
+		iae.add(float64(math.Abs(e) * dt))
+		ise.add(float64(e * e * dt))
+		itae.add(float64(T[i] * math.Abs(e) * dt))
+		if len(U) == len(T) {
+			effort.add(float64(U[i-1] * U[i-1] * dt))
+			m.MaxU = math.Max(m.MaxU, math.Abs(U[i-1]))
+		}
+
+		if step != 0 {
+			peak = math.Max(peak, -e/step)
+		}
+		if math.Abs(e) > band || math.IsNaN(e) {
+			lastOut = i
+		}
+	}
+
+	m.IAE, m.ISE, m.ITAE, m.Effort = iae.value(), ise.value(), itae.value(), effort.value()
+	m.Overshoot = 100 * peak
+	m.Settled = lastOut < len(T)-1
+	if m.Settled {
+		m.SettlingTime = T[lastOut+1]
+	} else {
+		m.SettlingTime = T[len(T)-1]
+	}
+	return m
+}
+
+// Get returns the metric with the given name.
+func (m Metrics) Get(name string) (float64, error) {
+	switch name {
+	case "iae":
+		return m.IAE, nil
+	case "ise":
+		return m.ISE, nil
+	case "itae":
+		return m.ITAE, nil
+	case "overshoot":
+		return m.Overshoot, nil
+	case "settling":
+		return m.SettlingTime, nil
+	case "effort":
+		return m.Effort, nil
+	case "umax":
+		return m.MaxU, nil
+	case "cost":
+		return m.Cost, nil
+	case "iaeHeating":
+		if m.Heating == nil {
+			return 0, nil
+		}
+		return m.Heating.IAE, nil
+	case "iaeCooling":
+		if m.Cooling == nil {
+			return 0, nil
+		}
+		return m.Cooling.IAE, nil
+	case "levelDeviation":
+		if m.Level == nil {
+			return 0, nil
+		}
+		return m.Level.MaxDeviation, nil
+	case "outflowRate":
+		if m.Level == nil {
+			return 0, nil
+		}
+		return m.Level.MaxOutflowRate, nil
+	}
+	return 0, fmt.Errorf("critère inconnu %q", name)
+}
+
+// Rounded returns the metrics rounded for output, see numfmt.
+func (m Metrics) Rounded() Metrics {
+	m.IAE = numfmt.Round(m.IAE)
+	m.ISE = numfmt.Round(m.ISE)
+	m.ITAE = numfmt.Round(m.ITAE)
+	m.Overshoot = numfmt.Round(m.Overshoot)
+	m.SettlingTime = numfmt.Round(m.SettlingTime)
+	m.Effort = numfmt.Round(m.Effort)
+	m.MaxU = numfmt.Round(m.MaxU)
+	m.Cost = numfmt.Round(m.Cost)
+	m.Heating = m.Heating.rounded()
+	m.Cooling = m.Cooling.rounded()
+	m.Level = m.Level.rounded()
+	return m
+}
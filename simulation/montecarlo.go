@@ -0,0 +1,67 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Perturbation describes the uncertainty on the plant parameters, as
+// relative deviations drawn uniformly in [-x, +x].
+type Perturbation struct {
+	K      float64 `json:"K"`
+	Tau    float64 `json:"Tau"`
+	Plants int     `json:"plants"`
+	Seed   uint64  `json:"seed"`
+}
+
+// MaxPlants bounds the size of a Monte Carlo plant family.
+const MaxPlants = 100
+
+// PlantFamily returns the nominal scenario followed by p.Plants copies whose
+// plant gain and time constant are randomly perturbed.
+func PlantFamily(nominal Scenario, p Perturbation) ([]Scenario, error) {
+
+	if p.Plants < 1 || p.Plants > MaxPlants {
+		return nil, fmt.Errorf("la famille doit compter entre 1 et %d procédés", MaxPlants)
+	}
+	if p.K < 0 || p.Tau < 0 || p.Tau >= 1 {
+		return nil, fmt.Errorf("les écarts relatifs doivent être positifs, celui de Tau inférieur à 1")
+	}
+
+	rng := rand.New(rand.NewPCG(p.Seed, p.Seed))
+	deviate := func(x float64) float64 { return 2*x*rng.Float64() - x }
+
+	family := []Scenario{nominal}
+	for i := 0; i < p.Plants; i++ {
+		sc := nominal
+		sc.K *= 1 + deviate(p.K)
+		sc.Tau *= 1 + deviate(p.Tau)
+		family = append(family, sc)
+	}
+	return family, nil
+}
+
+// WorstCase combines the metrics of several runs into the worst value of
+// each metric; the result is settled only if every run settled.
+func WorstCase(ms []Metrics) Metrics {
+
+	if len(ms) == 0 {
+		return Metrics{}
+	}
+	w := ms[0]
+	for _, m := range ms[1:] {
+		w.IAE = max(w.IAE, m.IAE)
+		w.ISE = max(w.ISE, m.ISE)
+		w.ITAE = max(w.ITAE, m.ITAE)
+		w.Overshoot = max(w.Overshoot, m.Overshoot)
+		w.SettlingTime = max(w.SettlingTime, m.SettlingTime)
+		w.Settled = w.Settled && m.Settled
+		w.Effort = max(w.Effort, m.Effort)
+		w.MaxU = max(w.MaxU, m.MaxU)
+		w.Cost = max(w.Cost, m.Cost)
+		w.Heating = w.Heating.worst(m.Heating)
+		w.Cooling = w.Cooling.worst(m.Cooling)
+		w.Level = w.Level.worst(m.Level)
+	}
+	return w
+}
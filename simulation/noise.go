@@ -0,0 +1,54 @@
+package simulation
+
+import (
+	"math/rand/v2"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Noise is the noise of the transmitter: white noise of standard
+// deviation Std, in units of the measurement, added to the measurement the
+// controller reads and PV records, the plant itself staying undisturbed.
+// The same seed gives the same run.
+type Noise struct {
+	Std  float64 `json:"std"`
+	Seed uint64  `json:"seed"`
+}
+
+// NoiseSchema describes the optional "noise" member of a scenario.
+var NoiseSchema = schema.Object(map[string]*schema.Schema{
+	"std":  schema.Number("Écart type du bruit de mesure, en unités de la mesure").Min(0),
+	"seed": schema.Integer("Graine du générateur aléatoire").Min(0).WithDefault(1),
+}, "std")
+
+// noiseRun draws the measurement noise of a run, one value per time step;
+// a nil noiseRun adds none.
+type noiseRun struct {
+	std float64
+	rng *rand.Rand
+	v   float64
+}
+
+func newNoiseRun(n *Noise) *noiseRun {
+	if n == nil || n.Std == 0 {
+		return nil
+	}
+	r := &noiseRun{std: n.Std, rng: rand.New(rand.NewPCG(n.Seed, n.Seed))}
+	r.draw()
+	return r
+}
+
+// draw draws the noise of the next time step.
+func (r *noiseRun) draw() {
+	if r != nil {
+		r.v = float64(r.std * r.rng.NormFloat64())
+	}
+}
+
+// value returns the noise of the current time step.
+func (r *noiseRun) value() float64 {
+	if r == nil {
+		return 0
+	}
+	return r.v
+}
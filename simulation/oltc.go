@@ -0,0 +1,110 @@
+package simulation
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+	"github.com/Ivan69-tech/PIDControllerResponse/units"
+)
+
+// TapChanger is the on-load tap changer of a transformer of the electrical
+// system: when the voltage of Bus stays outside Target ± Deadband for Delay
+// seconds, it moves the tap one position towards the band, each position
+// changing the ratio by Step of the nominal Tap of the transformer. The
+// timer restarts after every move and whenever the voltage is back in the
+// band.
+//
+// Regulating the bus a Q(U) droop also reads, the tap changer and the
+// reactive power controller act on the same voltage and can hunt.
+type TapChanger struct {
+	// Block is the index of the transformer in the blocks.
+	Block int `json:"block"`
+	// Bus is the regulated bus, BusInverters when empty.
+	Bus      string  `json:"bus,omitempty"`
+	Target   float64 `json:"target"`
+	Deadband float64 `json:"deadband"`
+	Delay    float64 `json:"delay"`
+	Step     float64 `json:"step"`
+	// Min and Max bound the position, 0 being the nominal Tap.
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// TapChangerSchema describes the "tapChanger" member of the electrical
+// system.
+var TapChangerSchema = schema.Object(map[string]*schema.Schema{
+	"block":    schema.Integer("Rang du transformateur dans blocks").Min(0),
+	"bus":      schema.String("Nœud régulé : inverters, system ou le nom d'un bloc ; inverters par défaut"),
+	"target":   schema.Number("Tension visée au nœud régulé (V)").In(units.Volt).Above(0),
+	"deadband": schema.Number("Bande morte autour de la tension visée (V)").In(units.Volt).Min(0),
+	"delay":    schema.Number("Temporisation avant chaque changement de prise (s)").In(units.Second).Min(0).WithDefault(30.0),
+	"step":     schema.Number("Variation du rapport par prise, en fraction du rapport nominal").Above(0).WithDefault(0.0125),
+	"min":      schema.Integer("Prise la plus basse").Max(0).WithDefault(-8),
+	"max":      schema.Integer("Prise la plus haute").Min(0).WithDefault(8),
+}, "block", "target", "deadband")
+
+func (tc *TapChanger) check(e *Electrical) []schema.Error {
+	var errs []schema.Error
+	if tc.Block >= len(e.Blocks) || e.Blocks[tc.Block].Type != BlockTransformer {
+		errs = append(errs, schema.Error{Path: "/tapChanger/block", Message: fmt.Sprintf("le bloc %d n'est pas un transformateur", tc.Block)})
+	}
+	if tc.Bus != "" && !slices.Contains(e.busNames(), tc.Bus) {
+		errs = append(errs, schema.Error{Path: "/tapChanger/bus", Message: fmt.Sprintf("nœud inconnu %q, nœuds possibles : %v", tc.Bus, e.busNames())})
+	}
+	return errs
+}
+
+// tapRun is the state of a tap changer during a run, moving the tap of
+// its transformer in the blocks of the run, a copy of the scenario's.
+type tapRun struct {
+	tc       *TapChanger
+	block    *Block
+	nominal  float64
+	position int
+	timer    float64
+}
+
+// newTapRun copies the blocks of e, for the tap changer to move the tap
+// of its copy.
+func newTapRun(e *Electrical) *tapRun {
+	e.Blocks = slices.Clone(e.Blocks)
+	b := &e.Blocks[e.TapChanger.Block]
+	return &tapRun{tc: e.TapChanger, block: b, nominal: b.ratio()}
+}
+
+// ratio returns the current ratio of the transformer.
+func (r *tapRun) ratio() float64 {
+	return r.block.ratio()
+}
+
+// advance follows the regulated voltage of f at time t, dt after the
+// previous one, and moves the tap when it is due, returning the event of
+// the move.
+func (r *tapRun) advance(t, dt float64, f Flow) *Event {
+
+	bus := BusInverters
+	if r.tc.Bus != "" {
+		bus = r.tc.Bus
+	}
+	U := f.bus(bus).U
+	dir := 0
+	switch {
+	case U > r.tc.Target+r.tc.Deadband && r.position > r.tc.Min:
+		dir = -1
+	case U < r.tc.Target-r.tc.Deadband && r.position < r.tc.Max:
+		dir = 1
+	}
+	if dir == 0 {
+		r.timer = 0
+		return nil
+	}
+	if r.timer += dt; r.timer < r.tc.Delay {
+		return nil
+	}
+	r.timer = 0
+	r.position += dir
+	r.block.Tap = r.nominal * (1 + float64(r.tc.Step*float64(r.position)))
+	return &Event{T: t, Type: EventTap,
+		Message: fmt.Sprintf("Prise %+d : %s à %.0f V, rapport %.5g", r.position, bus, U, r.block.Tap)}
+}
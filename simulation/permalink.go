@@ -0,0 +1,44 @@
+package simulation
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// maxPermalinkSize bounds the scenario a permalink expands to, so that a
+// forged code cannot inflate without limit.
+const maxPermalinkSize = 1 << 20
+
+// Permalink encodes the scenario as a compact code for a URL: its JSON
+// form, normalized as re-encoding a decoded scenario does, deflated then
+// written in unpadded URL-safe base64. The same scenario always gives the
+// same code, which ParsePermalink reads back on any instance.
+func (sc Scenario) Permalink() string {
+	raw, _ := json.Marshal(sc)
+	var b bytes.Buffer
+	zw, _ := flate.NewWriter(&b, flate.BestCompression)
+	zw.Write(raw)
+	zw.Close()
+	return base64.RawURLEncoding.EncodeToString(b.Bytes())
+}
+
+// ParsePermalink returns the JSON form of the scenario of a permalink, to
+// be validated as any posted scenario.
+func ParsePermalink(code string) ([]byte, error) {
+	deflated, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil {
+		return nil, errors.New("lien permanent illisible : base64 invalide")
+	}
+	raw, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(deflated)), maxPermalinkSize+1))
+	if err != nil {
+		return nil, errors.New("lien permanent illisible : données compressées invalides")
+	}
+	if len(raw) > maxPermalinkSize {
+		return nil, errors.New("lien permanent trop volumineux")
+	}
+	return raw, nil
+}
@@ -0,0 +1,50 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// waterIonProduct is Kw = [H+][OH-] at 25 °C.
+const waterIonProduct = 1e-14
+
+// PH is the neutralization, in a stirred tank, of an influent strong acid
+// by a strong base reagent dosed by the controller, the canonical hard
+// control problem: the measured pH follows the titration curve, whose
+// slope around neutrality is hundreds of times that of the acid side.
+//
+// The reaction invariant x = [Na+] - [Cl-], in mol/L, is a first-order lag
+// of time constant Tau (the mixing) of K·u - Acid, K being the reagent
+// concentration brought by one unit of output. The tank starts at rest
+// with no reagent, at the pH of the influent.
+type PH struct {
+	// Acid is the concentration of the influent acid, in mol/L.
+	Acid float64 `json:"acid"`
+}
+
+// PHSchema describes the "ph" member of a scenario.
+var PHSchema = schema.Object(map[string]*schema.Schema{
+	"acid": schema.Number("Concentration de l'acide fort de l'effluent (mol/L)").Above(0).WithDefault(1e-3),
+}, "acid")
+
+// measure returns the pH for the first-order response z = x + Acid.
+func (p *PH) measure(z float64) float64 {
+	x := z - p.Acid
+	// [H+] solves [H+]² + x[H+] - Kw = 0, written to avoid cancellation.
+	root := math.Sqrt(float64(x*x) + 4*waterIonProduct)
+	var h float64
+	if x > 0 {
+		h = 2 * waterIonProduct / (x + root)
+	} else {
+		h = (root - x) / 2
+	}
+	return -math.Log10(h)
+}
+
+// slope returns dpH/dx at pH ph.
+func phSlope(ph float64) float64 {
+	h := math.Pow(10, -ph)
+	x := waterIonProduct/h - h
+	return 1 / (math.Ln10 * math.Sqrt(float64(x*x)+4*waterIonProduct))
+}
@@ -0,0 +1,71 @@
+package simulation
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// PhasePlane is the trajectory of a response in the error, error rate
+// plane: a settling loop spirals into the origin while a limit cycle, as
+// left by a saturated or nonlinear loop, draws a closed orbit.
+type PhasePlane struct {
+	Time      []float64 `json:"time"`
+	Error     []float64 `json:"e"`
+	ErrorRate []float64 `json:"de"`
+}
+
+// PhasePlane returns the phase-plane trajectory of the response. The error
+// is taken against the working setpoint when there is one; its rate is the
+// central difference, one-sided at the ends.
+func (r Result) PhasePlane() PhasePlane {
+
+	sp := r.SP
+	if len(r.WSP) == len(r.Time) {
+		sp = r.WSP
+	}
+	n := min(len(r.Time), len(sp), len(r.PV))
+	pp := PhasePlane{Time: r.Time[:n], Error: make([]float64, n), ErrorRate: make([]float64, n)}
+	for k := range n {
+		pp.Error[k] = sp[k] - r.PV[k]
+	}
+	for k := range n {
+		a, b := max(k-1, 0), min(k+1, n-1)
+		if b > a {
+			pp.ErrorRate[k] = (pp.Error[b] - pp.Error[a]) / (pp.Time[b] - pp.Time[a])
+		}
+	}
+	return pp
+}
+
+// Rounded returns a copy rounded for output, see numfmt.
+func (pp PhasePlane) Rounded() PhasePlane {
+	return PhasePlane{Time: numfmt.Series(pp.Time), Error: numfmt.Series(pp.Error), ErrorRate: numfmt.Series(pp.ErrorRate)}
+}
+
+// WritePlot draws the trajectory to w in the format, as Result.WritePlot.
+func (pp PhasePlane) WritePlot(w io.Writer, format string, opts PlotOptions) error {
+
+	loc, theme, err := opts.style()
+	if err != nil {
+		return err
+	}
+
+	if len(pp.Error) != len(pp.ErrorRate) {
+		return fmt.Errorf("Erreur dans le tracé, X et Y ne sont pas de la même taille")
+	}
+	return opts.Render(w, chart.Figure{
+		Title:        loc.PhaseTitle,
+		XLabel:       loc.Error,
+		YLabel:       loc.ErrorRate,
+		Style:        theme.style(),
+		DecimalComma: loc.DecimalComma,
+		Series: []chart.Series{
+			{X: numfmt.Series(pp.Error), Y: numfmt.Series(pp.ErrorRate), Color: theme.Measurement, Width: theme.LineWidth},
+			// The origin, where a settled loop ends.
+			{Kind: chart.KindPoints, X: []float64{0}, Y: []float64{0}, Color: theme.Setpoint, Width: 1},
+		},
+	}, format)
+}
@@ -0,0 +1,266 @@
+package simulation
+
+// Preset is a ready-made scenario, such as a hard plant with its
+// recommended tuning, selectable by name.
+type Preset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Tier ranks the examples of the library by difficulty, from
+	// TierLagDominant to TierIntegrating; it is zero for other presets.
+	Tier int `json:"tier,omitempty"`
+	// Tunings are the reference tunings of an example, the scenario
+	// running the first one.
+	Tunings []Tuning `json:"tunings,omitempty"`
+	// Industry is the profile an industry preset is built from.
+	Industry *IndustryProfile `json:"industry,omitempty"`
+	Scenario Scenario         `json:"scenario"`
+}
+
+// Presets lists the presets, by topic.
+var Presets []Preset
+
+// FindPreset returns the preset with the given name.
+func FindPreset(name string) (Preset, bool) {
+	for _, p := range Presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+func init() {
+	Presets = append(Presets, examplePresets()...)
+	Presets = append(Presets, industryPresets()...)
+	Presets = append(Presets, phPresets()...)
+	Presets = append(Presets, levelPresets()...)
+	Presets = append(Presets, reactivePresets()...)
+	Presets = append(Presets, tapChangerPresets()...)
+}
+
+// phPresets compares, on the neutralization plant, fixed gains tuned at
+// neutrality or on the acid side with gains scheduled along the titration
+// curve.
+func phPresets() []Preset {
+
+	const (
+		K, tau = 2e-5, 10.0
+		// lambda is the closed-loop time constant aimed at.
+		lambda = 10.0
+	)
+	// Lambda tuning of a PI on the first-order plant linearized at pH:
+	// P = Tau / (g·λ), Ki = 1 / (g·λ), g being the local gain dpH/du.
+	tuning := func(ph float64) GainPoint {
+		g := K * phSlope(ph)
+		return GainPoint{PV: ph, P: short(tau / (g * lambda)), Ki: short(1 / (g * lambda))}
+	}
+
+	uMin, uMax := 0.0, 100.0
+	base := Scenario{
+		Sp: 7, Tau: tau, K: K, Dt: 0.1, N: 6000,
+		Plant: PlantPH, PH: &PH{Acid: 1e-3},
+		Limits: Limits{UMin: &uMin, UMax: &uMax, ConditionalIntegration: true},
+	}
+
+	neutral, acid := base, base
+	at7, at4 := tuning(7), tuning(4)
+	neutral.P, neutral.Ki = at7.P, at7.Ki
+	acid.P, acid.Ki = at4.P, at4.Ki
+
+	scheduled := base
+	for _, ph := range []float64{3, 4, 5, 5.5, 6, 6.5, 7, 7.5, 8, 8.5, 9, 10, 11} {
+		scheduled.Schedule = append(scheduled.Schedule, tuning(ph))
+	}
+	scheduled.P, scheduled.Ki = at7.P, at7.Ki
+
+	return []Preset{
+		{
+			Name:        "ph-fixed-neutral",
+			Description: "Neutralisation de pH 3 à 7 avec des gains fixes réglés à la neutralité : stable mais très lent loin de la consigne, où le procédé est des centaines de fois moins sensible",
+			Scenario:    neutral,
+		},
+		{
+			Name:        "ph-fixed-acid",
+			Description: "Neutralisation avec des gains fixes réglés côté acide (pH 4) : rapide au départ puis cycle limite autour de la neutralité",
+			Scenario:    acid,
+		},
+		{
+			Name:        "ph-scheduled",
+			Description: "Neutralisation avec des gains programmés sur le pH, inversement proportionnels à la pente de la courbe de titrage (réglage lambda, λ = 10 s)",
+			Scenario:    scheduled,
+		},
+	}
+}
+
+// levelPresets compare, on a surge tank fed by a disturbed inflow, the
+// averaging level control, which lets the level move within the tank to
+// pass on a smooth outflow, with a tight level control, which passes the
+// inflow disturbances on downstream.
+func levelPresets() []Preset {
+
+	const (
+		K, tau = 0.1, 100.0
+		inflow = 5.0
+	)
+	// Lambda tuning of a PI on the integrating plant of gain K / Tau, for
+	// an arrest time λ: P = 2 / (g·λ), Ki = 1 / (g·λ²), negative as the
+	// controller is direct-acting.
+	tuning := func(lambda float64) (P, Ki float64) {
+		g := K / tau
+		return -short(2 / (g * lambda)), -short(1 / (g * lambda * lambda))
+	}
+
+	uMin, uMax := 0.0, 100.0
+	base := Scenario{
+		Sp: 50, Tau: tau, K: K, Dt: 0.5, N: 2400,
+		Plant: PlantLevel,
+		Level: &Level{
+			Initial: 50,
+			Inflow:  inflow,
+			Profile: &Profile{Hold: true, Points: []Breakpoint{
+				{T: 0, Value: inflow}, {T: 100, Value: 7}, {T: 500, Value: 4}, {T: 800, Value: inflow},
+			}},
+			Noise: &InflowNoise{Std: 0.3, Tau: 10, Seed: 1},
+		},
+		// Started at balance, the outflow matching the inflow.
+		Manual: &Manual{Output: inflow / K, Until: 10},
+		Limits: Limits{UMin: &uMin, UMax: &uMax},
+	}
+
+	averaging, tight := base, base
+	averaging.P, averaging.Ki = tuning(200)
+	tight.P, tight.Ki = tuning(10)
+
+	return []Preset{
+		{
+			Name:        "level-averaging",
+			Description: "Régulation de niveau moyennante : réglage lent (λ = 200 s) qui laisse le niveau varier dans le bac pour lisser le débit sortant envoyé à l'aval",
+			Scenario:    averaging,
+		},
+		{
+			Name:        "level-tight",
+			Description: "Régulation de niveau serrée (λ = 10 s) : le niveau reste à la consigne mais le débit sortant reproduit les variations du débit entrant",
+			Scenario:    tight,
+		},
+	}
+}
+
+// reactivePresets regulate the reactive power at the POC of a plant whose
+// active power triples, which triples the current and multiplies by nine
+// the reactive power absorbed by the collection grid, with a fixed
+// setpoint or a Q(U) droop.
+func reactivePresets() []Preset {
+
+	const (
+		K, tau = 1.0, 0.2
+		// lambda is the closed-loop time constant aimed at.
+		lambda = 0.5
+	)
+	base := Scenario{
+		Sp: 500e3, Tau: tau, K: K, Dt: 0.01, N: 1000,
+		// Lambda tuning of a PI on the first-order inverter response.
+		P: short(tau / (K * lambda)), Ki: short(1 / (K * lambda)),
+		Plant: PlantReactive,
+		Electrical: &Electrical{
+			ElectricalSystem: DefaultElectricalSystem,
+			Pond:             1e6,
+			Profile: &Profile{Hold: true, Points: []Breakpoint{
+				{T: 0, Value: 1e6}, {T: 5, Value: 3e6},
+			}},
+		},
+	}
+
+	// with returns a copy of the electrical system of s changed by change.
+	with := func(s Scenario, change func(e *Electrical)) *Electrical {
+		e := *s.Electrical
+		change(&e)
+		return &e
+	}
+
+	droop := base
+	droop.Electrical = with(base, func(e *Electrical) {
+		e.Droop = &Droop{Slope: 1000, Deadband: 20, XGrid: 4}
+	})
+
+	// At 3 MW, inverters rated 3.3 MVA produce 1.37 Mvar at most at their
+	// nominal voltage.
+	capability := base
+	capability.Sp = 1.5e6
+	capability.Electrical = with(base, func(e *Electrical) {
+		e.Capability = &Capability{SMax: 3.3e6}
+	})
+
+	// The droop injects reactive power during the dip and absorbs it
+	// during the swell.
+	rideThrough := droop
+	rideThrough.Electrical = with(droop, func(e *Electrical) {
+		e.Profile = nil
+		e.Grid = []GridEvent{{Preset: "dip-50-150ms", At: 2}, {Preset: "swell-10-1s", At: 5}, {Preset: "phase-jump-20", At: 8}}
+	})
+
+	return []Preset{
+		{
+			Name:        "reactive-poc",
+			Description: "Régulation de la puissance réactive au POC à 500 kvar : la puissance active passe de 1 à 3 MW à 5 s et le système électrique absorbe neuf fois plus de réactif, que le régulateur compense",
+			Scenario:    base,
+		},
+		{
+			Name:        "reactive-droop",
+			Description: "Régulation de la puissance réactive avec une caractéristique Q(U) : la consigne de 500 kvar baisse de 1 kvar par volt au-delà de 20 V de hausse de tension au POC, la réactance du réseau étant de 4 Ω",
+			Scenario:    droop,
+		},
+		{
+			Name:        "reactive-capability",
+			Description: "Régulation de la puissance réactive au POC à 1,5 Mvar par des onduleurs de 3,3 MVA : à 3 MW, leur diagramme P–Q limite leur puissance réactive à environ 1,5 Mvar, moins que la consigne plus ce qu'absorbe le système électrique, et la consigne n'est plus tenue",
+			Scenario:    capability,
+		},
+		{
+			Name:        "reactive-ride-through",
+			Description: "Régulation Q(U) soumise à un creux de tension de 50 % à 2 s, une surtension de 10 % à 5 s et un saut de phase de 20° à 8 s",
+			Scenario:    rideThrough,
+		},
+	}
+}
+
+// tapChangerPresets regulate with an on-load tap changer the voltage at the
+// inverter end of a 6.9/20 kV transformer, whose active power quadruples,
+// alone or together with a Q(U) droop reading the same voltage: the droop
+// moves the voltage by more than the deadband of the tap changer, which
+// then hunts between two taps.
+func tapChangerPresets() []Preset {
+
+	system := DefaultElectricalSystem
+	system.UPoc = 20e3
+	system.Blocks = []Block{{Type: BlockTransformer, Name: "TR", R: 0.05, X: 0.4, Tap: 0.345}}
+	base := Scenario{
+		Sp: 0, Tau: 0.2, K: 1, P: 0.4, Ki: 2, Dt: 0.05, N: 2400,
+		Plant: PlantReactive,
+		Electrical: &Electrical{
+			ElectricalSystem: system,
+			Pond:             2e6,
+			Profile: &Profile{Hold: true, Points: []Breakpoint{
+				{T: 0, Value: 2e6}, {T: 20, Value: 8e6},
+			}},
+			TapChanger: &TapChanger{Block: 0, Target: 6900, Deadband: 60, Delay: 10, Step: 0.0125, Min: -8, Max: 8},
+		},
+	}
+
+	hunting := base
+	electrical := *base.Electrical
+	electrical.Droop = &Droop{Slope: 5000, XGrid: 2, Bus: BusInverters}
+	electrical.TapChanger = &TapChanger{Block: 0, Target: 7000, Deadband: 30, Delay: 5, Step: 0.0125, Min: -8, Max: 8}
+	hunting.Electrical = &electrical
+
+	return []Preset{
+		{
+			Name:        "reactive-oltc",
+			Description: "Régleur en charge d'un transformateur 6,9/20 kV maintenant 6,9 kV ± 60 V côté onduleurs, la puissance active passant de 2 à 8 MW à 20 s : une prise de 1,25 % ramène la tension dans la bande, la puissance réactive au POC restant régulée à 0",
+			Scenario:    base,
+		},
+		{
+			Name:        "reactive-oltc-hunting",
+			Description: "Le même régleur, visant 7 kV ± 30 V, avec une caractéristique Q(U) de 5 kvar/V sur la même tension : la caractéristique ramène la tension vers sa référence, hors de la bande du régleur, qui oscille entre deux prises toutes les 5 s",
+			Scenario:    hunting,
+		},
+	}
+}
@@ -0,0 +1,215 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Breakpoint is the value of a parameter at time T.
+type Breakpoint struct {
+	T     float64 `json:"t"`
+	Value float64 `json:"value"`
+}
+
+// Interpolations between the breakpoints of a profile.
+const (
+	InterpolationLinear = "linear"
+	// InterpolationHold keeps the value of the previous breakpoint, as a
+	// zero-order hold.
+	InterpolationHold = "hold"
+	// InterpolationSpline is a monotone cubic (PCHIP): smooth, and never
+	// beyond the values of the surrounding breakpoints.
+	InterpolationSpline = "spline"
+)
+
+// Extrapolations of a profile before its first breakpoint and after its
+// last one.
+const (
+	// ExtrapolationHold keeps the value of the nearest breakpoint.
+	ExtrapolationHold = "hold"
+	// ExtrapolationLinear continues the first or last segment.
+	ExtrapolationLinear = "linear"
+	// ExtrapolationPeriodic repeats the profile, whose period is the time
+	// between its first and last breakpoints.
+	ExtrapolationPeriodic = "periodic"
+)
+
+// Profile is a parameter varying in time, given by breakpoints sorted by
+// time. Between two breakpoints the value follows Interpolation, linear
+// by default; Hold is the former spelling of InterpolationHold. Outside
+// the breakpoints it follows Extrapolation, constant by default.
+type Profile struct {
+	Points        []Breakpoint `json:"points"`
+	Hold          bool         `json:"hold,omitempty"`
+	Interpolation string       `json:"interpolation,omitempty"`
+	Extrapolation string       `json:"extrapolation,omitempty"`
+}
+
+// At returns the value of the profile at time t.
+func (p *Profile) At(t float64) float64 {
+	pts := p.Points
+	first, last := pts[0], pts[len(pts)-1]
+	if len(pts) > 1 && (t < first.T || t > last.T) {
+		switch p.Extrapolation {
+		case ExtrapolationLinear:
+			a, b := pts[0], pts[1]
+			if t > last.T {
+				a, b = pts[len(pts)-2], last
+			}
+			return a.Value + float64((b.Value-a.Value)*(t-a.T)/(b.T-a.T))
+		case ExtrapolationPeriodic:
+			period := last.T - first.T
+			t = first.T + math.Mod(t-first.T, period)
+			if t < first.T {
+				t += period
+			}
+		}
+	}
+
+	i := sort.Search(len(pts), func(i int) bool { return pts[i].T > t })
+	switch {
+	case i == 0:
+		return first.Value
+	case i == len(pts) || p.Hold || p.Interpolation == InterpolationHold:
+		return pts[i-1].Value
+	case p.Interpolation == InterpolationSpline:
+		return p.spline(i-1, t)
+	}
+	a, b := pts[i-1], pts[i]
+	return a.Value + float64((b.Value-a.Value)*(t-a.T)/(b.T-a.T))
+}
+
+// spline evaluates the monotone cubic on the segment starting at
+// breakpoint i, a Hermite cubic whose tangents are those of Fritsch and
+// Butland: zero at extrema, a weighted harmonic mean of the neighbouring
+// slopes elsewhere. Only the neighbouring breakpoints are needed.
+func (p *Profile) spline(i int, t float64) float64 {
+	pts := p.Points
+	a, b := pts[i], pts[i+1]
+	h := b.T - a.T
+	m0, m1 := p.tangent(i), p.tangent(i+1)
+	s := (t - a.T) / h
+	s2, s3 := float64(s*s), float64(s*s*s)
+	h00 := 2*s3 - 3*s2 + 1
+	h10 := s3 - 2*s2 + s
+	h01 := -2*s3 + 3*s2
+	h11 := s3 - s2
+	return float64(h00*a.Value) + float64(h10*h*m0) + float64(h01*b.Value) + float64(h11*h*m1)
+}
+
+// tangent returns the slope of the monotone cubic at breakpoint i.
+func (p *Profile) tangent(i int) float64 {
+	pts := p.Points
+	slope := func(j int) (float64, float64) {
+		h := pts[j+1].T - pts[j].T
+		return (pts[j+1].Value - pts[j].Value) / h, h
+	}
+	switch i {
+	case 0:
+		d, _ := slope(0)
+		return d
+	case len(pts) - 1:
+		d, _ := slope(i - 1)
+		return d
+	}
+	d0, h0 := slope(i - 1)
+	d1, h1 := slope(i)
+	if d0*d1 <= 0 {
+		return 0
+	}
+	return 3 * (h0 + h1) / ((2*h1+h0)/d0 + (h1+2*h0)/d1)
+}
+
+// segment returns the index of the breakpoint starting the segment of
+// time t, −1 before the first one; the repetitions of a periodic profile
+// count as further segments.
+func (p *Profile) segment(t float64) int {
+	pts := p.Points
+	first, last := pts[0], pts[len(pts)-1]
+	cycle := 0.0
+	if p.Extrapolation == ExtrapolationPeriodic && len(pts) > 1 && (t < first.T || t > last.T) {
+		cycle = math.Floor((t - first.T) / (last.T - first.T))
+		t -= float64(cycle * (last.T - first.T))
+	}
+	return int(cycle)*len(pts) + sort.Search(len(pts), func(i int) bool { return pts[i].T > t }) - 1
+}
+
+// profileWatch follows the segments of a profile through a run.
+type profileWatch struct {
+	p   *Profile
+	seg int
+}
+
+func newProfileWatch(p *Profile, t float64) *profileWatch {
+	if p == nil {
+		return nil
+	}
+	return &profileWatch{p: p, seg: p.segment(t)}
+}
+
+// enter moves the watch to time t and returns the breakpoint starting the
+// segment then reached, ok being false while t stays in the same segment.
+func (w *profileWatch) enter(t float64) (b Breakpoint, ok bool) {
+	if w == nil {
+		return b, false
+	}
+	seg := w.p.segment(t)
+	if seg == w.seg || seg < 0 {
+		return b, false
+	}
+	w.seg = seg
+	return w.p.Points[seg%len(w.p.Points)], true
+}
+
+// check reports the problems of the profile found at path that the schema
+// cannot express; values must be positive when positive is set.
+func (p *Profile) check(path string, positive bool) []schema.Error {
+	var errs []schema.Error
+	for i, pt := range p.Points {
+		if i > 0 && pt.T <= p.Points[i-1].T {
+			errs = append(errs, schema.Error{Path: fmt.Sprintf("%s/points/%d/t", path, i), Message: "les instants doivent être strictement croissants"})
+		}
+		if positive && pt.Value <= 0 {
+			errs = append(errs, schema.Error{Path: fmt.Sprintf("%s/points/%d/value", path, i), Message: fmt.Sprintf("doit être strictement supérieur à 0, reçu %g", pt.Value)})
+		}
+	}
+	if positive && p.Extrapolation == ExtrapolationLinear {
+		errs = append(errs, schema.Error{Path: path + "/extrapolation", Message: "une extrapolation linéaire peut rendre la valeur négative"})
+	}
+	if p.Hold && p.Interpolation != "" && p.Interpolation != InterpolationHold {
+		errs = append(errs, schema.Error{Path: path + "/hold", Message: fmt.Sprintf("contredit l'interpolation %q", p.Interpolation)})
+	}
+	return errs
+}
+
+// PlantVariation makes the plant parameters vary during a run. A profile
+// replaces the constant parameter of the scenario.
+type PlantVariation struct {
+	K   *Profile `json:"K,omitempty"`
+	Tau *Profile `json:"Tau,omitempty"`
+}
+
+func profileSchema(description string) *schema.Schema {
+	s := schema.Object(map[string]*schema.Schema{
+		"points": schema.Array(schema.Object(map[string]*schema.Schema{
+			"t":     schema.Number("Instant (s)").Min(0),
+			"value": schema.Number("Valeur à cet instant"),
+		}, "t", "value")).AtLeast(1),
+		"hold": schema.Boolean("Paliers au lieu de rampes entre les points (équivaut à interpolation hold)").WithDefault(false),
+		"interpolation": schema.String("Interpolation entre les points : linéaire, bloqueur d'ordre zéro ou spline cubique monotone").
+			OneOf(InterpolationLinear, InterpolationHold, InterpolationSpline).WithDefault(InterpolationLinear),
+		"extrapolation": schema.String("Valeur avant le premier point et après le dernier : maintenue, prolongée linéairement ou périodique").
+			OneOf(ExtrapolationHold, ExtrapolationLinear, ExtrapolationPeriodic).WithDefault(ExtrapolationHold),
+	}, "points")
+	s.Description = description
+	return s
+}
+
+// VariationSchema describes the optional "variation" member of a scenario.
+var VariationSchema = schema.Object(map[string]*schema.Schema{
+	"K":   profileSchema("Évolution du gain K"),
+	"Tau": profileSchema("Évolution de la constante de temps Tau"),
+})
@@ -0,0 +1,141 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Phase is one step of a recipe, as in batch reactors and ovens.
+type Phase struct {
+	Name string `json:"name"`
+	// Sp is the setpoint of the phase, the previous one when omitted.
+	// SpProfile, with times relative to the start of the phase, overrides
+	// it.
+	Sp        *float64 `json:"Sp,omitempty"`
+	SpProfile *Profile `json:"spProfile,omitempty"`
+	// The phase ends after Duration seconds or when one of the Until
+	// conditions fires, whichever comes first. At least one is required.
+	Duration float64         `json:"duration,omitempty"`
+	Until    *StopConditions `json:"until,omitempty"`
+	// Gains replaces the controller gains during the phase, the previous
+	// ones being kept when omitted.
+	Gains *PhaseGains `json:"gains,omitempty"`
+}
+
+// PhaseGains is the gain set allowed during a phase.
+type PhaseGains struct {
+	P  float64 `json:"P"`
+	Ki float64 `json:"Ki"`
+	Kd float64 `json:"Kd"`
+}
+
+// PhaseRun is a phase as it ran: its time span and what ended it, either
+// "duration", the name of an Until condition, or "end" when the run ended
+// first.
+type PhaseRun struct {
+	Name    string  `json:"name"`
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	EndedBy string  `json:"endedBy"`
+}
+
+// RecipeSchema describes the optional "recipe" member of a scenario.
+var RecipeSchema = schema.Array(schema.Object(map[string]*schema.Schema{
+	"name":      schema.String("Nom de la phase"),
+	"Sp":        schema.Number("Consigne de la phase (par défaut celle de la phase précédente)"),
+	"spProfile": profileSchema("Évolution de la consigne, instants relatifs au début de la phase"),
+	"duration":  schema.Number("Durée de la phase (s)").Above(0),
+	"until":     StopSchema,
+	"gains": schema.Object(map[string]*schema.Schema{
+		"P":  schema.Number("Coefficient proportionnel"),
+		"Ki": schema.Number("Coefficient intégral"),
+		"Kd": schema.Number("Coefficient dérivé"),
+	}, "P", "Ki", "Kd"),
+}, "name")).AtLeast(1)
+
+// checkRecipe reports the problems of a recipe that the schema cannot
+// express.
+func checkRecipe(recipe []Phase) []schema.Error {
+	var errs []schema.Error
+	for i, ph := range recipe {
+		path := fmt.Sprintf("/recipe/%d", i)
+		if ph.Duration == 0 && ph.Until == nil {
+			errs = append(errs, schema.Error{Path: path, Message: "durée ou condition de fin (until) obligatoire"})
+		}
+		if ph.SpProfile != nil {
+			errs = append(errs, ph.SpProfile.check(path+"/spProfile", false)...)
+		}
+	}
+	return errs
+}
+
+// recipeRun drives a loop through the phases of a recipe.
+type recipeRun struct {
+	recipe []Phase
+	index  int
+	start  float64
+	watch  stopWatch
+	runs   []PhaseRun
+}
+
+// enter starts phase index at the current time of the loop and returns the
+// events of the change.
+func (r *recipeRun) enter(loop *Loop) []Event {
+
+	ph := r.recipe[r.index]
+	r.start = loop.T
+	r.watch = stopWatch{c: ph.Until}
+	r.runs = append(r.runs, PhaseRun{Name: ph.Name, Start: loop.T})
+
+	sc := loop.Scenario
+	if ph.Sp != nil {
+		sc.Sp = *ph.Sp
+	}
+	if ph.SpProfile != nil {
+		sc.Sp = ph.SpProfile.At(0)
+	}
+	if g := ph.Gains; g != nil {
+		sc.P, sc.Ki, sc.Kd = g.P, g.Ki, g.Kd
+	}
+
+	events := []Event{{T: loop.T, Type: EventPhase, Message: fmt.Sprintf("Phase %d : %s", r.index+1, ph.Name)}}
+	events = append(events, Changes(loop.T, loop.Scenario, sc)...)
+	loop.Update(sc)
+	return events
+}
+
+// follow applies the setpoint profile of the phase before a step.
+func (r *recipeRun) follow(loop *Loop) {
+	if p := r.recipe[r.index].SpProfile; p != nil {
+		loop.Scenario.Sp = p.At(loop.T - r.start)
+	}
+}
+
+// check tells whether the phase ended at the sample just computed, and
+// enters the next one. done is set after the last phase.
+func (r *recipeRun) check(loop *Loop, u float64) (events []Event, done bool) {
+
+	ph := r.recipe[r.index]
+	endedBy := r.watch.check(loop.T, loop.Scenario.Sp, loop.Y, u)
+	// Half a step of tolerance absorbs the rounding of the summed time.
+	if endedBy == "" && ph.Duration > 0 && loop.T-r.start >= ph.Duration-loop.Scenario.Dt/2 {
+		endedBy = "duration"
+	}
+	if endedBy == "" {
+		return nil, false
+	}
+
+	r.close(loop.T, endedBy)
+	r.index++
+	if r.index == len(r.recipe) {
+		return nil, true
+	}
+	return r.enter(loop), false
+}
+
+// close ends the current phase.
+func (r *recipeRun) close(t float64, endedBy string) {
+	last := &r.runs[len(r.runs)-1]
+	last.End, last.EndedBy = t, endedBy
+}
@@ -0,0 +1,197 @@
+package simulation
+
+import (
+	"io"
+	"math"
+	"time"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// Status tells how a simulated response ended.
+type Status string
+
+const (
+	// Settled: the measurement stays within SettlingBand of the setpoint
+	// at the end of the run.
+	Settled Status = "settled"
+	// NotSettled: the run ended before the measurement settled.
+	NotSettled Status = "not-settled"
+	// Diverged: the measurement became infinite or NaN.
+	Diverged Status = "diverged"
+)
+
+// Components are the proportional, integral and derivative parts of the
+// controller output, U = P + I + D.
+type Components struct {
+	P []float64 `json:"p"`
+	I []float64 `json:"i"`
+	D []float64 `json:"d"`
+}
+
+// Result is a simulated closed-loop response. Every series has one sample
+// per element of Time, in seconds from the start of the run. Start and
+// Timestamps are only set for results anchored with At.
+type Result struct {
+	Start      *time.Time  `json:"start,omitempty"`
+	Timestamps []time.Time `json:"timestamps,omitempty"`
+	Time       []float64   `json:"time"`
+	SP         []float64   `json:"sp"`
+	// WSP is the working setpoint followed by the controller, set when the
+	// scenario ramps its setpoint or tracks the measurement in manual; SP
+	// is then the target.
+	WSP        []float64  `json:"wsp,omitempty"`
+	PV         []float64  `json:"pv"`
+	U          []float64  `json:"u"`
+	Components Components `json:"components"`
+	// Adaptation is set for the adaptive controller.
+	Adaptation *Adaptation `json:"adaptation,omitempty"`
+	// Inflow is set for the level plant, with the inflow at each sample.
+	Inflow []float64 `json:"inflow,omitempty"`
+	// Losses are set for the reactive power plant, with the mean losses of
+	// every part of the electrical system over the run.
+	Losses []Loss `json:"losses,omitempty"`
+	// Voltage is set for the reactive power plant, with the voltages at
+	// the inverter terminals and at the POC at each sample.
+	Voltage *Voltage `json:"voltage,omitempty"`
+	// Tap is set when the electrical system has a tap changer, with the
+	// ratio of its transformer at each sample.
+	Tap []float64 `json:"tap,omitempty"`
+	// Disturbance is set when the scenario drives a load disturbance.
+	Disturbance []float64 `json:"disturbance,omitempty"`
+	Metrics     Metrics   `json:"metrics"`
+	Cost        *Cost     `json:"cost,omitempty"`
+	Status      Status    `json:"status"`
+	// StoppedBy is set when a stop condition ended the run early.
+	StoppedBy *Stop `json:"stoppedBy,omitempty"`
+	// Phases is set when the scenario has a recipe.
+	Phases []PhaseRun `json:"phases,omitempty"`
+	// Bands are set when the scenario has bands, with their edges along
+	// the setpoint.
+	Bands []BandTrace `json:"bands,omitempty"`
+	// Events are sorted by time.
+	Events []Event `json:"events"`
+	// Permalink is the path of the URL reproducing the run on any
+	// instance, set by the server, see Scenario.Permalink.
+	Permalink string `json:"permalink,omitempty"`
+}
+
+// Voltage is the voltage at both ends of the electrical system, in volts.
+type Voltage struct {
+	Inverters []float64 `json:"inverters"`
+	POC       []float64 `json:"poc"`
+}
+
+func status(r Result) Status {
+	for _, y := range r.PV {
+		if math.IsNaN(y) || math.IsInf(y, 0) {
+			return Diverged
+		}
+	}
+	if r.Metrics.Settled {
+		return Settled
+	}
+	return NotSettled
+}
+
+// Rounded returns a copy of the result rounded for output, see numfmt.
+func (r Result) Rounded() Result {
+	var stop *Stop
+	if r.StoppedBy != nil {
+		stop = &Stop{Condition: r.StoppedBy.Condition, Time: numfmt.Round(r.StoppedBy.Time)}
+	}
+	events := make([]Event, len(r.Events))
+	for k, e := range r.Events {
+		e.T = numfmt.Round(e.T)
+		events[k] = e
+	}
+	var adaptation *Adaptation
+	if r.Adaptation != nil {
+		adaptation = &Adaptation{K: numfmt.Series(r.Adaptation.K), P: numfmt.Series(r.Adaptation.P)}
+	}
+	var voltage *Voltage
+	if r.Voltage != nil {
+		voltage = &Voltage{Inverters: numfmt.Series(r.Voltage.Inverters), POC: numfmt.Series(r.Voltage.POC)}
+	}
+	var cost *Cost
+	if r.Cost != nil {
+		c := r.Cost.Rounded()
+		cost = &c
+	}
+	var bands []BandTrace
+	for _, b := range r.Bands {
+		b.Low, b.High = numfmt.Series(b.Low), numfmt.Series(b.High)
+		bands = append(bands, b)
+	}
+	var phases []PhaseRun
+	for _, ph := range r.Phases {
+		ph.Start, ph.End = numfmt.Round(ph.Start), numfmt.Round(ph.End)
+		phases = append(phases, ph)
+	}
+	return Result{
+		Start:       r.Start,
+		Timestamps:  r.Timestamps,
+		Time:        numfmt.Series(r.Time),
+		SP:          numfmt.Series(r.SP),
+		WSP:         numfmt.Series(r.WSP),
+		PV:          numfmt.Series(r.PV),
+		Inflow:      numfmt.Series(r.Inflow),
+		Disturbance: numfmt.Series(r.Disturbance),
+		Losses:      roundLosses(r.Losses),
+		Voltage:     voltage,
+		Tap:         numfmt.Series(r.Tap),
+		U:           numfmt.Series(r.U),
+		Components: Components{
+			P: numfmt.Series(r.Components.P),
+			I: numfmt.Series(r.Components.I),
+			D: numfmt.Series(r.Components.D),
+		},
+		Adaptation: adaptation,
+		Metrics:    r.Metrics.Rounded(),
+		Status:     r.Status,
+		StoppedBy:  stop,
+		Cost:       cost,
+		Phases:     phases,
+		Bands:      bands,
+		Events:     events,
+		Permalink:  r.Permalink,
+	}
+}
+
+// WriteCSV writes the series as t,sp,pv,u,p,i,d rows in the dialect f,
+// preceded by a timestamp column when the result is anchored. Missing
+// series, as in runs stored without them, give empty cells.
+func (r Result) WriteCSV(w io.Writer, f numfmt.CSV) error {
+
+	cell := func(xs []float64, k int) string {
+		if k >= len(xs) {
+			return ""
+		}
+		return f.Number(xs[k])
+	}
+
+	header := []string{"t", "sp", "pv", "u", "p", "i", "d"}
+	if r.Start != nil {
+		header = append([]string{"timestamp"}, header...)
+	}
+
+	cw := f.Writer(w)
+	cw.Write(header)
+	for k := range r.Time {
+		var row []string
+		if r.Start != nil {
+			row = append(row, f.Timestamp(Timestamp(*r.Start, r.Time[k])))
+		}
+		cw.Write(append(row,
+			cell(r.Time, k),
+			cell(r.SP, k),
+			cell(r.PV, k),
+			cell(r.U, k),
+			cell(r.Components.P, k),
+			cell(r.Components.I, k),
+			cell(r.Components.D, k),
+		))
+	}
+	cw.Flush()
+	return cw.Error()
+}
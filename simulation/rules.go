@@ -0,0 +1,137 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Tuning rule names accepted by ApplyRule.
+const (
+	RuleSIMC              = "simc"
+	RuleZieglerNicholsPI  = "ziegler-nichols-pi"
+	RuleZieglerNicholsPID = "ziegler-nichols-pid"
+)
+
+// DelayedModel is the model the tuning rules start from: the first-order
+// plus dead-time plant K·e^(-Theta·s) / (1 + T·s), or the integrator
+// K·e^(-Theta·s) / s when T is zero, as identified from a step response.
+type DelayedModel struct {
+	K     float64 `json:"K"`
+	T     float64 `json:"T"`
+	Theta float64 `json:"theta"`
+}
+
+// DelayedModelSchema describes a DelayedModel.
+var DelayedModelSchema = schema.Object(map[string]*schema.Schema{
+	"K":     schema.Number("Gain du procédé, par unité de commande (par seconde pour un intégrateur)"),
+	"T":     schema.Number("Constante de temps (s), nulle pour un procédé intégrateur").Min(0).WithDefault(0.0),
+	"theta": schema.Number("Retard pur (s)").Above(0),
+}, "K", "theta")
+
+// TuningSchema describes the "tuning" member of a scenario, as returned by
+// ApplyRule.
+var TuningSchema = schema.Object(map[string]*schema.Schema{
+	"rule":        schema.String("Règle de réglage appliquée"),
+	"P":           schema.Number("Coefficient proportionnel proposé"),
+	"Ki":          schema.Number("Coefficient intégral proposé"),
+	"Kd":          schema.Number("Coefficient dérivé proposé").WithDefault(0.0),
+	"explanation": {Type: "object", Description: "Explication du réglage : modèle, formules, hypothèses et domaine de validité"},
+}, "rule", "P", "Ki")
+
+// Check reports the inconsistencies the schema cannot express.
+func (m DelayedModel) Check() []schema.Error {
+	if m.K == 0 {
+		return []schema.Error{{Path: "/K", Message: "le gain du procédé doit être non nul"}}
+	}
+	return nil
+}
+
+// Explanation tells why a tuning rule suggested its gains: the model of
+// the plant it started from, the formulae it applied in order with their
+// values, the assumptions it rests on and the plants it is meant for.
+type Explanation struct {
+	Model       string     `json:"model"`
+	Parameters  []Quantity `json:"parameters"`
+	Formulae    []Formula  `json:"formulae"`
+	Assumptions []string   `json:"assumptions"`
+	Validity    Validity   `json:"validity"`
+}
+
+// Quantity is a named parameter of the model.
+type Quantity struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Value       float64 `json:"value"`
+}
+
+// Formula is one step of a rule: Name = Expression, worth Value.
+type Formula struct {
+	Name       string  `json:"name"`
+	Expression string  `json:"expression"`
+	Value      float64 `json:"value"`
+}
+
+// Validity is the range of the ratio θ/T a rule is meant for, Max being
+// nil when it has no upper bound, and whether the model falls within. An
+// integrator is the limit θ/T = 0.
+type Validity struct {
+	Ratio  float64  `json:"ratio"`
+	Min    float64  `json:"min"`
+	Max    *float64 `json:"max,omitempty"`
+	Within bool     `json:"within"`
+	Note   string   `json:"note,omitempty"`
+}
+
+// ApplyRule returns the tuning of the rule for the model, explained.
+func ApplyRule(rule string, m DelayedModel) (Tuning, error) {
+	switch rule {
+	case RuleSIMC:
+		return simc(m.K, m.T, m.Theta), nil
+	case RuleZieglerNicholsPI:
+		return zieglerNichols(m.K, m.T, m.Theta, false), nil
+	case RuleZieglerNicholsPID:
+		return zieglerNichols(m.K, m.T, m.Theta, true), nil
+	}
+	return Tuning{}, fmt.Errorf("règle de réglage inconnue %q", rule)
+}
+
+// explain starts the explanation of a rule applied to the model: its
+// transfer function, its parameters and the ratio θ/T.
+func explain(K, T, theta float64) *Explanation {
+	x := &Explanation{
+		Model: "K·e^(-θ·s) / (1 + T·s)",
+		Parameters: []Quantity{
+			{Name: "K", Description: "gain du procédé", Value: K},
+			{Name: "T", Description: "constante de temps (s)", Value: T},
+			{Name: "θ", Description: "retard pur (s)", Value: theta},
+		},
+		Assumptions: []string{"le procédé se comporte comme son modèle, identifié autour du point de fonctionnement : un gain qui varie avec la charge change le comportement réglé"},
+	}
+	if T == 0 {
+		x.Model = "K·e^(-θ·s) / s"
+		x.Parameters = []Quantity{
+			{Name: "K", Description: "gain du procédé intégrateur (par seconde)", Value: K},
+			{Name: "θ", Description: "retard pur (s)", Value: theta},
+		}
+	} else {
+		x.Validity.Ratio = short(theta / T)
+	}
+	return x
+}
+
+// step appends a formula whose value is rounded as the gains are.
+func (x *Explanation) step(name, expression string, value float64) {
+	x.Formulae = append(x.Formulae, Formula{Name: name, Expression: expression, Value: short(value)})
+}
+
+// within sets the validity range, max being zero when unbounded.
+func (x *Explanation) within(min, max float64, note string) {
+	x.Validity.Min = min
+	x.Validity.Within = x.Validity.Ratio >= min
+	if max > 0 {
+		x.Validity.Max = &max
+		x.Validity.Within = x.Validity.Within && x.Validity.Ratio <= max
+	}
+	x.Validity.Note = note
+}
@@ -0,0 +1,157 @@
+package simulation
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// MaxScanPoints bounds the number of frequencies of an impedance scan.
+const MaxScanPoints = 5000
+
+// Resonance types accepted in Resonance.Type.
+const (
+	// ResonanceParallel is a peak of |Z|, amplifying the harmonic voltages
+	// of the currents injected at that frequency.
+	ResonanceParallel = "parallel"
+	// ResonanceSeries is a dip of |Z|, drawing the harmonic currents of
+	// the grid at that frequency.
+	ResonanceSeries = "series"
+)
+
+// Resonance is a local extremum of |Z| in an impedance scan.
+type Resonance struct {
+	Type      string  `json:"type"`
+	Frequency float64 `json:"frequency"`
+	// Order is the frequency over the grid frequency F.
+	Order     float64 `json:"order"`
+	Magnitude float64 `json:"magnitude"`
+}
+
+// ImpedanceScan is the impedance of the system seen from the POC over a
+// range of frequencies, the inverters shorted as ideal voltage sources:
+// its magnitude in ohms and its angle in degrees at every frequency, in
+// hertz, and the resonances found between them.
+type ImpedanceScan struct {
+	Frequency  []float64   `json:"frequency"`
+	Magnitude  []float64   `json:"magnitude"`
+	Angle      []float64   `json:"angle"`
+	Resonances []Resonance `json:"resonances"`
+}
+
+// impedance returns the impedance of the system seen from the POC at the
+// frequency f: the lumped R, L and C in series, then every block, a
+// transformer referring what precedes it to its POC side and a cable
+// adding half of its susceptance at each end.
+func (sys ElectricalSystem) impedance(f float64) complex128 {
+
+	sys = sys.at(f)
+	w := 2 * math.Pi * f
+	Z := complex(sys.R, float64(w*sys.L))
+	if sys.C != 0 {
+		Z -= complex(0, 1/float64(w*sys.C))
+	}
+	for _, b := range sys.Blocks {
+		Z = shunt(Z, b.B/2)
+		Z += complex(b.R, b.X)
+		Z = shunt(Z, b.B/2)
+		if r := b.ratio(); r != 1 {
+			Z /= complex(float64(r*r), 0)
+		}
+	}
+	return Z
+}
+
+// shunt returns Z in parallel with the susceptance B.
+func shunt(Z complex128, B float64) complex128 {
+	if B == 0 {
+		return Z
+	}
+	return Z / (1 + Z*complex(0, B))
+}
+
+// Scan computes the impedance of the system seen from the POC at points
+// frequencies spaced logarithmically from from to to, in hertz, the
+// reactances scaled from those at F.
+func (sys ElectricalSystem) Scan(from, to float64, points int) (ImpedanceScan, error) {
+
+	if from <= 0 || to <= from {
+		return ImpedanceScan{}, fmt.Errorf("la plage de fréquences doit vérifier 0 < from < to, reçu %g et %g", from, to)
+	}
+	if points < 2 || points > MaxScanPoints {
+		return ImpedanceScan{}, fmt.Errorf("le nombre de fréquences doit être compris entre 2 et %d", MaxScanPoints)
+	}
+	s := ImpedanceScan{
+		Frequency: make([]float64, points),
+		Magnitude: make([]float64, points),
+		Angle:     make([]float64, points),
+	}
+	ratio := math.Log(to / from)
+	for k := range points {
+		f := from * math.Exp(ratio*float64(k)/float64(points-1))
+		Z := sys.impedance(f)
+		s.Frequency[k] = f
+		s.Magnitude[k] = cmplx.Abs(Z)
+		s.Angle[k] = cmplx.Phase(Z) * 180 / math.Pi
+	}
+	for k := 1; k < points-1; k++ {
+		m := s.Magnitude
+		var kind string
+		switch {
+		case m[k] > m[k-1] && m[k] >= m[k+1]:
+			kind = ResonanceParallel
+		case m[k] < m[k-1] && m[k] <= m[k+1]:
+			kind = ResonanceSeries
+		default:
+			continue
+		}
+		s.Resonances = append(s.Resonances, Resonance{Type: kind, Frequency: s.Frequency[k], Order: s.Frequency[k] / sys.F, Magnitude: m[k]})
+	}
+	return s, nil
+}
+
+// Rounded returns a copy rounded for output, see numfmt.
+func (s ImpedanceScan) Rounded() ImpedanceScan {
+	out := ImpedanceScan{
+		Frequency:  numfmt.Series(s.Frequency),
+		Magnitude:  numfmt.Series(s.Magnitude),
+		Angle:      numfmt.Series(s.Angle),
+		Resonances: make([]Resonance, len(s.Resonances)),
+	}
+	for i, r := range s.Resonances {
+		out.Resonances[i] = Resonance{Type: r.Type, Frequency: numfmt.Round(r.Frequency), Order: numfmt.Round(r.Order), Magnitude: numfmt.Round(r.Magnitude)}
+	}
+	return out
+}
+
+// WritePlot draws |Z| against the frequency to w in the format, as
+// Result.WritePlot, the resonances marked by points.
+func (s ImpedanceScan) WritePlot(w io.Writer, format string, opts PlotOptions) error {
+
+	loc, theme, err := opts.style()
+	if err != nil {
+		return err
+	}
+
+	peaks := chart.Series{Kind: chart.KindPoints, Label: loc.Resonance, Color: theme.Setpoint, Width: theme.LineWidth * 2}
+	for _, r := range s.Resonances {
+		peaks.X = append(peaks.X, r.Frequency)
+		peaks.Y = append(peaks.Y, r.Magnitude)
+	}
+	return opts.Render(w, chart.Figure{
+		Title:        loc.ScanTitle,
+		XLabel:       loc.Frequency,
+		YLabel:       loc.Impedance,
+		Style:        theme.style(),
+		DecimalComma: loc.DecimalComma,
+		Legend:       len(s.Resonances) > 0,
+		Series: []chart.Series{
+			{Label: "|Z|", X: s.Frequency, Y: s.Magnitude, Color: theme.Measurement, Width: theme.LineWidth},
+			peaks,
+		},
+	}, format)
+}
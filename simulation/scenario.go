@@ -0,0 +1,226 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// Scenario gathers the parameters of one simulation run, as posted to
+// /sendData.
+type Scenario struct {
+	// Controller is one of the Controllers, ControllerPID when empty.
+	Controller string `json:"controller,omitempty"`
+
+	Sp  float64 `json:"Sp"`
+	Tau float64 `json:"Tau"`
+	K   float64 `json:"K"`
+	P   float64 `json:"P"`
+	Ki  float64 `json:"Ki"`
+	Kd  float64 `json:"Kd"`
+	Dt  float64 `json:"dt"`
+	N   float64 `json:"N"`
+
+	// SpRamp limits the rate of change of the working setpoint, in units
+	// per second: the controller follows a ramp from the measurement at
+	// rest towards Sp, and towards every later value of Sp, instead of a
+	// step.
+	SpRamp *float64 `json:"spRamp,omitempty"`
+	// Manual starts the run in manual mode.
+	Manual *Manual `json:"manual,omitempty"`
+
+	// Plant is one of the Plants, PlantFirstOrder when empty.
+	Plant string `json:"plant,omitempty"`
+	// StateSpace is the model of a PlantStateSpace plant, which then
+	// ignores K, Tau and their variations.
+	StateSpace *StateSpace `json:"stateSpace,omitempty"`
+	// Cooling is the cooling side of a PlantHeatCool plant.
+	Cooling *Cooling `json:"cooling,omitempty"`
+	// PH is the acid of a PlantPH plant.
+	PH *PH `json:"ph,omitempty"`
+	// Level is the inflow of a PlantLevel plant.
+	Level *Level `json:"level,omitempty"`
+	// Electrical is the system and the active power of a PlantReactive
+	// plant.
+	Electrical *Electrical `json:"electrical,omitempty"`
+	// Schedule, when set, replaces the controller gains by gains scheduled
+	// on the measurement, sorted by PV.
+	Schedule []GainPoint `json:"schedule,omitempty"`
+
+	// KDrift makes the plant gain vary linearly, in gain units per second.
+	KDrift float64 `json:"kDrift,omitempty"`
+	// Variation replaces K or Tau by profiles varying in time.
+	Variation *PlantVariation `json:"variation,omitempty"`
+	// Recipe runs the phases in order; the run ends with the last one.
+	Recipe []Phase `json:"recipe,omitempty"`
+	// Drive replays a recorded setpoint and load disturbance.
+	Drive *Drive `json:"drive,omitempty"`
+	// Costs, when set, prices the run in Result.Cost and Metrics.Cost.
+	Costs *CostRates `json:"costs,omitempty"`
+	// Forgetting is the forgetting factor of the adaptive controller.
+	Forgetting float64 `json:"forgetting,omitempty"`
+	// Noise disturbs the measurement read by the controller.
+	Noise *Noise `json:"noise,omitempty"`
+	// Theta is the dead time of the plant, in seconds: the plant responds
+	// to the controller output u(t − Theta), interpolated between two
+	// samples when Theta is not a multiple of dt.
+	Theta float64 `json:"theta,omitempty"`
+	// Tuning is the rule the gains P, Ki and Kd were taken from, with its
+	// explanation, kept with the run.
+	Tuning *Tuning `json:"tuning,omitempty"`
+	// Bands are the target bands and regions the plots shade.
+	Bands []Band `json:"bands,omitempty"`
+
+	Limits
+
+	Stop *StopConditions `json:"stop,omitempty"`
+}
+
+// Run simulates the scenario.
+func (sc Scenario) Run() Result {
+	return Simulation(sc)
+}
+
+// Evaluate simulates the scenario and returns the metrics of its response.
+// The series of the run are recycled, see trajectoryPool.
+func (sc Scenario) Evaluate() Metrics {
+	res, _ := simulate(sc, getTrajectory(int(sc.N)+1))
+	m := res.Metrics
+	recycle(res)
+	return m
+}
+
+// Param returns the parameter with the given JSON name, so that sweeps and
+// optimizers can address scenario fields generically.
+func (sc *Scenario) Param(name string) (*float64, error) {
+	switch name {
+	case "Sp":
+		return &sc.Sp, nil
+	case "Tau":
+		return &sc.Tau, nil
+	case "K":
+		return &sc.K, nil
+	case "P":
+		return &sc.P, nil
+	case "Ki":
+		return &sc.Ki, nil
+	case "Kd":
+		return &sc.Kd, nil
+	case "dt":
+		return &sc.Dt, nil
+	case "N":
+		return &sc.N, nil
+	case "spRamp":
+		return optional(&sc.SpRamp), nil
+	case "kDrift":
+		return &sc.KDrift, nil
+	case "forgetting":
+		return &sc.Forgetting, nil
+	case "theta":
+		return &sc.Theta, nil
+	case "uMin":
+		return optional(&sc.UMin), nil
+	case "uMax":
+		return optional(&sc.UMax), nil
+	case "iMax":
+		return optional(&sc.IMax), nil
+	case "dMax":
+		return optional(&sc.DMax), nil
+	case "backCalculation":
+		return optional(&sc.BackCalculation), nil
+	}
+	return nil, fmt.Errorf("paramètre inconnu %q", name)
+}
+
+// Gain returns the plant gain at time t.
+func (sc Scenario) Gain(t float64) float64 {
+	k := sc.K
+	if sc.Variation != nil && sc.Variation.K != nil {
+		k = sc.Variation.K.At(t)
+	}
+	return k + float64(sc.KDrift*t)
+}
+
+// TimeConstant returns the plant time constant at time t.
+func (sc Scenario) TimeConstant(t float64) float64 {
+	if sc.Variation != nil && sc.Variation.Tau != nil {
+		return sc.Variation.Tau.At(t)
+	}
+	return sc.Tau
+}
+
+// Check reports the inconsistencies between parameters that the scenario
+// schema cannot express.
+func (sc Scenario) Check() []schema.Error {
+	var errs []schema.Error
+	if sc.Controller == ControllerExternalReset && sc.Ki != 0 && sc.P == 0 {
+		errs = append(errs, schema.Error{Path: "/P", Message: "doit être non nul pour le régulateur à reset externe (Ti = P / Ki)"})
+	}
+	errs = append(errs, checkRecipe(sc.Recipe)...)
+	if t := sc.Tuning; t != nil && (t.P != sc.P || t.Ki != sc.Ki || t.Kd != sc.Kd) {
+		errs = append(errs, schema.Error{Path: "/tuning", Message: fmt.Sprintf("les gains diffèrent de ceux du réglage %s : retirer tuning ou reprendre ses gains", t.Rule)})
+	}
+	switch {
+	case sc.Plant == PlantStateSpace && sc.StateSpace == nil:
+		errs = append(errs, schema.Error{Path: "/stateSpace", Message: "requis pour le procédé d'état"})
+	case sc.StateSpace != nil:
+		errs = append(errs, sc.StateSpace.check("/stateSpace")...)
+	}
+	if sc.Plant == PlantHeatCool && sc.Cooling == nil {
+		errs = append(errs, schema.Error{Path: "/cooling", Message: "requis pour le procédé de chauffage/refroidissement"})
+	}
+	if sc.Plant == PlantPH && sc.PH == nil {
+		errs = append(errs, schema.Error{Path: "/ph", Message: "requis pour le procédé de neutralisation"})
+	}
+	switch {
+	case sc.Plant == PlantLevel && sc.Level == nil:
+		errs = append(errs, schema.Error{Path: "/level", Message: "requis pour le procédé de niveau"})
+	case sc.Level != nil && sc.Level.Profile != nil:
+		errs = append(errs, sc.Level.Profile.check("/level/profile", false)...)
+	}
+	switch {
+	case sc.Plant == PlantReactive && sc.Electrical == nil:
+		errs = append(errs, schema.Error{Path: "/electrical", Message: "requis pour le procédé de puissance réactive"})
+	case sc.Electrical != nil:
+		for _, e := range sc.Electrical.Check() {
+			e.Path = "/electrical" + e.Path
+			errs = append(errs, e)
+		}
+	}
+	if sc.UMin != nil && sc.UMax != nil && *sc.UMin > *sc.UMax {
+		errs = append(errs, schema.Error{Path: "/uMax", Message: fmt.Sprintf("ne doit pas être inférieur à uMin = %g", *sc.UMin)})
+	}
+	switch {
+	case sc.Theta > float64(sc.N*sc.Dt):
+		errs = append(errs, schema.Error{Path: "/theta", Message: fmt.Sprintf("le retard %g s dépasse la durée de la simulation, N·dt = %g s", sc.Theta, float64(sc.N*sc.Dt))})
+	case sc.Dt > 0 && sc.Theta/sc.Dt > maxDelaySteps:
+		errs = append(errs, schema.Error{Path: "/theta", Message: fmt.Sprintf("le retard %g s dépasse %d pas de calcul, augmenter dt", sc.Theta, maxDelaySteps)})
+	}
+	errs = append(errs, checkSchedule(sc)...)
+	if sc.Drive != nil {
+		errs = append(errs, sc.Drive.check(len(sc.Recipe) > 0)...)
+	}
+	for i, b := range sc.Bands {
+		errs = append(errs, b.check(fmt.Sprintf("/bands/%d", i))...)
+	}
+	if v := sc.Variation; v != nil {
+		if v.K != nil {
+			errs = append(errs, v.K.check("/variation/K", false)...)
+		}
+		if v.Tau != nil {
+			errs = append(errs, v.Tau.check("/variation/Tau", true)...)
+		}
+	}
+	return errs
+}
+
+// optional gives access to an optional parameter. The value is copied to a
+// new variable first, since copies of a scenario share their pointers.
+func optional(p **float64) *float64 {
+	v := 0.0
+	if *p != nil {
+		v = **p
+	}
+	*p = &v
+	return &v
+}
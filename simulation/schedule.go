@@ -0,0 +1,79 @@
+package simulation
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// GainPoint is the gain set of a gain schedule at measurement PV.
+type GainPoint struct {
+	PV float64 `json:"pv"`
+	P  float64 `json:"P"`
+	Ki float64 `json:"Ki"`
+	Kd float64 `json:"Kd"`
+}
+
+// ScheduleSchema describes the optional "schedule" member of a scenario.
+var ScheduleSchema = schema.Array(schema.Object(map[string]*schema.Schema{
+	"pv": schema.Number("Mesure à laquelle s'appliquent ces gains"),
+	"P":  schema.Number("Coefficient proportionnel"),
+	"Ki": schema.Number("Coefficient intégral"),
+	"Kd": schema.Number("Coefficient dérivé"),
+}, "pv", "P", "Ki", "Kd")).AtLeast(1)
+
+// scheduledGains returns the gains of the schedule at measurement pv,
+// interpolated linearly between the points and held beyond the first and
+// the last.
+func scheduledGains(points []GainPoint, pv float64) (P, Ki, Kd float64) {
+	i := scheduleSegment(points, pv)
+	switch {
+	case i == 0:
+		return points[0].P, points[0].Ki, points[0].Kd
+	case i == len(points):
+		return points[i-1].P, points[i-1].Ki, points[i-1].Kd
+	}
+	a, b := points[i-1], points[i]
+	f := (pv - a.PV) / (b.PV - a.PV)
+	lerp := func(x, y float64) float64 { return x + float64((y-x)*f) }
+	return lerp(a.P, b.P), lerp(a.Ki, b.Ki), lerp(a.Kd, b.Kd)
+}
+
+// scheduleSegment returns the segment of the schedule at measurement pv:
+// 0 below the first point, len(points) beyond the last, i between points
+// i−1 and i.
+func scheduleSegment(points []GainPoint, pv float64) int {
+	return sort.Search(len(points), func(i int) bool { return points[i].PV > pv })
+}
+
+// scheduleEvent is the event of the measurement pv entering segment i of
+// the schedule, where the gains become P, Ki and Kd.
+func scheduleEvent(t float64, points []GainPoint, i int, pv, P, Ki, Kd float64) Event {
+	var where string
+	switch {
+	case i == 0:
+		where = fmt.Sprintf("sous %g", points[0].PV)
+	case i == len(points):
+		where = fmt.Sprintf("au-delà de %g", points[i-1].PV)
+	default:
+		where = fmt.Sprintf("entre %g et %g", points[i-1].PV, points[i].PV)
+	}
+	return Event{T: t, Type: EventSchedule,
+		Message: fmt.Sprintf("Mesure %.3g %s : gains (P, Ki, Kd) (%.3g, %.3g, %.3g)", pv, where, P, Ki, Kd)}
+}
+
+// checkSchedule reports the problems of a schedule that the schema cannot
+// express.
+func checkSchedule(sc Scenario) []schema.Error {
+	var errs []schema.Error
+	for i, pt := range sc.Schedule {
+		if i > 0 && pt.PV <= sc.Schedule[i-1].PV {
+			errs = append(errs, schema.Error{Path: fmt.Sprintf("/schedule/%d/pv", i), Message: "les mesures doivent être strictement croissantes"})
+		}
+	}
+	if len(sc.Schedule) > 0 && sc.Controller == ControllerAdaptive {
+		errs = append(errs, schema.Error{Path: "/schedule", Message: "incompatible avec le régulateur adaptatif"})
+	}
+	return errs
+}
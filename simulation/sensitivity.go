@@ -0,0 +1,142 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// SensitivityGains are the gains nudged by Scenario.Sensitivity.
+var SensitivityGains = []string{"P", "Ki", "Kd"}
+
+// SensitivityMetrics are the metrics of Scenario.Sensitivity by default.
+var SensitivityMetrics = []string{"overshoot", "settling", "iae", "umax"}
+
+// Sensitivity is the local sensitivity of metrics to the gains, by finite
+// differences around the scenario.
+type Sensitivity struct {
+	Gains []string `json:"gains"`
+	// Steps are the nudges of the gains: the relative step times the gain,
+	// or times the largest gain for a gain at zero.
+	Steps []float64        `json:"steps"`
+	Rows  []SensitivityRow `json:"rows"`
+}
+
+// SensitivityRow is the sensitivity of one metric, its slices following
+// Sensitivity.Gains.
+type SensitivityRow struct {
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+	// Derivatives are ∂metric/∂gain, by central differences, or forward
+	// differences for a gain at zero.
+	Derivatives []float64 `json:"derivatives"`
+	// Effects are the changes of the metric for the step of each gain,
+	// which compare the gains whatever their units.
+	Effects []float64 `json:"effects"`
+	// Knob is the gain with the largest effect, and Direction how to turn
+	// it to lower the metric: "increase" or "decrease", every metric being
+	// better lower. Both are empty when no gain moves the metric.
+	Knob      string `json:"knob,omitempty"`
+	Direction string `json:"direction,omitempty"`
+}
+
+// Sensitivity nudges each gain of the scenario by rel of its value and
+// returns the derivatives of the metrics, SensitivityMetrics when none are
+// given. It runs the scenario 1 + 2 × len(SensitivityGains) times.
+func (sc Scenario) Sensitivity(metrics []string, rel float64) (Sensitivity, error) {
+
+	if len(metrics) == 0 {
+		metrics = SensitivityMetrics
+	}
+	if !(rel > 0 && rel < 1) {
+		return Sensitivity{}, fmt.Errorf("pas relatif invalide %g, attendu entre 0 et 1 exclus", rel)
+	}
+	values := func(m Metrics) ([]float64, error) {
+		out := make([]float64, len(metrics))
+		for i, name := range metrics {
+			v, err := m.Get(name)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	base, err := values(sc.Run().Metrics)
+	if err != nil {
+		return Sensitivity{}, err
+	}
+
+	scale := 0.0
+	for _, name := range SensitivityGains {
+		p, _ := sc.Param(name)
+		scale = math.Max(scale, math.Abs(*p))
+	}
+	if scale == 0 {
+		scale = 1
+	}
+
+	s := Sensitivity{Gains: SensitivityGains, Steps: make([]float64, len(SensitivityGains))}
+	for i, name := range metrics {
+		s.Rows = append(s.Rows, SensitivityRow{
+			Metric:      name,
+			Value:       base[i],
+			Derivatives: make([]float64, len(SensitivityGains)),
+			Effects:     make([]float64, len(SensitivityGains)),
+		})
+	}
+	for j, name := range SensitivityGains {
+		nudged := func(delta float64) []float64 {
+			run := sc
+			p, _ := run.Param(name)
+			*p += delta
+			v, _ := values(run.Run().Metrics)
+			return v
+		}
+		p, _ := sc.Param(name)
+		h := rel * math.Abs(*p)
+		if h == 0 {
+			h = rel * scale
+		}
+		s.Steps[j] = h
+
+		up := nudged(h)
+		down, span := base, h
+		if *p != 0 {
+			down, span = nudged(-h), 2*h
+		}
+		for i := range metrics {
+			d := (up[i] - down[i]) / span
+			s.Rows[i].Derivatives[j] = d
+			s.Rows[i].Effects[j] = float64(d * h)
+		}
+	}
+
+	for i := range s.Rows {
+		row := &s.Rows[i]
+		best := 0.0
+		for j, e := range row.Effects {
+			if math.Abs(e) > best {
+				best = math.Abs(e)
+				row.Knob, row.Direction = s.Gains[j], "decrease"
+				if e < 0 {
+					row.Direction = "increase"
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+// Rounded returns a copy of s rounded for output, see numfmt.
+func (s Sensitivity) Rounded() Sensitivity {
+	out := Sensitivity{Gains: s.Gains, Steps: numfmt.Series(s.Steps)}
+	for _, row := range s.Rows {
+		row.Value = numfmt.Round(row.Value)
+		row.Derivatives = numfmt.Series(row.Derivatives)
+		row.Effects = numfmt.Series(row.Effects)
+		out.Rows = append(out.Rows, row)
+	}
+	return out
+}
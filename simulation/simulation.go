@@ -1,9 +1,59 @@
 package simulation
 
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// terms are the parts of one controller output.
+type terms struct{ p, i, d float64 }
+
 type PID struct {
-	Kp, Ki, Kd        float64
-	integral          float64
+	Kp, Ki, Kd float64
+	// integral is ∫e dt, compensated so that long runs do not drift.
+	integral          kahanSum
 	previouserror_pid float64
+	// slope is the last estimate de/dt of the derivative of the error.
+	slope float64
+
+	// ExternalReset selects the external-reset form, see externalReset.
+	ExternalReset bool
+	reset         float64
+
+	Limits
+	last      terms
+	saturated bool
+}
+
+// Limits are the industrial options of the PID. Each one is off when nil
+// or false, so that anti-windup strategies can be compared one by one.
+type Limits struct {
+	// UMin and UMax saturate the output.
+	UMin *float64 `json:"uMin,omitempty"`
+	UMax *float64 `json:"uMax,omitempty"`
+	// IMax clamps the integral term to ±IMax; the integral itself is
+	// clamped with it so that it does not keep winding up.
+	IMax *float64 `json:"iMax,omitempty"`
+	// DMax clamps the derivative term to ±DMax.
+	DMax *float64 `json:"dMax,omitempty"`
+	// ConditionalIntegration freezes the integral while the output is
+	// saturated and the error would drive it further into saturation.
+	ConditionalIntegration bool `json:"conditionalIntegration,omitempty"`
+	// BackCalculation is the tracking time constant Tt, in seconds, of the
+	// back-calculation anti-windup: while the output is saturated, the
+	// difference between the saturated and the computed outputs is fed
+	// back into the integral term at the rate 1/Tt, which unwinds it
+	// instead of freezing it. Tt = Ti = P/Ki is a common choice.
+	BackCalculation *float64 `json:"backCalculation,omitempty"`
+}
+
+// clamp bounds v to ±limit when limit is set.
+func clamp(v float64, limit *float64) float64 {
+	if limit == nil {
+		return v
+	}
+	return math.Max(-*limit, math.Min(*limit, v))
 }
 
 // NewPID creates a new PID controller with the specified gains
@@ -20,37 +70,539 @@ func (pid *PID) Compute(setpoint, currentValue, dt float64) float64 {
 
 	error_pid := setpoint - currentValue
 
-	proportional := pid.Kp * error_pid
+	// The explicit float64 conversions round every product, which keeps
+	// the compiler from fusing multiply-adds on some architectures (arm64,
+	// ppc64, s390x) so that results are identical everywhere.
+	proportional := float64(pid.Kp * error_pid)
 
-	pid.integral += error_pid * dt
-	integral := pid.Ki * pid.integral
-
-	derivative := pid.Kd * (error_pid - pid.previouserror_pid) / dt
+	derivative := clamp(float64(pid.Kd*(error_pid-pid.previouserror_pid))/dt, pid.DMax)
+	pid.slope = (error_pid - pid.previouserror_pid) / dt
 	pid.previouserror_pid = error_pid
 
-	output := proportional + integral + derivative
+	if pid.ExternalReset {
+		return pid.externalReset(proportional, derivative, dt)
+	}
+
+	previousIntegral := pid.integral
+	pid.integral.add(float64(error_pid * dt))
+	integral := pid.integralTerm()
+
+	output, saturated := pid.saturate(proportional + integral + derivative)
+	if pid.ConditionalIntegration && saturated != 0 && math.Signbit(error_pid) == (saturated < 0) {
+		pid.integral = previousIntegral
+		integral = pid.integralTerm()
+		output, saturated = pid.saturate(proportional + integral + derivative)
+	}
+	pid.saturated = saturated != 0
+	if pid.BackCalculation != nil && saturated != 0 && pid.Ki != 0 {
+		// d(Ki·∫e)/dt gets (u_sat − u)/Tt, the integral ∫e that over Ki.
+		excess := output - (proportional + integral + derivative)
+		pid.integral.add(float64(excess/float64(pid.Ki**pid.BackCalculation)) * dt)
+	}
+
+	pid.last = terms{proportional, integral, derivative}
 	return output
 }
 
-func Simulation(Sp, Tau, K, P, Ki, Kd, dt, N float64) ([]float64, []float64) {
+// externalReset computes the output of the external-reset form used by many
+// DCS: the integral action is a first-order lag, of time constant
+// Ti = Kp/Ki, of the output actually applied, fed back positively. As the
+// lag follows the saturated output the integral cannot wind up, so
+// ConditionalIntegration and BackCalculation have no effect.
+func (pid *PID) externalReset(proportional, derivative, dt float64) float64 {
+
+	reset := clamp(pid.reset, pid.IMax)
+	output, saturated := pid.saturate(proportional + reset + derivative)
+	pid.saturated = saturated != 0
+
+	if pid.Kp != 0 {
+		pid.reset = reset + float64(float64(dt*pid.Ki/pid.Kp)*(output-reset))
+	}
+
+	pid.last = terms{proportional, reset, derivative}
+	return output
+}
+
+// Track aligns the controller on an output applied instead of its own, as
+// in manual mode, so that the next computed output continues from it
+// without a bump: the integral takes up the difference with the
+// proportional term, and the derivative starts from the current error.
+func (pid *PID) Track(output, setpoint, currentValue float64) {
+
+	error_pid := setpoint - currentValue
+	proportional := float64(pid.Kp * error_pid)
+	pid.previouserror_pid, pid.slope = error_pid, 0
+	switch {
+	case pid.ExternalReset:
+		pid.reset = output - proportional
+	case pid.Ki != 0:
+		pid.integral = kahanSum{sum: (output - proportional) / pid.Ki}
+	}
+	pid.saturated = false
+	pid.last = terms{proportional, output - proportional, 0}
+}
+
+// SetGains changes the gains keeping the integral term, so that a gain
+// schedule does not bump the output.
+func (pid *PID) SetGains(kp, ki, kd float64) {
+	if ki != 0 && pid.Ki != 0 {
+		pid.integral = kahanSum{sum: float64(pid.integral.value()*pid.Ki) / ki}
+	}
+	pid.Kp, pid.Ki, pid.Kd = kp, ki, kd
+}
+
+// integralTerm returns Ki·∫e dt, clamping the integral when IMax is set.
+func (pid *PID) integralTerm() float64 {
+	integral := float64(pid.Ki * pid.integral.value())
+	if pid.IMax != nil && math.Abs(integral) > *pid.IMax {
+		integral = clamp(integral, pid.IMax)
+		pid.integral = kahanSum{sum: integral / pid.Ki}
+	}
+	return integral
+}
+
+// saturate applies the output limits and tells which one was hit: -1 for
+// UMin, 1 for UMax, 0 for none.
+func (pid *PID) saturate(u float64) (float64, int) {
+	switch {
+	case pid.UMax != nil && u > *pid.UMax:
+		return *pid.UMax, 1
+	case pid.UMin != nil && u < *pid.UMin:
+		return *pid.UMin, -1
+	}
+	return u, 0
+}
+
+// Saturated reports whether the last output was limited by UMin or UMax.
+func (pid *PID) Saturated() bool {
+	return pid.saturated
+}
 
-	measure := []float64{0}
-	T := []float64{0}
+// Terms returns the proportional, integral and derivative parts of the
+// last output.
+func (pid *PID) Terms() (p, i, d float64) {
+	return pid.last.p, pid.last.i, pid.last.d
+}
+
+// Simulation runs the closed loop of the scenario for N steps from rest, or
+// until one of its stop conditions fires. Every series of the result has
+// one sample more than the steps run; U[k] is the controller output
+// computed from PV[k], the last one being computed but never applied.
+func Simulation(sc Scenario) Result {
+	res, _ := simulate(sc, newTrajectory(int(sc.N)+1))
+	return res
+}
 
-	pid := NewPID(P, Ki, Kd)
+// simulate runs sc, recording the series in tr, and returns the result
+// and the loop as it ended.
+func simulate(sc Scenario, tr *trajectory) (Result, *Loop) {
 
-	var un float64
+	n := int(sc.N) + 1
+	res := tr.result()
 
-	for k := 1; k <= int(N); k++ {
-		un = pid.Compute(Sp, measure[len(measure)-1], dt)
-		ynn := DynamicResponse(un, measure[len(measure)-1], dt, Tau, K)
-		measure = append(measure, ynn)
-		T = append(T, T[len(T)-1]+dt)
+	loop := NewLoop(sc)
+	if loop.adapt != nil {
+		res.Adaptation = &Adaptation{K: make([]float64, 0, n), P: make([]float64, 0, n)}
+	}
+	if sc.SpRamp != nil || sc.Manual != nil && sc.Manual.TrackPV {
+		res.WSP = make([]float64, 0, n)
+	}
+	var log []Event
+	saturated := false
+	record := func(u float64) {
+		if loop.pid.Saturated() != saturated {
+			saturated = !saturated
+			log = append(log, saturationEvent(res.Time[len(res.U)], saturated, u))
+		}
+		if K, P, ok := loop.Estimate(); ok {
+			res.Adaptation.K = append(res.Adaptation.K, K)
+			res.Adaptation.P = append(res.Adaptation.P, P)
+		}
+		if res.WSP != nil {
+			res.WSP = append(res.WSP, loop.wsp)
+		}
+		p, i, d := loop.pid.Terms()
+		res.U = append(res.U, u)
+		res.Components.P = append(res.Components.P, p)
+		res.Components.I = append(res.Components.I, i)
+		res.Components.D = append(res.Components.D, d)
+	}
+
+	watch := stopWatch{c: sc.Stop}
+	var recipe *recipeRun
+	if len(sc.Recipe) > 0 {
+		recipe = &recipeRun{recipe: sc.Recipe}
+		log = append(log, recipe.enter(loop)...)
 	}
 
-	return T, measure
+	// The setpoint recorded with a sample is the one the controller output
+	// of that sample is computed from.
+	res.Time = append(res.Time, 0)
+	res.PV = append(res.PV, loop.Measurement())
+	if loop.level != nil {
+		res.Inflow = make([]float64, 0, n)
+		res.Inflow = append(res.Inflow, loop.level.q)
+	}
+	if sc.Plant == PlantReactive {
+		res.Losses = make([]Loss, len(loop.flow.Losses))
+		for i, l := range loop.flow.Losses {
+			res.Losses[i].Block = l.Block
+		}
+		res.Voltage = &Voltage{Inverters: make([]float64, 0, n), POC: make([]float64, 0, n)}
+		res.Voltage.add(loop.flow)
+		if loop.oltc != nil {
+			res.Tap = make([]float64, 0, n)
+			res.Tap = append(res.Tap, loop.oltc.ratio())
+		}
+	}
+	if sc.Drive != nil && sc.Drive.Disturbance != nil {
+		res.Disturbance = make([]float64, 0, n)
+		res.Disturbance = append(res.Disturbance, sc.Drive.load(0))
+	}
+	if recipe != nil {
+		recipe.follow(loop)
+	}
+	res.SP = append(res.SP, loop.target())
+	manual := loop.manual()
+	if manual {
+		log = append(log, manualEvent(sc.Manual))
+	}
+	for k := 1; k <= int(sc.N); k++ {
+		u := loop.Step()
+		record(u)
+		res.Time = append(res.Time, loop.T)
+		res.PV = append(res.PV, loop.Measurement())
+		if loop.level != nil {
+			res.Inflow = append(res.Inflow, loop.level.q)
+		}
+		for i, l := range loop.flow.Losses {
+			res.Losses[i].P += l.P
+			res.Losses[i].Q += l.Q
+		}
+		if res.Voltage != nil {
+			res.Voltage.add(loop.flow)
+		}
+		if res.Tap != nil {
+			res.Tap = append(res.Tap, loop.oltc.ratio())
+		}
+		if res.Disturbance != nil {
+			res.Disturbance = append(res.Disturbance, sc.Drive.load(loop.T))
+		}
+		if manual && !loop.manual() {
+			manual = false
+			log = append(log, autoEvent(loop.T, sc.Manual, loop.Y, loop.Scenario.Sp-loop.Y))
+		}
+
+		done := false
+		if recipe != nil {
+			var events []Event
+			events, done = recipe.check(loop, u)
+			log = append(log, events...)
+			if !done {
+				recipe.follow(loop)
+			}
+		}
+		res.SP = append(res.SP, loop.target())
+		if done {
+			break
+		}
+
+		if cond := watch.check(loop.T, loop.Scenario.Sp, loop.Y, u); cond != "" {
+			res.StoppedBy = &Stop{Condition: cond, Time: loop.T}
+			break
+		}
+	}
+	if recipe != nil {
+		if recipe.index < len(sc.Recipe) {
+			recipe.close(loop.T, "end")
+		}
+		res.Phases = recipe.runs
+	}
+	record(loop.output())
+	log = append(log, loop.events...)
+	for i := range res.Losses {
+		res.Losses[i].P /= float64(len(res.Time) - 1)
+		res.Losses[i].Q /= float64(len(res.Time) - 1)
+	}
+
+	res.Metrics = TrackingMetrics(res.Time, res.SP, res.PV, res.U)
+	if sc.Plant == PlantHeatCool {
+		res.Metrics.Heating, res.Metrics.Cooling = directionMetrics(res.Time, res.SP, res.PV, res.U)
+	}
+	if sc.Plant == PlantLevel {
+		res.Metrics.Level = levelMetrics(res.Time, res.SP, res.PV, res.U, sc.K)
+	}
+	if sc.Costs != nil {
+		cost := sc.Costs.Account(res)
+		res.Cost = &cost
+		res.Metrics.Cost = cost.Total
+	}
+	res.Bands = BandTraces(sc.Bands, res.SP)
+	res.Status = status(res)
+	res.Events = append(log, events(res)...)
+	slices.SortStableFunc(res.Events, func(a, b Event) int { return cmp.Compare(a.T, b.T) })
+	return res, loop
+}
+
+// Loop is the closed loop run by Simulation, advanced one time step at a
+// time so that it can also be driven in real time.
+type Loop struct {
+	Scenario Scenario
+	T, Y     float64 // current time and measurement
+
+	pid   *PID
+	adapt *adaptive
+	ss    *stateSpaceRun
+	noise *noiseRun
+	delay *delayLine
+
+	// wsp is the working setpoint of the last step; ramping is set once
+	// the setpoint ramp has started.
+	wsp     float64
+	ramping bool
+	// z is the first-order response behind the pH of a PlantPH plant and
+	// the reactive power of the inverters of a PlantReactive plant.
+	z     float64
+	level *levelRun
+	// elec is the electrical system of a PlantReactive plant, its blocks
+	// copied when oltc moves a tap, and flow its power flow.
+	elec       *Electrical
+	oltc       *tapRun
+	capability *capabilityRun
+	flow       Flow
+	// events are those of the plant, such as tap changes.
+	events []Event
+	// segment is the segment of the gain schedule at the last step;
+	// disturbance and inflow follow the profiles of the driven load and of
+	// the inflow of a level plant.
+	segment     int
+	disturbance *profileWatch
+	inflow      *profileWatch
+}
+
+// NewLoop returns the loop of the scenario at rest at t = 0.
+func NewLoop(sc Scenario) *Loop {
+	pid := NewPID(sc.P, sc.Ki, sc.Kd)
+	pid.Limits = sc.Limits
+	pid.ExternalReset = sc.Controller == ControllerExternalReset
+	l := &Loop{Scenario: sc, pid: pid, noise: newNoiseRun(sc.Noise), delay: newDelayLine(sc.Theta, sc.Dt)}
+	if sc.Controller == ControllerAdaptive {
+		l.adapt = newAdaptive(sc)
+	}
+	if sc.Plant == PlantStateSpace {
+		l.ss = newStateSpaceRun(sc.StateSpace)
+	}
+	if sc.Plant == PlantPH {
+		l.Y = sc.PH.measure(0)
+	}
+	if sc.Plant == PlantLevel {
+		l.level = newLevelRun(sc.Level)
+		l.inflow = newProfileWatch(sc.Level.Profile, 0)
+		l.Y = sc.Level.Initial
+	}
+	if sc.Plant == PlantReactive {
+		e := *sc.Electrical
+		l.elec = &e
+		if e.TapChanger != nil {
+			l.oltc = newTapRun(l.elec)
+		}
+		if e.Capability != nil {
+			l.capability = &capabilityRun{c: e.Capability}
+		}
+		l.events = gridEvents(e.Grid)
+		l.flow = l.elec.flow(0, 0)
+		l.Y = l.flow.QPoc
+	}
+	if len(sc.Schedule) > 0 {
+		l.segment = scheduleSegment(sc.Schedule, l.Measurement())
+	}
+	l.disturbance = sc.Drive.watch(0)
+	sc.Drive.follow(l)
+	return l
+}
+
+// Step computes the controller output from the current measurement,
+// applies it to the plant during one time step and returns it. The plant
+// parameters follow their variation and drift, and the plant input, the
+// output delayed by the dead time, carries the driven load disturbance; an
+// adaptive controller first updates its estimate of the gain and rescales
+// its gains. Switches of the gain schedule and the segments of the
+// disturbance and inflow profiles are recorded as events.
+func (l *Loop) Step() float64 {
+	sc := l.Scenario
+	pv := l.Measurement()
+	if l.adapt != nil {
+		l.adapt.observe(pv)
+		s := l.adapt.scale()
+		l.pid.Kp, l.pid.Ki, l.pid.Kd = sc.P*s, sc.Ki*s, sc.Kd*s
+	}
+	if len(sc.Schedule) > 0 {
+		P, Ki, Kd := scheduledGains(sc.Schedule, pv)
+		l.pid.SetGains(P, Ki, Kd)
+		if i := scheduleSegment(sc.Schedule, pv); i != l.segment {
+			l.segment = i
+			l.events = append(l.events, scheduleEvent(l.T, sc.Schedule, i, pv, P, Ki, Kd))
+		}
+	}
+	un := l.output()
+	if l.adapt != nil {
+		l.adapt.applied(pv, un)
+	}
+	if b, ok := l.disturbance.enter(l.T); ok {
+		l.events = append(l.events, disturbanceEvent(l.T, "Perturbation de charge", b))
+	}
+	up := l.delay.push(un) + sc.Drive.load(l.T)
+	switch {
+	case l.ss != nil:
+		l.Y = l.ss.apply(up, sc.Dt)
+	case sc.Plant == PlantPH:
+		l.z = DynamicResponse(up, l.z, sc.Dt, sc.TimeConstant(l.T), sc.Gain(l.T))
+		l.Y = sc.PH.measure(l.z)
+	case l.level != nil:
+		l.Y += float64(sc.Dt*(l.level.q-float64(sc.Gain(l.T)*up))) / sc.TimeConstant(l.T)
+		l.level.advance(l.T+sc.Dt, sc.Dt)
+		if b, ok := l.inflow.enter(l.T + sc.Dt); ok {
+			l.events = append(l.events, disturbanceEvent(l.T+sc.Dt, "Débit entrant", b))
+		}
+	case sc.Plant == PlantReactive:
+		if l.capability != nil {
+			var e *Event
+			up, e = l.capability.limit(l.T, up, l.elec.power(l.T), l.flow.Voltages[0].PU)
+			l.event(e)
+		}
+		l.z = DynamicResponse(up, l.z, sc.Dt, sc.TimeConstant(l.T), sc.Gain(l.T))
+		l.flow = l.elec.flow(l.T+sc.Dt, l.z)
+		l.Y = l.flow.QPoc
+		if l.oltc != nil {
+			l.event(l.oltc.advance(l.T+sc.Dt, sc.Dt, l.flow))
+		}
+	case sc.Plant == PlantHeatCool && up < 0:
+		l.Y = DynamicResponse(up, l.Y, sc.Dt, sc.Cooling.Tau, sc.Cooling.K)
+	default:
+		l.Y = DynamicResponse(up, l.Y, sc.Dt, sc.TimeConstant(l.T), sc.Gain(l.T))
+	}
+	l.T += sc.Dt
+	l.noise.draw()
+	sc.Drive.follow(l)
+	return un
+}
+
+// Measurement returns the measurement read by the controller at the
+// current time: Y with the noise of the transmitter, if any.
+func (l *Loop) Measurement() float64 {
+	return l.Y + l.noise.value()
+}
+
+// event records an event of the plant, if any.
+func (l *Loop) event(e *Event) {
+	if e != nil {
+		l.events = append(l.events, *e)
+	}
+}
+
+// TakeEvents returns the events of the plant and of the drive up to the
+// current time and forgets them, as a live session reads them after each
+// step; events planned later, such as grid events, are kept.
+func (l *Loop) TakeEvents() []Event {
+	var due, later []Event
+	for _, e := range l.events {
+		if e.T <= l.T {
+			due = append(due, e)
+		} else {
+			later = append(later, e)
+		}
+	}
+	l.events = later
+	return due
+}
+
+// manual reports whether the loop is in manual mode at the current time.
+func (l *Loop) manual() bool {
+	return l.Scenario.Manual != nil && l.T < l.Scenario.Manual.Until
+}
+
+// output computes the controller output from the current measurement: the
+// manual output, which the PID tracks, while in manual mode, the PID's
+// otherwise.
+func (l *Loop) output() float64 {
+	sp := l.setpoint()
+	if l.manual() {
+		u := l.Scenario.Manual.Output
+		l.pid.Track(u, sp, l.Measurement())
+		return u
+	}
+	return l.pid.Compute(sp, l.Measurement(), l.Scenario.Dt)
+}
+
+// setpoint returns the working setpoint at the current time: the target, or with
+// SpRamp a ramp started from the measurement and moving towards Sp by at
+// most SpRamp·dt per step. In manual mode with TrackPV it is the
+// measurement, from which the ramp then starts.
+func (l *Loop) setpoint() float64 {
+	sc := l.Scenario
+	switch {
+	case l.manual() && sc.Manual.TrackPV:
+		l.wsp, l.ramping = l.Measurement(), true
+	case sc.SpRamp == nil:
+		l.wsp, l.ramping = l.target(), false
+	case !l.ramping:
+		l.wsp, l.ramping = l.Measurement(), true
+	default:
+		rate := float64(*sc.SpRamp * sc.Dt)
+		l.wsp += clamp(l.target()-l.wsp, &rate)
+	}
+	return l.wsp
+}
+
+// target returns the setpoint at the current time: Sp, moved by the droop
+// of a PlantReactive plant.
+func (l *Loop) target() float64 {
+	if l.elec != nil {
+		return l.elec.setpoint(l.Scenario.Sp, l.flow)
+	}
+	return l.Scenario.Sp
+}
+
+// Estimate returns the plant gain estimated by an adaptive controller and
+// the proportional gain applied, ok is false for other controllers.
+func (l *Loop) Estimate() (K, P float64, ok bool) {
+	if l.adapt == nil {
+		return 0, 0, false
+	}
+	return l.adapt.gain, l.pid.Kp, true
+}
+
+// ControllerState is the internal state of the controller at its last
+// output: the error SP − PV it was computed from, the proportional,
+// integral and derivative parts of the output and whether the output was
+// limited by UMin or UMax.
+type ControllerState struct {
+	Error     float64 `json:"error"`
+	P         float64 `json:"p"`
+	I         float64 `json:"i"`
+	D         float64 `json:"d"`
+	Saturated bool    `json:"saturated"`
+}
+
+// State returns the state of the controller at the last step.
+func (l *Loop) State() ControllerState {
+	p, i, d := l.pid.Terms()
+	return ControllerState{Error: l.pid.previouserror_pid, P: p, I: i, D: d, Saturated: l.pid.Saturated()}
+}
+
+// Update replaces the scenario parameters while keeping the loop state, as
+// when an operator changes the setpoint or the gains of a running loop;
+// the gains change as through SetGains, without bumping the output.
+func (l *Loop) Update(sc Scenario) {
+	if sc.Theta != l.Scenario.Theta {
+		l.delay = l.delay.resize(sc.Theta, sc.Dt)
+	}
+	l.Scenario = sc
+	l.disturbance = sc.Drive.watch(l.T)
+	l.pid.SetGains(sc.P, sc.Ki, sc.Kd)
+	l.pid.Limits = sc.Limits
 }
 
 func DynamicResponse(un, yn, dt, Tau, K float64) float64 {
-	return (dt/Tau)*(K*un-yn) + yn
+	return float64((dt/Tau)*(float64(K*un)-yn)) + yn
 }
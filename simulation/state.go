@@ -0,0 +1,117 @@
+package simulation
+
+import "slices"
+
+// LoopState is the internal state of a loop at time T, unrounded, for
+// advanced users to check an implementation against hand calculations:
+// the controller as it computed its last output, the plant and the
+// filters.
+type LoopState struct {
+	T float64 `json:"t"`
+	// Setpoint is the working setpoint, see Loop.setpoint.
+	Setpoint   float64    `json:"setpoint"`
+	Controller PIDState   `json:"controller"`
+	Plant      PlantState `json:"plant"`
+	// Filters are the states of the filters and estimators of the run,
+	// by name: "measurementNoise", the noise of the transmitter,
+	// "inflowNoise", the filtered disturbance of the inflow of a level,
+	// and "adaptiveGain" and "adaptiveCovariance", the estimate of the
+	// plant gain of an adaptive controller.
+	Filters map[string]float64 `json:"filters,omitempty"`
+}
+
+// PIDState is the state of the PID behind its last output. Kp, Ki and Kd
+// are the gains applied, scheduled or adapted. Integral is the integrator
+// ∫e dt, the integral term being Ki·Integral unless clamped by IMax; Reset
+// replaces it in the external-reset form, the output of its lag.
+// Derivative is the estimate de/dt, the derivative term being
+// Kd·Derivative unless clamped by DMax.
+type PIDState struct {
+	ControllerState
+	Kp         float64  `json:"kp"`
+	Ki         float64  `json:"ki"`
+	Kd         float64  `json:"kd"`
+	Integral   float64  `json:"integral"`
+	Reset      *float64 `json:"reset,omitempty"`
+	Derivative float64  `json:"derivative"`
+}
+
+// PlantState is the state of the plant. Y is its output and Measurement
+// the one read by the controller, with the noise of the transmitter. Input
+// is the output of the controller the plant received at the last step,
+// delayed by the dead time Theta, set when there is one. X are
+// the states of a PlantStateSpace plant; Z is the first-order response
+// behind the pH of a PlantPH plant and the reactive power of a
+// PlantReactive one; Inflow is the inflow of a level and Tap the ratio of
+// the tap changer of a PlantReactive plant.
+type PlantState struct {
+	Y           float64   `json:"y"`
+	Measurement float64   `json:"measurement"`
+	Input       *float64  `json:"input,omitempty"`
+	X           []float64 `json:"x,omitempty"`
+	Z           *float64  `json:"z,omitempty"`
+	Inflow      *float64  `json:"inflow,omitempty"`
+	Tap         *float64  `json:"tap,omitempty"`
+}
+
+// Inspect returns the internal state of the loop.
+func (l *Loop) Inspect() LoopState {
+
+	pid := l.pid
+	st := LoopState{
+		T:        l.T,
+		Setpoint: l.wsp,
+		Controller: PIDState{
+			ControllerState: l.State(),
+			Kp:              pid.Kp,
+			Ki:              pid.Ki,
+			Kd:              pid.Kd,
+			Integral:        pid.integral.value(),
+			Derivative:      pid.slope,
+		},
+		Plant:   PlantState{Y: l.Y, Measurement: l.Measurement()},
+		Filters: map[string]float64{},
+	}
+	if pid.ExternalReset {
+		reset := pid.reset
+		st.Controller.Reset = &reset
+	}
+	if l.delay != nil {
+		in := l.delay.out
+		st.Plant.Input = &in
+	}
+	if l.ss != nil {
+		st.Plant.X = slices.Clone(l.ss.x)
+	}
+	if l.Scenario.Plant == PlantPH || l.Scenario.Plant == PlantReactive {
+		z := l.z
+		st.Plant.Z = &z
+	}
+	if l.level != nil {
+		q := l.level.q
+		st.Plant.Inflow = &q
+		if l.level.rng != nil {
+			st.Filters["inflowNoise"] = l.level.noise
+		}
+	}
+	if l.oltc != nil {
+		tap := l.oltc.ratio()
+		st.Plant.Tap = &tap
+	}
+	if l.noise != nil {
+		st.Filters["measurementNoise"] = l.noise.value()
+	}
+	if l.adapt != nil {
+		st.Filters["adaptiveGain"], st.Filters["adaptiveCovariance"] = l.adapt.gain, l.adapt.cov
+	}
+	return st
+}
+
+// FinalState runs the scenario and returns the internal state of its loop
+// as the run ended, the controller at the output of the last sample. Runs
+// being deterministic, it is that of any earlier run of the scenario.
+func FinalState(sc Scenario) LoopState {
+	res, loop := simulate(sc, getTrajectory(int(sc.N)+1))
+	recycle(res)
+	return loop.Inspect()
+}
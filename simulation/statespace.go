@@ -0,0 +1,122 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// StateSpace is the linear plant dx/dt = A·x + B·u, y = C·x + D·u with n
+// states, m inputs and p outputs. The matrices are stored row-major in flat
+// slices, A being n×n, B n×m, C p×n and D p×m; D may be omitted.
+//
+// The loop drives the first input and measures the first output; the
+// other inputs are held at zero.
+type StateSpace struct {
+	States  int       `json:"n"`
+	Inputs  int       `json:"m"`
+	Outputs int       `json:"p"`
+	A       []float64 `json:"A"`
+	B       []float64 `json:"B"`
+	C       []float64 `json:"C"`
+	D       []float64 `json:"D,omitempty"`
+}
+
+// StateSpaceSchema describes the "stateSpace" member of a scenario.
+var StateSpaceSchema = schema.Object(map[string]*schema.Schema{
+	"n": schema.Integer("Nombre d'états").Min(1),
+	"m": schema.Integer("Nombre d'entrées, la commande est la première").Min(1),
+	"p": schema.Integer("Nombre de sorties, la mesure est la première").Min(1),
+	"A": schema.Array(schema.Number("")).AtLeast(1),
+	"B": schema.Array(schema.Number("")).AtLeast(1),
+	"C": schema.Array(schema.Number("")).AtLeast(1),
+	"D": schema.Array(schema.Number("")),
+}, "n", "m", "p", "A", "B", "C")
+
+// check reports the matrices whose size does not match the dimensions.
+func (ss *StateSpace) check(path string) []schema.Error {
+	var errs []schema.Error
+	for _, m := range []struct {
+		name       string
+		data       []float64
+		rows, cols int
+		optional   bool
+	}{
+		{"A", ss.A, ss.States, ss.States, false},
+		{"B", ss.B, ss.States, ss.Inputs, false},
+		{"C", ss.C, ss.Outputs, ss.States, false},
+		{"D", ss.D, ss.Outputs, ss.Inputs, true},
+	} {
+		if len(m.data) != m.rows*m.cols && !(m.optional && len(m.data) == 0) {
+			errs = append(errs, schema.Error{Path: path + "/" + m.name, Message: fmt.Sprintf("%d×%d = %d coefficients attendus ligne par ligne, reçu %d", m.rows, m.cols, m.rows*m.cols, len(m.data))})
+		}
+	}
+	return errs
+}
+
+// stateSpaceRun is the state of a StateSpace plant. The vectors are
+// allocated once so that a step does not allocate.
+type stateSpaceRun struct {
+	ss       *StateSpace
+	x, dx, y []float64
+	u        []float64
+}
+
+func newStateSpaceRun(ss *StateSpace) *stateSpaceRun {
+	return &stateSpaceRun{
+		ss: ss,
+		x:  make([]float64, ss.States),
+		dx: make([]float64, ss.States),
+		y:  make([]float64, ss.Outputs),
+		u:  make([]float64, ss.Inputs),
+	}
+}
+
+// step integrates the plant over dt with explicit Euler for the inputs u,
+// then updates the outputs. As in PID.Compute, products are rounded before
+// summing so that the result does not depend on FMA fusion.
+func (r *stateSpaceRun) step(u []float64, dt float64) {
+
+	n, m := r.ss.States, r.ss.Inputs
+	A, B, C, D := r.ss.A, r.ss.B, r.ss.C, r.ss.D
+	x, dx := r.x, r.dx
+
+	for i := 0; i < n; i++ {
+		row := A[i*n : i*n+n]
+		s := 0.0
+		for j, a := range row {
+			s += float64(a * x[j])
+		}
+		row = B[i*m : i*m+m]
+		for j, b := range row {
+			s += float64(b * u[j])
+		}
+		dx[i] = s
+	}
+	for i := range x {
+		x[i] += float64(dt * dx[i])
+	}
+
+	for i := range r.y {
+		row := C[i*n : i*n+n]
+		s := 0.0
+		for j, c := range row {
+			s += float64(c * x[j])
+		}
+		if len(D) > 0 {
+			row = D[i*m : i*m+m]
+			for j, d := range row {
+				s += float64(d * u[j])
+			}
+		}
+		r.y[i] = s
+	}
+}
+
+// apply drives the first input with un during dt and returns the first
+// output.
+func (r *stateSpaceRun) apply(un, dt float64) float64 {
+	r.u[0] = un
+	r.step(r.u, dt)
+	return r.y[0]
+}
@@ -0,0 +1,86 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/schema"
+)
+
+// StopConditions end a simulation before its N steps. Every condition is
+// optional; the run stops at the first sample where one of them fires.
+type StopConditions struct {
+	// PVAbove and PVBelow fire when the measurement reaches the threshold,
+	// e.g. PVAbove = 0.95·Sp measures the time to reach 95 % of the
+	// setpoint.
+	PVAbove *float64 `json:"pvAbove,omitempty"`
+	PVBelow *float64 `json:"pvBelow,omitempty"`
+	// ErrorWithin fires once |Sp - PV| ≤ Limit has held for For seconds.
+	ErrorWithin *Hold `json:"errorWithin,omitempty"`
+	// USaturated fires once |u| ≥ Limit has held for For seconds.
+	USaturated *Hold `json:"uSaturated,omitempty"`
+}
+
+// Hold is a condition that must stay true for a duration.
+type Hold struct {
+	Limit float64 `json:"limit"`
+	For   float64 `json:"for"`
+}
+
+// Stop tells which condition ended a run and when.
+type Stop struct {
+	Condition string  `json:"condition"`
+	Time      float64 `json:"time"`
+}
+
+// StopSchema describes the optional "stop" member of a scenario.
+var StopSchema = schema.Object(map[string]*schema.Schema{
+	"pvAbove": schema.Number("Arrêt quand la mesure atteint ou dépasse ce seuil"),
+	"pvBelow": schema.Number("Arrêt quand la mesure atteint ou passe sous ce seuil"),
+	"errorWithin": schema.Object(map[string]*schema.Schema{
+		"limit": schema.Number("Écart maximal |Sp - PV|").Min(0),
+		"for":   schema.Number("Durée pendant laquelle l'écart doit rester sous la limite (s)").Min(0),
+	}, "limit", "for"),
+	"uSaturated": schema.Object(map[string]*schema.Schema{
+		"limit": schema.Number("Sortie |u| considérée comme saturée").Min(0),
+		"for":   schema.Number("Durée de saturation avant l'arrêt (s)").Min(0),
+	}, "limit", "for"),
+})
+
+// stopWatch evaluates the stop conditions sample after sample.
+type stopWatch struct {
+	c                  *StopConditions
+	errorSince, uSince float64
+	errorHeld, uHeld   bool
+}
+
+// hold tracks a condition that must stay true for h.For seconds.
+func hold(h *Hold, ok bool, t float64, since *float64, held *bool) bool {
+	if !ok {
+		*held = false
+		return false
+	}
+	if !*held {
+		*since, *held = t, true
+	}
+	return t-*since >= h.For
+}
+
+// check returns the condition fired at time t, where the measurement is pv
+// and u is the controller output applied during the step that led there.
+func (w *stopWatch) check(t, sp, pv, u float64) string {
+
+	c := w.c
+	switch {
+	case c == nil:
+		return ""
+	case c.PVAbove != nil && pv >= *c.PVAbove:
+		return "pvAbove"
+	case c.PVBelow != nil && pv <= *c.PVBelow:
+		return "pvBelow"
+	case c.ErrorWithin != nil && hold(c.ErrorWithin, math.Abs(sp-pv) <= c.ErrorWithin.Limit, t, &w.errorSince, &w.errorHeld):
+		return "errorWithin"
+	case c.USaturated != nil && hold(c.USaturated, math.Abs(u) >= c.USaturated.Limit, t, &w.uSince, &w.uHeld):
+		return "uSaturated"
+	}
+	return ""
+}
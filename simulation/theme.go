@@ -0,0 +1,104 @@
+package simulation
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/chart"
+)
+
+// Theme is the style of a plot. Colors are written "#rrggbb" or "#rgb";
+// sizes are in points.
+type Theme struct {
+	Background  string  `json:"background"`
+	Foreground  string  `json:"foreground"` // text, axes and ticks
+	Setpoint    string  `json:"setpoint"`
+	Measurement string  `json:"measurement"`
+	FontSize    float64 `json:"fontSize"`  // axis labels and legend
+	LineWidth   float64 `json:"lineWidth"` // series
+}
+
+// Theme names accepted in PlotOptions.Theme.
+const (
+	ThemeLight     = "light"
+	ThemeDark      = "dark"
+	ThemeProjector = "projector"
+)
+
+// Themes lists the available plot themes by name. The light one matches
+// the web UI; the projector one keeps the contrast and the readability of
+// a projected slide.
+var Themes = map[string]Theme{
+	ThemeLight: {
+		Background: "#ffffff", Foreground: "#333333",
+		Setpoint: "#808080", Measurement: "#d62728",
+		FontSize: 12, LineWidth: 1,
+	},
+	ThemeDark: {
+		Background: "#1e1e1e", Foreground: "#e0e0e0",
+		Setpoint: "#a0a0a0", Measurement: "#ff6b6b",
+		FontSize: 12, LineWidth: 1,
+	},
+	ThemeProjector: {
+		Background: "#ffffff", Foreground: "#000000",
+		Setpoint: "#000000", Measurement: "#0050c8",
+		FontSize: 20, LineWidth: 3,
+	},
+}
+
+// theme returns the theme of the options: the named one, ThemeLight when
+// empty, with the custom colors and sizes of the options replacing its
+// own.
+func (opts PlotOptions) theme() (Theme, error) {
+
+	name := opts.Theme
+	if name == "" {
+		name = ThemeLight
+	}
+	t, ok := Themes[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("thème inconnu %q, attendu %q, %q ou %q", name, ThemeLight, ThemeDark, ThemeProjector)
+	}
+
+	for _, c := range []struct {
+		name   string
+		custom string
+		color  *string
+	}{
+		{"background", opts.Background, &t.Background},
+		{"foreground", opts.Foreground, &t.Foreground},
+		{"setpoint", opts.Setpoint, &t.Setpoint},
+		{"measurement", opts.Measurement, &t.Measurement},
+	} {
+		if c.custom == "" {
+			continue
+		}
+		if _, err := chart.ParseColor(c.custom); err != nil {
+			return Theme{}, fmt.Errorf("%s : %w", c.name, err)
+		}
+		*c.color = c.custom
+	}
+	if opts.FontSize != 0 {
+		if opts.FontSize < 4 || opts.FontSize > 72 {
+			return Theme{}, fmt.Errorf("fontSize doit être compris entre 4 et 72 points, reçu %g", opts.FontSize)
+		}
+		t.FontSize = opts.FontSize
+	}
+	if opts.LineWidth != 0 {
+		if opts.LineWidth < 0.1 || opts.LineWidth > 20 {
+			return Theme{}, fmt.Errorf("lineWidth doit être compris entre 0,1 et 20 points, reçu %g", opts.LineWidth)
+		}
+		t.LineWidth = opts.LineWidth
+	}
+	return t, nil
+}
+
+// color returns the color c of the theme, checked by PlotOptions.theme.
+func (t Theme) color(c string) color.RGBA {
+	return chart.Color(c)
+}
+
+// style returns the style of the figures drawn with the theme.
+func (t Theme) style() chart.Style {
+	return chart.Style{Background: t.Background, Foreground: t.Foreground, FontSize: t.FontSize}
+}
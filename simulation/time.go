@@ -0,0 +1,30 @@
+package simulation
+
+import (
+	"math"
+	"time"
+)
+
+// Duration converts simulated seconds into a time.Duration rounded to the
+// nanosecond.
+func Duration(t float64) time.Duration {
+	return time.Duration(math.Round(t * float64(time.Second)))
+}
+
+// Timestamp returns the wall-clock time reached after t simulated seconds
+// of a run started at start.
+func Timestamp(start time.Time, t float64) time.Time {
+	return start.Add(Duration(t))
+}
+
+// At returns the result anchored at an absolute start time: Start is set
+// and Timestamps holds the wall-clock time of every sample, so that the
+// series can be lined up with historian data.
+func (r Result) At(start time.Time) Result {
+	r.Start = &start
+	r.Timestamps = make([]time.Time, len(r.Time))
+	for k, t := range r.Time {
+		r.Timestamps[k] = Timestamp(start, t)
+	}
+	return r
+}
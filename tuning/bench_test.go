@@ -0,0 +1,37 @@
+package tuning
+
+import (
+	"testing"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+var benchBase = simulation.Scenario{Sp: 10, Tau: 1, K: 1, P: 5, Ki: 10, Dt: 0.01, N: 1000}
+
+// BenchmarkPareto samples 64 gain triples on two objectives, each
+// evaluated through Scenario.Evaluate.
+func BenchmarkPareto(b *testing.B) {
+	b.ReportAllocs()
+	problem := Problem{
+		Base:   benchBase,
+		Bounds: Bounds{P: Range{0.5, 10}, Ki: Range{0.1, 20}, Kd: Range{0, 1}},
+	}
+	for range b.N {
+		if _, _, err := Pareto(problem, []string{"iae", "overshoot"}, 64, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSweep evaluates a 7×7 grid of P and Ki and refines it once
+// around its three best cells.
+func BenchmarkSweep(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		s, err := NewSweep(benchBase, Axis{"P", 1, 10}, Axis{"Ki", 1, 20}, "itae", 7)
+		if err != nil {
+			b.Fatal(err)
+		}
+		s.Refine(3)
+	}
+}
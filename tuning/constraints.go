@@ -0,0 +1,126 @@
+package tuning
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Constraints are hard limits a tuning must satisfy. A nil field disables
+// the corresponding limit.
+type Constraints struct {
+	MaxOvershoot *float64 `json:"maxOvershoot,omitempty"` // in % of the step
+	MaxU         *float64 `json:"maxU,omitempty"`         // peak |u|
+	MaxSettling  *float64 `json:"maxSettling,omitempty"`  // in seconds, the response must settle
+}
+
+// Violation is a constraint a candidate does not satisfy.
+type Violation struct {
+	Constraint string  `json:"constraint"`
+	Limit      float64 `json:"limit"`
+	Value      float64 `json:"value"`
+}
+
+// Infeasibility explains why no evaluated candidate met the constraints.
+type Infeasibility struct {
+	Evaluated int `json:"evaluated"`
+	// Closest is the candidate with the smallest total violation.
+	Closest *Candidate `json:"closest,omitempty"`
+	// Violated counts, for each constraint, the candidates violating it.
+	Violated map[string]int `json:"violated"`
+	// BestReached is the best value reached for each constrained metric.
+	BestReached map[string]float64 `json:"bestReached"`
+}
+
+// InfeasibleError is returned by the optimizers when no candidate satisfies
+// the constraints.
+type InfeasibleError struct {
+	Report Infeasibility
+}
+
+func (e *InfeasibleError) Error() string {
+	return fmt.Sprintf("aucun des %d réglages évalués ne respecte les contraintes", e.Report.Evaluated)
+}
+
+// Check returns the constraints violated by the metrics.
+func (c Constraints) Check(m simulation.Metrics) []Violation {
+
+	var v []Violation
+	if c.MaxOvershoot != nil && m.Overshoot > *c.MaxOvershoot {
+		v = append(v, Violation{"maxOvershoot", *c.MaxOvershoot, m.Overshoot})
+	}
+	if c.MaxU != nil && m.MaxU > *c.MaxU {
+		v = append(v, Violation{"maxU", *c.MaxU, m.MaxU})
+	}
+	if c.MaxSettling != nil && (!m.Settled || m.SettlingTime > *c.MaxSettling) {
+		v = append(v, Violation{"maxSettling", *c.MaxSettling, m.SettlingTime})
+	}
+	return v
+}
+
+// amount is the total violation, each term normalized by its limit so that
+// constraints in different units can be summed.
+func amount(violations []Violation) float64 {
+	total := 0.0
+	for _, v := range violations {
+		total += (v.Value - v.Limit) / math.Max(math.Abs(v.Limit), 1)
+	}
+	return total
+}
+
+// less ranks candidates: stable responses first, then feasible ones, then
+// by total violation for infeasible candidates or by the first objective
+// for feasible ones.
+func less(a, b Candidate) bool {
+	switch {
+	case (a.Objectives == nil) != (b.Objectives == nil):
+		return b.Objectives == nil
+	case a.Objectives == nil:
+		return false
+	case a.Feasible() != b.Feasible():
+		return a.Feasible()
+	case !a.Feasible():
+		return amount(a.Violations) < amount(b.Violations)
+	}
+	return a.Objectives[0] < b.Objectives[0]
+}
+
+// infeasibility builds the report for a set of candidates none of which is
+// feasible.
+func infeasibility(c Constraints, candidates []Candidate) *InfeasibleError {
+
+	report := Infeasibility{
+		Evaluated:   len(candidates),
+		Violated:    map[string]int{},
+		BestReached: map[string]float64{},
+	}
+
+	best := func(name string, v float64) {
+		if old, ok := report.BestReached[name]; !ok || v < old {
+			report.BestReached[name] = v
+		}
+	}
+
+	for i, cand := range candidates {
+		if cand.Objectives == nil {
+			continue
+		}
+		for _, v := range cand.Violations {
+			report.Violated[v.Constraint]++
+		}
+		if c.MaxOvershoot != nil {
+			best("maxOvershoot", cand.Metrics.Overshoot)
+		}
+		if c.MaxU != nil {
+			best("maxU", cand.Metrics.MaxU)
+		}
+		if c.MaxSettling != nil && cand.Metrics.Settled {
+			best("maxSettling", cand.Metrics.SettlingTime)
+		}
+		if report.Closest == nil || less(cand, *report.Closest) {
+			report.Closest = &candidates[i]
+		}
+	}
+	return &InfeasibleError{Report: report}
+}
@@ -0,0 +1,163 @@
+package tuning
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// ErrUnstable is returned by Genetic when every candidate diverged.
+var ErrUnstable = errors.New("aucun jeu de gains ne donne une réponse stable dans les bornes")
+
+// GAOptions configures the genetic algorithm tuner.
+type GAOptions struct {
+	Objective    string  `json:"objective"`
+	Population   int     `json:"population"`
+	Generations  int     `json:"generations"`
+	MutationRate float64 `json:"mutationRate"`
+	Elite        int     `json:"elite"`
+	Seed         uint64  `json:"seed"`
+}
+
+// DefaultGAOptions returns the options used for omitted fields.
+func DefaultGAOptions() GAOptions {
+	return GAOptions{
+		Objective:    "itae",
+		Population:   30,
+		Generations:  40,
+		MutationRate: 0.2,
+		Elite:        2,
+		Seed:         1,
+	}
+}
+
+// Generation reports the state of the population after one generation.
+type Generation struct {
+	Index    int       `json:"generation"`
+	Best     Candidate `json:"best"`
+	MeanCost float64   `json:"meanCost"`
+	Feasible int       `json:"feasible"` // stable candidates meeting the constraints
+}
+
+// Validate checks the options.
+func (o GAOptions) Validate() error {
+	switch {
+	case !slices.Contains(simulation.MetricNames, o.Objective):
+		return fmt.Errorf("critère inconnu %q", o.Objective)
+	case o.Generations < 1:
+		return fmt.Errorf("au moins une génération est nécessaire")
+	// Each factor is bounded before the product, which could overflow.
+	case o.Population < 4 || o.Population > MaxSamples || o.Generations > MaxSamples/o.Population:
+		return fmt.Errorf("population (au moins 4) × générations doit rester inférieur à %d", MaxSamples)
+	case o.MutationRate < 0 || o.MutationRate > 1:
+		return fmt.Errorf("le taux de mutation doit être compris entre 0 et 1")
+	case o.Elite < 0 || o.Elite >= o.Population:
+		return fmt.Errorf("l'élite doit être inférieure à la population")
+	}
+	return nil
+}
+
+// Genetic searches the gains minimizing the objective with a real-coded
+// genetic algorithm (tournament selection, blend crossover, gaussian
+// mutation, elitism). Infeasible candidates rank after feasible ones, by
+// amount of violation. progress is called after every generation; returning
+// false stops the search early. The best candidate found is returned, or an
+// *InfeasibleError if none satisfies the constraints.
+func Genetic(problem Problem, opts GAOptions, progress func(Generation) bool) (Candidate, error) {
+
+	if err := problem.prepare(); err != nil {
+		return Candidate{}, err
+	}
+	if err := opts.Validate(); err != nil {
+		return Candidate{}, err
+	}
+
+	bounds := problem.Bounds
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+	objectives := []string{opts.Objective}
+
+	eval := func(g Gains) Candidate {
+		c, ok := problem.evaluate(g, objectives)
+		if !ok {
+			c.Objectives = nil
+		}
+		return c
+	}
+
+	population := make([]Candidate, opts.Population)
+	for i := range population {
+		population[i] = eval(problem.Bounds.sample(rng))
+	}
+
+	tournament := func() Candidate {
+		best := population[rng.IntN(len(population))]
+		for k := 0; k < 2; k++ {
+			if c := population[rng.IntN(len(population))]; less(c, best) {
+				best = c
+			}
+		}
+		return best
+	}
+
+	for gen := 0; gen < opts.Generations; gen++ {
+
+		sort.SliceStable(population, func(i, j int) bool { return less(population[i], population[j]) })
+
+		report := Generation{Index: gen, Best: population[0]}
+		for _, c := range population {
+			if c.Objectives != nil && c.Feasible() {
+				report.MeanCost += c.Objectives[0]
+				report.Feasible++
+			}
+		}
+		if report.Feasible > 0 {
+			report.MeanCost /= float64(report.Feasible)
+		}
+		if progress != nil && !progress(report) {
+			break
+		}
+		if gen == opts.Generations-1 {
+			break
+		}
+
+		next := slices.Clone(population[:opts.Elite])
+		for len(next) < opts.Population {
+			a, b := tournament(), tournament()
+			child := Gains{
+				P:  blend(rng, a.P, b.P, bounds.P, opts.MutationRate),
+				Ki: blend(rng, a.Ki, b.Ki, bounds.Ki, opts.MutationRate),
+				Kd: blend(rng, a.Kd, b.Kd, bounds.Kd, opts.MutationRate),
+			}
+			next = append(next, eval(child))
+		}
+		population = next
+	}
+
+	sort.SliceStable(population, func(i, j int) bool { return less(population[i], population[j]) })
+	switch best := population[0]; {
+	case best.Objectives == nil:
+		return best, ErrUnstable
+	case !best.Feasible():
+		return best, infeasibility(problem.Constraints, population)
+	}
+	return population[0], nil
+}
+
+// blend crosses two parent genes (BLX-0.5), mutates the result with the
+// given probability and keeps it within the range.
+func blend(rng *rand.Rand, a, b float64, r Range, mutationRate float64) float64 {
+
+	lo, hi := math.Min(a, b), math.Max(a, b)
+	d := hi - lo
+	x := lo - 0.5*d + rng.Float64()*2*d
+
+	if rng.Float64() < mutationRate {
+		x += rng.NormFloat64() * 0.1 * (r.Max - r.Min)
+	}
+	return math.Max(r.Min, math.Min(r.Max, x))
+}
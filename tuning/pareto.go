@@ -0,0 +1,143 @@
+package tuning
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Range bounds one gain during a search.
+type Range struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// Gains is a gain triple of the PID controller.
+type Gains struct {
+	P  float64 `json:"P"`
+	Ki float64 `json:"Ki"`
+	Kd float64 `json:"Kd"`
+}
+
+// Bounds is the search space of the gain optimizers.
+type Bounds struct {
+	P  Range `json:"P"`
+	Ki Range `json:"Ki"`
+	Kd Range `json:"Kd"`
+}
+
+// Candidate is an evaluated gain triple.
+type Candidate struct {
+	Gains
+	Metrics    simulation.Metrics `json:"metrics"`
+	Objectives []float64          `json:"objectives"`
+	Violations []Violation        `json:"violations,omitempty"`
+}
+
+// Feasible reports whether the candidate meets every constraint.
+func (c Candidate) Feasible() bool {
+	return len(c.Violations) == 0
+}
+
+// MaxSamples bounds the number of candidates evaluated by one search.
+const MaxSamples = 5000
+
+// Validate checks that every range is well formed.
+func (b Bounds) Validate() error {
+	for name, r := range map[string]Range{"P": b.P, "Ki": b.Ki, "Kd": b.Kd} {
+		if r.Max < r.Min {
+			return fmt.Errorf("intervalle invalide pour %s : min > max", name)
+		}
+	}
+	return nil
+}
+
+// sample draws a gain triple uniformly in the bounds.
+func (b Bounds) sample(rng *rand.Rand) Gains {
+	draw := func(r Range) float64 { return r.Min + rng.Float64()*(r.Max-r.Min) }
+	return Gains{P: draw(b.P), Ki: draw(b.Ki), Kd: draw(b.Kd)}
+}
+
+// apply returns a copy of sc using the gains g.
+func (g Gains) apply(sc simulation.Scenario) simulation.Scenario {
+	sc.P, sc.Ki, sc.Kd = g.P, g.Ki, g.Kd
+	return sc
+}
+
+// dominates reports whether a is no worse than b on every objective and
+// strictly better on at least one.
+func dominates(a, b Candidate) bool {
+	better := false
+	for i := range a.Objectives {
+		if a.Objectives[i] > b.Objectives[i] {
+			return false
+		}
+		if a.Objectives[i] < b.Objectives[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// ParetoFront returns the non-dominated candidates, sorted by the first
+// objective.
+func ParetoFront(candidates []Candidate) []Candidate {
+
+	var front []Candidate
+	for i, c := range candidates {
+		dominated := false
+		for j, o := range candidates {
+			if i != j && dominates(o, c) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			front = append(front, c)
+		}
+	}
+	sort.Slice(front, func(i, j int) bool { return front[i].Objectives[0] < front[j].Objectives[0] })
+	return front
+}
+
+// Pareto samples gain triples within the bounds and returns the front of
+// the feasible ones that are not dominated on the given objectives (all
+// minimized), along with the number of candidates that could be evaluated.
+// If none satisfies the constraints, an *InfeasibleError is returned.
+func Pareto(problem Problem, objectives []string, samples int, seed uint64) ([]Candidate, int, error) {
+
+	if len(objectives) < 2 {
+		return nil, 0, fmt.Errorf("au moins deux critères sont nécessaires")
+	}
+	for _, name := range objectives {
+		if !slices.Contains(simulation.MetricNames, name) {
+			return nil, 0, fmt.Errorf("critère inconnu %q", name)
+		}
+	}
+	if samples < 1 || samples > MaxSamples {
+		return nil, 0, fmt.Errorf("le nombre d'échantillons doit être compris entre 1 et %d", MaxSamples)
+	}
+	if err := problem.prepare(); err != nil {
+		return nil, 0, err
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	var candidates, feasible []Candidate
+	for i := 0; i < samples; i++ {
+		c, ok := problem.evaluate(problem.Bounds.sample(rng), objectives)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, c)
+		if c.Feasible() {
+			feasible = append(feasible, c)
+		}
+	}
+	if len(feasible) == 0 {
+		return nil, len(candidates), infeasibility(problem.Constraints, candidates)
+	}
+	return ParetoFront(feasible), len(candidates), nil
+}
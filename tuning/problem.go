@@ -0,0 +1,62 @@
+package tuning
+
+import (
+	"math"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Evaluate returns the metrics of a scenario the optimizers and sweeps
+// try, simulation.Scenario.Evaluate by default. A server may replace it,
+// before any search starts, to share the evaluations of identical
+// scenarios between requests; it is called concurrently.
+var Evaluate = simulation.Scenario.Evaluate
+
+// Problem is what the gain optimizers work on: a scenario whose gains are
+// searched within bounds, under constraints, optionally for a whole family
+// of perturbed plants.
+type Problem struct {
+	Base        simulation.Scenario      `json:"-"`
+	Bounds      Bounds                   `json:"bounds"`
+	Constraints Constraints              `json:"constraints"`
+	Robust      *simulation.Perturbation `json:"robust,omitempty"`
+
+	family []simulation.Scenario
+}
+
+// prepare validates the problem and builds the plant family.
+func (p *Problem) prepare() error {
+
+	if err := p.Bounds.Validate(); err != nil {
+		return err
+	}
+	if p.Robust == nil {
+		p.family = []simulation.Scenario{p.Base}
+		return nil
+	}
+	family, err := simulation.PlantFamily(p.Base, *p.Robust)
+	p.family = family
+	return err
+}
+
+// evaluate simulates the gains on every plant of the family, keeps the
+// worst case of each metric, extracts the objectives and checks the
+// constraints. It returns false if any objective is not finite.
+func (p *Problem) evaluate(g Gains, objectives []string) (Candidate, bool) {
+
+	ms := make([]simulation.Metrics, len(p.family))
+	for i, sc := range p.family {
+		ms[i] = Evaluate(g.apply(sc))
+	}
+
+	c := Candidate{Gains: g, Metrics: simulation.WorstCase(ms)}
+	c.Violations = p.Constraints.Check(c.Metrics)
+	for _, name := range objectives {
+		v, _ := c.Metrics.Get(name)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return c, false
+		}
+		c.Objectives = append(c.Objectives, v)
+	}
+	return c, true
+}
@@ -0,0 +1,146 @@
+package tuning
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// Surface is the metric of a sweep on the grid of every X and Y value
+// evaluated, coarse and refined cells together. Z[j][i] is the metric at
+// X[i], Y[j]; it is nil where the cell was not evaluated or diverged.
+type Surface struct {
+	XName  string       `json:"xName"`
+	YName  string       `json:"yName"`
+	Metric string       `json:"metric"`
+	X      []float64    `json:"x"`
+	Y      []float64    `json:"y"`
+	Z      [][]*float64 `json:"z"`
+}
+
+// Surface returns the metric landscape of the sweep.
+func (s *Sweep) Surface() Surface {
+
+	// Values are matched with the rounded key of eval.
+	key := func(v float64) string { return fmt.Sprintf("%.9g", v) }
+	// axis sorts the distinct values and indexes them by key.
+	axis := func(values []float64) ([]float64, map[string]int) {
+		slices.Sort(values)
+		values = slices.CompactFunc(values, func(a, b float64) bool { return key(a) == key(b) })
+		m := make(map[string]int, len(values))
+		for i, v := range values {
+			m[key(v)] = i
+		}
+		return values, m
+	}
+
+	sf := Surface{XName: s.X.Name, YName: s.Y.Name, Metric: s.Metric}
+	for _, p := range s.Points {
+		sf.X = append(sf.X, p.X)
+		sf.Y = append(sf.Y, p.Y)
+	}
+	var xi, yi map[string]int
+	sf.X, xi = axis(sf.X)
+	sf.Y, yi = axis(sf.Y)
+
+	sf.Z = make([][]*float64, len(sf.Y))
+	for j := range sf.Z {
+		sf.Z[j] = make([]*float64, len(sf.X))
+	}
+	for _, p := range s.Points {
+		if p.Cost != nil {
+			z := numfmt.Round(*p.Cost)
+			sf.Z[yi[key(p.Y)]][xi[key(p.X)]] = &z
+		}
+	}
+	return sf
+}
+
+// Plotly returns the surface as a plotly.js figure, to be passed to
+// Plotly.newPlot; missing cells are null and leave holes.
+func (sf Surface) Plotly() map[string]any {
+	return map[string]any{
+		"data": []map[string]any{{
+			"type": "surface",
+			"x":    numfmt.Series(sf.X),
+			"y":    numfmt.Series(sf.Y),
+			"z":    sf.Z,
+		}},
+		"layout": map[string]any{
+			"title": fmt.Sprintf("%s selon %s et %s", sf.Metric, sf.XName, sf.YName),
+			"scene": map[string]any{
+				"xaxis": map[string]any{"title": sf.XName},
+				"yaxis": map[string]any{"title": sf.YName},
+				"zaxis": map[string]any{"title": sf.Metric},
+			},
+		},
+	}
+}
+
+// WriteCSV writes the grid in the dialect f with one row per Y value: the
+// header holds the X values after the axis names, and missing cells are
+// empty.
+func (sf Surface) WriteCSV(w io.Writer, f numfmt.CSV) error {
+
+	cw := f.Writer(w)
+	header := []string{sf.YName + `\` + sf.XName}
+	for _, x := range sf.X {
+		header = append(header, f.Number(x))
+	}
+	cw.Write(header)
+	for j, y := range sf.Y {
+		row := []string{f.Number(y)}
+		for _, z := range sf.Z[j] {
+			cell := ""
+			if z != nil {
+				cell = f.Number(*z)
+			}
+			row = append(row, cell)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteOBJ writes the surface as a Wavefront OBJ mesh: one vertex x y z per
+// evaluated cell and two triangles per grid square whose four corners were
+// evaluated.
+func (sf Surface) WriteOBJ(w io.Writer) error {
+
+	if _, err := fmt.Fprintf(w, "# %s selon %s (x) et %s (y)\n", sf.Metric, sf.XName, sf.YName); err != nil {
+		return err
+	}
+
+	// Vertex numbers start at 1; 0 marks a missing cell.
+	vertex := make([][]int, len(sf.Y))
+	n := 0
+	for j, y := range sf.Y {
+		vertex[j] = make([]int, len(sf.X))
+		for i, x := range sf.X {
+			if z := sf.Z[j][i]; z != nil {
+				n++
+				vertex[j][i] = n
+				fmt.Fprintf(w, "v %s %s %s\n", obj(x), obj(y), obj(*z))
+			}
+		}
+	}
+	for j := 0; j+1 < len(sf.Y); j++ {
+		for i := 0; i+1 < len(sf.X); i++ {
+			a, b, c, d := vertex[j][i], vertex[j][i+1], vertex[j+1][i+1], vertex[j+1][i]
+			if a == 0 || b == 0 || c == 0 || d == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "f %d %d %d\nf %d %d %d\n", a, b, c, a, c, d)
+		}
+	}
+	return nil
+}
+
+// obj formats a coordinate for WriteOBJ, which readers expect in decimal.
+func obj(v float64) string {
+	return strconv.FormatFloat(numfmt.Round(v), 'f', -1, 64)
+}
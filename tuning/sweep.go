@@ -0,0 +1,205 @@
+package tuning
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Axis is one swept scenario parameter and its range.
+type Axis struct {
+	Name string  `json:"name"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+}
+
+// Point is one evaluated cell of a sweep. Cost is nil when the simulation
+// diverged.
+type Point struct {
+	X     float64  `json:"x"`
+	Y     float64  `json:"y"`
+	Level int      `json:"level"`
+	Cost  *float64 `json:"cost"`
+}
+
+// Sweep is a two-parameter cost landscape that is first evaluated on a
+// coarse grid and then refined around its best cells, one level per call.
+type Sweep struct {
+	Base   simulation.Scenario `json:"scenario"`
+	X      Axis                `json:"x"`
+	Y      Axis                `json:"y"`
+	Metric string              `json:"metric"`
+	Level  int                 `json:"level"`
+	Points []Point             `json:"points"`
+
+	step [2]float64
+	seen map[string]bool
+}
+
+// MaxGrid bounds the number of cells per axis of the initial grid.
+const MaxGrid = 21
+
+// ValidateSweep reports why NewSweep would refuse the sweep, before any
+// cell is evaluated.
+func ValidateSweep(base simulation.Scenario, x, y Axis, metric string, n int) error {
+
+	if n < 2 || n > MaxGrid {
+		return fmt.Errorf("la grille doit compter entre 2 et %d points par axe", MaxGrid)
+	}
+	if !slices.Contains(simulation.MetricNames, metric) {
+		return fmt.Errorf("critère inconnu %q", metric)
+	}
+	for _, a := range []Axis{x, y} {
+		if _, err := base.Param(a.Name); err != nil {
+			return err
+		}
+		if !(a.Max > a.Min) {
+			return fmt.Errorf("intervalle vide pour %s", a.Name)
+		}
+	}
+	if x.Name == y.Name {
+		return fmt.Errorf("les deux axes balayent le même paramètre %s", x.Name)
+	}
+	return checkCorners(base, x, y)
+}
+
+// NewSweep evaluates the initial n×n grid.
+func NewSweep(base simulation.Scenario, x, y Axis, metric string, n int) (*Sweep, error) {
+
+	if err := ValidateSweep(base, x, y, metric, n); err != nil {
+		return nil, err
+	}
+
+	s := &Sweep{
+		Base:   base,
+		X:      x,
+		Y:      y,
+		Metric: metric,
+		step:   [2]float64{(x.Max - x.Min) / float64(n-1), (y.Max - y.Min) / float64(n-1)},
+		seen:   make(map[string]bool),
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			s.eval(x.Min+float64(i)*s.step[0], y.Min+float64(j)*s.step[1])
+		}
+	}
+	return s, nil
+}
+
+// Corners returns the base scenario at the four corners of the ranges,
+// between which every cell of the sweep lies. Unknown parameters are left
+// as in base.
+func Corners(base simulation.Scenario, x, y Axis) []simulation.Scenario {
+	var corners []simulation.Scenario
+	for _, vx := range []float64{x.Min, x.Max} {
+		for _, vy := range []float64{y.Min, y.Max} {
+			sc := base
+			if p, err := sc.Param(x.Name); err == nil {
+				*p = vx
+			}
+			if p, err := sc.Param(y.Name); err == nil {
+				*p = vy
+			}
+			corners = append(corners, sc)
+		}
+	}
+	return corners
+}
+
+// checkCorners reports the first corner of the ranges that is not a valid
+// scenario, by the scenario schema and Scenario.Check, so that a range
+// cannot reach values, such as a huge N, the scenario decoding refuses.
+func checkCorners(base simulation.Scenario, x, y Axis) error {
+	for _, sc := range Corners(base, x, y) {
+		raw, err := json.Marshal(sc)
+		if err != nil {
+			return err
+		}
+		var doc any
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+		errs := simulation.ScenarioSchema().Validate(doc)
+		if len(errs) == 0 {
+			errs = sc.Check()
+		}
+		if len(errs) > 0 {
+			vx, _ := sc.Param(x.Name)
+			vy, _ := sc.Param(y.Name)
+			return fmt.Errorf("scénario invalide au coin %s = %g, %s = %g : %s %s", x.Name, *vx, y.Name, *vy, errs[0].Path, errs[0].Message)
+		}
+	}
+	return nil
+}
+
+// Refine halves the grid step and evaluates the neighbourhood of the best
+// cells found so far. It returns only the newly evaluated points.
+func (s *Sweep) Refine(best int) []Point {
+
+	ranked := make([]Point, 0, len(s.Points))
+	for _, p := range s.Points {
+		if p.Cost != nil {
+			ranked = append(ranked, p)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return *ranked[i].Cost < *ranked[j].Cost })
+	if len(ranked) > best {
+		ranked = ranked[:best]
+	}
+
+	s.Level++
+	s.step[0] /= 2
+	s.step[1] /= 2
+
+	before := len(s.Points)
+	for _, p := range ranked {
+		for di := -1; di <= 1; di++ {
+			for dj := -1; dj <= 1; dj++ {
+				x := p.X + float64(di)*s.step[0]
+				y := p.Y + float64(dj)*s.step[1]
+				if x < s.X.Min || x > s.X.Max || y < s.Y.Min || y > s.Y.Max {
+					continue
+				}
+				s.eval(x, y)
+			}
+		}
+	}
+	return s.Points[before:]
+}
+
+func (s *Sweep) eval(x, y float64) {
+
+	// Neighbourhoods of different cells overlap; the rounded key absorbs
+	// the floating point noise of the halved steps.
+	key := fmt.Sprintf("%.9g,%.9g", x, y)
+	if s.seen[key] {
+		return
+	}
+	s.seen[key] = true
+
+	sc := s.Base
+	px, _ := sc.Param(s.X.Name)
+	py, _ := sc.Param(s.Y.Name)
+	*px, *py = x, y
+
+	p := Point{X: x, Y: y, Level: s.Level}
+	if cost, ok := Cost(sc, s.Metric); ok {
+		p.Cost = &cost
+	}
+	s.Points = append(s.Points, p)
+}
+
+// Cost simulates the scenario and returns the requested metric, or false
+// if the response diverged.
+func Cost(sc simulation.Scenario, metric string) (float64, bool) {
+
+	cost, err := Evaluate(sc).Get(metric)
+	if err != nil || math.IsNaN(cost) || math.IsInf(cost, 0) {
+		return 0, false
+	}
+	return cost, true
+}
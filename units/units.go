@@ -0,0 +1,117 @@
+// Package units reads and writes the electrical quantities with their
+// unit and an SI prefix, such as "20 kV" or "2,8 mH", so that a value
+// given in kV where volts are expected is either converted or refused
+// instead of being silently taken a thousand times too small.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+// Units of the electrical quantities.
+const (
+	Volt       = "V"
+	Ampere     = "A"
+	Watt       = "W"
+	Var        = "var"
+	VoltAmpere = "VA"
+	Ohm        = "Ω"
+	Henry      = "H"
+	Farad      = "F"
+	Siemens    = "S"
+	Hertz      = "Hz"
+	Second     = "s"
+	VarPerVolt = "var/V"
+)
+
+// aliases are the other spellings of the units, as typed on a keyboard
+// without Ω or as the grid codes write the reactive power.
+var aliases = map[string][]string{
+	Ohm: {"ohm", "Ohm", "ohms"},
+	Var: {"VAr", "VAR"},
+}
+
+// prefixes are the SI prefixes accepted before a unit, µ also written u.
+var prefixes = map[string]float64{
+	"G": 1e9, "M": 1e6, "k": 1e3,
+	"m": 1e-3, "µ": 1e-6, "μ": 1e-6, "u": 1e-6, "n": 1e-9,
+}
+
+// Split separates the number of a quantity written with the given unit
+// from its unit, returning the scale of its prefix: "20 kV" is "20" and
+// 1000 for Volt. A bare number has the scale 1; another unit, such as
+// "20 kW" for Volt, is an error.
+func Split(s, unit string) (number string, scale float64, err error) {
+
+	s = strings.TrimSpace(s)
+	end := len(s)
+	for end > 0 {
+		r, size := utf8.DecodeLastRuneInString(s[:end])
+		// No unit has an e, which belongs to the exponent of the number.
+		if !unicode.IsLetter(r) && r != '/' || r == 'e' || r == 'E' {
+			break
+		}
+		end -= size
+	}
+	number, suffix := strings.TrimSpace(s[:end]), s[end:]
+	if suffix == "" {
+		return number, 1, nil
+	}
+	for _, u := range append([]string{unit}, aliases[unit]...) {
+		if suffix == u {
+			return number, 1, nil
+		}
+		if p, ok := strings.CutSuffix(suffix, u); ok {
+			if scale, ok := prefixes[p]; ok {
+				return number, scale, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("unité invalide %q, attendu %s avec ou sans préfixe (k, M, m…)", suffix, unit)
+}
+
+// Parse reads a quantity written with the given unit, or without unit,
+// the number being read by numfmt.Parse: Parse("2,8 mH", Henry) is 0.0028.
+func Parse(s, unit string) (float64, error) {
+	number, scale, err := Split(s, unit)
+	if err != nil {
+		return 0, err
+	}
+	v, err := numfmt.Parse(number)
+	if err != nil {
+		return 0, err
+	}
+	return float64(v * scale), nil
+}
+
+// Format writes v in the unit with the SI prefix keeping between one and
+// three digits before the decimal point: Format(20000, Volt) is "20 kV".
+// A ratio, whose unit is empty, is written without prefix.
+func Format(v float64, unit string) string {
+
+	if unit == "" {
+		return strconv.FormatFloat(v, 'g', 4, 64)
+	}
+
+	prefix, scale := "", 1.0
+	if a := math.Abs(v); a != 0 && !math.IsInf(a, 0) && !math.IsNaN(a) {
+		for _, p := range []string{"G", "M", "k", "", "m", "µ", "n"} {
+			s := 1.0
+			if p != "" {
+				s = prefixes[p]
+			}
+			if a >= s || p == "n" {
+				prefix, scale = p, s
+				break
+			}
+		}
+	}
+	return strconv.FormatFloat(v/scale, 'g', 4, 64) + " " + prefix + unit
+}
@@ -0,0 +1,242 @@
+// Package validation checks the accuracy of the simulator against the
+// closed-loop responses known in closed form: a first-order plant under
+// P and PI control, simulated at several time steps, must follow the
+// analytic trajectory ever more closely as the step shrinks.
+package validation
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+	"github.com/Ivan69-tech/PIDControllerResponse/simulation"
+)
+
+// Case is a first-order plant K / (1 + Tau·s) under P or PI control,
+// answering a step of the setpoint to Sp from rest over Duration seconds.
+type Case struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	K           float64 `json:"K"`
+	Tau         float64 `json:"Tau"`
+	P           float64 `json:"P"`
+	Ki          float64 `json:"Ki"`
+	Sp          float64 `json:"Sp"`
+	Duration    float64 `json:"duration"`
+}
+
+// Cases lists the cases of the suite, one per form of the closed-loop
+// response.
+var Cases = []Case{
+	{
+		Name:        "p-first-order",
+		Description: "Action proportionnelle seule : premier ordre en boucle fermée, avec erreur statique",
+		K:           2, Tau: 5, P: 1.5, Sp: 1, Duration: 20,
+	},
+	{
+		Name:        "pi-overdamped",
+		Description: "PI, pôles réels distincts",
+		K:           1, Tau: 10, P: 2, Ki: 0.1, Sp: 1, Duration: 60,
+	},
+	{
+		Name:        "pi-critical",
+		Description: "PI, pôle réel double (amortissement critique)",
+		K:           1, Tau: 1, P: 1, Ki: 1, Sp: 1, Duration: 10,
+	},
+	{
+		Name:        "pi-underdamped",
+		Description: "PI, pôles complexes : réponse oscillante amortie",
+		K:           1, Tau: 10, P: 1, Ki: 1, Sp: 1, Duration: 60,
+	},
+}
+
+// Steps are the time steps of every case, in fractions of the time
+// constant of its fastest closed-loop pole.
+var Steps = []float64{0.1, 0.01, 0.001}
+
+// Tolerance bounds the largest error at the finest step, in fraction of
+// the setpoint.
+const Tolerance = 0.005
+
+// Analytic returns the closed-loop measurement of the case at time t.
+//
+// Under P control, Tau·y' + (1 + K·P)·y = K·P·Sp. Under PI control,
+// differentiating the loop gives Tau·y” + (1 + K·P)·y' + K·Ki·y = K·Ki·Sp,
+// from y(0) = 0 and y'(0) = K·P·Sp / Tau, the output starting at P·Sp.
+func (c Case) Analytic(t float64) float64 {
+
+	a := 1 + c.K*c.P
+	if c.Ki == 0 {
+		return c.K * c.P * c.Sp / a * (1 - math.Exp(-a*t/c.Tau))
+	}
+	b := c.K * c.Ki
+	dy0 := c.K * c.P * c.Sp / c.Tau
+	// Roots of Tau·s² + a·s + b.
+	disc := cmplx.Sqrt(complex(a*a-4*c.Tau*b, 0))
+	r1 := (complex(-a, 0) + disc) / complex(2*c.Tau, 0)
+	r2 := (complex(-a, 0) - disc) / complex(2*c.Tau, 0)
+	if cmplx.Abs(r1-r2) < 1e-9*cmplx.Abs(r1) {
+		A := -c.Sp
+		B := dy0 - real(r1)*A
+		return c.Sp + (A+B*t)*math.Exp(real(r1)*t)
+	}
+	// y = Sp + A·e^(r1·t) + B·e^(r2·t), A + B = -Sp, r1·A + r2·B = y'(0).
+	A := (complex(dy0, 0) + r2*complex(c.Sp, 0)) / (r1 - r2)
+	B := complex(-c.Sp, 0) - A
+	return c.Sp + real(A*cmplx.Exp(r1*complex(t, 0))+B*cmplx.Exp(r2*complex(t, 0)))
+}
+
+// timeConstant returns the time constant of the fastest closed-loop pole.
+func (c Case) timeConstant() float64 {
+	a := 1 + c.K*c.P
+	if c.Ki == 0 {
+		return c.Tau / a
+	}
+	disc := cmplx.Sqrt(complex(a*a-4*c.Tau*c.K*c.Ki, 0))
+	fastest := math.Min(real(complex(-a, 0)+disc), real(complex(-a, 0)-disc)) / (2 * c.Tau)
+	return -1 / fastest
+}
+
+// Run is the comparison of one simulation of a case with the analytic
+// response: the largest error over the samples, in units of the
+// measurement, and its time.
+type Run struct {
+	Dt       float64 `json:"dt"`
+	Steps    int     `json:"steps"`
+	MaxError float64 `json:"maxError"`
+	At       float64 `json:"at"`
+}
+
+// CaseReport is the outcome of a case: its runs from the coarsest step to
+// the finest. It passes when the error shrinks with the step and ends
+// below Tolerance times Sp.
+type CaseReport struct {
+	Case
+	Runs   []Run  `json:"runs"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Report is the outcome of the suite.
+type Report struct {
+	Passed    bool          `json:"passed"`
+	Tolerance float64       `json:"tolerance"`
+	Cases     []CaseReport  `json:"cases"`
+	Integral  IntegralCheck `json:"integral"`
+}
+
+// The long run of IntegralCheck: ten hours at a millisecond step.
+const (
+	LongRun   = 10 * 3600
+	LongRunDt = 1e-3
+	// IntegralTolerance bounds the relative error of the integral.
+	IntegralTolerance = 1e-12
+)
+
+// IntegralCheck integrates a constant error of 1 with a pure integral
+// controller over LongRun seconds at LongRunDt: the integral term must be
+// the elapsed time within IntegralTolerance, relative. NaiveError is the
+// relative error of the plain running sum of the same steps, which the
+// compensated summation of the controller avoids.
+type IntegralCheck struct {
+	Dt         float64 `json:"dt"`
+	Steps      int     `json:"steps"`
+	Expected   float64 `json:"expected"`
+	Integral   float64 `json:"integral"`
+	Error      float64 `json:"error"`
+	NaiveError float64 `json:"naiveError"`
+	Passed     bool    `json:"passed"`
+}
+
+// CheckIntegral runs the long integration of IntegralCheck.
+func CheckIntegral() IntegralCheck {
+
+	c := IntegralCheck{Dt: LongRunDt, Steps: int(math.Round(LongRun / LongRunDt))}
+	c.Expected = float64(c.Steps) * c.Dt
+	pid := simulation.NewPID(0, 1, 0)
+	naive := 0.0
+	for range c.Steps {
+		c.Integral = pid.Compute(1, 0, c.Dt)
+		naive += c.Dt
+	}
+	c.Error = math.Abs(c.Integral-c.Expected) / c.Expected
+	c.NaiveError = math.Abs(naive-c.Expected) / c.Expected
+	c.Passed = c.Error <= IntegralTolerance
+	return c
+}
+
+// Check simulates the case at every step of Steps.
+func (c Case) Check() CaseReport {
+
+	r := CaseReport{Case: c, Passed: true}
+	for _, step := range Steps {
+		r.Runs = append(r.Runs, c.run(float64(step*c.timeConstant())))
+	}
+	for i := 1; i < len(r.Runs); i++ {
+		if r.Runs[i].MaxError >= r.Runs[i-1].MaxError {
+			r.Passed = false
+			r.Reason = fmt.Sprintf("l'erreur ne diminue pas avec le pas : %g à dt = %g s, %g à dt = %g s",
+				r.Runs[i-1].MaxError, r.Runs[i-1].Dt, r.Runs[i].MaxError, r.Runs[i].Dt)
+			return r
+		}
+	}
+	if last := r.Runs[len(r.Runs)-1]; last.MaxError > Tolerance*math.Abs(c.Sp) {
+		r.Passed = false
+		r.Reason = fmt.Sprintf("erreur %g à t = %g s au pas le plus fin, au-delà de %g", last.MaxError, last.At, Tolerance*math.Abs(c.Sp))
+	}
+	return r
+}
+
+func (c Case) run(dt float64) Run {
+
+	n := math.Ceil(c.Duration / dt)
+	res := simulation.Scenario{
+		Plant: simulation.PlantFirstOrder,
+		Sp:    c.Sp, K: c.K, Tau: c.Tau,
+		P: c.P, Ki: c.Ki,
+		Dt: dt, N: n,
+	}.Run()
+	r := Run{Dt: dt, Steps: int(n)}
+	for k, t := range res.Time {
+		if e := math.Abs(res.PV[k] - c.Analytic(t)); e > r.MaxError {
+			r.MaxError, r.At = e, t
+		}
+	}
+	return r
+}
+
+// Suite runs every case of Cases and the integral check.
+func Suite() Report {
+	rep := Report{Passed: true, Tolerance: Tolerance}
+	for _, c := range Cases {
+		r := c.Check()
+		rep.Passed = rep.Passed && r.Passed
+		rep.Cases = append(rep.Cases, r)
+	}
+	rep.Integral = CheckIntegral()
+	rep.Passed = rep.Passed && rep.Integral.Passed
+	return rep
+}
+
+// Rounded returns a copy rounded for output, see numfmt.
+func (rep Report) Rounded() Report {
+	out := rep
+	out.Cases = make([]CaseReport, len(rep.Cases))
+	for i, c := range rep.Cases {
+		runs := make([]Run, len(c.Runs))
+		for j, r := range c.Runs {
+			runs[j] = Run{Dt: numfmt.Round(r.Dt), Steps: r.Steps, MaxError: numfmt.Round(r.MaxError), At: numfmt.Round(r.At)}
+		}
+		c.Runs = runs
+		out.Cases[i] = c
+	}
+	i := rep.Integral
+	out.Integral = IntegralCheck{
+		Dt: i.Dt, Steps: i.Steps, Passed: i.Passed,
+		Expected: numfmt.Round(i.Expected), Integral: numfmt.Round(i.Integral),
+		// The errors are what the check is about: kept unrounded.
+		Error: i.Error, NaiveError: i.NaiveError,
+	}
+	return out
+}
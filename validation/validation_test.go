@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"math"
+	"testing"
+)
+
+// TestCases simulates every case of Cases at each step of Steps: the error
+// must shrink with the step and end within Tolerance of the setpoint.
+func TestCases(t *testing.T) {
+
+	for _, c := range Cases {
+		t.Run(c.Name, func(t *testing.T) {
+
+			r := c.Check()
+			if len(r.Runs) != len(Steps) {
+				t.Fatalf("%d runs for %d steps", len(r.Runs), len(Steps))
+			}
+			for _, run := range r.Runs {
+				t.Logf("dt = %g s: max error %g at t = %g s", run.Dt, run.MaxError, run.At)
+			}
+			switch last := r.Runs[len(r.Runs)-1]; {
+			case last.MaxError > Tolerance*math.Abs(c.Sp):
+				t.Errorf("max error %g at t = %g s at the finest step, above %g", last.MaxError, last.At, Tolerance*math.Abs(c.Sp))
+			case !r.Passed:
+				t.Error(r.Reason)
+			}
+		})
+	}
+}
+
+// TestCheckIntegral checks that the compensated integral of the long run
+// stays within IntegralTolerance where the plain running sum drifts out.
+func TestCheckIntegral(t *testing.T) {
+
+	c := CheckIntegral()
+	if !c.Passed || c.Error > IntegralTolerance {
+		t.Errorf("integral %v after %d steps, want %v: relative error %g above %g", c.Integral, c.Steps, c.Expected, c.Error, IntegralTolerance)
+	}
+	if c.NaiveError <= IntegralTolerance {
+		t.Errorf("naive sum relative error %g within %g, the run no longer shows the drift it guards against", c.NaiveError, IntegralTolerance)
+	}
+}
@@ -0,0 +1,238 @@
+// Package ws implements the server side of the WebSocket protocol (RFC 6455)
+// for the simulator's streaming endpoints, using only the standard library.
+package ws
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Ivan69-tech/PIDControllerResponse/numfmt"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// MaxMessageSize bounds the size of a message read from a client.
+const MaxMessageSize = 1 << 20
+
+// ErrClosed is returned by ReadMessage once the peer closed the connection.
+var ErrClosed = errors.New("ws: connexion fermée")
+
+// Conn is an upgraded WebSocket connection. Writes are safe for concurrent
+// use; reads must happen from a single goroutine.
+type Conn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex
+}
+
+// AllowedOrigins are the origins, such as "https://scada.example.com",
+// allowed to open a connection besides that of the server itself; "*"
+// allows any. Browsers send the Origin of the page opening the connection,
+// and without this check any web site could open the streams with the
+// user's access to the server (cross-site WebSocket hijacking).
+var AllowedOrigins []string
+
+// sameOrigin reports whether the request may be upgraded: it has no Origin
+// header, as from a program rather than a browser, or an origin whose host
+// is that of the server or which is allowed.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if slices.Contains(AllowedOrigins, "*") || slices.Contains(AllowedOrigins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// IsUpgrade reports whether the request asks for a WebSocket upgrade.
+func IsUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// Upgrade performs the opening handshake and takes over the connection,
+// refusing with 403 a browser page of a foreign origin, see
+// AllowedOrigins. On failure an HTTP error has already been written.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !IsUpgrade(r) || key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		http.Error(w, "Connexion WebSocket attendue", http.StatusBadRequest)
+		return nil, errors.New("ws: requête d'upgrade invalide")
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "Origine non autorisée", http.StatusForbidden)
+		return nil, errors.New("ws: origine non autorisée " + r.Header.Get("Origin"))
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket non supporté", http.StatusInternalServerError)
+		return nil, errors.New("ws: hijack impossible")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: conn, rw: rw}, nil
+}
+
+// WriteJSON sends v as a text message, NaN and infinities written null.
+func (c *Conn) WriteJSON(v any) error {
+	b, _, err := numfmt.MarshalJSON(v, numfmt.NonFiniteNull)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(opText, b)
+}
+
+// WriteText sends a text message.
+func (c *Conn) WriteText(b []byte) error {
+	return c.writeFrame(opText, b)
+}
+
+// ReadMessage returns the next text or binary message, answering pings on
+// the way. It returns ErrClosed when the client closes the connection.
+func (c *Conn) ReadMessage() ([]byte, error) {
+
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			c.writeFrame(opClose, nil)
+			return nil, ErrClosed
+		case opText, opBinary, opContinuation:
+			msg = append(msg, payload...)
+			if len(msg) > MaxMessageSize {
+				return nil, errors.New("ws: message trop volumineux")
+			}
+			if fin {
+				return msg, nil
+			}
+		}
+	}
+}
+
+// Close sends a close frame and closes the underlying connection.
+func (c *Conn) Close() error {
+	c.writeFrame(opClose, nil)
+	return c.conn.Close()
+}
+
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+
+	var head [2]byte
+	if _, err = io.ReadFull(c.rw, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > MaxMessageSize {
+		err = errors.New("ws: trame trop volumineuse")
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.rw, mask[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.rw, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}